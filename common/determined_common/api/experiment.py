@@ -218,7 +218,8 @@ def generate_random_hparam_values(hparam_def: Dict[str, Any]) -> Dict[str, Any]:
             if hparam["type"] == "const":
                 return hparam["val"]
             elif hparam["type"] == "int":
-                return random.randint(hparam["minval"], hparam["maxval"])
+                step = hparam.get("step", 1)
+                return random.randrange(hparam["minval"], hparam["maxval"] + 1, step)
             elif hparam["type"] == "double":
                 return random.uniform(hparam["minval"], hparam["maxval"])
             elif hparam["type"] == "categorical":
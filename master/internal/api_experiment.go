@@ -85,8 +85,11 @@ func (a *apiServer) PreviewHPSearch(
 		return nil, status.Errorf(codes.InvalidArgument, "invalid experiment config: %s", err)
 	}
 
-	sm := searcher.NewSearchMethod(config.Searcher)
-	s := searcher.NewSearcher(req.Seed, sm, config.Hyperparameters)
+	sm, err := searcher.NewSearchMethod(config.Searcher)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid experiment config: %s", err)
+	}
+	s := searcher.NewSearcher(req.Seed, sm, config.Hyperparameters, config.Searcher.Constraints)
 	sim, err := searcher.Simulate(s, nil, searcher.RandomValidation, true, config.Searcher.Metric)
 	if err != nil {
 		return nil, err
@@ -25,8 +25,11 @@ func (m *Master) getSearcherPreview(c echo.Context) (interface{}, error) {
 		return nil, verr
 	}
 
-	sm := searcher.NewSearchMethod(config.Searcher)
-	s := searcher.NewSearcher(0, sm, config.Hyperparameters)
+	sm, serr := searcher.NewSearchMethod(config.Searcher)
+	if serr != nil {
+		return nil, serr
+	}
+	s := searcher.NewSearcher(0, sm, config.Hyperparameters, config.Searcher.Constraints)
 	return searcher.Simulate(s, nil, searcher.RandomValidation, true, config.Searcher.Metric)
 }
 
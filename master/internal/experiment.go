@@ -3,6 +3,7 @@ package internal
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -13,12 +14,16 @@ import (
 	"github.com/determined-ai/determined/master/internal/scheduler"
 	"github.com/determined-ai/determined/master/internal/telemetry"
 	"github.com/determined-ai/determined/master/pkg/actor"
+	"github.com/determined-ai/determined/master/pkg/actor/actors"
 	"github.com/determined-ai/determined/master/pkg/archive"
 	"github.com/determined-ai/determined/master/pkg/model"
 	"github.com/determined-ai/determined/master/pkg/searcher"
 	"github.com/determined-ai/determined/proto/pkg/apiv1"
 )
 
+// searcherTimeoutInterval is how often the searcher is prompted to close trials that have stalled.
+const searcherTimeoutInterval = time.Minute
+
 // Experiment-specific actor messages.
 type (
 	trialCreated struct {
@@ -37,13 +42,31 @@ type (
 	}
 	trialExitedEarly struct {
 		trialID      int
-		exitedReason *searcher.ExitedReason
+		exitedReason searcher.ExitedReason
+	}
+	// trialNextValidationStep asks where the trial should next validate within its current
+	// operations; the response is a zero length if the search method has no preference.
+	trialNextValidationStep struct {
+		requestID searcher.RequestID
+		trained   model.Length
+	}
+	// trialResumed reports that a trial has been rolled back to a checkpoint at which it had
+	// completed the given length of training; the response is whether the search method replaced
+	// the operations the trial had yet to complete.
+	trialResumed struct {
+		trialID   int
+		completed model.Length
 	}
 	getProgress    struct{}
 	getTrial       struct{ trialID int }
 	restoreTrials  struct{}
 	trialsRestored struct{}
 	killExperiment struct{}
+	// checkSearcherTimeouts periodically prompts the searcher to close trials that have stalled.
+	checkSearcherTimeouts struct{}
+	// drainSearcherOperations handles the next operations that the searcher held back because of
+	// max_operations_per_call.
+	drainSearcherOperations struct{}
 
 	// doneProcessingSearcherOperations message is only used during master restart, to ensure that
 	// all the searcher operations created by a given event (experiment created / trial created /
@@ -84,6 +107,8 @@ type experiment struct {
 	warmStartCheckpoint *model.Checkpoint
 	bestValidation      *float64
 	replaying           bool
+	// drainScheduled is whether a drainSearcherOperations message is on its way.
+	drainScheduled bool
 
 	pendingEvents []*model.SearcherEvent
 
@@ -95,8 +120,13 @@ type experiment struct {
 // the returned object's ID appropriately.
 func newExperiment(master *Master, expModel *model.Experiment) (*experiment, error) {
 	conf := expModel.Config
-	method := searcher.NewSearchMethod(conf.Searcher)
-	search := searcher.NewSearcher(conf.Reproducibility.ExperimentSeed, method, conf.Hyperparameters)
+	method, err := searcher.NewSearchMethod(conf.Searcher)
+	if err != nil {
+		return nil, err
+	}
+	search := searcher.NewSearcher(
+		conf.Reproducibility.ExperimentSeed, method, conf.Hyperparameters, conf.Searcher.Constraints)
+	search.SetMaxOperationsPerCall(conf.Searcher.MaxOperationsPerCall)
 
 	// Retrieve the warm start checkpoint, if provided.
 	checkpoint, err := checkpointFromTrialIDOrUUID(
@@ -283,11 +313,25 @@ func (e *experiment) Receive(ctx *actor.Context) error {
 		ctx.Tell(e.rp, scheduler.SetWeight{Weight: e.Config.Resources.Weight, Handler: ctx.Self()})
 		ops, err := e.searcher.InitialOperations()
 		e.processOperations(ctx, ops, err)
+		actors.NotifyAfter(ctx, searcherTimeoutInterval, checkSearcherTimeouts{})
+	case checkSearcherTimeouts:
+		// Timeouts are only checked against live trials, not while replaying past events.
+		if !e.replaying {
+			ops, err := e.searcher.CheckTimeouts()
+			e.processOperations(ctx, ops, err)
+		}
+		actors.NotifyAfter(ctx, searcherTimeoutInterval, checkSearcherTimeouts{})
+	case drainSearcherOperations:
+		e.drainScheduled = false
+		e.processOperations(ctx, e.searcher.DrainPending(), nil)
 	case trialCreated:
 		ops, err := e.searcher.TrialCreated(msg.create, msg.trialID)
 		e.processOperations(ctx, ops, err)
 	case trialCompletedOperation:
 		ops, err := e.searcher.OperationCompleted(msg.trialID, msg.op, msg.metrics)
+		if kind, _ := searcher.ErrorKindOf(err); kind == searcher.MetricErrorKind {
+			e.reportToTrial(ctx, msg.trialID, err)
+		}
 		e.processOperations(ctx, ops, err)
 	case trialCompletedWorkload:
 		e.searcher.WorkloadCompleted(msg.completedMessage, msg.unitsCompleted)
@@ -303,8 +347,21 @@ func (e *experiment) Receive(ctx *actor.Context) error {
 		if err := e.db.SaveExperimentProgress(e.ID, &progress); err != nil {
 			ctx.Log().WithError(err).Error("failed to save experiment progress")
 		}
+	case trialNextValidationStep:
+		step, _ := e.searcher.NextValidationStep(msg.requestID, msg.trained)
+		ctx.Respond(step)
+	case trialResumed:
+		ops, err := e.searcher.TrialResumed(msg.trialID, msg.completed)
+		replaced := false
+		for _, op := range ops {
+			if _, ok := op.(searcher.Runnable); ok {
+				replaced = err == nil
+			}
+		}
+		e.processOperations(ctx, ops, err)
+		ctx.Respond(replaced)
 	case trialExitedEarly:
-		ops, err := e.searcher.TrialExitedEarly(msg.trialID)
+		ops, err := e.searcher.TrialExitedEarly(msg.trialID, msg.exitedReason)
 		e.processOperations(ctx, ops, err)
 	case actor.ChildFailed:
 		ctx.Log().WithError(msg.Error).Error("trial failed unexpectedly")
@@ -466,7 +523,12 @@ func (e *experiment) processOperations(
 		return
 	}
 	if err != nil {
-		ctx.Log().Error(err)
+		message, isBug := searcherFailureMessage(err)
+		if isBug {
+			ctx.Log().WithError(err).Error(message)
+		} else {
+			ctx.Log().Error(message)
+		}
 		e.updateState(ctx, model.StoppingErrorState)
 		return
 	}
@@ -542,6 +604,48 @@ func (e *experiment) processOperations(
 			e.pendingEvents = e.pendingEvents[:0]
 		}
 	}
+
+	if e.searcher.HasPendingOperations() {
+		if e.replaying {
+			// The next event replayed may be reported by a trial for one of the operations, so they
+			// are all handled before it is.
+			e.processOperations(ctx, e.searcher.DrainPending(), nil)
+		} else if !e.drainScheduled {
+			e.drainScheduled = true
+			ctx.Tell(ctx.Self(), drainSearcherOperations{})
+		}
+	}
+}
+
+// searcherFailureMessage returns the message that the experiment is stopped with when the searcher
+// fails with the error, and whether the failure is a bug in the searcher rather than a problem with
+// the experiment that the user can fix.
+func searcherFailureMessage(err error) (string, bool) {
+	kind, _ := searcher.ErrorKindOf(err)
+	switch kind {
+	case searcher.ConfigErrorKind:
+		return fmt.Sprintf(
+			"experiment stopped: invalid searcher configuration: %s", errors.Cause(err)), false
+	case searcher.MetricErrorKind:
+		return fmt.Sprintf(
+			"experiment stopped: unusable validation metric: %s", errors.Cause(err)), false
+	case searcher.InternalErrorKind:
+		return "experiment stopped: internal searcher error; this is a bug", true
+	default:
+		return fmt.Sprintf("experiment stopped: searcher failed: %s", err), false
+	}
+}
+
+// reportToTrial writes an error that the user can act on, e.g., a searcher metric that the trial
+// does not report, to the logs of the trial so that it is shown alongside the trial's own output.
+func (e *experiment) reportToTrial(ctx *actor.Context, trialID int, err error) {
+	if e.replaying || e.trialLogger == nil {
+		return
+	}
+	ctx.Tell(e.trialLogger, model.TrialLog{
+		TrialID: trialID,
+		Message: fmt.Sprintf("experiment failed: %s\n", errors.Cause(err)),
+	})
 }
 
 func (e *experiment) isBestValidation(metrics searcher.ValidationMetrics) bool {
@@ -3,6 +3,7 @@ package internal
 import (
 	"testing"
 
+	"github.com/pkg/errors"
 	"gotest.tools/assert"
 
 	"github.com/determined-ai/determined/master/pkg/model"
@@ -49,3 +50,30 @@ func TestBestValidation(t *testing.T) {
 		[]metricCase{{5, true}, {9, true}, {4, false}, {10, true}, {7, false}, {3, false}},
 	)
 }
+
+func TestSearcherFailureMessage(t *testing.T) {
+	message, isBug := searcherFailureMessage(errors.Wrap(errors.WithStack(searcher.ConfigError{
+		Err: errors.New("max_length must be in batches"),
+	}), "error relaying trial resumed to trial 1"))
+	assert.Equal(t, message,
+		"experiment stopped: invalid searcher configuration: max_length must be in batches")
+	assert.Assert(t, !isBug)
+
+	_, err := searcher.ValidationMetrics{
+		Metrics: map[string]interface{}{"loss": "high"},
+	}.Metric("loss")
+	message, isBug = searcherFailureMessage(errors.Wrap(err, "error relaying validation"))
+	assert.Equal(t, message,
+		"experiment stopped: unusable validation metric: 'loss' is not a scalar float value")
+	assert.Assert(t, !isBug)
+
+	message, isBug = searcherFailureMessage(errors.WithStack(searcher.InternalError{
+		Err: errors.New("cannot resume unknown trial"),
+	}))
+	assert.Equal(t, message, "experiment stopped: internal searcher error; this is a bug")
+	assert.Assert(t, isBug)
+
+	message, isBug = searcherFailureMessage(errors.New("no more trials"))
+	assert.Equal(t, message, "experiment stopped: searcher failed: no more trials")
+	assert.Assert(t, !isBug)
+}
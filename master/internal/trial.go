@@ -4,6 +4,7 @@ import (
 	"archive/tar"
 	"encoding/json"
 	"fmt"
+	"math"
 	"sort"
 	"time"
 
@@ -157,6 +158,9 @@ type trial struct {
 	close  *searcher.Close
 
 	sequencer *trialWorkloadSequencer
+	// validationHints is whether the search method of the experiment asks trials to validate within
+	// their operations; if not, the trial never asks where to validate next.
+	validationHints bool
 
 	// restarts is essentially a failure count, it increments when the trial fails and we retry it.
 	restarts int
@@ -212,7 +216,8 @@ func newTrial(
 		modelDefinition:       exp.modelDefinition,
 		warmStartCheckpointID: warmStartCheckpointID,
 
-		sequencer: newTrialWorkloadSequencer(exp.Experiment, create, firstCheckpoint),
+		sequencer:       newTrialWorkloadSequencer(exp.Experiment, create, firstCheckpoint),
+		validationHints: exp.searcher.HintsValidations(),
 
 		create:    create,
 		replaying: exp.replaying,
@@ -232,16 +237,21 @@ func (t *trial) Receive(ctx *actor.Context) error {
 	case model.State:
 		t.experimentState = msg
 	case []searcher.Operation:
+		requested := false
 		for _, operation := range msg {
 			switch op := operation.(type) {
 			case searcher.Runnable:
 				if err := t.sequencer.OperationRequested(op); err != nil {
 					return errors.Wrap(err, "error passing runnable to sequencer")
 				}
+				requested = true
 			case searcher.Close:
 				t.close = &op
 			}
 		}
+		if requested {
+			t.updateValidationHint(ctx)
+		}
 
 	// Restoration-related messages.
 	case trialCreated:
@@ -501,6 +511,20 @@ func (t *trial) processAssigned(ctx *actor.Context, msg scheduler.TaskAssigned)
 	return nil
 }
 
+// updateValidationHint asks the experiment where the search method would have the trial validate
+// next within its current operations and passes it on to the sequencer.
+func (t *trial) updateValidationHint(ctx *actor.Context) {
+	if !t.validationHints {
+		return
+	}
+	trained := model.NewLengthFromBatches(
+		t.sequencer.totalBatchesProcessed, t.sequencer.unitContext)
+	resp := ctx.Ask(ctx.Self().Parent(), trialNextValidationStep{t.create.RequestID, trained})
+	if step, ok := resp.Get().(model.Length); ok {
+		t.sequencer.SetValidationHint(step)
+	}
+}
+
 func (t *trial) processCompletedWorkload(ctx *actor.Context, msg searcher.CompletedMessage) error {
 	if !t.replaying && (msg.ExitedReason == nil || *msg.ExitedReason == searcher.UserCanceled) {
 		if err := markWorkloadCompleted(t.db, msg); err != nil {
@@ -519,6 +543,9 @@ func (t *trial) processCompletedWorkload(ctx *actor.Context, msg searcher.Comple
 	case op != nil:
 		ctx.Tell(ctx.Self().Parent(), trialCompletedOperation{t.id, op, metrics})
 	}
+	if msg.Workload.Kind == searcher.ComputeValidationMetrics {
+		t.updateValidationHint(ctx)
+	}
 
 	switch op, metrics, err = t.sequencer.CompleteCachedCheckpoints(); {
 	case err != nil:
@@ -531,9 +558,11 @@ func (t *trial) processCompletedWorkload(ctx *actor.Context, msg searcher.Comple
 	terminateNow := false
 	if msg.ExitedReason != nil {
 		ctx.Log().Info("exiting trial early")
-		ctx.Tell(ctx.Self().Parent(), trialExitedEarly{t.id, msg.ExitedReason})
+		reason := exitedReason(msg, t.experiment.Config.Searcher.Metric)
+		ctx.Tell(ctx.Self().Parent(), trialExitedEarly{t.id, reason})
 		t.earlyExit = true
-		if *msg.ExitedReason == searcher.Errored {
+		// An errored or preempted trial has no task left to run workloads in.
+		if *msg.ExitedReason == searcher.Errored || *msg.ExitedReason == searcher.Preempted {
 			return nil
 		}
 	}
@@ -838,6 +867,31 @@ func classifyStatus(state terminatedContainerWithState) agent.ContainerStopped {
 	}
 }
 
+// exitedReason returns why the trial exited early with the completed message, as the searcher is
+// to be told: a trial that errored with a non-finite value of the searcher metric diverged.
+func exitedReason(msg searcher.CompletedMessage, metric string) searcher.ExitedReason {
+	reason := *msg.ExitedReason
+	if reason != searcher.Errored || msg.ValidationMetrics == nil {
+		return reason
+	}
+	if value, err := msg.ValidationMetrics.Metric(metric); err == nil &&
+		(math.IsNaN(value) || math.IsInf(value, 0)) {
+		return searcher.Diverged
+	}
+	return reason
+}
+
+// failureExitedReason returns why a trial that has run out of restarts after the failure exited
+// early: a trial that lost its agent, or was never given one, was preempted rather than errored.
+func failureExitedReason(failure agent.ContainerFailure) searcher.ExitedReason {
+	switch failure.FailureType {
+	case agent.AgentFailed, agent.AgentError:
+		return searcher.Preempted
+	default:
+		return searcher.Errored
+	}
+}
+
 func (t *trial) resetTrial(
 	ctx *actor.Context,
 	status agent.ContainerStopped,
@@ -886,7 +940,7 @@ func (t *trial) resetTrial(
 			}
 		}
 	}
-	e := searcher.Errored
+	e := failureExitedReason(*status.Failure)
 	w, err := t.sequencer.Workload()
 	if err != nil {
 		ctx.Log().Error(err)
@@ -926,6 +980,24 @@ func (t *trial) restore(ctx *actor.Context) {
 	if err := t.db.RollBackTrial(t.id, step); err != nil {
 		ctx.Log().Error(err)
 	}
+
+	// The rollback cleared the validation hint, which was for the training that was lost.
+	t.updateValidationHint(ctx)
+	if !t.replaying {
+		t.resumeFromCheckpoint(ctx)
+	}
+}
+
+// resumeFromCheckpoint tells the experiment how much training the trial had completed as of the
+// checkpoint it was rolled back to, so that the search method may replace the operations the trial
+// has yet to complete with ones for only the training that is left.
+func (t *trial) resumeFromCheckpoint(ctx *actor.Context) {
+	completed := model.NewLengthFromBatches(
+		t.sequencer.totalBatchesProcessed, t.sequencer.unitContext)
+	resp := ctx.Ask(ctx.Self().Parent(), trialResumed{t.id, completed})
+	if replaced, ok := resp.Get().(bool); ok && replaced {
+		t.sequencer.ReplacePendingOperations()
+	}
 }
 
 func (t *trial) trialClosing() bool {
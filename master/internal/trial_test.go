@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"math"
 	"sort"
 	"strconv"
 	"testing"
@@ -13,6 +14,7 @@ import (
 	"github.com/determined-ai/determined/master/pkg/actor/api"
 	"github.com/determined-ai/determined/master/pkg/agent"
 	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/searcher"
 )
 
 type mockActor struct {
@@ -208,3 +210,46 @@ func TestRendezvousInfo(t *testing.T) {
 		}
 	})
 }
+
+func TestExitedReason(t *testing.T) {
+	errored := searcher.Errored
+	canceled := searcher.UserCanceled
+	metrics := func(value interface{}) *searcher.ValidationMetrics {
+		return &searcher.ValidationMetrics{Metrics: map[string]interface{}{"loss": value}}
+	}
+	for _, c := range []struct {
+		msg      searcher.CompletedMessage
+		expected searcher.ExitedReason
+	}{
+		{searcher.CompletedMessage{ExitedReason: &errored}, searcher.Errored},
+		{searcher.CompletedMessage{ExitedReason: &canceled}, searcher.UserCanceled},
+		{
+			searcher.CompletedMessage{ExitedReason: &errored, ValidationMetrics: metrics(0.5)},
+			searcher.Errored,
+		},
+		{
+			searcher.CompletedMessage{ExitedReason: &errored, ValidationMetrics: metrics(math.NaN())},
+			searcher.Diverged,
+		},
+		{
+			searcher.CompletedMessage{ExitedReason: &errored, ValidationMetrics: metrics(math.Inf(1))},
+			searcher.Diverged,
+		},
+		{
+			searcher.CompletedMessage{ExitedReason: &canceled, ValidationMetrics: metrics(math.NaN())},
+			searcher.UserCanceled,
+		},
+	} {
+		assert.Equal(t, exitedReason(c.msg, "loss"), c.expected)
+	}
+
+	for failureType, expected := range map[agent.FailureType]searcher.ExitedReason{
+		agent.AgentFailed:     searcher.Preempted,
+		agent.AgentError:      searcher.Preempted,
+		agent.ContainerFailed: searcher.Errored,
+		agent.TaskError:       searcher.Errored,
+	} {
+		failure := agent.ContainerFailure{FailureType: failureType}
+		assert.Equal(t, failureExitedReason(failure), expected, "failure type %s", failureType)
+	}
+}
@@ -27,6 +27,13 @@ type trialWorkloadSequencer struct {
 	checkpointPolicy    string
 	minValidationPeriod model.Length
 	minCheckpointPeriod model.Length
+	// validationHint is the total length of training at which the search method has asked the
+	// trial to validate next, within its current operations; it is unset if Units is 0.
+	validationHint model.Length
+
+	// replacing is whether the next operation requested replaces the Train and Validate operations
+	// the trial has yet to complete, rather than following them; see ReplacePendingOperations.
+	replacing bool
 
 	unitContext model.UnitContext
 
@@ -116,6 +123,9 @@ func (s *trialWorkloadSequencer) WorkloadManagerType() model.WorkloadManagerType
 func (s *trialWorkloadSequencer) OperationRequested(op searcher.Runnable) error {
 	switch op := op.(type) {
 	case searcher.Runnable:
+		if s.replacing {
+			s.dropPendingOperations()
+		}
 		s.ops = append(s.ops, op)
 	default:
 		return errors.Errorf("illegal workload for trialWorkloadSequencer: %v", op)
@@ -123,6 +133,37 @@ func (s *trialWorkloadSequencer) OperationRequested(op searcher.Runnable) error
 	return nil
 }
 
+// ReplacePendingOperations makes the operations next requested replace the Train and Validate
+// operations the trial has yet to complete, as when the search method has planned the training left
+// for a trial resumed from a checkpoint. Pending Checkpoint operations are kept.
+func (s *trialWorkloadSequencer) ReplacePendingOperations() {
+	s.replacing = true
+}
+
+// dropPendingOperations drops the Train and Validate operations the trial has yet to complete,
+// including any progress toward the current one.
+func (s *trialWorkloadSequencer) dropPendingOperations() {
+	s.replacing = false
+	kept := s.ops[:s.curOpIdx:s.curOpIdx]
+	for _, op := range s.ops[s.curOpIdx:] {
+		if _, ok := op.(searcher.Checkpoint); ok {
+			kept = append(kept, op)
+		}
+	}
+	s.ops = kept
+	s.batchesTowardsCurrentOp = 0
+	// The snapshot is of the checkpoint the trial resumed from, and is rolled back to again if the
+	// trial fails before its next checkpoint; its progress was toward the dropped operation, too.
+	s.latestCheckpointSequencerSnapshot.batchesTowardsCurrentOp = 0
+}
+
+// SetValidationHint records the total length of training at which the search method has asked the
+// trial to validate next, as returned by searcher.NextValidationStep; a zero length clears it. Such
+// a validation is not reported to the searcher as completing a Validate operation.
+func (s *trialWorkloadSequencer) SetValidationHint(step model.Length) {
+	s.validationHint = step
+}
+
 // CompleteCachedCheckpoints attempts to complete cached checkpoints that we received previously
 // but did not need yet.
 func (s *trialWorkloadSequencer) CompleteCachedCheckpoints() (
@@ -295,7 +336,7 @@ func (s trialWorkloadSequencer) Workload() (searcher.Workload, error) {
 		return s.checkpoint(), nil
 	}
 
-	if s.minValidationNeeded() {
+	if s.minValidationNeeded() || s.hintedValidationNeeded() {
 		return s.validate(), nil
 	}
 
@@ -317,10 +358,12 @@ func (s trialWorkloadSequencer) Workload() (searcher.Workload, error) {
 	case searcher.Train:
 		batchesLeft := tOp.Length.ToNearestBatch(s.unitContext) - s.batchesTowardsCurrentOp
 		batchesTilVal := s.batchesUntilValNeeded()
+		batchesTilHint := s.batchesUntilHintedVal()
 		batchesTilCkpt := s.batchesUntilCkptNeeded()
 		batchesThisStep := min(
 			batchesLeft,
 			batchesTilVal,
+			batchesTilHint,
 			batchesTilCkpt,
 			s.schedulingUnit,
 		)
@@ -354,9 +397,11 @@ func (s trialWorkloadSequencer) TerminateWorkload() *searcher.Workload {
 	}
 }
 
-// Rollback sequencer rolls back the sequencer to the last available step with a checkpoint.
+// Rollback sequencer rolls back the sequencer to the last available step with a checkpoint. The
+// validation hint is cleared, since it was for the training done before the rollback.
 func (s *trialWorkloadSequencer) RollBackSequencer() int {
 	s.trialWorkloadSequencerState = s.latestCheckpointSequencerSnapshot.deepCopy()
+	s.validationHint = model.Length{}
 	return s.curStepID
 }
 
@@ -416,6 +461,24 @@ func (s *trialWorkloadSequencer) batchesUntilValNeeded() int {
 	return s.minValidationPeriod.ToNearestBatch(s.unitContext) - s.batchesSinceLastVal
 }
 
+func (s *trialWorkloadSequencer) hintedValidationNeeded() bool {
+	if s.validationHint.Units == 0 || s.batchesSinceLastVal == 0 {
+		return false
+	}
+	return s.validationHint.EqualWithinBatch(s.totalBatchesProcessed, s.unitContext)
+}
+
+func (s *trialWorkloadSequencer) batchesUntilHintedVal() int {
+	if s.validationHint.Units == 0 {
+		return math.MaxInt32
+	}
+	batches := s.validationHint.ToNearestBatch(s.unitContext) - s.totalBatchesProcessed
+	if batches <= 0 {
+		return math.MaxInt32
+	}
+	return batches
+}
+
 func (s *trialWorkloadSequencer) minCheckpointNeeded() bool {
 	if s.minCheckpointPeriod.Units == 0 {
 		return false
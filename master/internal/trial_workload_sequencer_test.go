@@ -283,3 +283,196 @@ checkpoint_policy: none
 	_, err = s.Workload()
 	assert.Error(t, err, "cannot call sequencer.Workload() with sequencer.UpToDate() == true")
 }
+
+func TestTrialWorkloadSequencerValidationHint(t *testing.T) {
+	yam := `
+checkpoint_storage:
+  type: s3
+  access_key: my key
+  secret_key: my secret
+  bucket: my bucket
+hyperparameters:
+  global_batch_size: 64
+searcher:
+  name: single
+  metric: loss
+  max_length:
+    batches: 500
+checkpoint_policy: none
+`
+	expConfig := model.DefaultExperimentConfig()
+	assert.NilError(t, yaml.Unmarshal([]byte(yam), &expConfig, yaml.DisallowUnknownFields))
+	experiment := &model.Experiment{ID: 1, State: model.ActiveState, Config: expConfig}
+	create := searcher.NewCreate(nprand.New(0), map[string]interface{}{
+		model.GlobalBatchSize: 64,
+	}, model.TrialWorkloadSequencerType)
+
+	s := newTrialWorkloadSequencer(experiment, create, nil)
+	s.SetTrialID(1)
+	train := searcher.NewTrain(create.RequestID, model.NewLength(model.Batches, 500))
+	assert.NilError(t, s.OperationRequested(train))
+	assert.NilError(t, s.OperationRequested(searcher.NewValidate(create.RequestID)))
+
+	// next completes the workload the sequencer asks for next and returns it.
+	next := func() searcher.Workload {
+		w, err := s.Workload()
+		assert.NilError(t, err)
+		msg := searcher.CompletedMessage{Workload: w}
+		if w.Kind == searcher.ComputeValidationMetrics {
+			msg.ValidationMetrics = &searcher.ValidationMetrics{}
+		}
+		op, _, err := s.WorkloadCompleted(msg, nil)
+		assert.NilError(t, err)
+		if w.TotalBatchesProcessed+w.NumBatches < 500 {
+			assert.Equal(t, op, nil, "should not have finished %v yet", op)
+		}
+		return w
+	}
+	trainWorkload := func(stepID, numBatches, processed int) searcher.Workload {
+		return searcher.Workload{
+			Kind:                  searcher.RunStep,
+			ExperimentID:          1,
+			TrialID:               1,
+			StepID:                stepID,
+			NumBatches:            numBatches,
+			TotalBatchesProcessed: processed,
+		}
+	}
+	validationWorkload := func(stepID, processed int) searcher.Workload {
+		return searcher.Workload{
+			Kind:                  searcher.ComputeValidationMetrics,
+			ExperimentID:          1,
+			TrialID:               1,
+			StepID:                stepID,
+			TotalBatchesProcessed: processed,
+		}
+	}
+
+	// The trial validates at each hinted step in the middle of the Train operation, with steps cut
+	// short to reach it; those validations do not complete the Validate operation.
+	s.SetValidationHint(model.NewLength(model.Batches, 150))
+	assert.Equal(t, next(), trainWorkload(1, 100, 0))
+	assert.Equal(t, next(), trainWorkload(2, 50, 100))
+	assert.Equal(t, next(), validationWorkload(2, 150))
+	s.SetValidationHint(model.NewLength(model.Batches, 400))
+	assert.Equal(t, next(), trainWorkload(3, 100, 150))
+	assert.Equal(t, next(), trainWorkload(4, 100, 250))
+	assert.Equal(t, next(), trainWorkload(5, 50, 350))
+	assert.Equal(t, next(), validationWorkload(5, 400))
+
+	// Without a hint, the trial trains through to the end of the operation.
+	s.SetValidationHint(model.Length{})
+	assert.Equal(t, next(), trainWorkload(6, 100, 400))
+	assert.Assert(t, !s.UpToDate())
+
+	// A rollback clears the hint, which was for the training done before it.
+	s.SetValidationHint(model.NewLength(model.Batches, 450))
+	s.RollBackSequencer()
+	assert.Equal(t, s.validationHint, model.Length{})
+}
+
+func TestTrialWorkloadSequencerReplacePendingOperations(t *testing.T) {
+	yam := `
+checkpoint_storage:
+  type: s3
+  access_key: my key
+  secret_key: my secret
+  bucket: my bucket
+hyperparameters:
+  global_batch_size: 64
+searcher:
+  name: single
+  metric: loss
+  max_length:
+    batches: 500
+checkpoint_policy: none
+`
+	expConfig := model.DefaultExperimentConfig()
+	assert.NilError(t, yaml.Unmarshal([]byte(yam), &expConfig, yaml.DisallowUnknownFields))
+	experiment := &model.Experiment{ID: 1, State: model.ActiveState, Config: expConfig}
+	create := searcher.NewCreate(nprand.New(0), map[string]interface{}{
+		model.GlobalBatchSize: 64,
+	}, model.TrialWorkloadSequencerType)
+
+	s := newTrialWorkloadSequencer(experiment, create, nil)
+	s.SetTrialID(1)
+	assert.NilError(t, s.OperationRequested(
+		searcher.NewTrain(create.RequestID, model.NewLength(model.Batches, 500))))
+	assert.NilError(t, s.OperationRequested(searcher.NewValidate(create.RequestID)))
+
+	trainWorkload := func(stepID, processed int) searcher.Workload {
+		return searcher.Workload{
+			Kind:                  searcher.RunStep,
+			ExperimentID:          1,
+			TrialID:               1,
+			StepID:                stepID,
+			NumBatches:            100,
+			TotalBatchesProcessed: processed,
+		}
+	}
+	// complete completes the workload, which must be the one the sequencer asks for next, and
+	// returns the operation it completed.
+	complete := func(w searcher.Workload) searcher.Runnable {
+		next, err := s.Workload()
+		assert.NilError(t, err)
+		assert.Equal(t, next, w)
+		msg := searcher.CompletedMessage{Workload: w}
+		switch w.Kind {
+		case searcher.ComputeValidationMetrics:
+			msg.ValidationMetrics = &searcher.ValidationMetrics{}
+		case searcher.CheckpointModel:
+			msg.CheckpointMetrics = &searcher.CheckpointMetrics{UUID: uuid.New()}
+		}
+		op, _, err := s.WorkloadCompleted(msg, nil)
+		assert.NilError(t, err)
+		return op
+	}
+	checkpointWorkload := func(stepID, processed int) searcher.Workload {
+		return searcher.Workload{
+			Kind:                  searcher.CheckpointModel,
+			ExperimentID:          1,
+			TrialID:               1,
+			StepID:                stepID,
+			TotalBatchesProcessed: processed,
+		}
+	}
+
+	// Train 200 batches and checkpoint, then train a little further before the trial is preempted.
+	assert.Equal(t, complete(trainWorkload(1, 0)), nil)
+	assert.Equal(t, complete(trainWorkload(2, 100)), nil)
+	checkpointMetrics := searcher.CheckpointMetrics{UUID: uuid.New()}
+	_, _, err := s.WorkloadCompleted(searcher.CompletedMessage{
+		Workload:          checkpointWorkload(2, 200),
+		CheckpointMetrics: &checkpointMetrics,
+	}, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, complete(trainWorkload(3, 200)), nil)
+	assert.Equal(t, s.RollBackSequencer(), 2)
+
+	// The search method plans the 300 batches left from the checkpoint, which replace the rest of
+	// the original Train operation rather than following it.
+	s.ReplacePendingOperations()
+	train := searcher.NewTrain(create.RequestID, model.NewLength(model.Batches, 300))
+	validate := searcher.NewValidate(create.RequestID)
+	assert.NilError(t, s.OperationRequested(train))
+	assert.NilError(t, s.OperationRequested(validate))
+	assert.Equal(t, len(s.ops), 2)
+
+	// Rolling back again before the next checkpoint does not count the progress made toward the
+	// replaced operation.
+	assert.Equal(t, complete(trainWorkload(3, 200)), nil)
+	assert.Equal(t, s.RollBackSequencer(), 2)
+
+	assert.Equal(t, complete(trainWorkload(3, 200)), nil)
+	assert.Equal(t, complete(trainWorkload(4, 300)), nil)
+	assert.Equal(t, complete(trainWorkload(5, 400)), train)
+	assert.Equal(t, complete(checkpointWorkload(5, 500)), nil)
+	assert.Equal(t, complete(searcher.Workload{
+		Kind:                  searcher.ComputeValidationMetrics,
+		ExperimentID:          1,
+		TrialID:               1,
+		StepID:                5,
+		TotalBatchesProcessed: 500,
+	}), validate)
+	assert.Assert(t, s.UpToDate())
+}
@@ -44,6 +44,9 @@ func DefaultExperimentConfig() ExperimentConfig {
 		Hyperparameters: make(map[string]Hyperparameter),
 		Searcher: SearcherConfig{
 			SmallerIsBetter: true,
+			RandomConfig: &RandomConfig{
+				SmallerIsBetter: true,
+			},
 			SyncHalvingConfig: &SyncHalvingConfig{
 				SmallerIsBetter: true,
 				Divisor:         4,
@@ -66,6 +69,8 @@ func DefaultExperimentConfig() ExperimentConfig {
 				SmallerIsBetter:     true,
 				Divisor:             4,
 				MaxConcurrentTrials: 0,
+				EarlyExitMode:       ParticipateEarlyExitMode,
+				RungRounding:        FloorRounding,
 			},
 			AdaptiveASHAConfig: &AdaptiveASHAConfig{
 				SmallerIsBetter:     true,
@@ -77,6 +82,38 @@ func DefaultExperimentConfig() ExperimentConfig {
 			PBTConfig: &PBTConfig{
 				SmallerIsBetter: true,
 			},
+			MultiObjectiveConfig: &MultiObjectiveConfig{
+				Divisor: 4,
+			},
+			TPEConfig: &TPEConfig{
+				SmallerIsBetter:  true,
+				NumStartupTrials: 10,
+				Gamma:            0.25,
+				NumCandidates:    24,
+				PriorWeight:      0.5,
+			},
+			BayesianConfig: &BayesianConfig{
+				SmallerIsBetter:  true,
+				NumStartupTrials: 5,
+				NumCandidates:    500,
+				Kernel:           Matern52Kernel,
+				LengthScale:      0.25,
+				Jitter:           1e-4,
+				PriorWeight:      0.5,
+			},
+			BOHBConfig: &BOHBConfig{
+				SmallerIsBetter:  true,
+				Divisor:          4,
+				Mode:             StandardMode,
+				MaxRungs:         5,
+				NumStartupTrials: 10,
+				Gamma:            0.25,
+				NumCandidates:    24,
+			},
+			RacingConfig: &RacingConfig{
+				SmallerIsBetter: true,
+				Delta:           0.05,
+			},
 		},
 		Resources: ResourcesConfig{
 			SlotsPerTrial:  1,
@@ -43,16 +43,27 @@ func (e ExperimentConfig) Validate() []error {
 	// Do some checks for grid search; since this involves looking at both the searcher config and the
 	// hyperparameter config, we have to do it at this level.
 	// - Check that counts are specified for all parameters.
+	// - Check that no parameters are conditional, since the grid is a full cross product.
 	// - Compute the total number of trials that would be created and check that it is not too large.
 	gridTrials := 1
 	noCountParams := make([]string, 0)
+	conditionalParams := make([]string, 0)
 	if e.Searcher.GridConfig != nil {
 		e.Hyperparameters.Each(func(name string, param Hyperparameter) {
+			if param.Condition != nil {
+				conditionalParams = append(conditionalParams, name)
+			}
 			mult := 1
 			switch {
 			case param.IntHyperparameter != nil:
 				p := param.IntHyperparameter
 				switch {
+				case p.Step != nil:
+					// Without a count, grid search takes every value of the lattice.
+					mult = p.GridSize()
+					if p.Count != nil && *p.Count < mult {
+						mult = *p.Count
+					}
 				case p.Count == nil:
 					noCountParams = append(noCountParams, name)
 				case *p.Count > p.Maxval-p.Minval:
@@ -81,10 +92,33 @@ func (e ExperimentConfig) Validate() []error {
 			}
 			gridTrials *= mult
 		})
+		if maxTrials := e.Searcher.GridConfig.MaxTrials; maxTrials != nil && *maxTrials < gridTrials {
+			gridTrials = *maxTrials
+		}
 	}
 
 	errs := []error{}
 
+	// The points of a list search must be points of the hyperparameter space.
+	if e.Searcher.ListConfig != nil {
+		for i, point := range e.Searcher.ListConfig.Points {
+			if err := e.Hyperparameters.CheckPoint(point); err != nil {
+				errs = append(errs, errors.Wrapf(err, "invalid point %d of list search", i))
+			}
+		}
+	}
+
+	// Constraints may only refer to declared hyperparameters; otherwise, a misspelled name would
+	// make the constraint look inactive, and so satisfied, for every sample.
+	for _, constraint := range e.Searcher.Constraints {
+		for _, name := range constraint.identifiers() {
+			if _, ok := e.Hyperparameters[name]; !ok {
+				errs = append(errs, errors.Errorf(
+					"constraint %q refers to undeclared hyperparameter %s", constraint, name))
+			}
+		}
+	}
+
 	// If the configuration is not a native submission, the user must specify an
 	// entrypoint in the configuration.
 	if e.Internal == nil || e.Internal.Native == nil {
@@ -96,6 +130,9 @@ func (e ExperimentConfig) Validate() []error {
 		check.TrueSilent(len(noCountParams) == 0,
 			"these hyperparameters must specify counts for grid search: %s",
 			strings.Join(noCountParams, ", ")),
+		check.TrueSilent(len(conditionalParams) == 0,
+			"grid search does not support conditional hyperparameters: %s",
+			strings.Join(conditionalParams, ", ")),
 		check.LessThanOrEqualTo(gridTrials, MaxAllowedTrials,
 			"number of trials for grid search must be <= %d", MaxAllowedTrials),
 		check.GreaterThanOrEqualTo(e.MaxRestarts, 0, "max_restarts must be >= 0"),
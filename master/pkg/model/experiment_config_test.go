@@ -161,6 +161,15 @@ func TestGridValidation(t *testing.T) {
 		assert.ErrorContains(t, check.Validate(config), "number of trials")
 	}
 
+	// Check that capping the number of trials avoids that error.
+	{
+		config := validGridSearchConfig()
+		config.Hyperparameters["log"].LogHyperparameter.Count = intP(MaxAllowedTrials)
+		config.Hyperparameters["int"].IntHyperparameter.Count = intP(2)
+		config.Searcher.GridConfig.MaxTrials = intP(MaxAllowedTrials)
+		assert.NilError(t, check.Validate(config))
+	}
+
 	// Check that counts for int hyperparameters are clamped properly.
 	{
 		config := validGridSearchConfig()
@@ -175,6 +184,141 @@ func TestGridValidation(t *testing.T) {
 		config.Hyperparameters["log"].LogHyperparameter.Count = nil
 		assert.ErrorContains(t, check.Validate(config), "must specify counts for grid search: log")
 	}
+
+	// Check that int hyperparameters with a step need no count.
+	{
+		config := validGridSearchConfig()
+		config.Hyperparameters["int"].IntHyperparameter.Count = nil
+		config.Hyperparameters["int"].IntHyperparameter.Step = intP(5)
+		assert.NilError(t, check.Validate(config))
+		config.Hyperparameters["int"].IntHyperparameter.Step = intP(0)
+		assert.ErrorContains(t, check.Validate(config), "step must be > 0")
+	}
+
+	// Check that a conditional hyperparameter triggers an error.
+	{
+		config := validGridSearchConfig()
+		param := config.Hyperparameters["log"]
+		param.Condition = &HyperparameterCondition{Parent: "int", Vals: []interface{}{1.0}}
+		config.Hyperparameters["log"] = param
+		assert.ErrorContains(t, check.Validate(config),
+			"grid search does not support conditional hyperparameters: log")
+	}
+}
+
+// TestListValidation tests that the points of a list search must be points of the hyperparameter
+// space.
+func TestListValidation(t *testing.T) {
+	listConfig := func(points ...map[string]interface{}) ExperimentConfig {
+		config := validGridSearchConfig()
+		config.Hyperparameters["int"].IntHyperparameter.Step = intP(2)
+		config.Searcher = SearcherConfig{ListConfig: &ListConfig{
+			MaxLength: NewLengthInBatches(1000),
+			Points:    points,
+		}}
+		return config
+	}
+	point := func(name string, value interface{}) map[string]interface{} {
+		values := map[string]interface{}{
+			"const": map[string]interface{}{"test": []interface{}{1., 2., 3.}},
+			"cat":   1.0,
+			"int":   54.0,
+			"log":   1e-4,
+		}
+		if value == nil {
+			delete(values, name)
+		} else {
+			values[name] = value
+		}
+		return values
+	}
+
+	assert.NilError(t, check.Validate(listConfig(point("cat", "a"), point("int", 60))))
+	assert.ErrorContains(t, check.Validate(listConfig()), "points must not be empty")
+
+	cases := []struct {
+		point    map[string]interface{}
+		expected string
+	}{
+		{point("depth", 3.0), "invalid point 0 of list search: unknown hyperparameters: [depth]"},
+		{point("log", nil), "missing a value for hyperparameter log"},
+		{point("const", 1.0), "hyperparameter const: 1 is not the constant value"},
+		{point("cat", "b"), "hyperparameter cat: b is not one of [a 1]"},
+		{point("int", 55.0), "hyperparameter int: 55 is not 50 plus a multiple of the step 2"},
+		{point("int", 62.0), "hyperparameter int: 62 is not between 50 and 60"},
+		{point("int", "52"), "hyperparameter int: 52 is not an integer"},
+		{point("log", 0.1), "hyperparameter log: 0.1 is not between 1e-06 and 0.01"},
+	}
+	for _, c := range cases {
+		assert.ErrorContains(t, check.Validate(listConfig(point("cat", "a"), c.point)),
+			strings.Replace(c.expected, "point 0", "point 1", 1))
+	}
+}
+
+// TestConstraintValidation tests that hyperparameter constraints may only refer to declared
+// hyperparameters.
+func TestConstraintValidation(t *testing.T) {
+	constrained := func(constraints ...HyperparameterConstraint) ExperimentConfig {
+		config := validGridSearchConfig()
+		config.Searcher.Constraints = constraints
+		return config
+	}
+	assert.NilError(t, check.Validate(constrained(
+		"int * 2 <= 100 && (log < 1e-3 || cat == true)", "!false")))
+
+	err := check.Validate(constrained("int * bacth_size <= 100 && cat == true"))
+	assert.ErrorContains(t, err,
+		`constraint "int * bacth_size <= 100 && cat == true" refers to undeclared hyperparameter `+
+			"bacth_size")
+	assert.Assert(t, !strings.Contains(err.Error(), "undeclared hyperparameter int"), err)
+	assert.Assert(t, !strings.Contains(err.Error(), "undeclared hyperparameter true"), err)
+}
+
+// TestConditionalHyperparameters tests parsing and validation of hyperparameter conditions.
+func TestConditionalHyperparameters(t *testing.T) {
+	var hparams Hyperparameters
+	assert.NilError(t, json.Unmarshal([]byte(`{
+  "optimizer": {"type": "categorical", "vals": ["sgd", "adam"]},
+  "momentum": {
+    "type": "double", "minval": 0, "maxval": 1,
+    "condition": {"parent": "optimizer", "vals": ["sgd"]}
+  },
+  "nesterov": {
+    "type": "categorical", "vals": [true, false],
+    "condition": {"parent": "momentum", "vals": [0.9]}
+  }
+}`), &hparams))
+	assert.NilError(t, check.Validate(hparams))
+	assert.Assert(t, hparams["optimizer"].Condition == nil)
+	assert.DeepEqual(t, hparams["momentum"].Condition,
+		&HyperparameterCondition{Parent: "optimizer", Vals: []interface{}{"sgd"}})
+
+	// Conditions survive a JSON round trip, and are omitted when unset.
+	bytes, err := json.Marshal(hparams)
+	assert.NilError(t, err)
+	assert.Equal(t, strings.Count(string(bytes), "condition"), 2)
+	var rebuilt Hyperparameters
+	assert.NilError(t, json.Unmarshal(bytes, &rebuilt))
+	assert.DeepEqual(t, hparams, rebuilt)
+
+	// Check that an undefined parent triggers an error.
+	{
+		invalid := Hyperparameters{"momentum": hparams["momentum"]}
+		assert.ErrorContains(t, check.Validate(invalid),
+			"hyperparameter momentum depends on undefined hyperparameter optimizer")
+	}
+
+	// Check that a cycle triggers an error.
+	{
+		optimizer := hparams["optimizer"]
+		optimizer.Condition = &HyperparameterCondition{Parent: "nesterov", Vals: []interface{}{true}}
+		invalid := Hyperparameters{
+			"optimizer": optimizer,
+			"momentum":  hparams["momentum"],
+			"nesterov":  hparams["nesterov"],
+		}
+		assert.ErrorContains(t, check.Validate(invalid), "cyclic condition")
+	}
 }
 
 // TestResourcesValidation tests that invalid resources configurations produce validation errors and
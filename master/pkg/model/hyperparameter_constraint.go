@@ -0,0 +1,261 @@
+package model
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// HyperparameterConstraint is a boolean expression over hyperparameter values that every set of
+// sampled hyperparameters must satisfy, e.g., `batch_size * seq_len <= 65536`. Expressions may
+// use numeric, string, and boolean literals, hyperparameter names, arithmetic (`+ - * / %`),
+// comparisons (`== != < <= > >=`), logical operators (`&& || !`), and parentheses.
+type HyperparameterConstraint string
+
+// errInactive is returned while evaluating a constraint that refers to a hyperparameter that is
+// not part of the sample, e.g., a conditional hyperparameter whose condition does not hold.
+var errInactive = errors.New("constraint refers to an inactive hyperparameter")
+
+// parsedConstraints caches the syntax tree of each constraint that has parsed successfully, since
+// constraints are checked against every sample that a search method draws.
+var parsedConstraints sync.Map
+
+// parse returns the syntax tree of the constraint, parsing it only the first time.
+func (c HyperparameterConstraint) parse() (ast.Expr, error) {
+	if expr, ok := parsedConstraints.Load(c); ok {
+		return expr.(ast.Expr), nil
+	}
+	expr, err := parser.ParseExpr(string(c))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid constraint %q", c)
+	}
+	parsedConstraints.Store(c, expr)
+	return expr, nil
+}
+
+// Validate implements the check.Validatable interface.
+func (c HyperparameterConstraint) Validate() []error {
+	expr, err := c.parse()
+	if err != nil {
+		return []error{err}
+	}
+	if err := checkConstraintExpr(expr); err != nil {
+		return []error{errors.Wrapf(err, "invalid constraint %q", c)}
+	}
+	return nil
+}
+
+// Satisfied returns whether the given hyperparameter values satisfy the constraint. A constraint
+// is trivially satisfied if it refers to a hyperparameter that is not among the values.
+func (c HyperparameterConstraint) Satisfied(hparams map[string]interface{}) (bool, error) {
+	expr, err := c.parse()
+	if err != nil {
+		return false, err
+	}
+	result, err := evalConstraintExpr(expr, hparams)
+	switch {
+	case err == errInactive:
+		return true, nil
+	case err != nil:
+		return false, errors.Wrapf(err, "error evaluating constraint %q", c)
+	}
+	satisfied, ok := result.(bool)
+	if !ok {
+		return false, errors.Errorf("constraint %q evaluated to %v instead of a boolean", c, result)
+	}
+	return satisfied, nil
+}
+
+// identifiers returns the sorted names of the hyperparameters the constraint refers to, or nil if
+// it does not parse.
+func (c HyperparameterConstraint) identifiers() []string {
+	expr, err := c.parse()
+	if err != nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	ast.Inspect(expr, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Ident); ok && ident.Name != "true" && ident.Name != "false" {
+			seen[ident.Name] = true
+		}
+		return true
+	})
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkConstraintExpr returns an error if the expression uses syntax constraints do not support.
+func checkConstraintExpr(expr ast.Expr) error {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return checkConstraintExpr(e.X)
+	case *ast.Ident:
+		return nil
+	case *ast.BasicLit:
+		if e.Kind == token.CHAR || e.Kind == token.IMAG {
+			return errors.Errorf("unsupported literal %s", e.Value)
+		}
+		return nil
+	case *ast.UnaryExpr:
+		if e.Op != token.SUB && e.Op != token.ADD && e.Op != token.NOT {
+			return errors.Errorf("unsupported operator %s", e.Op)
+		}
+		return checkConstraintExpr(e.X)
+	case *ast.BinaryExpr:
+		switch e.Op {
+		case token.ADD, token.SUB, token.MUL, token.QUO, token.REM,
+			token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ,
+			token.LAND, token.LOR:
+		default:
+			return errors.Errorf("unsupported operator %s", e.Op)
+		}
+		if err := checkConstraintExpr(e.X); err != nil {
+			return err
+		}
+		return checkConstraintExpr(e.Y)
+	default:
+		return errors.Errorf("unsupported expression %T", expr)
+	}
+}
+
+func evalConstraintExpr(expr ast.Expr, hparams map[string]interface{}) (interface{}, error) {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return evalConstraintExpr(e.X, hparams)
+	case *ast.Ident:
+		switch val, ok := hparams[e.Name]; {
+		case ok:
+			return normalizeConstraintValue(val), nil
+		case e.Name == "true":
+			return true, nil
+		case e.Name == "false":
+			return false, nil
+		default:
+			return nil, errInactive
+		}
+	case *ast.BasicLit:
+		switch e.Kind {
+		case token.INT, token.FLOAT:
+			return strconv.ParseFloat(e.Value, 64)
+		case token.STRING:
+			return strconv.Unquote(e.Value)
+		}
+	case *ast.UnaryExpr:
+		x, err := evalConstraintExpr(e.X, hparams)
+		if err != nil {
+			return nil, err
+		}
+		switch x := x.(type) {
+		case float64:
+			switch e.Op {
+			case token.SUB:
+				return -x, nil
+			case token.ADD:
+				return x, nil
+			}
+		case bool:
+			if e.Op == token.NOT {
+				return !x, nil
+			}
+		}
+		return nil, errors.Errorf("cannot apply %s to %v", e.Op, x)
+	case *ast.BinaryExpr:
+		return evalConstraintBinaryExpr(e, hparams)
+	}
+	return nil, errors.Errorf("unsupported expression %T", expr)
+}
+
+func evalConstraintBinaryExpr(
+	e *ast.BinaryExpr, hparams map[string]interface{},
+) (interface{}, error) {
+	x, err := evalConstraintExpr(e.X, hparams)
+	if err != nil {
+		return nil, err
+	}
+	// Short circuit logical operators so that the right-hand side can assume the left-hand side.
+	if x, ok := x.(bool); ok {
+		if (e.Op == token.LAND && !x) || (e.Op == token.LOR && x) {
+			return x, nil
+		}
+	}
+	y, err := evalConstraintExpr(e.Y, hparams)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.Op {
+	case token.EQL, token.NEQ:
+		if !isConstraintScalar(x) || !isConstraintScalar(y) {
+			return nil, errors.Errorf("cannot compare %v and %v", x, y)
+		}
+		return (x == y) == (e.Op == token.EQL), nil
+	}
+
+	switch x := x.(type) {
+	case float64:
+		if y, ok := y.(float64); ok {
+			switch e.Op {
+			case token.ADD:
+				return x + y, nil
+			case token.SUB:
+				return x - y, nil
+			case token.MUL:
+				return x * y, nil
+			case token.QUO:
+				return x / y, nil
+			case token.REM:
+				return math.Mod(x, y), nil
+			case token.LSS:
+				return x < y, nil
+			case token.LEQ:
+				return x <= y, nil
+			case token.GTR:
+				return x > y, nil
+			case token.GEQ:
+				return x >= y, nil
+			}
+		}
+	case bool:
+		if y, ok := y.(bool); ok {
+			switch e.Op {
+			case token.LAND:
+				return x && y, nil
+			case token.LOR:
+				return x || y, nil
+			}
+		}
+	}
+	return nil, errors.Errorf("cannot apply %s to %v and %v", e.Op, x, y)
+}
+
+func isConstraintScalar(val interface{}) bool {
+	switch val.(type) {
+	case float64, string, bool:
+		return true
+	default:
+		return false
+	}
+}
+
+// normalizeConstraintValue converts all numbers to floats so that int and double hyperparameters
+// can be compared to each other and to literals.
+func normalizeConstraintValue(val interface{}) interface{} {
+	switch val := val.(type) {
+	case int:
+		return float64(val)
+	case int64:
+		return float64(val)
+	default:
+		return val
+	}
+}
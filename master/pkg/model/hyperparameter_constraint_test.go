@@ -0,0 +1,63 @@
+package model
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/determined-ai/determined/master/pkg/check"
+)
+
+func TestHyperparameterConstraintSatisfied(t *testing.T) {
+	hparams := map[string]interface{}{
+		"batch_size": 32,
+		"seq_len":    128,
+		"dropout":    0.5,
+		"optimizer":  "sgd",
+		"nesterov":   true,
+	}
+	testCases := []struct {
+		constraint HyperparameterConstraint
+		expected   bool
+	}{
+		{"batch_size * seq_len <= 4096", true},
+		{"batch_size * seq_len < 4096", false},
+		{"(batch_size + 32) / 2 == 32", true},
+		{"seq_len % 3 != 0", true},
+		{"-dropout > -1 && dropout >= 0.5", true},
+		{`optimizer == "sgd" || batch_size > 1000`, true},
+		{`optimizer != "sgd" && batch_size > 1000`, false},
+		{"nesterov && !false", true},
+		{"nesterov == false", false},
+		// Constraints on inactive hyperparameters are trivially satisfied.
+		{"momentum > 0.5", true},
+	}
+	for _, tc := range testCases {
+		assert.NilError(t, check.Validate(tc.constraint), tc.constraint)
+		satisfied, err := tc.constraint.Satisfied(hparams)
+		assert.NilError(t, err, tc.constraint)
+		assert.Equal(t, satisfied, tc.expected, tc.constraint)
+	}
+}
+
+func TestHyperparameterConstraintErrors(t *testing.T) {
+	for constraint, expected := range map[HyperparameterConstraint]string{
+		"batch_size <=":       "invalid constraint",
+		"len(optimizer) > 2":  "unsupported expression",
+		"batch_size << 2 > 0": "unsupported operator",
+		"'a' == optimizer":    "unsupported literal",
+	} {
+		assert.ErrorContains(t, check.Validate(constraint), expected)
+	}
+
+	hparams := map[string]interface{}{"batch_size": 32, "optimizer": "sgd"}
+	for constraint, expected := range map[HyperparameterConstraint]string{
+		"batch_size * 2":           "instead of a boolean",
+		`optimizer > 1`:            "cannot apply",
+		`optimizer + batch_size`:   "cannot apply",
+		"-optimizer == batch_size": "cannot apply",
+	} {
+		_, err := constraint.Satisfied(hparams)
+		assert.ErrorContains(t, err, expected)
+	}
+}
@@ -2,8 +2,12 @@ package model
 
 import (
 	"encoding/json"
+	"math"
+	"reflect"
 	"sort"
 
+	"github.com/pkg/errors"
+
 	"github.com/determined-ai/determined/master/pkg/check"
 	"github.com/determined-ai/determined/master/pkg/union"
 )
@@ -26,6 +30,68 @@ func (h Hyperparameters) Each(f func(name string, param Hyperparameter)) {
 	}
 }
 
+// Validate implements the check.Validatable interface.
+func (h Hyperparameters) Validate() []error {
+	var errs []error
+	h.Each(func(name string, param Hyperparameter) {
+		if param.Condition == nil {
+			return
+		}
+		if _, ok := h[param.Condition.Parent]; !ok {
+			errs = append(errs, errors.Errorf(
+				"hyperparameter %s depends on undefined hyperparameter %s",
+				name, param.Condition.Parent))
+			return
+		}
+		// Follow the chain of parents; it must end before coming back around to this hyperparameter.
+		for parent, steps := param.Condition.Parent, 0; steps <= len(h); steps++ {
+			if parent == name {
+				errs = append(errs, errors.Errorf(
+					"hyperparameter %s has a cyclic condition", name))
+				return
+			}
+			condition := h[parent].Condition
+			if condition == nil {
+				return
+			}
+			parent = condition.Parent
+		}
+	})
+	return errs
+}
+
+// CheckPoint returns an error if the values do not make up a point of the hyperparameter space:
+// every value must be for a hyperparameter and within its range, and every hyperparameter that is
+// not conditional must have a value.
+func (h Hyperparameters) CheckPoint(point map[string]interface{}) error {
+	var unknown []string
+	for name := range point {
+		if _, ok := h[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return errors.Errorf("unknown hyperparameters: %v", unknown)
+	}
+	var err error
+	h.Each(func(name string, param Hyperparameter) {
+		if err != nil {
+			return
+		}
+		value, ok := point[name]
+		switch {
+		case !ok && param.Condition == nil:
+			err = errors.Errorf("missing a value for hyperparameter %s", name)
+		case ok:
+			if valueErr := param.CheckValue(value); valueErr != nil {
+				err = errors.Wrapf(valueErr, "invalid value for hyperparameter %s", name)
+			}
+		}
+	})
+	return err
+}
+
 // Hyperparameter is a sum type for hyperparameters.
 type Hyperparameter struct {
 	ConstHyperparameter       *ConstHyperparameter       `union:"type,const" json:"-"`
@@ -33,6 +99,95 @@ type Hyperparameter struct {
 	DoubleHyperparameter      *DoubleHyperparameter      `union:"type,double" json:"-"`
 	LogHyperparameter         *LogHyperparameter         `union:"type,log" json:"-"`
 	CategoricalHyperparameter *CategoricalHyperparameter `union:"type,categorical" json:"-"`
+	// Condition, if set, makes the hyperparameter only exist for trials that satisfy it.
+	Condition *HyperparameterCondition `json:"condition,omitempty"`
+}
+
+// CheckValue returns an error if the hyperparameter cannot take the value. Numbers may be ints or
+// float64s, as parsed from JSON.
+func (h Hyperparameter) CheckValue(value interface{}) error {
+	number, isNumber := hparamNumber(value)
+	switch {
+	case h.ConstHyperparameter != nil:
+		if !hparamValuesEqual(value, h.ConstHyperparameter.Val) {
+			return errors.Errorf("%v is not the constant value %v", value, h.ConstHyperparameter.Val)
+		}
+	case h.IntHyperparameter != nil:
+		p := h.IntHyperparameter
+		switch {
+		case !isNumber || number != math.Trunc(number):
+			return errors.Errorf("%v is not an integer", value)
+		case number < float64(p.Minval) || number > float64(p.Maxval):
+			return errors.Errorf("%v is not between %d and %d", value, p.Minval, p.Maxval)
+		case (int(number)-p.Minval)%p.StepSize() != 0:
+			return errors.Errorf("%v is not %d plus a multiple of the step %d",
+				value, p.Minval, p.StepSize())
+		}
+	case h.DoubleHyperparameter != nil:
+		p := h.DoubleHyperparameter
+		switch {
+		case !isNumber:
+			return errors.Errorf("%v is not a number", value)
+		case number < p.Minval || number > p.Maxval:
+			return errors.Errorf("%v is not between %v and %v", value, p.Minval, p.Maxval)
+		}
+	case h.LogHyperparameter != nil:
+		p := h.LogHyperparameter
+		low, high := math.Pow(p.Base, p.Minval), math.Pow(p.Base, p.Maxval)
+		switch {
+		case !isNumber:
+			return errors.Errorf("%v is not a number", value)
+		case number < low || number > high:
+			return errors.Errorf("%v is not between %v and %v", value, low, high)
+		}
+	case h.CategoricalHyperparameter != nil:
+		for _, val := range h.CategoricalHyperparameter.Vals {
+			if hparamValuesEqual(value, val) {
+				return nil
+			}
+		}
+		return errors.Errorf("%v is not one of %v", value, h.CategoricalHyperparameter.Vals)
+	}
+	return nil
+}
+
+// hparamNumber returns the value as a float64 if it is a number.
+func hparamNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// hparamValuesEqual compares values of hyperparameters, treating ints and float64s with the same
+// value as equal.
+func hparamValuesEqual(a, b interface{}) bool {
+	if number, ok := a.(int); ok {
+		a = float64(number)
+	}
+	if number, ok := b.(int); ok {
+		b = float64(number)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// HyperparameterCondition restricts a hyperparameter to trials in which another (parent)
+// hyperparameter exists and took one of the given values.
+type HyperparameterCondition struct {
+	Parent string        `json:"parent"`
+	Vals   []interface{} `json:"vals"`
+}
+
+// Validate implements the check.Validatable interface.
+func (c HyperparameterCondition) Validate() []error {
+	return []error{
+		check.NotEmpty(c.Parent, "condition must specify a parent"),
+		check.GreaterThan(len(c.Vals), 0, "condition must have at least one value"),
+	}
 }
 
 // MarshalJSON implements the json.Marshaler interface.
@@ -47,7 +202,18 @@ func (h *Hyperparameter) UnmarshalJSON(data []byte) error {
 		return err
 	}
 	if _, ok := parsed.(map[string]interface{}); ok {
-		return union.Unmarshal(data, h)
+		if err := union.Unmarshal(data, h); err != nil {
+			return err
+		}
+		// Union unmarshaling only fills in the union fields.
+		var condition struct {
+			Condition *HyperparameterCondition `json:"condition"`
+		}
+		if err := json.Unmarshal(data, &condition); err != nil {
+			return err
+		}
+		h.Condition = condition.Condition
+		return nil
 	}
 	h.ConstHyperparameter = &ConstHyperparameter{Val: parsed}
 	return nil
@@ -63,6 +229,9 @@ type IntHyperparameter struct {
 	Minval int  `json:"minval"`
 	Maxval int  `json:"maxval"`
 	Count  *int `json:"count"`
+	// Step, if set, restricts the values to those of the form minval + k * step, e.g., so that a
+	// number of channels is a multiple of 8.
+	Step *int `json:"step,omitempty"`
 }
 
 // Validate implements the check.Validatable interface.
@@ -70,7 +239,22 @@ func (i IntHyperparameter) Validate() []error {
 	return []error{
 		check.GreaterThan(i.Maxval, i.Minval, "minval is greater than maxval"),
 		check.GreaterThan(i.Count, 0, "count must be >= 0"),
+		check.GreaterThan(i.Step, 0, "step must be > 0"),
+	}
+}
+
+// StepSize returns the distance between consecutive values of the hyperparameter. An invalid step
+// counts as 1, so that the rest of the configuration can be validated regardless.
+func (i IntHyperparameter) StepSize() int {
+	if i.Step == nil || *i.Step <= 0 {
+		return 1
 	}
+	return *i.Step
+}
+
+// GridSize returns the number of values of the hyperparameter between minval and maxval inclusive.
+func (i IntHyperparameter) GridSize() int {
+	return (i.Maxval-i.Minval)/i.StepSize() + 1
 }
 
 // DoubleHyperparameter is an interval of float64s.
@@ -110,11 +294,27 @@ func (h *LogHyperparameter) Validate() []error {
 // CategoricalHyperparameter is a collection of values (levels) of the category.
 type CategoricalHyperparameter struct {
 	Vals []interface{} `json:"vals"`
+	// Weights, if set, are the relative probabilities of sampling each of the values.
+	Weights []float64 `json:"weights,omitempty"`
+	// Ordinal marks the values as ordered, so that values next to each other in Vals are considered
+	// closer than values further apart.
+	Ordinal bool `json:"ordinal,omitempty"`
 }
 
 // Validate implements the check.Validatable interface.
 func (h *CategoricalHyperparameter) Validate() []error {
-	return []error{
+	errs := []error{
 		check.GreaterThan(len(h.Vals), 0, "must have at least one category"),
 	}
+	if h.Weights != nil {
+		errs = append(errs,
+			check.Equal(len(h.Weights), len(h.Vals), "must have exactly one weight per category"))
+		total := 0.0
+		for _, weight := range h.Weights {
+			errs = append(errs, check.GreaterThanOrEqualTo(weight, 0.0, "weights must be >= 0"))
+			total += weight
+		}
+		errs = append(errs, check.GreaterThan(total, 0.0, "at least one weight must be > 0"))
+	}
+	return errs
 }
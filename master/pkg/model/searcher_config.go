@@ -2,6 +2,11 @@ package model
 
 import (
 	"encoding/json"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
 
 	"github.com/determined-ai/determined/master/pkg/check"
 	"github.com/determined-ai/determined/master/pkg/union"
@@ -19,6 +24,10 @@ type SearcherConfig struct {
 	SmallerIsBetter      bool    `json:"smaller_is_better"`
 	SourceTrialID        *int    `json:"source_trial_id"`
 	SourceCheckpointUUID *string `json:"source_checkpoint_uuid"`
+	// Constraints restricts the hyperparameters of every trial to those satisfying all of them.
+	Constraints []HyperparameterConstraint `json:"constraints,omitempty"`
+	// MaxOperationsPerCall, if positive, is the most operations the searcher returns at once.
+	MaxOperationsPerCall int `json:"max_operations_per_call,omitempty"`
 
 	SingleConfig         *SingleConfig         `union:"name,single" json:"-"`
 	RandomConfig         *RandomConfig         `union:"name,random" json:"-"`
@@ -29,22 +38,111 @@ type SearcherConfig struct {
 	AdaptiveSimpleConfig *AdaptiveSimpleConfig `union:"name,adaptive_simple" json:"-"`
 	AdaptiveASHAConfig   *AdaptiveASHAConfig   `union:"name,adaptive_asha" json:"-"`
 	PBTConfig            *PBTConfig            `union:"name,pbt" json:"-"`
+	MultiObjectiveConfig *MultiObjectiveConfig `union:"name,multi_objective_asha" json:"-"`
+	TPEConfig            *TPEConfig            `union:"name,tpe" json:"-"`
+	BayesianConfig       *BayesianConfig       `union:"name,bayesian" json:"-"`
+	BOHBConfig           *BOHBConfig           `union:"name,bohb" json:"-"`
+	ListConfig           *ListConfig           `union:"name,list" json:"-"`
+	RacingConfig         *RacingConfig         `union:"name,racing" json:"-"`
+
+	// CustomConfig holds the configuration of a searcher that is not built in.
+	CustomConfig *CustomSearcherConfig `json:"-"`
 }
 
 // MarshalJSON implements the json.Marshaler interface.
 func (s SearcherConfig) MarshalJSON() ([]byte, error) {
-	return union.Marshal(s)
+	if s.CustomConfig == nil {
+		return union.Marshal(s)
+	}
+	data := make(map[string]interface{})
+	if err := json.Unmarshal(s.CustomConfig.Config, &data); err != nil {
+		return nil, err
+	}
+	type DefaultParser SearcherConfig
+	base, err := json.Marshal(DefaultParser(s))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(base, &data); err != nil {
+		return nil, err
+	}
+	data["name"] = s.CustomConfig.Name
+	return json.Marshal(data)
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
 func (s *SearcherConfig) UnmarshalJSON(data []byte) error {
+	var named struct {
+		Name *string `json:"name"`
+	}
+	if err := json.Unmarshal(data, &named); err != nil {
+		return err
+	}
+	if named.Name != nil {
+		builtIn, err := union.Defines(s, "name", *named.Name)
+		if err != nil {
+			return err
+		}
+		// Searchers that are not built in are left to the search method to configure.
+		if !builtIn {
+			if err := union.Clear(s); err != nil {
+				return err
+			}
+			s.CustomConfig = &CustomSearcherConfig{
+				Name: *named.Name, Config: append(json.RawMessage{}, data...),
+			}
+			type DefaultParser *SearcherConfig
+			return json.Unmarshal(data, DefaultParser(s))
+		}
+	}
 	if err := union.Unmarshal(data, s); err != nil {
 		return err
 	}
+	s.CustomConfig = nil
 	type DefaultParser *SearcherConfig
 	return json.Unmarshal(data, DefaultParser(s))
 }
 
+// Name returns the name of the configured searcher.
+func (s SearcherConfig) Name() string {
+	switch {
+	case s.SingleConfig != nil:
+		return "single"
+	case s.RandomConfig != nil:
+		return "random"
+	case s.GridConfig != nil:
+		return "grid"
+	case s.SyncHalvingConfig != nil:
+		return "sync_halving"
+	case s.AdaptiveConfig != nil:
+		return "adaptive"
+	case s.AdaptiveSimpleConfig != nil:
+		return "adaptive_simple"
+	case s.AsyncHalvingConfig != nil:
+		return "async_halving"
+	case s.AdaptiveASHAConfig != nil:
+		return "adaptive_asha"
+	case s.PBTConfig != nil:
+		return "pbt"
+	case s.MultiObjectiveConfig != nil:
+		return "multi_objective_asha"
+	case s.TPEConfig != nil:
+		return "tpe"
+	case s.BayesianConfig != nil:
+		return "bayesian"
+	case s.BOHBConfig != nil:
+		return "bohb"
+	case s.ListConfig != nil:
+		return "list"
+	case s.RacingConfig != nil:
+		return "racing"
+	case s.CustomConfig != nil:
+		return s.CustomConfig.Name
+	default:
+		return ""
+	}
+}
+
 // Unit implements the model.InUnits interface.
 func (s SearcherConfig) Unit() Unit {
 	switch {
@@ -66,11 +164,72 @@ func (s SearcherConfig) Unit() Unit {
 		return s.AdaptiveASHAConfig.Unit()
 	case s.PBTConfig != nil:
 		return s.PBTConfig.Unit()
+	case s.MultiObjectiveConfig != nil:
+		return s.MultiObjectiveConfig.Unit()
+	case s.TPEConfig != nil:
+		return s.TPEConfig.Unit()
+	case s.BayesianConfig != nil:
+		return s.BayesianConfig.Unit()
+	case s.BOHBConfig != nil:
+		return s.BOHBConfig.Unit()
+	case s.ListConfig != nil:
+		return s.ListConfig.Unit()
+	case s.RacingConfig != nil:
+		return s.RacingConfig.Unit()
+	case s.CustomConfig != nil:
+		return s.CustomConfig.Unit()
 	default:
 		panic("no searcher type specified")
 	}
 }
 
+// CustomSearcherConfig configures a searcher that is registered with the master rather than
+// built in. The configuration is kept as raw JSON for the search method to interpret.
+type CustomSearcherConfig struct {
+	Name   string
+	Config json.RawMessage
+}
+
+var (
+	searcherNamesMu sync.RWMutex
+	searcherNames   = make(map[string]bool)
+)
+
+// RegisterSearcherName records that a searcher that is not built in is available under the name,
+// so that configurations naming it validate. The searcher package registers the name of every
+// search method registered with it; configurations naming any other searcher, e.g., misspelling a
+// built-in one, fail validation rather than reach the master.
+func RegisterSearcherName(name string) {
+	searcherNamesMu.Lock()
+	defer searcherNamesMu.Unlock()
+	searcherNames[name] = true
+}
+
+func searcherNameRegistered(name string) bool {
+	searcherNamesMu.RLock()
+	defer searcherNamesMu.RUnlock()
+	return searcherNames[name]
+}
+
+// Validate implements the check.Validatable interface.
+func (c CustomSearcherConfig) Validate() []error {
+	return []error{
+		check.TrueSilent(searcherNameRegistered(c.Name), "unknown searcher type: %s", c.Name),
+	}
+}
+
+// Unit implements the model.InUnits interface. It is the unit of the max_length field of the
+// configuration, if there is one.
+func (c CustomSearcherConfig) Unit() Unit {
+	var parsed struct {
+		MaxLength *Length `json:"max_length"`
+	}
+	if err := json.Unmarshal(c.Config, &parsed); err != nil || parsed.MaxLength == nil {
+		return Batches
+	}
+	return parsed.MaxLength.Unit
+}
+
 // SingleConfig configures a single trial.
 type SingleConfig struct {
 	MaxLength Length `json:"max_length"`
@@ -90,8 +249,21 @@ func (s SingleConfig) Unit() Unit {
 
 // RandomConfig configures a random search.
 type RandomConfig struct {
-	MaxLength Length `json:"max_length"`
-	MaxTrials int    `json:"max_trials"`
+	Metric              string `json:"metric"`
+	SmallerIsBetter     bool   `json:"smaller_is_better"`
+	MaxLength           Length `json:"max_length"`
+	MaxTrials           int    `json:"max_trials"`
+	MaxConcurrentTrials int    `json:"max_concurrent_trials"`
+	// ValidationPeriod, if set, is how long trials train between validations; otherwise, trials
+	// validate once, after training for MaxLength.
+	ValidationPeriod *Length `json:"validation_period,omitempty"`
+	// Patience, if positive, is the number of consecutive validations a trial may go without
+	// improving its best metric by more than MinImprovement before it is stopped.
+	Patience       int     `json:"patience"`
+	MinImprovement float64 `json:"min_improvement"`
+	// MetricHistoryWindow, if positive, is the number of the most recent validation metrics of
+	// each trial that patience is judged against; otherwise, it is patience + 1.
+	MetricHistoryWindow int `json:"metric_history_window"`
 }
 
 // Unit implements the model.InUnits interface.
@@ -101,15 +273,35 @@ func (r RandomConfig) Unit() Unit {
 
 // Validate implements the check.Validatable interface.
 func (r RandomConfig) Validate() (errs []error) {
-	return []error{
+	errs = []error{
 		check.GreaterThan(r.MaxLength.Units, 0, "max_length must be > 0"),
 		check.GreaterThan(r.MaxTrials, 0, "max_trials must be > 0"),
+		check.GreaterThanOrEqualTo(r.MaxConcurrentTrials, 0, "max_concurrent_trials must be >= 0"),
+		check.GreaterThanOrEqualTo(r.Patience, 0, "patience must be >= 0"),
+		check.GreaterThanOrEqualTo(r.MinImprovement, 0.0, "min_improvement must be >= 0"),
+	}
+	if r.ValidationPeriod != nil {
+		errs = append(errs,
+			check.GreaterThan(r.ValidationPeriod.Units, 0, "validation_period must be > 0"),
+			check.Equal(r.ValidationPeriod.Unit, r.MaxLength.Unit,
+				"validation_period must be in the same unit as max_length"))
+	}
+	if r.Patience > 0 {
+		errs = append(errs, check.True(r.ValidationPeriod != nil,
+			"patience requires a validation_period"))
 	}
+	if r.MetricHistoryWindow != 0 {
+		errs = append(errs, check.GreaterThan(r.MetricHistoryWindow, r.Patience,
+			"metric_history_window must be > patience"))
+	}
+	return errs
 }
 
 // GridConfig configures a grid search.
 type GridConfig struct {
 	MaxLength Length `json:"max_length"`
+	// MaxTrials, if set, limits the search to the first points of the grid in enumeration order.
+	MaxTrials *int `json:"max_trials,omitempty"`
 }
 
 // Unit implements the model.InUnits interface.
@@ -119,9 +311,77 @@ func (g GridConfig) Unit() Unit {
 
 // Validate implements the check.Validatable interface.
 func (g GridConfig) Validate() (errs []error) {
-	return []error{
+	errs = []error{
 		check.GreaterThan(g.MaxLength.Units, 0, "max_length must be > 0"),
 	}
+	if g.MaxTrials != nil {
+		errs = append(errs, check.GreaterThan(*g.MaxTrials, 0, "max_trials must be > 0"))
+	}
+	return errs
+}
+
+// ListConfig configures a search that trains one trial for the full length for each of a fixed
+// list of hyperparameter points, in order, e.g., for ablation studies.
+type ListConfig struct {
+	MaxLength           Length                   `json:"max_length"`
+	MaxConcurrentTrials int                      `json:"max_concurrent_trials"`
+	Points              []map[string]interface{} `json:"points"`
+}
+
+// Unit implements the model.InUnits interface.
+func (l ListConfig) Unit() Unit {
+	return l.MaxLength.Unit
+}
+
+// Validate implements the check.Validatable interface. Whether the points match the
+// hyperparameters of the experiment is checked by ExperimentConfig.
+func (l ListConfig) Validate() []error {
+	return []error{
+		check.GreaterThan(l.MaxLength.Units, 0, "max_length must be > 0"),
+		check.GreaterThanOrEqualTo(l.MaxConcurrentTrials, 0, "max_concurrent_trials must be >= 0"),
+		check.GreaterThan(len(l.Points), 0, "points must not be empty"),
+		check.LessThanOrEqualTo(len(l.Points), MaxAllowedTrials,
+			"number of points for list search must be <= %d", MaxAllowedTrials),
+	}
+}
+
+// RacingConfig configures a racing search, which trains all of its trials side by side, treating
+// each validation as a noisy sample of how good the trial is, and closes each trial as soon as a
+// Hoeffding bound shows that it is worse than another.
+type RacingConfig struct {
+	Metric          string `json:"metric"`
+	SmallerIsBetter bool   `json:"smaller_is_better"`
+	MaxLength       Length `json:"max_length"`
+	MaxTrials       int    `json:"max_trials"`
+	// ValidationPeriod is how long trials train between validations.
+	ValidationPeriod Length `json:"validation_period"`
+	// Delta is the probability with which each bound on the mean metric of a trial may be wrong;
+	// the smaller it is, the more validations it takes to close a trial.
+	Delta float64 `json:"delta"`
+	// MetricRange is the width of the range that the metric of every validation falls within,
+	// e.g., 1 for an accuracy.
+	MetricRange float64 `json:"metric_range"`
+}
+
+// Unit implements the model.InUnits interface.
+func (r RacingConfig) Unit() Unit {
+	return r.MaxLength.Unit
+}
+
+// Validate implements the check.Validatable interface.
+func (r RacingConfig) Validate() []error {
+	return []error{
+		check.GreaterThan(r.MaxLength.Units, 0, "max_length must be > 0"),
+		check.GreaterThan(r.MaxTrials, 0, "max_trials must be > 0"),
+		check.LessThanOrEqualTo(r.MaxTrials, MaxAllowedTrials,
+			"max_trials for racing search must be <= %d", MaxAllowedTrials),
+		check.GreaterThan(r.ValidationPeriod.Units, 0, "validation_period must be > 0"),
+		check.Equal(r.ValidationPeriod.Unit, r.MaxLength.Unit,
+			"validation_period must be in the same unit as max_length"),
+		check.GreaterThan(r.Delta, 0.0, "delta must be > 0"),
+		check.LessThan(r.Delta, 1.0, "delta must be < 1"),
+		check.GreaterThan(r.MetricRange, 0.0, "metric_range must be > 0"),
+	}
 }
 
 // SyncHalvingConfig configures synchronous successive halving.
@@ -133,6 +393,18 @@ type SyncHalvingConfig struct {
 	Budget          Length  `json:"budget"`
 	Divisor         float64 `json:"divisor"`
 	TrainStragglers bool    `json:"train_stragglers"`
+	// RungBarrier delays all promotions out of a rung until every trial in it has reported, as in
+	// the original (synchronous) Hyperband algorithm.
+	RungBarrier bool `json:"rung_barrier"`
+}
+
+// Validate implements the check.Validatable interface.
+func (s SyncHalvingConfig) Validate() []error {
+	if !s.RungBarrier {
+		return nil
+	}
+	// A search with a single rung never promotes a trial, so a barrier would have nothing to delay.
+	return []error{check.GreaterThan(s.NumRungs, 1, "rung_barrier requires num_rungs > 1")}
 }
 
 // Unit implements the model.InUnits interface.
@@ -149,17 +421,427 @@ type AsyncHalvingConfig struct {
 	MaxTrials           int     `json:"max_trials"`
 	Divisor             float64 `json:"divisor"`
 	MaxConcurrentTrials int     `json:"max_concurrent_trials"`
+	// FailOnNonFiniteMetric fails the search when a trial reports a NaN or infinite metric instead
+	// of treating the trial as having the worst possible metric.
+	FailOnNonFiniteMetric bool `json:"fail_on_non_finite_metric"`
+	// MaxTime, if set, stops the search from starting new trials or promotions once it has been
+	// running for that long; trials already training are allowed to finish their current rung.
+	MaxTime *Duration `json:"max_time,omitempty"`
+	// TrialValidationTimeout, if set, is how long a trial may take to validate after it is created
+	// or promoted; a trial that takes longer is closed and treated as if it had exited early.
+	TrialValidationTimeout *Duration `json:"trial_validation_timeout,omitempty"`
+	// MetricAggregation, if set, ranks trials by an aggregate of several validation metrics
+	// instead of by Metric alone.
+	MetricAggregation *MetricAggregationConfig `json:"metric_aggregation,omitempty"`
+	// PromotionRatio, if set, is the fraction of the trials in each rung that are promoted to the
+	// next one; otherwise, it is 1/Divisor. Divisor always determines the spacing of the rungs.
+	PromotionRatio *float64 `json:"promotion_ratio,omitempty"`
+	// WarmStart holds hyperparameter values, e.g., those of the best trials of a previous
+	// experiment, for the first trials of the search to use instead of sampling them.
+	WarmStart []map[string]interface{} `json:"warm_start,omitempty"`
+	// RungResources, if set, is the length each trial in each rung trains for, in the unit of
+	// MaxLength, instead of the geometric schedule derived from MaxLength and Divisor.
+	RungResources []int `json:"rung_resources,omitempty"`
+	// EarlyExitMode is how trials that exit early are treated.
+	EarlyExitMode EarlyExitMode `json:"early_exit_mode"`
+	// CancelStragglers, if set, is the fraction of MaxTrials that must have completed before the
+	// search starts closing trials in lower rungs that can no longer be promoted, instead of waiting
+	// for every trial in those rungs to report.
+	CancelStragglers *float64 `json:"cancel_stragglers,omitempty"`
+	// MetricTransform, if set, is applied to the metric of each validation before trials are ranked
+	// by it; SmallerIsBetter applies to the transformed metric.
+	MetricTransform MetricTransform `json:"metric_transform,omitempty"`
+	// MinTrialsPerRung is the number of metrics each rung must have before any trial is promoted
+	// from it.
+	MinTrialsPerRung int `json:"min_trials_per_rung"`
+	// RungRounding is how the length of each rung is rounded to a whole number of units when it is
+	// derived from MaxLength and Divisor.
+	RungRounding RoundingPolicy `json:"rung_rounding"`
+	// SmoothingFactor, if set, ranks each trial by an exponential moving average of the metrics it
+	// has reported instead of by its latest metric alone: each new metric is weighted by
+	// SmoothingFactor and the average of the previous ones by 1 - SmoothingFactor.
+	SmoothingFactor *float64 `json:"smoothing_factor,omitempty"`
+	// PromoteOnBest ranks each trial by the best metric it has reported so far instead of by its
+	// latest one, so that a trial whose metric degrades late in training keeps the credit for its
+	// peak. It applies after SmoothingFactor, if both are set.
+	PromoteOnBest bool `json:"promote_on_best"`
+	// MaxPromotionRung, if set, is the index of the highest rung to which trials are promoted; trials
+	// that report in it are closed as if they had completed the top rung, and the rungs above it
+	// are never used.
+	MaxPromotionRung *int `json:"max_promotion_rung,omitempty"`
+	// InitialDesign is how the hyperparameters of the trials the search starts with are sampled.
+	InitialDesign InitialDesign `json:"initial_design,omitempty"`
+	// RungMetrics, if set, is the metric trials are ranked by in each rung instead of Metric, e.g.,
+	// a cheap proxy in the lower rungs and the real objective in the top one.
+	RungMetrics []RungMetric `json:"rung_metrics,omitempty"`
+	// CheckpointBeforePromotion makes the search checkpoint each promoted trial before it trains
+	// for its new rung, so that the trial resumes from the rung boundary if it is rescheduled. The
+	// trial's workload sequencer runs the checkpoint as a CHECKPOINT_MODEL workload and
+	// acknowledges it to the search like any other Checkpoint operation.
+	CheckpointBeforePromotion bool `json:"checkpoint_before_promotion"`
+	// CostAware, if set, ranks trials by their metric penalized by how long they take to train, so
+	// that a slightly worse but much cheaper trial may be promoted ahead of a better one.
+	CostAware *CostAwareConfig `json:"cost_aware,omitempty"`
+	// TrialRetries is how many times a trial that errored out, e.g., because of a hardware fault, is
+	// started over with the same hyperparameters before it is treated as having exited early.
+	TrialRetries int `json:"trial_retries,omitempty"`
+	// TiebreakMetrics are further validation metrics that rank trials whose searcher metrics are
+	// equal, compared in the order listed.
+	TiebreakMetrics []Objective `json:"tiebreak_metrics,omitempty"`
+	// TiebreakByResource ranks trials that are still tied after TiebreakMetrics by the units they
+	// have trained, more first, so that a tie is broken in favor of the trial that would be cheapest
+	// to train further when its checkpoint is reused.
+	TiebreakByResource bool `json:"tiebreak_by_resource,omitempty"`
+	// PromoteTies promotes every trial that ranks equal to the worst of the best trials of a rung
+	// that are promoted, rather than only those that trial ordering puts inside the cutoff. Trials
+	// that remain tied after any tiebreaks are then all promoted or none are, regardless of their
+	// request IDs.
+	PromoteTies bool `json:"promote_ties,omitempty"`
+	// ReplacementRungThreshold is the index of the lowest rung whose reports create new trials to
+	// take the place of those that finished, so that the bottom rung does not churn while the trials
+	// already in it are yet to be promoted. Reports in lower rungs only create new trials when no
+	// other trial is training.
+	ReplacementRungThreshold int `json:"replacement_rung_threshold,omitempty"`
+	// ValidationsPerRung, if greater than one, is the number of times each trial validates while it
+	// trains toward a rung, at evenly spaced points that end at the rung boundary. Only the last of
+	// them is recorded in the rung and may lead to promotions.
+	ValidationsPerRung int `json:"validations_per_rung,omitempty"`
+	// SpaceFilling draws the hyperparameters of every trial the search creates, not just those of
+	// the initial ones as InitialDesign does, from a randomized Sobol sequence of max_trials points
+	// that is generated when the search starts, in the order the trials are created. The trials are
+	// then spread evenly across the hyperparameter space, however few of them there are, and are
+	// the same for the same seed.
+	SpaceFilling bool `json:"space_filling,omitempty"`
+	// MinTopRungTrials, if set, is the number of trials that must report a metric in the top rung
+	// before the search completes. If the search would otherwise complete with fewer, it creates
+	// more trials than max_trials until enough of them reach the top rung.
+	MinTopRungTrials int `json:"min_top_rung_trials,omitempty"`
+	// Penalties, if set, make the score trials are ranked by worse for each of the soft constraints
+	// on other validation metrics that they violate, e.g., a latency target: the score is the
+	// searcher metric, sign-adjusted so that smaller is better, plus the weight of each penalty
+	// times how far its metric is past its target.
+	Penalties []MetricPenalty `json:"penalties,omitempty"`
+	// AutoFallback, if set, runs a random search with early stopping instead of asynchronous
+	// halving when the hyperparameter space is too small for halving to be worth its overhead.
+	AutoFallback *AutoFallbackConfig `json:"auto_fallback,omitempty"`
+	// ConvergenceStop, if set, stops creating trials once the best metric reported in the bottom
+	// rung has stopped improving, so that max_trials is only an upper bound.
+	ConvergenceStop *ConvergenceStopConfig `json:"convergence_stop,omitempty"`
+}
+
+// AutoFallbackConfig sets when an asynchronous halving search falls back to random search and how
+// that search stops trials early.
+type AutoFallbackConfig struct {
+	// MaxDimensions is the most hyperparameters the search may be over for it to fall back; it
+	// only falls back if all of them are continuous, i.e., double or log hyperparameters.
+	// Constant hyperparameters do not count.
+	MaxDimensions int `json:"max_dimensions"`
+	// ValidationPeriod, Patience, and MinImprovement are as for the random searcher: trials train
+	// and validate in increments of ValidationPeriod, and, with a positive Patience, are stopped
+	// after that many validations without improving by more than MinImprovement.
+	ValidationPeriod Length  `json:"validation_period"`
+	Patience         int     `json:"patience"`
+	MinImprovement   float64 `json:"min_improvement"`
+}
+
+// RandomConfig returns the configuration of the random search that an asynchronous halving search
+// with the given configuration falls back to.
+func (f AutoFallbackConfig) RandomConfig(a AsyncHalvingConfig) RandomConfig {
+	validationPeriod := f.ValidationPeriod
+	return RandomConfig{
+		Metric:              a.Metric,
+		SmallerIsBetter:     a.SmallerIsBetter,
+		MaxLength:           a.MaxLength,
+		MaxTrials:           a.MaxTrials,
+		MaxConcurrentTrials: a.MaxConcurrentTrials,
+		ValidationPeriod:    &validationPeriod,
+		Patience:            f.Patience,
+		MinImprovement:      f.MinImprovement,
+	}
+}
+
+// ConvergenceStopConfig sets when an asynchronous halving search stops creating trials because its
+// best metric has stopped improving.
+type ConvergenceStopConfig struct {
+	// Window is the number of the latest trials to report in the bottom rung over which the best
+	// metric must improve for the search to go on.
+	Window int `json:"window"`
+	// MinImprovement is how much the best metric must improve over the window; a smaller
+	// improvement stops the search from creating more trials.
+	MinImprovement float64 `json:"min_improvement"`
+}
+
+// CostAwareConfig sets the tradeoff between metric and cost for cost-aware promotion.
+type CostAwareConfig struct {
+	// MetricPerSecond is how much of the metric a second of training per batch is worth: each trial
+	// is ranked as if its metric were worse by MetricPerSecond times the wall-clock seconds it has
+	// taken per batch so far.
+	MetricPerSecond float64 `json:"metric_per_second"`
+}
+
+// MetricPenalty is a soft constraint on a validation metric of an asynchronous halving search.
+type MetricPenalty struct {
+	Metric string  `json:"metric"`
+	Target float64 `json:"target"`
+	// SmallerIsBetter must be set: values of the metric above the target violate the constraint if
+	// it is true, and values below it if it is false.
+	SmallerIsBetter *bool `json:"smaller_is_better"`
+	// Weight is how much worse the score of a trial becomes per unit its metric is past the target.
+	Weight float64 `json:"weight"`
 }
 
 // Validate implements the check.Validatable interface.
-func (a AsyncHalvingConfig) Validate() (errs []error) {
+func (p MetricPenalty) Validate() []error {
 	return []error{
-		check.GreaterThan(a.MaxLength.Units, 0, "max_length must be > 0"),
+		check.NotEmpty(p.Metric, "penalties must specify a metric"),
+		check.True(p.SmallerIsBetter != nil,
+			"penalties must specify smaller_is_better for metric %s", p.Metric),
+		check.GreaterThan(p.Weight, 0.0, "penalties must have a weight > 0 for metric %s", p.Metric),
+	}
+}
+
+// Violation returns how far the value of the metric is past the target, or 0 if it is not.
+func (p MetricPenalty) Violation(value float64) float64 {
+	violation := value - p.Target
+	if p.SmallerIsBetter != nil && !*p.SmallerIsBetter {
+		violation *= -1
+	}
+	return math.Max(violation, 0)
+}
+
+// RungMetric is the metric trials are ranked by in one rung of an asynchronous halving search.
+type RungMetric struct {
+	Metric string `json:"metric"`
+	// SmallerIsBetter must be set, since the direction of one metric says nothing about another's.
+	SmallerIsBetter *bool `json:"smaller_is_better"`
+}
+
+// Validate implements the check.Validatable interface.
+func (r RungMetric) Validate() []error {
+	return []error{
+		check.NotEmpty(r.Metric, "rung_metrics must specify a metric"),
+		check.True(r.SmallerIsBetter != nil,
+			"rung_metrics must specify smaller_is_better for metric %s", r.Metric),
+	}
+}
+
+// RungMetric returns the name of the metric trials are ranked by in the rung and whether smaller
+// values of it are better.
+func (a AsyncHalvingConfig) RungMetric(rungIndex int) (string, bool) {
+	if a.RungMetrics == nil {
+		return a.Metric, a.SmallerIsBetter
+	}
+	rungMetric := a.RungMetrics[rungIndex]
+	return rungMetric.Metric, *rungMetric.SmallerIsBetter
+}
+
+// InitialDesign specifies how the hyperparameters of the first trials of a search are sampled.
+type InitialDesign string
+
+const (
+	// RandomDesign samples the hyperparameters of each trial independently.
+	RandomDesign = "random"
+	// SobolDesign samples the hyperparameters of the trials jointly from a randomized Sobol
+	// sequence, which covers the hyperparameter space more evenly than independent samples.
+	SobolDesign = "sobol"
+)
+
+// RoundingPolicy specifies how fractional lengths are rounded to whole numbers of units.
+type RoundingPolicy string
+
+const (
+	// FloorRounding rounds down.
+	FloorRounding = "floor"
+	// RoundRounding rounds to the nearest whole number, with halves rounded away from zero.
+	RoundRounding = "round"
+	// CeilRounding rounds up.
+	CeilRounding = "ceil"
+)
+
+// RungUnits returns the number of units each trial trains for in each rung: RungResources if it is
+// set, and otherwise MaxLength divided by a power of Divisor for each rung below the top one,
+// rounded according to RungRounding but to at least one unit.
+func (a AsyncHalvingConfig) RungUnits() []int {
+	if a.RungResources != nil {
+		return append([]int{}, a.RungResources...)
+	}
+	units := make([]int, 0, a.NumRungs)
+	for id := 0; id < a.NumRungs; id++ {
+		downsamplingRate := math.Pow(a.Divisor, float64(a.NumRungs-id-1))
+		unitsNeeded := float64(a.MaxLength.Units) / downsamplingRate
+		switch a.RungRounding {
+		case RoundRounding:
+			unitsNeeded = math.Round(unitsNeeded)
+		case CeilRounding:
+			unitsNeeded = math.Ceil(unitsNeeded)
+		}
+		units = append(units, int(math.Max(unitsNeeded, 1)))
+	}
+	return units
+}
+
+// EarlyExitMode specifies how asynchronous successive halving treats trials that exit early.
+type EarlyExitMode string
+
+const (
+	// ParticipateEarlyExitMode keeps trials that exit early in the search as if they had reported the
+	// worst possible metric.
+	ParticipateEarlyExitMode = "participate"
+	// CloseEarlyExitMode drops trials that exit early from the search altogether, creating another
+	// trial in place of any that had not yet reported a metric.
+	CloseEarlyExitMode = "close"
+)
+
+// halvingMaxLengthError returns an error if the max_length of a successive halving search is not
+// positive. Every rung would then train trials for the single unit that rung lengths are rounded up
+// to, so the search would silently amount to a random search; the error suggests the shortest
+// max_length that gives each rung its own length instead.
+func halvingMaxLengthError(maxLength Length, numRungs int, divisor float64) error {
+	if maxLength.Units > 0 {
+		return nil
+	}
+	if numRungs <= 0 || divisor <= 1 {
+		return errors.Errorf(
+			"max_length must be > 0, since trials train for at least one unit in every rung; got %d",
+			maxLength.Units)
+	}
+	suggested := int(math.Ceil(math.Pow(divisor, float64(numRungs-1))))
+	return errors.Errorf(
+		"max_length must be > 0, since trials train for at least one unit in every rung and a "+
+			"search whose rungs all train for one unit is no better than random search; got %d, but "+
+			"with num_rungs %d and divisor %v, a max_length of at least %d %s gives each rung its "+
+			"own length", maxLength.Units, numRungs, divisor, suggested, maxLength.Unit)
+}
+
+// Validate implements the check.Validatable interface.
+func (a AsyncHalvingConfig) Validate() (errs []error) {
+	errs = []error{
+		halvingMaxLengthError(a.MaxLength, a.NumRungs, a.Divisor),
 		check.GreaterThan(a.MaxTrials, 0, "max_trials must be > 0"),
 		check.GreaterThan(a.Divisor, 1.0, "divisor must be > 1.0"),
 		check.GreaterThan(a.NumRungs, 0, "num_rungs must be > 0"),
 		check.GreaterThanOrEqualTo(a.MaxConcurrentTrials, 0, "max_concurrent_trials must be >= 0"),
+		check.GreaterThanOrEqualTo(a.MinTrialsPerRung, 0, "min_trials_per_rung must be >= 0"),
+	}
+	if a.MaxTime != nil {
+		errs = append(errs, check.GreaterThan(int64(*a.MaxTime), int64(0), "max_time must be > 0"))
+	}
+	if a.TrialValidationTimeout != nil {
+		errs = append(errs, check.GreaterThan(int64(*a.TrialValidationTimeout), int64(0),
+			"trial_validation_timeout must be > 0"))
+	}
+	if a.EarlyExitMode != "" {
+		errs = append(errs, check.In(string(a.EarlyExitMode),
+			[]string{ParticipateEarlyExitMode, CloseEarlyExitMode}, "invalid early_exit_mode"))
+	}
+	errs = append(errs, check.LessThanOrEqualTo(len(a.WarmStart), a.MaxTrials,
+		"warm_start must not have more points than max_trials"))
+	if a.RungResources != nil {
+		errs = append(errs, check.Equal(len(a.RungResources), a.NumRungs,
+			"rung_resources must have num_rungs entries"))
+		for i, resources := range a.RungResources {
+			if i == 0 {
+				errs = append(errs, check.GreaterThan(resources, 0, "rung_resources must be > 0"))
+			} else {
+				errs = append(errs, check.GreaterThan(resources, a.RungResources[i-1],
+					"rung_resources must be strictly increasing"))
+			}
+		}
+	}
+	if a.PromotionRatio != nil {
+		errs = append(errs,
+			check.GreaterThan(*a.PromotionRatio, 0.0, "promotion_ratio must be > 0"),
+			check.LessThan(*a.PromotionRatio, 1.0, "promotion_ratio must be < 1"))
+	}
+	if a.RungRounding != "" {
+		errs = append(errs, check.In(string(a.RungRounding),
+			[]string{FloorRounding, RoundRounding, CeilRounding}, "invalid rung_rounding"))
+	}
+	// Floor rounding is checked no further, since it is how rung lengths have always been computed.
+	if a.RungResources == nil && (a.RungRounding == RoundRounding || a.RungRounding == CeilRounding) {
+		units := a.RungUnits()
+		for i := 1; i < len(units); i++ {
+			if units[i] <= units[i-1] {
+				errs = append(errs, errors.Errorf(
+					"rung lengths %v are not strictly increasing with %s rounding; use fewer rungs",
+					units, a.RungRounding))
+				break
+			}
+		}
 	}
+	if a.MetricTransform != "" {
+		errs = append(errs, check.In(string(a.MetricTransform),
+			[]string{IdentityTransform, NegateTransform, ReciprocalTransform, LogTransform},
+			"invalid metric_transform"))
+	}
+	if a.CancelStragglers != nil {
+		errs = append(errs,
+			check.GreaterThan(*a.CancelStragglers, 0.0, "cancel_stragglers must be > 0"),
+			check.LessThanOrEqualTo(*a.CancelStragglers, 1.0, "cancel_stragglers must be <= 1"))
+	}
+	if a.SmoothingFactor != nil {
+		errs = append(errs,
+			check.GreaterThan(*a.SmoothingFactor, 0.0, "smoothing_factor must be > 0"),
+			check.LessThanOrEqualTo(*a.SmoothingFactor, 1.0, "smoothing_factor must be <= 1"))
+	}
+	if a.InitialDesign != "" {
+		errs = append(errs, check.In(string(a.InitialDesign),
+			[]string{RandomDesign, SobolDesign}, "invalid initial_design"))
+	}
+	if a.RungMetrics != nil {
+		// Aggregating, smoothing, or taking the best of metrics is only meaningful for one metric.
+		errs = append(errs,
+			check.Equal(len(a.RungMetrics), a.NumRungs, "rung_metrics must have num_rungs entries"),
+			check.True(a.MetricAggregation == nil,
+				"rung_metrics cannot be combined with metric_aggregation"),
+			check.True(a.SmoothingFactor == nil,
+				"rung_metrics cannot be combined with smoothing_factor"),
+			check.False(a.PromoteOnBest, "rung_metrics cannot be combined with promote_on_best"))
+	}
+	for _, penalty := range a.Penalties {
+		// The searcher metric is what is penalized, so it cannot be a constraint of its own.
+		errs = append(errs, check.True(penalty.Metric != a.Metric,
+			"penalties cannot constrain the searcher metric %s", a.Metric))
+	}
+	if a.MaxPromotionRung != nil {
+		errs = append(errs,
+			check.GreaterThanOrEqualTo(*a.MaxPromotionRung, 0, "max_promotion_rung must be >= 0"),
+			check.LessThan(*a.MaxPromotionRung, a.NumRungs, "max_promotion_rung must be < num_rungs"))
+	}
+	if a.CostAware != nil {
+		errs = append(errs, check.GreaterThan(a.CostAware.MetricPerSecond, 0.0,
+			"cost_aware.metric_per_second must be > 0"))
+	}
+	if a.AutoFallback != nil {
+		errs = append(errs,
+			check.GreaterThanOrEqualTo(a.AutoFallback.MaxDimensions, 0,
+				"auto_fallback.max_dimensions must be >= 0"),
+			check.GreaterThan(a.AutoFallback.ValidationPeriod.Units, 0,
+				"auto_fallback.validation_period must be > 0"),
+			check.Equal(a.AutoFallback.ValidationPeriod.Unit, a.MaxLength.Unit,
+				"auto_fallback.validation_period must be in the unit of max_length"),
+			check.GreaterThanOrEqualTo(a.AutoFallback.Patience, 0,
+				"auto_fallback.patience must be >= 0"),
+			check.GreaterThanOrEqualTo(a.AutoFallback.MinImprovement, 0.0,
+				"auto_fallback.min_improvement must be >= 0"))
+	}
+	if a.ConvergenceStop != nil {
+		errs = append(errs,
+			check.GreaterThan(a.ConvergenceStop.Window, 0, "convergence_stop.window must be > 0"),
+			check.GreaterThanOrEqualTo(a.ConvergenceStop.MinImprovement, 0.0,
+				"convergence_stop.min_improvement must be >= 0"))
+	}
+	errs = append(errs,
+		check.GreaterThanOrEqualTo(a.TrialRetries, 0, "trial_retries must be >= 0"),
+		check.GreaterThanOrEqualTo(a.ReplacementRungThreshold, 0,
+			"replacement_rung_threshold must be >= 0"),
+		check.LessThan(a.ReplacementRungThreshold, a.NumRungs,
+			"replacement_rung_threshold must be < num_rungs"),
+		check.GreaterThanOrEqualTo(a.ValidationsPerRung, 0, "validations_per_rung must be >= 0"),
+		check.GreaterThanOrEqualTo(a.MinTopRungTrials, 0, "min_top_rung_trials must be >= 0"),
+		check.LessThanOrEqualTo(a.MinTopRungTrials, a.MaxTrials,
+			"min_top_rung_trials must be <= max_trials"))
+	return errs
 }
 
 // Unit implements the model.InUnits interface.
@@ -167,6 +849,67 @@ func (a AsyncHalvingConfig) Unit() Unit {
 	return a.MaxLength.Unit
 }
 
+// MetricTransform names a function that is applied to a metric before it is compared.
+type MetricTransform string
+
+const (
+	// IdentityTransform leaves the metric unchanged.
+	IdentityTransform = "identity"
+	// NegateTransform negates the metric.
+	NegateTransform = "negate"
+	// ReciprocalTransform takes the reciprocal of the metric.
+	ReciprocalTransform = "reciprocal"
+	// LogTransform takes the natural logarithm of the metric.
+	LogTransform = "log"
+)
+
+// AggregationMode specifies how several validation metrics are combined into one.
+type AggregationMode string
+
+const (
+	// MeanAggregation takes the mean of the metrics.
+	MeanAggregation = "mean"
+	// MaxAggregation takes the largest of the metrics.
+	MaxAggregation = "max"
+	// MinAggregation takes the smallest of the metrics.
+	MinAggregation = "min"
+	// WeightedMeanAggregation takes the mean of the metrics weighted by the given weights.
+	WeightedMeanAggregation = "weighted_mean"
+)
+
+// MetricAggregationConfig configures how to combine several validation metrics, e.g., the same
+// metric computed on different validation datasets, into the single value a searcher ranks
+// trials by.
+type MetricAggregationConfig struct {
+	Mode    AggregationMode `json:"mode"`
+	Metrics []string        `json:"metrics"`
+	Weights []float64       `json:"weights,omitempty"`
+}
+
+// Validate implements the check.Validatable interface.
+func (m MetricAggregationConfig) Validate() []error {
+	errs := []error{
+		check.In(string(m.Mode),
+			[]string{MeanAggregation, MaxAggregation, MinAggregation, WeightedMeanAggregation},
+			"invalid metric aggregation mode"),
+		check.GreaterThan(len(m.Metrics), 0, "metric aggregation must have at least one metric"),
+	}
+	if m.Mode == WeightedMeanAggregation {
+		errs = append(errs, check.Equal(len(m.Weights), len(m.Metrics),
+			"weighted_mean aggregation must have one weight per metric"))
+		total := 0.0
+		for _, weight := range m.Weights {
+			errs = append(errs, check.GreaterThanOrEqualTo(weight, 0.0, "weights must be >= 0"))
+			total += weight
+		}
+		errs = append(errs, check.GreaterThan(total, 0.0, "weights must not all be zero"))
+	} else {
+		errs = append(errs, check.Equal(len(m.Weights), 0,
+			"weights are only supported by weighted_mean aggregation"))
+	}
+	return errs
+}
+
 // AdaptiveMode specifies how aggressively to perform early stopping.
 type AdaptiveMode string
 
@@ -192,11 +935,12 @@ type AdaptiveConfig struct {
 	TrainStragglers bool         `json:"train_stragglers"`
 	Mode            AdaptiveMode `json:"mode"`
 	MaxRungs        int          `json:"max_rungs"`
+	RungBarrier     bool         `json:"rung_barrier"`
 }
 
 // Validate implements the check.Validatable interface.
 func (a AdaptiveConfig) Validate() []error {
-	return []error{
+	errs := []error{
 		check.GreaterThan(a.Budget.Units, a.MaxLength.Units,
 			"budget must be > max_length"),
 		check.GreaterThan(a.MaxLength.Units, 0, "max_length must be > 0"),
@@ -208,6 +952,21 @@ func (a AdaptiveConfig) Validate() []error {
 		check.Equal(a.MaxLength.Unit, a.Budget.Unit,
 			"max_length and budget must be specified in terms of the same unit"),
 	}
+	if a.RungBarrier {
+		// The largest bracket is the one with max_rungs rungs unless the brackets are given.
+		maxBracketRungs := a.MaxRungs
+		if len(a.BracketRungs) > 0 {
+			maxBracketRungs = 0
+			for _, numRungs := range a.BracketRungs {
+				if numRungs > maxBracketRungs {
+					maxBracketRungs = numRungs
+				}
+			}
+		}
+		errs = append(errs, check.GreaterThan(maxBracketRungs, 1,
+			"rung_barrier requires a bracket with more than one rung"))
+	}
+	return errs
 }
 
 // Unit implements the model.InUnits interface.
@@ -256,6 +1015,10 @@ type AdaptiveASHAConfig struct {
 	Mode                AdaptiveMode `json:"mode"`
 	MaxRungs            int          `json:"max_rungs"`
 	MaxConcurrentTrials int          `json:"max_concurrent_trials"`
+	// AdaptToNoise holds back some of the trials from the brackets and assigns each of them to a
+	// bracket as the others finish, favoring the brackets that stop trials early when the metric
+	// ranks trials consistently across training lengths and the others when it is noisy.
+	AdaptToNoise bool `json:"adapt_to_noise"`
 }
 
 // Validate implements the check.Validatable interface.
@@ -276,6 +1039,241 @@ func (a AdaptiveASHAConfig) Unit() Unit {
 	return a.MaxLength.Unit
 }
 
+// BOHBConfig configures a BOHB search, which allocates training between trials like an adaptive
+// ASHA search but, after NumStartupTrials trials have reported in some rung, samples the
+// hyperparameters of new trials from a TPE model fit to the trials in the highest such rung.
+type BOHBConfig struct {
+	Metric              string       `json:"metric"`
+	SmallerIsBetter     bool         `json:"smaller_is_better"`
+	MaxLength           Length       `json:"max_length"`
+	MaxTrials           int          `json:"max_trials"`
+	BracketRungs        []int        `json:"bracket_rungs"`
+	Divisor             float64      `json:"divisor"`
+	Mode                AdaptiveMode `json:"mode"`
+	MaxRungs            int          `json:"max_rungs"`
+	MaxConcurrentTrials int          `json:"max_concurrent_trials"`
+	NumStartupTrials    int          `json:"num_startup_trials"`
+	Gamma               float64      `json:"gamma"`
+	NumCandidates       int          `json:"num_candidates"`
+}
+
+// AdaptiveASHAConfig returns the configuration of the adaptive ASHA search that allocates the
+// training of the trials.
+func (b BOHBConfig) AdaptiveASHAConfig() AdaptiveASHAConfig {
+	return AdaptiveASHAConfig{
+		Metric:              b.Metric,
+		SmallerIsBetter:     b.SmallerIsBetter,
+		MaxLength:           b.MaxLength,
+		MaxTrials:           b.MaxTrials,
+		BracketRungs:        b.BracketRungs,
+		Divisor:             b.Divisor,
+		Mode:                b.Mode,
+		MaxRungs:            b.MaxRungs,
+		MaxConcurrentTrials: b.MaxConcurrentTrials,
+	}
+}
+
+// Validate implements the check.Validatable interface.
+func (b BOHBConfig) Validate() []error {
+	return []error{
+		check.GreaterThan(b.MaxLength.Units, 0, "max_length must be > 0"),
+		check.GreaterThan(b.MaxTrials, 0, "max_trials must be > 0"),
+		check.GreaterThan(b.Divisor, 1.0, "divisor must be > 1.0"),
+		check.In(string(b.Mode), []string{AggressiveMode, StandardMode, ConservativeMode},
+			"invalid adaptive mode"),
+		check.GreaterThan(b.MaxRungs, 0, "max_rungs must be > 0"),
+		check.GreaterThanOrEqualTo(b.MaxConcurrentTrials, 0, "max_concurrent_trials must be >= 0"),
+		check.GreaterThan(b.NumStartupTrials, 0, "num_startup_trials must be > 0"),
+		check.GreaterThan(b.Gamma, 0.0, "gamma must be > 0"),
+		check.LessThan(b.Gamma, 1.0, "gamma must be < 1"),
+		check.GreaterThan(b.NumCandidates, 0, "num_candidates must be > 0"),
+	}
+}
+
+// Unit implements the model.InUnits interface.
+func (b BOHBConfig) Unit() Unit {
+	return b.MaxLength.Unit
+}
+
+// Objective is one of the validation metrics optimized by a multi-objective search.
+type Objective struct {
+	Metric          string `json:"metric"`
+	SmallerIsBetter bool   `json:"smaller_is_better"`
+}
+
+// Validate implements the check.Validatable interface.
+func (o Objective) Validate() []error {
+	return []error{
+		check.NotEmpty(o.Metric, "objectives must specify a metric"),
+	}
+}
+
+// MultiObjectiveConfig configures asynchronous successive halving over several objectives at
+// once: instead of ranking trials by a single metric, each rung promotes the trials that are
+// dominated by the fewest other trials in the rung.
+type MultiObjectiveConfig struct {
+	Objectives          []Objective `json:"objectives"`
+	NumRungs            int         `json:"num_rungs"`
+	MaxLength           Length      `json:"max_length"`
+	MaxTrials           int         `json:"max_trials"`
+	Divisor             float64     `json:"divisor"`
+	MaxConcurrentTrials int         `json:"max_concurrent_trials"`
+}
+
+// Validate implements the check.Validatable interface.
+func (m MultiObjectiveConfig) Validate() []error {
+	seen := make(map[string]bool)
+	var duplicates []string
+	for _, objective := range m.Objectives {
+		if seen[objective.Metric] {
+			duplicates = append(duplicates, objective.Metric)
+		}
+		seen[objective.Metric] = true
+	}
+	return []error{
+		check.GreaterThanOrEqualTo(len(m.Objectives), 2, "must specify at least two objectives"),
+		check.TrueSilent(len(duplicates) == 0, "duplicate objectives: %v", duplicates),
+		check.GreaterThan(m.MaxLength.Units, 0, "max_length must be > 0"),
+		check.GreaterThan(m.MaxTrials, 0, "max_trials must be > 0"),
+		check.GreaterThan(m.Divisor, 1.0, "divisor must be > 1.0"),
+		check.GreaterThan(m.NumRungs, 0, "num_rungs must be > 0"),
+		check.GreaterThanOrEqualTo(m.MaxConcurrentTrials, 0, "max_concurrent_trials must be >= 0"),
+	}
+}
+
+// Unit implements the model.InUnits interface.
+func (m MultiObjectiveConfig) Unit() Unit {
+	return m.MaxLength.Unit
+}
+
+// TPEConfig configures a tree-structured Parzen estimator (TPE) search: after NumStartupTrials
+// random trials, the hyperparameters of each new trial are chosen to be likely under the
+// hyperparameters of the best Gamma fraction of the completed trials and unlikely under those of
+// the rest.
+type TPEConfig struct {
+	Metric              string  `json:"metric"`
+	SmallerIsBetter     bool    `json:"smaller_is_better"`
+	MaxLength           Length  `json:"max_length"`
+	MaxTrials           int     `json:"max_trials"`
+	MaxConcurrentTrials int     `json:"max_concurrent_trials"`
+	NumStartupTrials    int     `json:"num_startup_trials"`
+	Gamma               float64 `json:"gamma"`
+	NumCandidates       int     `json:"num_candidates"`
+	// RestartProbability is the probability that a trial after the startup trials is sampled
+	// randomly rather than proposed from the densities, to keep the search exploring.
+	RestartProbability float64 `json:"restart_probability"`
+	// PriorWeight is how much each observation loaded from another experiment as a prior counts
+	// for, relative to a trial of the search, both in the densities and toward NumStartupTrials.
+	PriorWeight float64 `json:"prior_weight"`
+}
+
+// Validate implements the check.Validatable interface.
+func (t TPEConfig) Validate() []error {
+	return []error{
+		check.GreaterThan(t.MaxLength.Units, 0, "max_length must be > 0"),
+		check.GreaterThan(t.MaxTrials, 0, "max_trials must be > 0"),
+		check.GreaterThanOrEqualTo(t.MaxConcurrentTrials, 0, "max_concurrent_trials must be >= 0"),
+		check.GreaterThan(t.NumStartupTrials, 0, "num_startup_trials must be > 0"),
+		check.GreaterThan(t.Gamma, 0.0, "gamma must be > 0"),
+		check.LessThan(t.Gamma, 1.0, "gamma must be < 1"),
+		check.GreaterThan(t.NumCandidates, 0, "num_candidates must be > 0"),
+		check.GreaterThanOrEqualTo(t.RestartProbability, 0.0, "restart_probability must be >= 0"),
+		check.LessThanOrEqualTo(t.RestartProbability, 1.0, "restart_probability must be <= 1"),
+		check.GreaterThan(t.PriorWeight, 0.0, "prior_weight must be > 0"),
+		check.LessThanOrEqualTo(t.PriorWeight, 1.0, "prior_weight must be <= 1"),
+	}
+}
+
+// Unit implements the model.InUnits interface.
+func (t TPEConfig) Unit() Unit {
+	return t.MaxLength.Unit
+}
+
+// BayesianConfig configures a Bayesian optimization search: after NumStartupTrials random trials,
+// a Gaussian process is fit to the metrics of the completed trials, and each new trial uses the
+// candidate, out of NumCandidates random samples, with the highest expected improvement on the
+// best metric so far.
+type BayesianConfig struct {
+	Metric              string `json:"metric"`
+	SmallerIsBetter     bool   `json:"smaller_is_better"`
+	MaxLength           Length `json:"max_length"`
+	MaxTrials           int    `json:"max_trials"`
+	MaxConcurrentTrials int    `json:"max_concurrent_trials"`
+	NumStartupTrials    int    `json:"num_startup_trials"`
+	NumCandidates       int    `json:"num_candidates"`
+	// Kernel is the covariance function of the Gaussian process, and LengthScale its length scale
+	// over hyperparameters normalized to the unit interval.
+	Kernel      KernelType `json:"kernel"`
+	LengthScale float64    `json:"length_scale"`
+	// Jitter is added to the variance of every observation, both to model noise in the metric and
+	// to keep the covariance matrix well conditioned.
+	Jitter float64 `json:"jitter"`
+	// RestartProbability is the probability that a trial after the startup trials is sampled
+	// randomly rather than chosen by expected improvement, to keep the search exploring.
+	RestartProbability float64 `json:"restart_probability"`
+	// Monotone maps numeric hyperparameters to the direction in which changing them is known to
+	// improve the metric, at least up to a point.
+	Monotone map[string]Monotonicity `json:"monotone,omitempty"`
+	// PriorWeight is how much each observation loaded from another experiment as a prior counts
+	// for, relative to a trial of the search, toward NumStartupTrials; the Gaussian process treats
+	// it as having a noise variance of 1/prior_weight - 1 times that of the metrics.
+	PriorWeight float64 `json:"prior_weight"`
+}
+
+// Monotonicity specifies in which direction changing a hyperparameter improves the metric.
+type Monotonicity string
+
+const (
+	// IncreasingMonotonicity means that larger values of the hyperparameter are better.
+	IncreasingMonotonicity = "increasing"
+	// DecreasingMonotonicity means that smaller values of the hyperparameter are better.
+	DecreasingMonotonicity = "decreasing"
+)
+
+// KernelType specifies the covariance function of a Gaussian process.
+type KernelType string
+
+const (
+	// RBFKernel is the squared exponential kernel.
+	RBFKernel = "rbf"
+	// Matern52Kernel is the Matern kernel with smoothness 5/2.
+	Matern52Kernel = "matern52"
+)
+
+// Validate implements the check.Validatable interface.
+func (b BayesianConfig) Validate() []error {
+	errs := []error{
+		check.GreaterThan(b.MaxLength.Units, 0, "max_length must be > 0"),
+		check.GreaterThan(b.MaxTrials, 0, "max_trials must be > 0"),
+		check.GreaterThanOrEqualTo(b.MaxConcurrentTrials, 0, "max_concurrent_trials must be >= 0"),
+		check.GreaterThan(b.NumStartupTrials, 0, "num_startup_trials must be > 0"),
+		check.GreaterThan(b.NumCandidates, 0, "num_candidates must be > 0"),
+		check.In(string(b.Kernel), []string{RBFKernel, Matern52Kernel}, "invalid kernel"),
+		check.GreaterThan(b.LengthScale, 0.0, "length_scale must be > 0"),
+		check.GreaterThan(b.Jitter, 0.0, "jitter must be > 0"),
+		check.GreaterThanOrEqualTo(b.RestartProbability, 0.0, "restart_probability must be >= 0"),
+		check.LessThanOrEqualTo(b.RestartProbability, 1.0, "restart_probability must be <= 1"),
+		check.GreaterThan(b.PriorWeight, 0.0, "prior_weight must be > 0"),
+		check.LessThanOrEqualTo(b.PriorWeight, 1.0, "prior_weight must be <= 1"),
+	}
+	names := make([]string, 0, len(b.Monotone))
+	for name := range b.Monotone {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		errs = append(errs, check.In(string(b.Monotone[name]),
+			[]string{IncreasingMonotonicity, DecreasingMonotonicity},
+			"invalid monotonicity for hyperparameter %s", name))
+	}
+	return errs
+}
+
+// Unit implements the model.InUnits interface.
+func (b BayesianConfig) Unit() Unit {
+	return b.MaxLength.Unit
+}
+
 // PBTReplaceConfig configures replacement for a PBT search.
 type PBTReplaceConfig struct {
 	TruncateFraction float64 `json:"truncate_fraction"`
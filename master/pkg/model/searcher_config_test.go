@@ -3,9 +3,13 @@ package model
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"gotest.tools/assert"
+
+	"github.com/determined-ai/determined/master/pkg/check"
 )
 
 func TestASHAMaxConcurrentTrials(t *testing.T) {
@@ -257,3 +261,701 @@ func TestLength(t *testing.T) {
 		})
 	}
 }
+
+func TestCustomSearcherConfig(t *testing.T) {
+	var actual = DefaultExperimentConfig().Searcher
+	assert.NilError(t, json.Unmarshal([]byte(`
+{
+  "name": "custom",
+  "metric": "metric",
+  "max_length": {"records": 100},
+  "extra": [1, 2]
+}
+`), &actual))
+	assert.Equal(t, actual.Name(), "custom")
+	assert.Equal(t, actual.Unit(), Records)
+	// Searchers that are not built in only validate once they are registered.
+	assert.ErrorContains(t, check.Validate(actual), "unknown searcher type: custom")
+	RegisterSearcherName("custom")
+	assert.NilError(t, check.Validate(actual))
+	assert.Assert(t, actual.SingleConfig == nil)
+	assert.Equal(t, actual.Metric, "metric")
+
+	bytes, err := json.Marshal(actual)
+	assert.NilError(t, err)
+	var roundTrip map[string]interface{}
+	assert.NilError(t, json.Unmarshal(bytes, &roundTrip))
+	assert.Equal(t, roundTrip["name"], "custom")
+	assert.Equal(t, roundTrip["metric"], "metric")
+	assert.DeepEqual(t, roundTrip["extra"], []interface{}{1.0, 2.0})
+}
+
+func TestMetricAggregationConfig(t *testing.T) {
+	valid := MetricAggregationConfig{
+		Mode: WeightedMeanAggregation, Metrics: []string{"a", "b"}, Weights: []float64{1, 3},
+	}
+	assert.NilError(t, check.Validate(valid))
+	assert.NilError(t, check.Validate(MetricAggregationConfig{
+		Mode: MinAggregation, Metrics: []string{"a"},
+	}))
+
+	invalid := []MetricAggregationConfig{
+		{Mode: "median", Metrics: []string{"a"}},
+		{Mode: MeanAggregation},
+		{Mode: MeanAggregation, Metrics: []string{"a"}, Weights: []float64{1}},
+		{Mode: WeightedMeanAggregation, Metrics: []string{"a", "b"}, Weights: []float64{1}},
+		{Mode: WeightedMeanAggregation, Metrics: []string{"a"}, Weights: []float64{-1}},
+		{Mode: WeightedMeanAggregation, Metrics: []string{"a"}, Weights: []float64{0}},
+	}
+	for _, config := range invalid {
+		assert.Assert(t, check.Validate(config) != nil, "%+v", config)
+	}
+}
+
+func TestMultiObjectiveConfig(t *testing.T) {
+	var actual = DefaultExperimentConfig().Searcher
+	assert.NilError(t, json.Unmarshal([]byte(`
+{
+  "name": "multi_objective_asha",
+  "metric": "accuracy",
+  "objectives": [
+    {"metric": "accuracy", "smaller_is_better": false},
+    {"metric": "latency", "smaller_is_better": true}
+  ],
+  "num_rungs": 3,
+  "max_trials": 9,
+  "max_length": {"batches": 900}
+}
+`), &actual))
+	assert.Equal(t, actual.Name(), "multi_objective_asha")
+	assert.DeepEqual(t, *actual.MultiObjectiveConfig, MultiObjectiveConfig{
+		Objectives: []Objective{
+			{Metric: "accuracy", SmallerIsBetter: false},
+			{Metric: "latency", SmallerIsBetter: true},
+		},
+		NumRungs:  3,
+		MaxLength: NewLengthInBatches(900),
+		MaxTrials: 9,
+		Divisor:   4,
+	})
+	assert.NilError(t, check.Validate(actual))
+
+	invalid := *actual.MultiObjectiveConfig
+	invalid.Objectives = []Objective{{Metric: "accuracy"}, {Metric: "accuracy"}}
+	assert.ErrorContains(t, check.Validate(invalid), "duplicate objectives")
+	invalid.Objectives = invalid.Objectives[:1]
+	assert.ErrorContains(t, check.Validate(invalid), "at least two objectives")
+}
+
+func TestTPEConfig(t *testing.T) {
+	var actual = DefaultExperimentConfig().Searcher
+	assert.NilError(t, json.Unmarshal([]byte(`
+{
+  "name": "tpe",
+  "metric": "loss",
+  "max_trials": 50,
+  "max_length": {"batches": 1000},
+  "gamma": 0.2
+}
+`), &actual))
+	assert.Equal(t, actual.Name(), "tpe")
+	assert.DeepEqual(t, *actual.TPEConfig, TPEConfig{
+		Metric:           "loss",
+		SmallerIsBetter:  true,
+		MaxLength:        NewLengthInBatches(1000),
+		MaxTrials:        50,
+		NumStartupTrials: 10,
+		Gamma:            0.2,
+		NumCandidates:    24,
+		PriorWeight:      0.5,
+	})
+	assert.NilError(t, check.Validate(actual))
+
+	invalid := *actual.TPEConfig
+	invalid.Gamma = 1
+	assert.ErrorContains(t, check.Validate(invalid), "gamma must be < 1")
+	invalid.Gamma, invalid.NumStartupTrials = 0.2, 0
+	assert.ErrorContains(t, check.Validate(invalid), "num_startup_trials must be > 0")
+	invalid.NumStartupTrials, invalid.RestartProbability = 10, 1.5
+	assert.ErrorContains(t, check.Validate(invalid), "restart_probability must be <= 1")
+	invalid.RestartProbability, invalid.PriorWeight = 0, 0
+	assert.ErrorContains(t, check.Validate(invalid), "prior_weight must be > 0")
+}
+
+func TestBayesianConfig(t *testing.T) {
+	var actual = DefaultExperimentConfig().Searcher
+	assert.NilError(t, json.Unmarshal([]byte(`
+{
+  "name": "bayesian",
+  "metric": "loss",
+  "max_trials": 30,
+  "max_length": {"batches": 1000},
+  "kernel": "rbf"
+}
+`), &actual))
+	assert.Equal(t, actual.Name(), "bayesian")
+	assert.DeepEqual(t, *actual.BayesianConfig, BayesianConfig{
+		Metric:           "loss",
+		SmallerIsBetter:  true,
+		MaxLength:        NewLengthInBatches(1000),
+		MaxTrials:        30,
+		NumStartupTrials: 5,
+		NumCandidates:    500,
+		Kernel:           RBFKernel,
+		LengthScale:      0.25,
+		Jitter:           1e-4,
+		PriorWeight:      0.5,
+	})
+	assert.NilError(t, check.Validate(actual))
+
+	invalid := *actual.BayesianConfig
+	invalid.Kernel = "linear"
+	assert.ErrorContains(t, check.Validate(invalid), "invalid kernel")
+	invalid.Kernel, invalid.Jitter = RBFKernel, 0
+	assert.ErrorContains(t, check.Validate(invalid), "jitter must be > 0")
+	invalid.Jitter, invalid.RestartProbability = 1e-4, -0.1
+	assert.ErrorContains(t, check.Validate(invalid), "restart_probability must be >= 0")
+	invalid.RestartProbability = 0
+	invalid.Monotone = map[string]Monotonicity{"lr": DecreasingMonotonicity, "layers": "up"}
+	assert.ErrorContains(t, check.Validate(invalid), "invalid monotonicity for hyperparameter layers")
+	invalid.Monotone, invalid.PriorWeight = nil, 1.5
+	assert.ErrorContains(t, check.Validate(invalid), "prior_weight must be <= 1")
+}
+
+func TestBOHBConfig(t *testing.T) {
+	var actual = DefaultExperimentConfig().Searcher
+	assert.NilError(t, json.Unmarshal([]byte(`
+{
+  "name": "bohb",
+  "metric": "loss",
+  "max_trials": 64,
+  "max_length": {"batches": 256},
+  "max_concurrent_trials": 4
+}
+`), &actual))
+	assert.Equal(t, actual.Name(), "bohb")
+	assert.DeepEqual(t, *actual.BOHBConfig, BOHBConfig{
+		Metric:              "loss",
+		SmallerIsBetter:     true,
+		MaxLength:           NewLengthInBatches(256),
+		MaxTrials:           64,
+		Divisor:             4,
+		Mode:                StandardMode,
+		MaxRungs:            5,
+		MaxConcurrentTrials: 4,
+		NumStartupTrials:    10,
+		Gamma:               0.25,
+		NumCandidates:       24,
+	})
+	assert.NilError(t, check.Validate(actual))
+
+	invalid := *actual.BOHBConfig
+	invalid.Divisor = 1
+	assert.ErrorContains(t, check.Validate(invalid), "divisor must be > 1.0")
+	invalid.Divisor, invalid.Gamma = 4, 0
+	assert.ErrorContains(t, check.Validate(invalid), "gamma must be > 0")
+}
+
+func TestAsyncHalvingPromotionRatio(t *testing.T) {
+	config := AsyncHalvingConfig{
+		NumRungs:  3,
+		MaxLength: NewLengthInBatches(900),
+		MaxTrials: 9,
+		Divisor:   3,
+	}
+	assert.NilError(t, check.Validate(config))
+	for _, ratio := range []float64{0.1, 0.5, 0.9} {
+		config.PromotionRatio = &ratio
+		assert.NilError(t, check.Validate(config))
+	}
+	for _, ratio := range []float64{-0.5, 0, 1, 2} {
+		config.PromotionRatio = &ratio
+		assert.ErrorContains(t, check.Validate(config), "promotion_ratio")
+	}
+}
+
+func TestAsyncHalvingWarmStart(t *testing.T) {
+	config := AsyncHalvingConfig{
+		NumRungs:  3,
+		MaxLength: NewLengthInBatches(900),
+		MaxTrials: 2,
+		Divisor:   3,
+		WarmStart: []map[string]interface{}{{"lr": 0.1}, {"lr": 0.01}},
+	}
+	assert.NilError(t, check.Validate(config))
+	config.WarmStart = append(config.WarmStart, map[string]interface{}{"lr": 0.001})
+	assert.ErrorContains(t, check.Validate(config), "warm_start")
+}
+
+func TestAsyncHalvingRungResources(t *testing.T) {
+	config := AsyncHalvingConfig{
+		NumRungs:      4,
+		MaxLength:     NewLengthInBatches(30),
+		MaxTrials:     27,
+		Divisor:       3,
+		RungResources: []int{1, 3, 9, 30},
+	}
+	assert.NilError(t, check.Validate(config))
+
+	for _, resources := range [][]int{{1, 3, 9}, {0, 3, 9, 30}, {1, 3, 3, 30}, {1, 9, 3, 30}} {
+		config.RungResources = resources
+		assert.ErrorContains(t, check.Validate(config), "rung_resources", "%v", resources)
+	}
+}
+
+func TestAsyncHalvingEarlyExitMode(t *testing.T) {
+	config := *DefaultExperimentConfig().Searcher.AsyncHalvingConfig
+	config.NumRungs, config.MaxLength, config.MaxTrials = 3, NewLengthInBatches(900), 9
+	assert.Equal(t, config.EarlyExitMode, EarlyExitMode(ParticipateEarlyExitMode))
+	assert.NilError(t, check.Validate(config))
+	config.EarlyExitMode = CloseEarlyExitMode
+	assert.NilError(t, check.Validate(config))
+	config.EarlyExitMode = "ignore"
+	assert.ErrorContains(t, check.Validate(config), "invalid early_exit_mode")
+}
+
+func TestRandomEarlyStopping(t *testing.T) {
+	config := *DefaultExperimentConfig().Searcher.RandomConfig
+	config.MaxLength, config.MaxTrials = NewLengthInBatches(1000), 4
+	assert.NilError(t, check.Validate(config))
+	config.Patience = 3
+	assert.ErrorContains(t, check.Validate(config), "patience requires a validation_period")
+	period := NewLengthInRecords(100)
+	config.ValidationPeriod = &period
+	assert.ErrorContains(t, check.Validate(config), "same unit as max_length")
+	period = NewLengthInBatches(100)
+	assert.NilError(t, check.Validate(config))
+	config.MetricHistoryWindow = 3
+	assert.ErrorContains(t, check.Validate(config), "metric_history_window must be > patience")
+	config.MetricHistoryWindow = 10
+	assert.NilError(t, check.Validate(config))
+	config.MinImprovement = -1
+	assert.ErrorContains(t, check.Validate(config), "min_improvement must be >= 0")
+}
+
+func TestAsyncHalvingCancelStragglers(t *testing.T) {
+	config := AsyncHalvingConfig{
+		NumRungs:  3,
+		MaxLength: NewLengthInBatches(900),
+		MaxTrials: 9,
+		Divisor:   3,
+	}
+	for _, fraction := range []float64{0.1, 0.5, 1} {
+		config.CancelStragglers = &fraction
+		assert.NilError(t, check.Validate(config))
+	}
+	for _, fraction := range []float64{-0.5, 0, 1.5} {
+		config.CancelStragglers = &fraction
+		assert.ErrorContains(t, check.Validate(config), "cancel_stragglers")
+	}
+}
+
+func TestAsyncHalvingSmoothingFactor(t *testing.T) {
+	config := AsyncHalvingConfig{
+		NumRungs:  3,
+		MaxLength: NewLengthInBatches(900),
+		MaxTrials: 9,
+		Divisor:   3,
+	}
+	for _, factor := range []float64{0.1, 0.5, 1} {
+		config.SmoothingFactor = &factor
+		assert.NilError(t, check.Validate(config))
+	}
+	for _, factor := range []float64{-0.5, 0, 1.5} {
+		config.SmoothingFactor = &factor
+		assert.ErrorContains(t, check.Validate(config), "smoothing_factor")
+	}
+}
+
+func TestAsyncHalvingMaxPromotionRung(t *testing.T) {
+	config := AsyncHalvingConfig{
+		NumRungs:  3,
+		MaxLength: NewLengthInBatches(900),
+		MaxTrials: 9,
+		Divisor:   3,
+	}
+	for _, rung := range []int{0, 1, 2} {
+		config.MaxPromotionRung = &rung
+		assert.NilError(t, check.Validate(config))
+	}
+	for _, rung := range []int{-1, 3, 4} {
+		config.MaxPromotionRung = &rung
+		assert.ErrorContains(t, check.Validate(config), "max_promotion_rung")
+	}
+}
+
+func TestAsyncHalvingInitialDesign(t *testing.T) {
+	config := AsyncHalvingConfig{
+		NumRungs:  3,
+		MaxLength: NewLengthInBatches(900),
+		MaxTrials: 9,
+		Divisor:   3,
+	}
+	for _, design := range []InitialDesign{"", RandomDesign, SobolDesign} {
+		config.InitialDesign = design
+		assert.NilError(t, check.Validate(config))
+	}
+	config.InitialDesign = "halton"
+	assert.ErrorContains(t, check.Validate(config), "invalid initial_design")
+}
+
+func TestAsyncHalvingTrialValidationTimeout(t *testing.T) {
+	config := AsyncHalvingConfig{
+		NumRungs:  3,
+		MaxLength: NewLengthInBatches(900),
+		MaxTrials: 9,
+		Divisor:   3,
+	}
+	timeout := Duration(time.Hour)
+	config.TrialValidationTimeout = &timeout
+	assert.NilError(t, check.Validate(config))
+	timeout = 0
+	assert.ErrorContains(t, check.Validate(config), "trial_validation_timeout must be > 0")
+}
+
+func TestAsyncHalvingRungMetrics(t *testing.T) {
+	smaller := true
+	config := AsyncHalvingConfig{
+		Metric:    "real",
+		NumRungs:  2,
+		MaxLength: NewLengthInBatches(900),
+		MaxTrials: 9,
+		Divisor:   3,
+		RungMetrics: []RungMetric{
+			{Metric: "proxy", SmallerIsBetter: &smaller}, {Metric: "real", SmallerIsBetter: &smaller},
+		},
+	}
+	assert.NilError(t, check.Validate(config))
+	name, smallerIsBetter := config.RungMetric(0)
+	assert.Equal(t, name, "proxy")
+	assert.Equal(t, smallerIsBetter, true)
+
+	config.RungMetrics[1].SmallerIsBetter = nil
+	assert.ErrorContains(t, check.Validate(config), "must specify smaller_is_better for metric real")
+	config.RungMetrics = config.RungMetrics[:1]
+	assert.ErrorContains(t, check.Validate(config), "rung_metrics must have num_rungs entries")
+	config.RungMetrics = []RungMetric{{Metric: "proxy", SmallerIsBetter: &smaller}, {}}
+	assert.ErrorContains(t, check.Validate(config), "rung_metrics must specify a metric")
+	config.RungMetrics[1] = RungMetric{Metric: "real", SmallerIsBetter: &smaller}
+	config.PromoteOnBest = true
+	assert.ErrorContains(t, check.Validate(config), "cannot be combined with promote_on_best")
+}
+
+func TestAsyncHalvingMetricTransform(t *testing.T) {
+	config := AsyncHalvingConfig{
+		NumRungs:  3,
+		MaxLength: NewLengthInBatches(900),
+		MaxTrials: 9,
+		Divisor:   3,
+	}
+	for _, transform := range []MetricTransform{
+		"", IdentityTransform, NegateTransform, ReciprocalTransform, LogTransform,
+	} {
+		config.MetricTransform = transform
+		assert.NilError(t, check.Validate(config))
+	}
+	config.MetricTransform = "square"
+	assert.ErrorContains(t, check.Validate(config), "invalid metric_transform")
+}
+
+func TestAsyncHalvingRungRounding(t *testing.T) {
+	config := *DefaultExperimentConfig().Searcher.AsyncHalvingConfig
+	config.NumRungs, config.MaxLength, config.MaxTrials = 3, NewLengthInBatches(10), 9
+	assert.Equal(t, config.RungRounding, RoundingPolicy(FloorRounding))
+	// The exact lengths are 10/16, 10/4, and 10.
+	for rounding, expected := range map[RoundingPolicy][]int{
+		FloorRounding: {1, 2, 10},
+		RoundRounding: {1, 3, 10},
+		CeilRounding:  {1, 3, 10},
+	} {
+		config.RungRounding = rounding
+		assert.DeepEqual(t, config.RungUnits(), expected)
+		assert.NilError(t, check.Validate(config))
+	}
+	config.RungRounding = "truncate"
+	assert.ErrorContains(t, check.Validate(config), "invalid rung_rounding")
+
+	// Rounding up collapses the two lowest rungs, which floor rounding has always allowed.
+	config.MaxLength = NewLengthInBatches(3)
+	config.RungRounding = CeilRounding
+	assert.DeepEqual(t, config.RungUnits(), []int{1, 1, 3})
+	assert.ErrorContains(t, check.Validate(config), "use fewer rungs")
+	config.RungRounding = FloorRounding
+	assert.NilError(t, check.Validate(config))
+}
+
+func TestAsyncHalvingCostAware(t *testing.T) {
+	config := AsyncHalvingConfig{
+		NumRungs:  3,
+		MaxLength: NewLengthInBatches(900),
+		MaxTrials: 9,
+		Divisor:   3,
+		CostAware: &CostAwareConfig{MetricPerSecond: 0.01},
+	}
+	assert.NilError(t, check.Validate(config))
+	config.CostAware.MetricPerSecond = 0
+	assert.ErrorContains(t, check.Validate(config), "cost_aware.metric_per_second must be > 0")
+}
+
+func TestAsyncHalvingAutoFallback(t *testing.T) {
+	config := AsyncHalvingConfig{
+		Metric:    "loss",
+		NumRungs:  3,
+		MaxLength: NewLengthInBatches(900),
+		MaxTrials: 9,
+		Divisor:   3,
+		AutoFallback: &AutoFallbackConfig{
+			MaxDimensions:    1,
+			ValidationPeriod: NewLengthInBatches(100),
+			Patience:         2,
+		},
+	}
+	assert.NilError(t, check.Validate(config))
+	period := NewLengthInBatches(100)
+	assert.DeepEqual(t, config.AutoFallback.RandomConfig(config), RandomConfig{
+		Metric:           "loss",
+		MaxLength:        NewLengthInBatches(900),
+		MaxTrials:        9,
+		ValidationPeriod: &period,
+		Patience:         2,
+	})
+
+	config.AutoFallback.ValidationPeriod = NewLengthInRecords(100)
+	assert.ErrorContains(t, check.Validate(config),
+		"auto_fallback.validation_period must be in the unit of max_length")
+	config.AutoFallback.ValidationPeriod = NewLengthInBatches(0)
+	assert.ErrorContains(t, check.Validate(config), "auto_fallback.validation_period must be > 0")
+	config.AutoFallback = &AutoFallbackConfig{
+		MaxDimensions: -1, ValidationPeriod: NewLengthInBatches(100), Patience: -1,
+	}
+	err := check.Validate(config)
+	assert.ErrorContains(t, err, "auto_fallback.max_dimensions must be >= 0")
+	assert.ErrorContains(t, err, "auto_fallback.patience must be >= 0")
+}
+
+func TestAsyncHalvingConvergenceStop(t *testing.T) {
+	config := AsyncHalvingConfig{
+		NumRungs:        3,
+		MaxLength:       NewLengthInBatches(900),
+		MaxTrials:       9,
+		Divisor:         3,
+		ConvergenceStop: &ConvergenceStopConfig{Window: 5, MinImprovement: 0.01},
+	}
+	assert.NilError(t, check.Validate(config))
+	config.ConvergenceStop.MinImprovement = 0
+	assert.NilError(t, check.Validate(config))
+	config.ConvergenceStop.MinImprovement = -1
+	assert.ErrorContains(t, check.Validate(config), "convergence_stop.min_improvement must be >= 0")
+	config.ConvergenceStop = &ConvergenceStopConfig{Window: 0}
+	assert.ErrorContains(t, check.Validate(config), "convergence_stop.window must be > 0")
+}
+
+func TestListConfig(t *testing.T) {
+	var actual = DefaultExperimentConfig().Searcher
+	assert.NilError(t, json.Unmarshal([]byte(`
+{
+  "name": "list",
+  "metric": "loss",
+  "max_length": {"batches": 1000},
+  "max_concurrent_trials": 2,
+  "points": [{"lr": 0.1, "layers": 2}, {"lr": 0.01, "layers": 4}]
+}
+`), &actual))
+	assert.Equal(t, actual.Name(), "list")
+	assert.DeepEqual(t, *actual.ListConfig, ListConfig{
+		MaxLength:           NewLengthInBatches(1000),
+		MaxConcurrentTrials: 2,
+		Points: []map[string]interface{}{
+			{"lr": 0.1, "layers": 2.0}, {"lr": 0.01, "layers": 4.0},
+		},
+	})
+	assert.NilError(t, check.Validate(actual))
+
+	invalid := *actual.ListConfig
+	invalid.MaxConcurrentTrials = -1
+	assert.ErrorContains(t, check.Validate(invalid), "max_concurrent_trials must be >= 0")
+}
+
+func TestAsyncHalvingReplacementRungThreshold(t *testing.T) {
+	config := AsyncHalvingConfig{
+		NumRungs:  3,
+		MaxLength: NewLengthInBatches(900),
+		MaxTrials: 9,
+		Divisor:   3,
+	}
+	for _, threshold := range []int{0, 1, 2} {
+		config.ReplacementRungThreshold = threshold
+		assert.NilError(t, check.Validate(config))
+	}
+	for _, threshold := range []int{-1, 3} {
+		config.ReplacementRungThreshold = threshold
+		assert.ErrorContains(t, check.Validate(config), "replacement_rung_threshold")
+	}
+}
+
+func TestAsyncHalvingValidationsPerRung(t *testing.T) {
+	config := AsyncHalvingConfig{
+		NumRungs:  3,
+		MaxLength: NewLengthInBatches(900),
+		MaxTrials: 9,
+		Divisor:   3,
+	}
+	for _, validations := range []int{0, 1, 5} {
+		config.ValidationsPerRung = validations
+		assert.NilError(t, check.Validate(config))
+	}
+	config.ValidationsPerRung = -1
+	assert.ErrorContains(t, check.Validate(config), "validations_per_rung must be >= 0")
+}
+
+func TestAsyncHalvingNonPositiveMaxLength(t *testing.T) {
+	config := AsyncHalvingConfig{
+		NumRungs:  3,
+		MaxTrials: 9,
+		Divisor:   4,
+	}
+	for _, units := range []int{0, -100} {
+		config.MaxLength = NewLengthInBatches(units)
+		err := check.Validate(config)
+		assert.ErrorContains(t, err, "max_length must be > 0")
+		assert.ErrorContains(t, err, "no better than random search")
+		assert.ErrorContains(t, err, fmt.Sprintf("got %d", units))
+		assert.ErrorContains(t, err, "a max_length of at least 16 batches")
+	}
+	config.MaxLength = NewLengthInRecords(0)
+	config.Divisor = 2.5
+	assert.ErrorContains(t, check.Validate(config), "a max_length of at least 7 records")
+
+	// Nothing is suggested when the rungs themselves are misconfigured.
+	config.NumRungs = 0
+	err := check.Validate(config)
+	assert.ErrorContains(t, err, "max_length must be > 0")
+	assert.Assert(t, !strings.Contains(err.Error(), "a max_length of"), err)
+
+	config = AsyncHalvingConfig{
+		NumRungs:  3,
+		MaxLength: NewLengthInBatches(16),
+		MaxTrials: 9,
+		Divisor:   4,
+	}
+	assert.NilError(t, check.Validate(config))
+}
+
+func TestAsyncHalvingPenalties(t *testing.T) {
+	smallerIsBetter := true
+	config := AsyncHalvingConfig{
+		Metric:    "accuracy",
+		NumRungs:  3,
+		MaxLength: NewLengthInBatches(900),
+		MaxTrials: 9,
+		Divisor:   3,
+		Penalties: []MetricPenalty{
+			{Metric: "latency", Target: 10, SmallerIsBetter: &smallerIsBetter, Weight: 0.1},
+		},
+	}
+	assert.NilError(t, check.Validate(config))
+	assert.Equal(t, config.Penalties[0].Violation(8), 0.0)
+	assert.Equal(t, config.Penalties[0].Violation(15), 5.0)
+	largerIsBetter := false
+	config.Penalties[0].SmallerIsBetter = &largerIsBetter
+	assert.Equal(t, config.Penalties[0].Violation(8), 2.0)
+	assert.Equal(t, config.Penalties[0].Violation(15), 0.0)
+
+	for _, c := range []struct {
+		penalty MetricPenalty
+		err     string
+	}{
+		{MetricPenalty{Target: 10, SmallerIsBetter: &smallerIsBetter, Weight: 1},
+			"penalties must specify a metric"},
+		{MetricPenalty{Metric: "latency", Target: 10, Weight: 1},
+			"penalties must specify smaller_is_better for metric latency"},
+		{MetricPenalty{Metric: "latency", SmallerIsBetter: &smallerIsBetter},
+			"penalties must have a weight > 0 for metric latency"},
+		{MetricPenalty{Metric: "accuracy", SmallerIsBetter: &smallerIsBetter, Weight: 1},
+			"penalties cannot constrain the searcher metric accuracy"},
+	} {
+		config.Penalties = []MetricPenalty{c.penalty}
+		assert.ErrorContains(t, check.Validate(config), c.err)
+	}
+}
+
+func TestAsyncHalvingMinTopRungTrials(t *testing.T) {
+	config := AsyncHalvingConfig{
+		NumRungs:  3,
+		MaxLength: NewLengthInBatches(900),
+		MaxTrials: 9,
+		Divisor:   3,
+	}
+	for _, trials := range []int{0, 1, 9} {
+		config.MinTopRungTrials = trials
+		assert.NilError(t, check.Validate(config))
+	}
+	config.MinTopRungTrials = -1
+	assert.ErrorContains(t, check.Validate(config), "min_top_rung_trials must be >= 0")
+	config.MinTopRungTrials = 10
+	assert.ErrorContains(t, check.Validate(config), "min_top_rung_trials must be <= max_trials")
+}
+
+func TestRacingConfig(t *testing.T) {
+	var actual = DefaultExperimentConfig().Searcher
+	assert.NilError(t, json.Unmarshal([]byte(`
+{
+  "name": "racing",
+  "metric": "accuracy",
+  "smaller_is_better": false,
+  "max_length": {"batches": 1000},
+  "max_trials": 8,
+  "validation_period": {"batches": 100},
+  "metric_range": 1
+}
+`), &actual))
+	assert.Equal(t, actual.Name(), "racing")
+	assert.DeepEqual(t, *actual.RacingConfig, RacingConfig{
+		Metric:           "accuracy",
+		MaxLength:        NewLengthInBatches(1000),
+		MaxTrials:        8,
+		ValidationPeriod: NewLengthInBatches(100),
+		Delta:            0.05,
+		MetricRange:      1,
+	})
+	assert.NilError(t, check.Validate(actual))
+
+	for _, delta := range []float64{0, 1} {
+		invalid := *actual.RacingConfig
+		invalid.Delta = delta
+		assert.ErrorContains(t, check.Validate(invalid), "delta must be")
+	}
+	invalid := *actual.RacingConfig
+	invalid.MetricRange = 0
+	assert.ErrorContains(t, check.Validate(invalid), "metric_range must be > 0")
+	invalid = *actual.RacingConfig
+	invalid.ValidationPeriod = NewLengthInEpochs(1)
+	assert.ErrorContains(t, check.Validate(invalid), "same unit as max_length")
+}
+
+func TestRungBarrierConfig(t *testing.T) {
+	adaptive := AdaptiveConfig{
+		Metric:      "loss",
+		MaxLength:   NewLengthInBatches(900),
+		Budget:      NewLengthInBatches(10800),
+		Divisor:     3,
+		Mode:        StandardMode,
+		MaxRungs:    3,
+		RungBarrier: true,
+	}
+	assert.NilError(t, check.Validate(adaptive))
+	adaptive.MaxRungs = 1
+	assert.ErrorContains(t, check.Validate(adaptive), "rung_barrier requires a bracket")
+	adaptive.BracketRungs = []int{1, 2}
+	assert.NilError(t, check.Validate(adaptive))
+	adaptive.BracketRungs = []int{1}
+	adaptive.MaxRungs = 3
+	assert.ErrorContains(t, check.Validate(adaptive), "rung_barrier requires a bracket")
+	adaptive.RungBarrier = false
+	assert.NilError(t, check.Validate(adaptive))
+
+	syncHalving := SyncHalvingConfig{NumRungs: 1, RungBarrier: true}
+	assert.ErrorContains(t, check.Validate(syncHalving), "rung_barrier requires num_rungs > 1")
+	syncHalving.NumRungs = 3
+	assert.NilError(t, check.Validate(syncHalving))
+}
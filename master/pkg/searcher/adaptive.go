@@ -26,6 +26,7 @@ func newAdaptiveSearch(config model.AdaptiveConfig) SearchMethod {
 			Budget:          config.Budget.DivInt(len(brackets)),
 			Divisor:         config.Divisor,
 			TrainStragglers: config.TrainStragglers,
+			RungBarrier:     config.RungBarrier,
 		}
 		methods = append(methods, newSyncHalvingSearch(c))
 	}
@@ -29,8 +29,18 @@ func getBracketMaxTrials(
 
 		allocated += bracketTrials[i]
 	}
-	remainder := max(maxTrials-allocated, 0)
-	bracketTrials[0] += remainder
+	bracketTrials[0] += max(maxTrials-allocated, 0)
+	// Every bracket gets at least one trial, which can take the total over maxTrials when some of
+	// them would otherwise get none; the excess is taken from the brackets with the most trials.
+	for ; allocated > maxTrials; allocated-- {
+		largest := 0
+		for i := range bracketTrials {
+			if bracketTrials[i] > bracketTrials[largest] {
+				largest = i
+			}
+		}
+		bracketTrials[largest]--
+	}
 	return bracketTrials
 }
 
@@ -61,6 +71,26 @@ func getBracketMaxConcurrentTrials(
 }
 
 func newAdaptiveASHASearch(config model.AdaptiveASHAConfig) SearchMethod {
+	if config.AdaptToNoise {
+		return newNoiseAdaptiveSearch(config)
+	}
+	return newTournamentSearch(adaptiveASHABrackets(config)...)
+}
+
+// adaptiveASHABrackets returns an asynchronous successive halving search for each bracket of an
+// adaptive ASHA search.
+func adaptiveASHABrackets(config model.AdaptiveASHAConfig) []SearchMethod {
+	configs := adaptiveASHABracketConfigs(config)
+	methods := make([]SearchMethod, 0, len(configs))
+	for _, c := range configs {
+		methods = append(methods, newAsyncHalvingSearch(c))
+	}
+	return methods
+}
+
+// adaptiveASHABracketConfigs returns the configuration of each bracket of an adaptive ASHA search,
+// from the one that stops trials the most aggressively to the one that stops them the least.
+func adaptiveASHABracketConfigs(config model.AdaptiveASHAConfig) []model.AsyncHalvingConfig {
 	modeFunc := parseAdaptiveMode(config.Mode)
 
 	brackets := config.BracketRungs
@@ -75,14 +105,18 @@ func newAdaptiveASHASearch(config model.AdaptiveASHAConfig) SearchMethod {
 	}
 	// We prioritize brackets that perform more early stopping to try to max speedups early on.
 	sort.Sort(sort.Reverse(sort.IntSlice(brackets)))
+	// Each bracket needs at least one trial, so there are no more brackets than trials.
+	if len(brackets) > config.MaxTrials {
+		brackets = brackets[:config.MaxTrials]
+	}
 	bracketMaxTrials := getBracketMaxTrials(
 		config.MaxTrials, config.Divisor, brackets)
 	bracketMaxConcurrentTrials := getBracketMaxConcurrentTrials(
 		config.MaxConcurrentTrials, config.Divisor, bracketMaxTrials)
 
-	methods := make([]SearchMethod, 0, len(brackets))
+	configs := make([]model.AsyncHalvingConfig, 0, len(brackets))
 	for i, numRungs := range brackets {
-		c := model.AsyncHalvingConfig{
+		configs = append(configs, model.AsyncHalvingConfig{
 			Metric:              config.Metric,
 			SmallerIsBetter:     config.SmallerIsBetter,
 			NumRungs:            numRungs,
@@ -90,9 +124,7 @@ func newAdaptiveASHASearch(config model.AdaptiveASHAConfig) SearchMethod {
 			MaxTrials:           bracketMaxTrials[i],
 			Divisor:             config.Divisor,
 			MaxConcurrentTrials: bracketMaxConcurrentTrials[i],
-		}
-		methods = append(methods, newAsyncHalvingSearch(c))
+		})
 	}
-
-	return newTournamentSearch(methods...)
+	return configs
 }
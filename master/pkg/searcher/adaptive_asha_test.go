@@ -13,6 +13,8 @@ func TestBracketMaxTrials(t *testing.T) {
 	assert.DeepEqual(t, getBracketMaxTrials(50, 3., []int{4, 3}), []int{35, 15})
 	assert.DeepEqual(t, getBracketMaxTrials(50, 4., []int{3, 2}), []int{37, 13})
 	assert.DeepEqual(t, getBracketMaxTrials(100, 4., []int{4, 3, 2}), []int{70, 22, 8})
+	// Brackets that would get no trials get one, at the expense of the largest one.
+	assert.DeepEqual(t, getBracketMaxTrials(10, 4., []int{5, 2, 1}), []int{8, 1, 1})
 }
 
 func TestBracketMaxConcurrentTrials(t *testing.T) {
@@ -22,6 +24,53 @@ func TestBracketMaxConcurrentTrials(t *testing.T) {
 	assert.DeepEqual(t, getBracketMaxConcurrentTrials(0, 4., []int{40, 10}), []int{10, 10})
 }
 
+func TestAdaptiveASHABracketConfigs(t *testing.T) {
+	type bracket struct{ NumRungs, MaxTrials, MaxConcurrentTrials int }
+	for _, c := range []struct {
+		mode                model.AdaptiveMode
+		maxTrials           int
+		divisor             float64
+		maxConcurrentTrials int
+		brackets            []bracket
+	}{
+		{model.AggressiveMode, 300, 4, 0, []bracket{{5, 300, 300}}},
+		{model.AggressiveMode, 300, 4, 8, []bracket{{5, 300, 8}}},
+		{model.StandardMode, 300, 4, 0, []bracket{{5, 212, 22}, {4, 66, 22}, {3, 22, 22}}},
+		{model.StandardMode, 300, 4, 8, []bracket{{5, 212, 3}, {4, 66, 3}, {3, 22, 2}}},
+		{model.ConservativeMode, 300, 4, 0, []bracket{
+			{5, 206, 4}, {4, 63, 4}, {3, 21, 4}, {2, 7, 4}, {1, 3, 4},
+		}},
+		{model.ConservativeMode, 300, 4, 8, []bracket{
+			{5, 206, 2}, {4, 63, 2}, {3, 21, 2}, {2, 7, 1}, {1, 3, 1},
+		}},
+		// There are fewer trials than brackets, so only the brackets with the most rungs are run.
+		{model.StandardMode, 3, 1.2, 0, []bracket{{6, 1, 1}, {5, 1, 1}, {4, 1, 1}}},
+		{model.ConservativeMode, 3, 1.2, 0, []bracket{{6, 1, 1}, {5, 1, 1}, {4, 1, 1}}},
+	} {
+		configs := adaptiveASHABracketConfigs(model.AdaptiveASHAConfig{
+			Metric:              defaultMetric,
+			SmallerIsBetter:     true,
+			MaxLength:           model.NewLengthInBatches(2560),
+			MaxTrials:           c.maxTrials,
+			Divisor:             c.divisor,
+			Mode:                c.mode,
+			MaxRungs:            6,
+			MaxConcurrentTrials: c.maxConcurrentTrials,
+		})
+		var brackets []bracket
+		trials := 0
+		for _, config := range configs {
+			assert.Equal(t, config.Divisor, c.divisor)
+			assert.DeepEqual(t, config.MaxLength, model.NewLengthInBatches(2560))
+			brackets = append(brackets,
+				bracket{config.NumRungs, config.MaxTrials, config.MaxConcurrentTrials})
+			trials += config.MaxTrials
+		}
+		assert.DeepEqual(t, brackets, c.brackets)
+		assert.Equal(t, trials, c.maxTrials)
+	}
+}
+
 func TestAdaptiveASHASearcherReproducibility(t *testing.T) {
 	conf := model.AdaptiveASHAConfig{
 		Metric: defaultMetric, SmallerIsBetter: true,
@@ -127,3 +176,38 @@ func TestAdaptiveASHASearchMethod(t *testing.T) {
 
 	runValueSimulationTestCases(t, testCases)
 }
+
+func TestAdaptiveASHASnapshotRestore(t *testing.T) {
+	config := model.AdaptiveASHAConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		MaxLength:           model.NewLengthInBatches(900),
+		MaxTrials:           12,
+		Divisor:             3,
+		Mode:                model.StandardMode,
+		MaxRungs:            3,
+		MaxConcurrentTrials: 4,
+	}
+	metricFn := func(trialIndex, _ int) float64 { return float64((trialIndex * 5) % 12) }
+
+	original, err := newQueueDriver(newAdaptiveASHASearch(config), nil, metricFn)
+	assert.NilError(t, err)
+	for i := 0; i < 25; i++ {
+		_, err = original.step()
+		assert.NilError(t, err)
+	}
+
+	snapshot, err := original.method.Snapshot()
+	assert.NilError(t, err)
+	restoredMethod := newAdaptiveASHASearch(config)
+	assert.NilError(t, restoredMethod.Restore(snapshot))
+
+	restored := original.clone(restoredMethod)
+	for len(original.pending) > 0 {
+		expected, expectedErr := original.step()
+		assert.NilError(t, expectedErr)
+		actual, actualErr := restored.step()
+		assert.NilError(t, actualErr)
+		assert.DeepEqual(t, actual, expected)
+	}
+}
@@ -124,3 +124,19 @@ func TestAdaptiveSearchMethod(t *testing.T) {
 
 	runValueSimulationTestCases(t, testCases)
 }
+
+func TestAdaptiveSearcherRungBarrier(t *testing.T) {
+	config := model.AdaptiveConfig{
+		Metric:      defaultMetric,
+		MaxLength:   model.NewLengthInBatches(900),
+		Budget:      model.NewLengthInBatches(10800),
+		Divisor:     3,
+		Mode:        model.ConservativeMode,
+		MaxRungs:    3,
+		RungBarrier: true,
+	}
+	search := newAdaptiveSearch(config).(*tournamentSearch)
+	for _, subSearch := range search.subSearches {
+		assert.Assert(t, subSearch.(*syncHalvingSearch).RungBarrier)
+	}
+}
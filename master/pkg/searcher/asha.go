@@ -1,8 +1,16 @@
 package searcher
 
 import (
+	"encoding/json"
+	"fmt"
 	"math"
+	"reflect"
 	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 
 	"github.com/determined-ai/determined/master/pkg/model"
 )
@@ -13,231 +21,1803 @@ import (
 type asyncHalvingSearch struct {
 	defaultSearchMethod
 	model.AsyncHalvingConfig
+	asyncHalvingSearchState
+
+	maxTrials int
+	// propose, if set, supplies the hyperparameters of new trials instead of sampleAll.
+	propose func(ctx context) (hparamSample, error)
+	// sampler, if set, samples the hyperparameters of the trials created by initialOperations
+	// jointly, and batch holds those of the samples it returned that are yet to be used.
+	sampler batchSampler
+	batch   []hparamSample
+	// comparator ranks the trials in each rung.
+	comparator MetricComparator
+	// mu guards asyncHalvingSearchState. The master may call into the search from several
+	// goroutines, so every exported method and callback holds it for its whole duration; the
+	// helpers they call assume it is held.
+	mu sync.Mutex
+}
 
-	rungs      []*rung
-	trialRungs map[RequestID]int
-	// earlyExitTrials contains trials that exited early that are still considered in the search.
-	earlyExitTrials map[RequestID]bool
-	closedTrials    map[RequestID]bool
-	maxTrials       int
-	trialsCompleted int
+// asyncHalvingSearchState holds all of the mutable state of an asyncHalvingSearch; it is what gets
+// persisted by Snapshot and loaded by Restore.
+type asyncHalvingSearchState struct {
+	Rungs      []*rung           `json:"rungs"`
+	TrialRungs map[RequestID]int `json:"trial_rungs"`
+	// EarlyExitTrials contains trials that exited early that are still considered in the search.
+	EarlyExitTrials map[RequestID]bool `json:"early_exit_trials"`
+	ClosedTrials    map[RequestID]bool `json:"closed_trials"`
+	// ValidatedRungs is the highest rung in which each trial has reported a validation; a trial only
+	// validates once per rung, so any later validation for the same rung is a duplicate delivery.
+	ValidatedRungs  map[RequestID]int `json:"validated_rungs"`
+	TrialsCompleted int               `json:"trials_completed"`
+	// StartTime is when the search started, used to enforce MaxTime.
+	StartTime time.Time `json:"start_time"`
+	// Progress is the highest progress reported so far; reported progress never decreases.
+	Progress float64 `json:"progress"`
+	// Concurrency is the maximum number of trials that may have outstanding work at once, and
+	// OutstandingTrials is the number of trials that currently do.
+	Concurrency       int `json:"concurrency"`
+	OutstandingTrials int `json:"outstanding_trials"`
+	// ReplacedTrials is the number of trials that were closed when they exited early before
+	// reporting a metric, or that were discarded, each of which is replaced by a new trial.
+	ReplacedTrials int `json:"replaced_trials"`
+	// DiscardedTrials contains trials that exited early for a reason that says nothing about their
+	// hyperparameters, e.g., preemption, and whose metrics were removed from the search.
+	DiscardedTrials map[RequestID]bool `json:"discarded_trials,omitempty"`
+	// Retries is how many times each trial that errored out has been retried, by the request ID of
+	// the original trial, and RetryOf maps each retry to the original trial.
+	Retries map[RequestID]int       `json:"retries,omitempty"`
+	RetryOf map[RequestID]RequestID `json:"retry_of,omitempty"`
+	// ExtendedMaxTrials is the target number of trials when it has been raised above the configured
+	// max_trials by ExtendMaxTrials, or lowered below it by ConvergenceStop.
+	ExtendedMaxTrials int `json:"extended_max_trials,omitempty"`
+	// CancelledTrials contains trials that were closed by cancelStragglers while they still had
+	// outstanding work.
+	CancelledTrials map[RequestID]bool `json:"cancelled_trials"`
+	// LowestRemaining is the lowest estimate of the remaining resource reported so far; reported
+	// estimates never increase.
+	LowestRemaining *int `json:"lowest_remaining,omitempty"`
+	// SmoothedMetrics is the exponential moving average of the sign-adjusted metrics each trial has
+	// reported, when SmoothingFactor is set.
+	SmoothedMetrics map[RequestID]float64 `json:"smoothed_metrics"`
+	// BestMetrics is the best sign-adjusted metric each trial has reported, when PromoteOnBest is
+	// set.
+	BestMetrics map[RequestID]float64 `json:"best_metrics"`
+	// TrialStarted is when each trial was created or last promoted, used to enforce
+	// TrialValidationTimeout.
+	TrialStarted map[RequestID]time.Time `json:"trial_started"`
+	// TrialHparams is the hyperparameters of each trial the search has created.
+	TrialHparams map[RequestID]hparamSample `json:"trial_hparams"`
+	// Paused is whether the search has been paused, and Deferred holds the operations it has
+	// decided on since, in order.
+	Paused   bool                `json:"paused"`
+	Deferred []deferredOperation `json:"deferred,omitempty"`
+	// PromotionStats counts the promotions out of each rung.
+	PromotionStats []RungPromotionStats `json:"promotion_stats"`
+	// TrialCosts is the wall-clock time each trial has spent training, used by CostAware.
+	TrialCosts map[RequestID]trialCost `json:"trial_costs"`
+	// TrialUnits is the number of units each trial has trained in all, used to account for the
+	// training trials have done toward the rung they are in before they report in it.
+	TrialUnits map[RequestID]int `json:"trial_units"`
+	// IntermediateValidations is the number of validations each trial has yet to report before the
+	// one at the boundary of the rung it is training toward, when ValidationsPerRung is set.
+	IntermediateValidations map[RequestID]int `json:"intermediate_validations,omitempty"`
+	// Sequence holds the hyperparameters generated for the trials after the warm start points, in
+	// the order they are to be created, when SpaceFilling is set.
+	Sequence []hparamSample `json:"sequence,omitempty"`
+	// BottomRungBests is the best sign-adjusted metric reported in the bottom rung after each report
+	// there, in order, and Converged is whether ConvergenceStop has stopped the search from creating
+	// more trials.
+	BottomRungBests []float64 `json:"bottom_rung_bests,omitempty"`
+	Converged       bool      `json:"converged,omitempty"`
+	// MetricHistories holds the sign-adjusted metric each trial has reported in each rung, used by
+	// LeaderConfidence.
+	MetricHistories map[RequestID]*metricHistory `json:"metric_histories"`
 }
 
+// trialCost is the wall-clock time a trial has spent training and the number of batches it trained
+// in that time.
+type trialCost struct {
+	Seconds float64 `json:"seconds"`
+	Batches int     `json:"batches"`
+}
+
+// ashaExitedMetricValue is the metric recorded for trials that exit early. It is only
+// ever compared against metrics that validationCompleted has already sign-adjusted so that smaller
+// is better, so it ranks as the worst possible result whatever the value of SmallerIsBetter.
 const ashaExitedMetricValue = math.MaxFloat64
 
 func newAsyncHalvingSearch(config model.AsyncHalvingConfig) SearchMethod {
 	rungs := make([]*rung, 0, config.NumRungs)
-	for id := 0; id < config.NumRungs; id++ {
-		// We divide the MaxLength by downsampling rate to get the target units
-		// for a rung.
-		downsamplingRate := math.Pow(config.Divisor, float64(config.NumRungs-id-1))
-		unitsNeeded := max(int(float64(config.MaxLength.Units)/downsamplingRate), 1)
+	for _, unitsNeeded := range config.RungUnits() {
 		rungs = append(rungs,
 			&rung{
-				unitsNeeded:       model.NewLength(config.Unit(), unitsNeeded),
-				outstandingTrials: 0,
+				UnitsNeeded:       model.NewLength(config.Unit(), unitsNeeded),
+				OutstandingTrials: 0,
 			})
 	}
 
+	comparator := newMetricComparator(config.TiebreakMetrics)
+	if config.TiebreakByResource {
+		comparator = lexicographicComparator{comparator, resourceComparator(config.TiebreakMetrics)}
+	}
+
 	return &asyncHalvingSearch{
 		AsyncHalvingConfig: config,
-		rungs:              rungs,
-		trialRungs:         make(map[RequestID]int),
-		earlyExitTrials:    make(map[RequestID]bool),
-		closedTrials:       make(map[RequestID]bool),
-		maxTrials:          config.MaxTrials,
+		asyncHalvingSearchState: asyncHalvingSearchState{
+			Rungs:           rungs,
+			TrialRungs:      make(map[RequestID]int),
+			EarlyExitTrials: make(map[RequestID]bool),
+			DiscardedTrials: make(map[RequestID]bool),
+			Retries:         make(map[RequestID]int),
+			RetryOf:         make(map[RequestID]RequestID),
+			ClosedTrials:    make(map[RequestID]bool),
+			ValidatedRungs:  make(map[RequestID]int),
+			CancelledTrials: make(map[RequestID]bool),
+			SmoothedMetrics: make(map[RequestID]float64),
+			BestMetrics:     make(map[RequestID]float64),
+			TrialHparams:    make(map[RequestID]hparamSample),
+			TrialStarted:    make(map[RequestID]time.Time),
+			PromotionStats:  make([]RungPromotionStats, len(rungs)),
+			TrialCosts:      make(map[RequestID]trialCost),
+			TrialUnits:      make(map[RequestID]int),
+			MetricHistories: make(map[RequestID]*metricHistory),
+
+			IntermediateValidations: make(map[RequestID]int),
+		},
+		maxTrials:  config.MaxTrials,
+		sampler:    newBatchSampler(config.InitialDesign),
+		comparator: comparator,
 	}
 }
 
-// promotions handles bookkeeping of validation metrics and returns a RequestID to promote if
-// appropriate.
-func (r *rung) promotionsAsync(requestID RequestID, metric float64, divisor float64) []RequestID {
+// insertMetric inserts the new trial result in the place in the list sorted by less and returns its
+// index. Trials that less ranks equally are ordered by request ID so that the order does not depend
+// on the order of reports.
+func (r *rung) insertMetric(
+	less MetricComparator, requestID RequestID, value TrialMetricValue,
+) int {
+	insertIndex := sort.Search(
+		len(r.Metrics),
+		func(i int) bool {
+			existing := r.Metrics[i].value()
+			if !less.Less(value, existing) && !less.Less(existing, value) {
+				return requestID.Before(r.Metrics[i].RequestID)
+			}
+			return less.Less(value, existing)
+		},
+	)
+	r.Metrics = append(r.Metrics, trialMetric{})
+	copy(r.Metrics[insertIndex+1:], r.Metrics[insertIndex:])
+	r.Metrics[insertIndex] = trialMetric{
+		RequestID:  requestID,
+		Metric:     value.Metric,
+		Objectives: value.Objectives,
+	}
+	return insertIndex
+}
+
+// hasMetric returns whether the trial has reported a result in the rung.
+func (r *rung) hasMetric(requestID RequestID) bool {
+	for _, trialMetric := range r.Metrics {
+		if trialMetric.RequestID == requestID {
+			return true
+		}
+	}
+	return false
+}
+
+// removeMetric removes the result of the trial from the rung, if it has reported one.
+func (r *rung) removeMetric(requestID RequestID) {
+	for i, trialMetric := range r.Metrics {
+		if trialMetric.RequestID == requestID {
+			r.Metrics = append(r.Metrics[:i], r.Metrics[i+1:]...)
+			return
+		}
+	}
+}
+
+// promotionSkipReason is why a rung promoted no trial when a trial reported a metric to it.
+type promotionSkipReason string
+
+const (
+	// notSkipped means that a trial was promoted.
+	notSkipped promotionSkipReason = ""
+	// skippedBelowMinTrials means that the rung is waiting for MinTrialsPerRung metrics.
+	skippedBelowMinTrials promotionSkipReason = "rung has fewer than min_trials_per_rung metrics"
+	// skippedNotGoodEnough means that the reporting trial is not among the best trials of the rung
+	// and no other trial became due for promotion.
+	skippedNotGoodEnough promotionSkipReason = "trial is not good enough to be promoted"
+	// skippedAlreadyPromoted means that the trial that became due for promotion had been promoted
+	// already, which happens when a trial that was promoted early falls back in the ranking.
+	skippedAlreadyPromoted promotionSkipReason = "trial due for promotion was promoted already"
+)
+
+// promotions handles bookkeeping of validation metrics, ranked by less, and returns the RequestIDs
+// to promote if appropriate. Nothing is promoted until the rung has minTrials metrics, at which
+// point all of the best trials that would have been promoted so far are promoted at once. It also
+// returns how many of the trials that were due to be promoted had been promoted already and, if it
+// promotes nothing, why not.
+func (r *rung) promotionsAsync(
+	less MetricComparator, requestID RequestID, value TrialMetricValue,
+	divisor float64, minTrials int,
+) (promotions []RequestID, skipped int, reason promotionSkipReason) {
+	if len(r.Metrics)+1 <= minTrials {
+		promotions, skipped = r.promoteBatch(less, []trialMetric{{
+			RequestID: requestID, Metric: value.Metric, Objectives: value.Objectives,
+		}}, divisor, minTrials)
+		switch {
+		case len(r.Metrics) < minTrials:
+			return nil, 0, skippedBelowMinTrials
+		case len(promotions) > 0:
+			return promotions, skipped, notSkipped
+		case skipped > 0:
+			return nil, skipped, skippedAlreadyPromoted
+		default:
+			return nil, 0, skippedNotGoodEnough
+		}
+	}
+
 	// See if there is a trial to promote. We are increasing the total number of trials seen by 1; the
 	// number of best trials that definitely should have been promoted so far (numPromote) can only
 	// stay the same or increase by 1.
-	oldNumPromote := int(float64(len(r.metrics)) / divisor)
-	numPromote := int(float64(len(r.metrics)+1) / divisor)
+	oldNumPromote := int(float64(len(r.Metrics)) / divisor)
+	numPromote := int(float64(len(r.Metrics)+1) / divisor)
 
-	// Insert the new trial result in the appropriate place in the sorted list.
-	insertIndex := sort.Search(
-		len(r.metrics),
-		func(i int) bool { return r.metrics[i].metric > metric },
-	)
+	insertIndex := r.insertMetric(less, requestID, value)
 	promoteNow := insertIndex < numPromote
-
-	r.metrics = append(r.metrics, trialMetric{})
-	copy(r.metrics[insertIndex+1:], r.metrics[insertIndex:])
-	r.metrics[insertIndex] = trialMetric{
-		requestID: requestID,
-		metric:    metric,
-		promoted:  promoteNow,
-	}
+	r.Metrics[insertIndex].Promoted = promoteNow
 
 	// If the new trial is good enough, it should be promoted immediately (whether or not numPromote
 	// changes). Otherwise, if numPromote changes, there is some other trial that should be promoted,
 	// unless it has been promoted already.
 	switch {
 	case promoteNow:
-		return []RequestID{requestID}
-	case numPromote != oldNumPromote && !r.metrics[oldNumPromote].promoted:
-		t := &r.metrics[oldNumPromote]
-		t.promoted = true
-		return []RequestID{t.requestID}
+		return []RequestID{requestID}, 0, notSkipped
+	case numPromote != oldNumPromote && !r.Metrics[oldNumPromote].Promoted:
+		t := &r.Metrics[oldNumPromote]
+		t.Promoted = true
+		return []RequestID{t.RequestID}, 0, notSkipped
+	case numPromote != oldNumPromote:
+		return nil, 1, skippedAlreadyPromoted
 	default:
+		return nil, 0, skippedNotGoodEnough
+	}
+}
+
+// promoteBatch inserts the metrics of several trials that reported at once, ranked by less, and
+// returns the RequestIDs to promote: those of all of the best trials that should have been promoted
+// so far and were not, as long as the rung has at least minTrials metrics. This leaves the rung as
+// promotionsAsync would have had the trials reported one at a time from best to worst, the only
+// order in which it promotes no trial that a later report pushes out of the best trials, so either
+// may be used for the reports that follow. It also returns how many of the best trials had been
+// promoted already.
+func (r *rung) promoteBatch(
+	less MetricComparator, metrics []trialMetric, divisor float64, minTrials int,
+) (promotions []RequestID, skipped int) {
+	for _, trialMetric := range metrics {
+		r.insertMetric(less, trialMetric.RequestID, trialMetric.value())
+	}
+	if len(r.Metrics) < minTrials {
+		return nil, 0
+	}
+	for i := 0; i < int(float64(len(r.Metrics))/divisor); i++ {
+		if r.Metrics[i].Promoted {
+			skipped++
+			continue
+		}
+		r.Metrics[i].Promoted = true
+		promotions = append(promotions, r.Metrics[i].RequestID)
+	}
+	return promotions, skipped
+}
+
+// promoteTies returns the RequestIDs of the trials that rank equal to the worst of the best trials
+// that should have been promoted so far, ranked by less, and were not promoted, and marks them
+// promoted. Trials that exited early are never promoted for a tie, since they all rank equal.
+func (r *rung) promoteTies(less MetricComparator, divisor float64, minTrials int) []RequestID {
+	numPromote := int(float64(len(r.Metrics)) / divisor)
+	if len(r.Metrics) < minTrials || numPromote == 0 {
 		return nil
 	}
+	cutoff := r.Metrics[numPromote-1]
+	if cutoff.Metric == ashaExitedMetricValue {
+		return nil
+	}
+	var promotions []RequestID
+	for i := numPromote; i < len(r.Metrics) && !less.Less(cutoff.value(), r.Metrics[i].value()); i++ {
+		if !r.Metrics[i].Promoted {
+			r.Metrics[i].Promoted = true
+			promotions = append(promotions, r.Metrics[i].RequestID)
+		}
+	}
+	return promotions
 }
 
 func (s *asyncHalvingSearch) initialOperations(ctx context) ([]Operation, error) {
 	// The number of initialOperations will control the degree of parallelism
 	// of the search experiment since we guarantee that each validationComplete
-	// call will return a new train workload until we reach MaxTrials.
-
-	// We will use searcher config field if available.
-	// Otherwise we will default to a number of trials that will
-	// guarantee at least one trial at the top rung.
-	var ops []Operation
-	var maxConcurrentTrials int
+	// call will return new train workloads up to the concurrency limit until we
+	// reach MaxTrials.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.StartTime = ctx.now()
+	s.Concurrency = s.defaultConcurrency()
+	switch {
+	case s.SpaceFilling:
+		if count := s.maxTrials - min(len(s.WarmStart), s.maxTrials); count > 0 {
+			sequence, err := sobolSampler{}.sampleBatch(ctx, count)
+			if err != nil {
+				return nil, err
+			}
+			s.Sequence = sequence
+		}
+	case s.sampler != nil:
+		// The warm start points are used first, and only the rest of the initial trials are sampled.
+		initial := min(s.Concurrency, s.maxTrials)
+		if count := initial - min(len(s.WarmStart), initial); count > 0 {
+			batch, err := s.sampler.sampleBatch(ctx, count)
+			if err != nil {
+				return nil, err
+			}
+			s.batch = batch
+			defer func() { s.batch = nil }()
+		}
+	}
+	return s.emit(s.backfill(ctx))
+}
 
+// defaultConcurrency uses the searcher config field if available. Otherwise, it defaults to a
+// number of trials that will guarantee at least one trial at the top rung. It is not capped at
+// MaxTrials: backfill never creates more trials than the search is to create anyway, and the cap
+// would keep the search from using its full concurrency once ExtendMaxTrials raises the number.
+func (s *asyncHalvingSearch) defaultConcurrency() int {
 	if s.MaxConcurrentTrials > 0 {
-		maxConcurrentTrials = min(s.MaxConcurrentTrials, s.MaxTrials)
-	} else {
-		maxConcurrentTrials = max(
-			min(int(math.Pow(s.Divisor, float64(s.NumRungs-1))), s.MaxTrials),
-			1)
+		return s.MaxConcurrentTrials
 	}
+	return halvingConcurrency(s.promotionDivisor(), s.topRung())
+}
 
-	for trial := 0; trial < maxConcurrentTrials; trial++ {
-		create := NewCreate(
-			ctx.rand, sampleAll(ctx.hparams, ctx.rand), model.TrialWorkloadSequencerType)
-		s.trialRungs[create.RequestID] = 0
+// halvingConcurrency returns divisor^topRung, the number of trials that a rung must start with
+// for one of them to be promoted to the top rung, as a number of trials to work on at once. It is
+// at least 1 whatever the configuration, e.g., if the power overflows because the divisor is huge,
+// so that a search never starts out with nothing to do; that it had to be raised is logged.
+func halvingConcurrency(divisor float64, topRung int) int {
+	concurrency := math.Pow(divisor, float64(topRung))
+	switch {
+	case concurrency >= math.MaxInt32:
+		return math.MaxInt32
+	case !(concurrency >= 1):
+		log.Warnf("raising the concurrency of %v computed from divisor %v and top rung %d to 1",
+			concurrency, divisor, topRung)
+		return 1
+	default:
+		return int(concurrency)
+	}
+}
+
+// topRung is the index of the highest rung trials are promoted to: MaxPromotionRung if it is set,
+// and otherwise the last rung.
+func (s *asyncHalvingSearch) topRung() int {
+	if s.MaxPromotionRung != nil {
+		return *s.MaxPromotionRung
+	}
+	return s.NumRungs - 1
+}
+
+// promotionDivisor is the inverse of the fraction of trials promoted out of each rung.
+func (s *asyncHalvingSearch) promotionDivisor() float64 {
+	if s.PromotionRatio != nil {
+		return 1 / *s.PromotionRatio
+	}
+	return s.Divisor
+}
+
+// SetMaxConcurrency changes the maximum number of trials that may have outstanding work at once.
+// Lowering it below the number of such trials defers the creation of new trials until enough of
+// them finish; raising it creates new trials as soon as any trial next reports. It is safe to call
+// concurrently with the rest of the search.
+func (s *asyncHalvingSearch) SetMaxConcurrency(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Concurrency = max(n, 1)
+}
+
+// ExtendMaxTrials raises the number of trials the search creates to n, which may not be fewer than
+// the trials created so far. Like SetMaxConcurrency, new trials are created as soon as any trial
+// next reports, and the search only closes out its rungs once the new number of trials has reported
+// in the bottom rung. Trials that were closed out before the extension stay closed, even if they
+// would now be promoted. It is safe to call concurrently with the rest of the search.
+func (s *asyncHalvingSearch) ExtendMaxTrials(n int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if created := len(s.TrialRungs) - s.ReplacedTrials; n < created {
+		return errors.Errorf(
+			"cannot set max_trials to %d, which is below the %d trials already created", n, created)
+	}
+	s.maxTrials = n
+	s.ExtendedMaxTrials = n
+	return nil
+}
+
+// addTrials raises the number of trials the search creates by n and creates as many of them as the
+// concurrency limit allows right away, rather than when a trial next reports as ExtendMaxTrials
+// does, since every trial of the search may already have been closed.
+func (s *asyncHalvingSearch) addTrials(ctx context, n int) ([]Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxTrials += n
+	s.ExtendedMaxTrials = s.maxTrials
+	return s.backfill(ctx)
+}
+
+// trialDemand returns whether the search has created every trial it is to create and whether it
+// has room under its concurrency limit for another.
+func (s *asyncHalvingSearch) trialDemand() (created, idle bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.TrialRungs) >= s.maxTrials+s.ReplacedTrials, s.OutstandingTrials < s.Concurrency
+}
+
+// backfill creates new trials until the number of trials with outstanding work reaches the
+// concurrency limit, or until the maximum number of trials has been created.
+func (s *asyncHalvingSearch) backfill(ctx context) ([]Operation, error) {
+	var ops []Operation
+	for s.OutstandingTrials < s.Concurrency && len(s.TrialRungs) < s.maxTrials+s.ReplacedTrials {
+		hparams, err := s.nextHparams(ctx)
+		if err != nil {
+			return nil, err
+		}
+		create := NewCreate(ctx.rand, hparams, model.TrialWorkloadSequencerType)
+		s.TrialRungs[create.RequestID] = 0
+		s.TrialHparams[create.RequestID] = create.Hparams
+		s.OutstandingTrials++
+		ctx.decided(Decision{Kind: TrialCreatedDecision, RequestID: create.RequestID})
 		ops = append(ops, create)
-		ops = append(ops, NewTrain(create.RequestID, s.rungs[0].unitsNeeded))
-		ops = append(ops, NewValidate(create.RequestID))
+		ops = append(ops, s.trainToward(create.RequestID, 0)...)
 	}
 	return ops, nil
 }
 
+// trainToward returns the operations that train the trial from the boundary of the rung below the
+// given one to that of the given rung, by at least one unit, and validate it. If ValidationsPerRung
+// is set, the training is split into that many parts of at least one unit each, each followed by a
+// validation.
+func (s *asyncHalvingSearch) trainToward(requestID RequestID, rungIndex int) []Operation {
+	previousUnits := 0
+	if rungIndex > 0 {
+		previousUnits = s.Rungs[rungIndex-1].UnitsNeeded.Units
+	}
+	return s.trainFrom(requestID, rungIndex, previousUnits)
+}
+
+// trainFrom returns the operations of trainToward that are left for a trial that has already
+// trained for the given number of units in all: the parts of the training that end after that are
+// shortened to start from it, and those that end before it are dropped. At least the validation at
+// the boundary of the rung is left.
+func (s *asyncHalvingSearch) trainFrom(
+	requestID RequestID, rungIndex int, completed int,
+) []Operation {
+	previousUnits := 0
+	if rungIndex > 0 {
+		previousUnits = s.Rungs[rungIndex-1].UnitsNeeded.Units
+	}
+	units := max(s.Rungs[rungIndex].UnitsNeeded.Units-previousUnits, 1)
+	parts := max(min(s.ValidationsPerRung, units), 1)
+
+	var ops []Operation
+	validations := 0
+	trained := max(completed, previousUnits)
+	for part := 1; part <= parts; part++ {
+		end := previousUnits + units*part/parts
+		if end <= trained && part < parts {
+			continue
+		}
+		if end > trained {
+			ops = append(ops, NewTrain(requestID, model.NewLength(s.Unit(), end-trained)))
+			trained = end
+		}
+		ops = append(ops, NewValidate(requestID))
+		validations++
+	}
+	s.IntermediateValidations[requestID] = validations - 1
+	return ops
+}
+
+// nextHparams returns the hyperparameters of the next trial to create: the warm start points come
+// first, in order, then the points of the space-filling sequence or any samples of the batch
+// sampler, and the rest are sampled or proposed. Retries do not count toward the points used, since
+// they reuse the hyperparameters of the trials they retry.
+func (s *asyncHalvingSearch) nextHparams(ctx context) (hparamSample, error) {
+	created := len(s.TrialRungs) - len(s.RetryOf)
+	if created < len(s.WarmStart) {
+		hparams := make(hparamSample, len(s.WarmStart[created]))
+		for name, value := range s.WarmStart[created] {
+			hparams[name] = value
+		}
+		return hparams, nil
+	}
+	if index := created - len(s.WarmStart); index < len(s.Sequence) {
+		hparams := make(hparamSample, len(s.Sequence[index]))
+		for name, value := range s.Sequence[index] {
+			hparams[name] = value
+		}
+		return hparams, nil
+	}
+	if len(s.batch) > 0 {
+		hparams := s.batch[0]
+		s.batch = s.batch[1:]
+		return hparams, nil
+	}
+	if s.propose != nil {
+		return s.propose(ctx)
+	}
+	return sampleAll(ctx)
+}
+
 func (s *asyncHalvingSearch) trialCreated(ctx context, requestID RequestID) ([]Operation, error) {
-	s.rungs[0].outstandingTrials++
-	s.trialRungs[requestID] = 0
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Rungs[0].OutstandingTrials++
+	s.TrialRungs[requestID] = 0
+	s.TrialStarted[requestID] = ctx.now()
 	return nil, nil
 }
 
 func (s *asyncHalvingSearch) trialClosed(ctx context, requestID RequestID) ([]Operation, error) {
-	s.trialsCompleted++
-	s.closedTrials[requestID] = true
-	return nil, nil
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Trials that exited early were already counted as completed in trialExitedEarly, and discarded
+	// trials are not counted at all.
+	if !s.EarlyExitTrials[requestID] && !s.DiscardedTrials[requestID] {
+		s.TrialsCompleted++
+	}
+	s.ClosedTrials[requestID] = true
+	return s.emit(s.cancelStragglers(ctx, requestID), nil)
 }
 
 func (s *asyncHalvingSearch) validationCompleted(
 	ctx context, requestID RequestID, validate Validate, metrics ValidationMetrics,
 ) ([]Operation, error) {
-	// Extract the relevant metric as a float.
-	metric, err := metrics.Metric(s.Metric)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.duplicateValidation(requestID) {
+		return nil, nil
+	}
+	if s.IntermediateValidations[requestID] > 0 {
+		// The trial has yet to reach the boundary of its rung, so it stays where it is.
+		s.IntermediateValidations[requestID]--
+		return nil, nil
+	}
+
+	// Extract the metric of the trial's rung as a float, aggregating several metrics if so
+	// configured.
+	metricName, smallerIsBetter := s.RungMetric(s.TrialRungs[requestID])
+	var metric float64
+	var err error
+	if s.MetricAggregation != nil {
+		metricName = fmt.Sprintf("%s%v", s.MetricAggregation.Mode, s.MetricAggregation.Metrics)
+		metric, err = metrics.AggregateMetric(*s.MetricAggregation)
+	} else {
+		metric, err = metrics.Metric(metricName)
+	}
+	if err == nil {
+		metric, err = transformMetric(s.MetricTransform, metric)
+	}
+	if err != nil {
+		return nil, err
+	}
+	objectives, err := s.tiebreakValues(metrics)
 	if err != nil {
 		return nil, err
 	}
-	if !s.SmallerIsBetter {
+	if s.TiebreakByResource {
+		objectives = append(objectives, float64(s.TrialUnits[requestID]))
+	}
+	penalty, err := s.penalty(metrics)
+	if err != nil {
+		return nil, err
+	}
+	if smallerIsBetter {
+		metric += penalty
+	} else {
+		metric -= penalty
+	}
+	s.ValidatedRungs[requestID] = s.TrialRungs[requestID]
+	if math.IsNaN(metric) || math.IsInf(metric, 0) {
+		if s.FailOnNonFiniteMetric {
+			return nil, errors.WithStack(MetricError{Err: errors.Errorf(
+				"trial %s reported a non-finite value for metric '%s': %f", requestID, metricName, metric)})
+		}
+		// A diverged trial is treated exactly like one that exited early.
+		log.WithField("request-id", requestID).WithField("metric", metricName).Warnf(
+			"treating non-finite metric value %f as the worst possible value", metric)
+		return s.emit(s.promoteAsync(ctx, requestID, ashaExitedMetricValue))
+	}
+	if !smallerIsBetter {
 		metric *= -1
 	}
+	s.recordHistory(requestID, metric)
+
+	metric = s.bestMetric(requestID, s.smoothMetric(requestID, metric))
+	return s.emit(s.promoteAsync(ctx, requestID, s.costAdjusted(requestID, metric), objectives...))
+}
+
+// tiebreakValues returns the values the trial reported for the TiebreakMetrics, in order.
+func (s *asyncHalvingSearch) tiebreakValues(metrics ValidationMetrics) ([]float64, error) {
+	var values []float64
+	for _, tiebreak := range s.TiebreakMetrics {
+		value, err := metrics.Metric(tiebreak.Metric)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// penalty returns the sum of the Penalties for the soft constraints the reported metrics violate,
+// by which the metric of the trial is made worse.
+func (s *asyncHalvingSearch) penalty(metrics ValidationMetrics) (float64, error) {
+	total := 0.0
+	for _, penalty := range s.Penalties {
+		value, err := metrics.Metric(penalty.Metric)
+		if err != nil {
+			return 0, err
+		}
+		total += penalty.Weight * penalty.Violation(value)
+	}
+	return total, nil
+}
+
+// recordCost implements the costRecorder interface.
+func (s *asyncHalvingSearch) recordCost(requestID RequestID, duration time.Duration, batches int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cost := s.TrialCosts[requestID]
+	cost.Seconds += duration.Seconds()
+	cost.Batches += batches
+	s.TrialCosts[requestID] = cost
+}
+
+// recordStep implements the stepRecorder interface.
+func (s *asyncHalvingSearch) recordStep(requestID RequestID, length model.Length) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.TrialUnits[requestID] += length.Units
+}
+
+// trialResumed implements the trialResumer interface. A trial that has yet to report in its rung
+// trains for only what is left between where it resumed and the boundary of the rung; the training
+// it did past its checkpoint was lost, so that is where its units are accounted from.
+func (s *asyncHalvingSearch) trialResumed(
+	ctx context, requestID RequestID, completed model.Length,
+) ([]Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Operations deferred while paused would only reach the trial after it carried on with its own,
+	// so a paused search lets it do so.
+	if s.ClosedTrials[requestID] || s.CancelledTrials[requestID] || s.Paused {
+		return nil, nil
+	}
+	rungIndex, ok := s.TrialRungs[requestID]
+	if !ok {
+		return nil, errors.WithStack(InternalError{
+			Err: errors.Errorf("cannot resume unknown trial %s", requestID),
+		})
+	}
+	if validatedRung, ok := s.ValidatedRungs[requestID]; ok && validatedRung >= rungIndex {
+		// The trial is waiting to be promoted rather than training.
+		return nil, nil
+	}
+	if completed.Unit != s.Unit() {
+		return nil, errors.WithStack(ConfigError{
+			Err: errors.Errorf("cannot resume trial %s from %s in a search in %s",
+				requestID, completed.Unit, s.Unit()),
+		})
+	}
+	s.TrialUnits[requestID] = completed.Units
+	return s.emit(s.trainFrom(requestID, rungIndex, completed.Units), nil)
+}
+
+// nextValidationStep implements the validationHinter interface. Trials validate at the boundary of
+// each rung, and ValidationsPerRung already plans any validations within a rung as operations, so
+// the search never hints at others.
+func (s *asyncHalvingSearch) nextValidationStep(RequestID, model.Length) (model.Length, bool) {
+	return model.Length{}, false
+}
+
+// hintsValidations implements the validationHinter interface.
+func (s *asyncHalvingSearch) hintsValidations() bool {
+	return false
+}
+
+// costAdjusted returns the sign-adjusted metric made worse by the seconds per batch the trial has
+// taken to train, weighted by CostAware, if it is set; otherwise, or if the trial has not reported
+// how long it took, it returns the metric unchanged.
+func (s *asyncHalvingSearch) costAdjusted(requestID RequestID, metric float64) float64 {
+	cost, ok := s.TrialCosts[requestID]
+	if s.CostAware == nil || !ok || cost.Batches == 0 {
+		return metric
+	}
+	return metric + s.CostAware.MetricPerSecond*cost.Seconds/float64(cost.Batches)
+}
+
+// smoothMetric folds the sign-adjusted metric into the moving average of the trial's metrics and
+// returns the average, if SmoothingFactor is set; otherwise, it returns the metric unchanged.
+// Non-finite metrics never reach it, so one diverged validation does not poison the average.
+func (s *asyncHalvingSearch) smoothMetric(requestID RequestID, metric float64) float64 {
+	if s.SmoothingFactor == nil {
+		return metric
+	}
+	if previous, ok := s.SmoothedMetrics[requestID]; ok {
+		metric = *s.SmoothingFactor*metric + (1-*s.SmoothingFactor)*previous
+	}
+	s.SmoothedMetrics[requestID] = metric
+	return metric
+}
 
-	return s.promoteAsync(ctx, requestID, metric), nil
+// bestMetric returns the best of the sign-adjusted metric and those the trial reported before, if
+// PromoteOnBest is set; otherwise, it returns the metric unchanged. Since metrics are sign-adjusted
+// so that smaller is better, the best one is always the smallest.
+func (s *asyncHalvingSearch) bestMetric(requestID RequestID, metric float64) float64 {
+	if !s.PromoteOnBest {
+		return metric
+	}
+	if best, ok := s.BestMetrics[requestID]; ok && best < metric {
+		metric = best
+	}
+	s.BestMetrics[requestID] = metric
+	return metric
+}
+
+// duplicateValidation returns whether the trial has already reported a validation in the rung it is
+// in, e.g., because a retry delivered the same validation twice. Recording it again would count the
+// trial twice in the rung.
+func (s *asyncHalvingSearch) duplicateValidation(requestID RequestID) bool {
+	rungIndex := s.TrialRungs[requestID]
+	if validatedRung, ok := s.ValidatedRungs[requestID]; !ok || validatedRung < rungIndex {
+		return false
+	}
+	log.WithField("request-id", requestID).Debugf(
+		"ignoring duplicate validation for rung %d", rungIndex)
+	return true
 }
 
 func (s *asyncHalvingSearch) promoteAsync(
-	ctx context, requestID RequestID, metric float64,
-) []Operation {
-	// Upon a validation complete, we should return at least one more train&val workload
-	// unless the bracket of successive halving is finished.
-	rungIndex := s.trialRungs[requestID]
-	rung := s.rungs[rungIndex]
-	rung.outstandingTrials--
-	addedTrainWorkload := false
+	ctx context, requestID RequestID, metric float64, objectives ...float64,
+) ([]Operation, error) {
+	// Upon a validation complete, we should return more train&val workloads up to the concurrency
+	// limit unless the bracket of successive halving is finished.
+	value := TrialMetricValue{Metric: metric, Objectives: objectives}
+	rungIndex := s.TrialRungs[requestID]
+	rung := s.Rungs[rungIndex]
+	if rung.hasMetric(requestID) {
+		return nil, errors.WithStack(InternalError{Err: errors.Errorf(
+			"trial %s already has a metric in rung %d", requestID, rungIndex)})
+	}
+	rung.OutstandingTrials--
+	s.OutstandingTrials--
 
 	var ops []Operation
-	// If the trial has completed the top rung's validation, close the trial.
-	if rungIndex == s.NumRungs-1 {
-		if !s.earlyExitTrials[requestID] {
+	// Once the time budget is spent, trials are allowed to finish the rung they are in, but nothing
+	// is promoted and no new trials are created.
+	if s.timeBudgetExceeded(ctx) {
+		rung.insertMetric(s.comparator, requestID, value)
+		rungIndexes := make([]int, 0, len(s.Rungs))
+		for i := range s.Rungs {
+			rungIndexes = append(rungIndexes, i)
+		}
+		return s.closeUnpromoted(ctx, requestID, rungIndexes...), nil
+	}
+	// If the trial has completed the top rung's validation, record its metric and close the trial.
+	if rungIndex == s.topRung() {
+		rung.insertMetric(s.comparator, requestID, value)
+		if !s.EarlyExitTrials[requestID] {
 			ops = append(ops, NewClose(requestID))
-			s.closedTrials[requestID] = true
+			s.ClosedTrials[requestID] = true
+			ctx.decided(Decision{
+				Kind:        TrialClosedDecision,
+				RequestID:   requestID,
+				Rung:        rungIndex,
+				Metric:      s.reportedMetric(rungIndex, requestID),
+				TriggeredBy: &requestID,
+			})
 		}
 	} else {
 		// This is not the top rung, so do promotions to the next rung.
-		nextRung := s.rungs[rungIndex+1]
-		for _, promotionID := range rung.promotionsAsync(
+		nextRung := s.Rungs[rungIndex+1]
+		stats := &s.PromotionStats[rungIndex]
+		promotions, skipped, reason := rung.promotionsAsync(
+			s.comparator,
 			requestID,
-			metric,
-			s.Divisor,
-		) {
-			s.trialRungs[promotionID] = rungIndex + 1
-			nextRung.outstandingTrials++
-			if !s.earlyExitTrials[promotionID] {
-				unitsNeeded := max(nextRung.unitsNeeded.Units-rung.unitsNeeded.Units, 1)
-				ops = append(ops, NewTrain(promotionID, model.NewLength(s.Unit(), unitsNeeded)))
-				ops = append(ops, NewValidate(promotionID))
-				addedTrainWorkload = true
+			value,
+			s.promotionDivisor(),
+			s.MinTrialsPerRung,
+		)
+		if s.PromoteTies {
+			ties := rung.promoteTies(s.comparator, s.promotionDivisor(), s.MinTrialsPerRung)
+			if len(ties) > 0 {
+				promotions, reason = append(promotions, ties...), notSkipped
+			}
+		}
+		stats.SkippedAlreadyPromoted += skipped
+		if reason != notSkipped {
+			log.WithField("request-id", requestID).WithField("rung", rungIndex).Debugf(
+				"promoting no trial: %s", reason)
+		}
+		for _, promotionID := range promotions {
+			if s.EarlyExitTrials[promotionID] && s.EarlyExitMode == model.CloseEarlyExitMode {
+				// The trial was closed when it exited early, so its promotion is forfeited.
+				continue
+			}
+			if s.ClosedTrials[promotionID] && !s.EarlyExitTrials[promotionID] {
+				// The trial was closed out before the search was extended by ExtendMaxTrials.
+				continue
+			}
+			s.TrialRungs[promotionID] = rungIndex + 1
+			s.TrialStarted[promotionID] = ctx.now()
+			nextRung.OutstandingTrials++
+			s.OutstandingTrials++
+			ctx.decided(Decision{
+				Kind:        TrialPromotedDecision,
+				RequestID:   promotionID,
+				Rung:        rungIndex + 1,
+				Metric:      s.reportedMetric(rungIndex, promotionID),
+				TriggeredBy: &requestID,
+			})
+			if !s.EarlyExitTrials[promotionID] {
+				stats.Granted++
+				if s.CheckpointBeforePromotion {
+					ops = append(ops, NewCheckpoint(promotionID))
+				}
+				ops = append(ops, s.trainToward(promotionID, rungIndex+1)...)
 			} else {
 				// We make a recursive call that will behave the same
 				// as if we'd actually run the promoted job and received
 				// the worse possible result in return.
-				return s.promoteAsync(ctx, promotionID, ashaExitedMetricValue)
+				stats.EarlyExitPromotions++
+				exitOps, err := s.promoteAsync(ctx, promotionID, ashaExitedMetricValue)
+				if err != nil {
+					return nil, err
+				}
+				ops = append(ops, exitOps...)
 			}
 		}
 	}
 
-	allTrials := len(s.trialRungs)
-	if !addedTrainWorkload && allTrials < s.maxTrials {
-		create := NewCreate(
-			ctx.rand, sampleAll(ctx.hparams, ctx.rand), model.TrialWorkloadSequencerType)
-		s.trialRungs[create.RequestID] = 0
-		ops = append(ops, create)
-		ops = append(ops, NewTrain(create.RequestID, s.rungs[0].unitsNeeded))
-		ops = append(ops, NewValidate(create.RequestID))
+	if rungIndex == 0 {
+		s.stopIfConverged(value.Metric)
+	}
+	s.extendForTopRung()
+	// Reports below ReplacementRungThreshold leave the places of the trials that finished empty,
+	// unless nothing would be left training, which would stall the search.
+	if rungIndex >= s.ReplacementRungThreshold || s.OutstandingTrials == 0 {
+		creates, err := s.backfill(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, creates...)
 	}
 
-	// Only close out trials once we have reached the maxTrials for the searcher.
-	if len(s.rungs[0].metrics) == s.maxTrials {
-		ops = append(ops, s.closeOutRungs()...)
+	// Only close out trials once we have reached the maxTrials for the searcher. While too few
+	// trials have reached the top rung, the trials left in lower rungs are kept open instead, so that
+	// the trials created by extendForTopRung may still promote them.
+	if len(s.Rungs[0].Metrics) == s.maxTrials && s.topRungShortfall() == 0 {
+		ops = append(ops, s.closeOutRungs(ctx, requestID)...)
 	}
-	return ops
+	return append(ops, s.cancelStragglers(ctx, requestID)...), nil
+}
+
+// stopIfConverged records the sign-adjusted metric of a report in the bottom rung and, if
+// ConvergenceStop is set and the best metric has improved by less than its MinImprovement over the
+// last Window reports there, lowers the number of trials the search creates to those created so
+// far. The trials already created still train and are promoted as usual.
+func (s *asyncHalvingSearch) stopIfConverged(metric float64) {
+	if s.ConvergenceStop == nil || s.Converged {
+		return
+	}
+	best := metric
+	if count := len(s.BottomRungBests); count > 0 {
+		best = math.Min(best, s.BottomRungBests[count-1])
+	}
+	s.BottomRungBests = append(s.BottomRungBests, best)
+	window := s.ConvergenceStop.Window
+	if len(s.BottomRungBests) <= window {
+		return
+	}
+	before := s.BottomRungBests[len(s.BottomRungBests)-1-window]
+	if before-best >= s.ConvergenceStop.MinImprovement {
+		return
+	}
+	s.Converged = true
+	s.maxTrials = min(s.maxTrials, len(s.TrialRungs)-s.ReplacedTrials)
+	s.ExtendedMaxTrials = s.maxTrials
+	log.Debugf("creating no trials past %d, since the best metric improved by %f over %d reports",
+		s.maxTrials, before-best, window)
+}
+
+// topRungShortfall returns how many more trials must report a metric in the top rung for the
+// search to have MinTopRungTrials of them.
+func (s *asyncHalvingSearch) topRungShortfall() int {
+	reported := 0
+	for _, trialMetric := range s.Rungs[s.topRung()].Metrics {
+		if trialMetric.Metric != ashaExitedMetricValue {
+			reported++
+		}
+	}
+	return max(s.MinTopRungTrials-reported, 0)
+}
+
+// extendForTopRung raises the number of trials the search creates when it would otherwise complete
+// with fewer than MinTopRungTrials trials having reported a metric in the top rung, i.e., when
+// every trial has reported in the bottom rung and none is left training. It is raised by the number
+// of new trials expected to bring the rest of them to the top rung: the promotion divisor to the
+// power of the top rung for each. If fewer make it, it is raised again once those trials have
+// finished.
+func (s *asyncHalvingSearch) extendForTopRung() {
+	if s.OutstandingTrials > 0 || len(s.Rungs[0].Metrics) < s.maxTrials {
+		return
+	}
+	shortfall := s.topRungShortfall()
+	if shortfall == 0 {
+		return
+	}
+	perTrial := math.Pow(s.promotionDivisor(), float64(s.topRung()))
+	s.maxTrials += int(math.Ceil(float64(shortfall) * perTrial))
+	s.ExtendedMaxTrials = s.maxTrials
+	log.Debugf("creating up to %d trials so that %d more trials reach the top rung",
+		s.maxTrials, shortfall)
+}
+
+// checkTimeouts closes the trials that have been training toward their rung for longer than
+// TrialValidationTimeout and treats them as if they had exited early, so that a trial that stalls
+// without ever reporting cannot keep its rung from being closed out. Any validation or early exit
+// the trial reports afterwards is ignored.
+func (s *asyncHalvingSearch) checkTimeouts(ctx context) ([]Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Trials whose work is deferred are not training, so none are timed out while paused.
+	if s.TrialValidationTimeout == nil || s.Paused {
+		return nil, nil
+	}
+	var ops []Operation
+	for rungIndex := range s.Rungs[:s.topRung()+1] {
+		for _, requestID := range s.outstandingIn(rungIndex) {
+			started, ok := s.TrialStarted[requestID]
+			if !ok || ctx.now().Sub(started) < time.Duration(*s.TrialValidationTimeout) {
+				continue
+			}
+			log.WithField("request-id", requestID).Warnf(
+				"closing trial that has not validated in rung %d after %s", rungIndex,
+				time.Duration(*s.TrialValidationTimeout))
+			ops = append(ops, NewClose(requestID))
+			ctx.decided(Decision{
+				Kind:      TrialClosedDecision,
+				RequestID: requestID,
+				Rung:      rungIndex,
+			})
+			s.ValidatedRungs[requestID] = rungIndex
+			s.CancelledTrials[requestID] = true
+			s.EarlyExitTrials[requestID] = true
+			s.ClosedTrials[requestID] = true
+			s.TrialsCompleted++
+			exitOps, err := s.promoteAsync(ctx, requestID, ashaExitedMetricValue)
+			if err != nil {
+				return nil, err
+			}
+			ops = append(ops, exitOps...)
+		}
+	}
+	return ops, nil
+}
+
+// timeBudgetExceeded returns whether the search has been running for longer than MaxTime.
+func (s *asyncHalvingSearch) timeBudgetExceeded(ctx context) bool {
+	if s.MaxTime == nil || s.StartTime.IsZero() {
+		return false
+	}
+	return ctx.now().Sub(s.StartTime) >= time.Duration(*s.MaxTime)
 }
 
 // closeOutRungs closes all remaining unpromoted trials in any rungs that have no more outstanding
-// trials.
-func (s *asyncHalvingSearch) closeOutRungs() []Operation {
+// trials. The trigger is the trial whose report prompted closing them.
+func (s *asyncHalvingSearch) closeOutRungs(ctx context, trigger RequestID) []Operation {
+	var rungIndexes []int
+	for i := 0; i < s.decidedRungs(); i++ {
+		rungIndexes = append(rungIndexes, i)
+	}
+	return s.closeUnpromoted(ctx, trigger, rungIndexes...)
+}
+
+// decidedRungs returns the number of rungs, counting up from the bottom, that have no outstanding
+// trials. Once every trial has reported in the bottom rung, no trial will ever enter those rungs
+// again, so which of their trials are promoted is final.
+func (s *asyncHalvingSearch) decidedRungs() int {
+	for i, rung := range s.Rungs {
+		if rung.OutstandingTrials > 0 {
+			return i
+		}
+	}
+	return len(s.Rungs)
+}
+
+// CanBePromoted returns whether the trial may still be promoted to a higher rung, e.g., so that
+// the checkpoints of trials that cannot be can be deleted. It is false for trials that have reached
+// the top rung, that are closed, or that were not promoted out of a rung that closeOutRungs would
+// close out, as well as for trials the search does not know of.
+func (s *asyncHalvingSearch) CanBePromoted(requestID RequestID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rungIndex, ok := s.TrialRungs[requestID]
+	if !ok || rungIndex >= s.topRung() || s.ClosedTrials[requestID] {
+		return false
+	}
+	return len(s.Rungs[0].Metrics) < s.maxTrials || s.topRungShortfall() > 0 ||
+		rungIndex >= s.decidedRungs()
+}
+
+// closeUnpromoted closes all trials in the rungs that were not promoted and are not yet closed. The
+// trials are closed in ascending request ID order, regardless of their metrics or rungs, so that
+// the order of the Close operations depends only on which trials are closed; each trial is closed
+// at most once.
+func (s *asyncHalvingSearch) closeUnpromoted(
+	ctx context, trigger RequestID, rungIndexes ...int,
+) []Operation {
+	trialRungs := make(map[RequestID]int)
+	var unpromoted []RequestID
+	for _, rungIndex := range rungIndexes {
+		for _, trialMetric := range s.Rungs[rungIndex].Metrics {
+			requestID := trialMetric.RequestID
+			if _, ok := trialRungs[requestID]; ok || trialMetric.Promoted ||
+				s.ClosedTrials[requestID] || s.EarlyExitTrials[requestID] {
+				continue
+			}
+			trialRungs[requestID] = rungIndex
+			unpromoted = append(unpromoted, requestID)
+		}
+	}
+	sort.Slice(unpromoted, func(i, j int) bool {
+		return unpromoted[i].Before(unpromoted[j])
+	})
+
 	var ops []Operation
-	for _, rung := range s.rungs {
-		if rung.outstandingTrials > 0 {
-			break
+	for _, requestID := range unpromoted {
+		rungIndex := trialRungs[requestID]
+		ops = append(ops, NewClose(requestID))
+		s.ClosedTrials[requestID] = true
+		ctx.decided(Decision{
+			Kind:        TrialClosedDecision,
+			RequestID:   requestID,
+			Rung:        rungIndex,
+			Metric:      s.reportedMetric(rungIndex, requestID),
+			TriggeredBy: &trigger,
+		})
+	}
+	return ops
+}
+
+// cancelStragglers closes, once enough trials have completed, the trials in each rung below the top
+// that cannot be promoted however well they do, including those still training toward the rung.
+// The trigger is the trial whose report prompted closing them.
+func (s *asyncHalvingSearch) cancelStragglers(ctx context, trigger RequestID) []Operation {
+	if s.CancelStragglers == nil ||
+		float64(s.TrialsCompleted) < *s.CancelStragglers*float64(s.maxTrials) {
+		return nil
+	}
+
+	var ops []Operation
+	// maxReports bounds the number of trials that will ever report in the rung: each report in a
+	// rung promotes at most one trial to the next, except for the report that brings the rung to
+	// MinTrialsPerRung, which may promote any of the trials in it.
+	maxReports := max(s.maxTrials, len(s.Rungs[0].Metrics))
+	for rungIndex, rung := range s.Rungs[:s.topRung()] {
+		// A trial is only promoted while it is among the best numPromote trials of its rung, and
+		// numPromote never exceeds maxPromote.
+		maxPromote := int(float64(maxReports) / s.promotionDivisor())
+		for index, trialMetric := range rung.Metrics {
+			if index < maxPromote || trialMetric.Promoted || s.ClosedTrials[trialMetric.RequestID] {
+				continue
+			}
+			ops = append(ops, s.cancel(ctx, trigger, rungIndex, trialMetric.RequestID)...)
 		}
-		for _, trialMetric := range rung.metrics {
-			if !trialMetric.promoted && !s.closedTrials[trialMetric.requestID] {
-				if !s.earlyExitTrials[trialMetric.requestID] {
-					ops = append(ops, NewClose(trialMetric.requestID))
-					s.closedTrials[trialMetric.requestID] = true
-				}
+		if maxPromote == 0 {
+			for _, requestID := range s.outstandingIn(rungIndex) {
+				// Record the trial as if it exited early so that the rung can still be closed out.
+				rung.insertMetric(
+					s.comparator, requestID, TrialMetricValue{Metric: ashaExitedMetricValue})
+				rung.OutstandingTrials--
+				s.OutstandingTrials--
+				s.ValidatedRungs[requestID] = rungIndex
+				s.CancelledTrials[requestID] = true
+				ops = append(ops, s.cancel(ctx, trigger, rungIndex, requestID)...)
 			}
 		}
+		futurePromotions := maxReports - len(rung.Metrics)
+		if len(rung.Metrics) < s.MinTrialsPerRung {
+			futurePromotions = maxReports
+		}
+		nextRung := s.Rungs[rungIndex+1]
+		maxReports = len(nextRung.Metrics) + nextRung.OutstandingTrials + futurePromotions
+	}
+	if len(ops) > 0 && len(s.Rungs[0].Metrics) == s.maxTrials {
+		ops = append(ops, s.closeOutRungs(ctx, trigger)...)
 	}
 	return ops
 }
 
+// outstandingIn returns the open trials that are training toward the rung, in request ID order.
+func (s *asyncHalvingSearch) outstandingIn(rungIndex int) []RequestID {
+	var outstanding []RequestID
+	for requestID, trialRung := range s.TrialRungs {
+		if trialRung != rungIndex || s.ClosedTrials[requestID] {
+			continue
+		}
+		if validatedRung, ok := s.ValidatedRungs[requestID]; ok && validatedRung >= rungIndex {
+			continue
+		}
+		outstanding = append(outstanding, requestID)
+	}
+	sort.Slice(outstanding, func(i, j int) bool {
+		return outstanding[i].Before(outstanding[j])
+	})
+	return outstanding
+}
+
+// cancel closes a trial in the given rung that cannot be promoted out of it.
+func (s *asyncHalvingSearch) cancel(
+	ctx context, trigger RequestID, rungIndex int, requestID RequestID,
+) []Operation {
+	s.ClosedTrials[requestID] = true
+	if s.EarlyExitTrials[requestID] {
+		return nil
+	}
+	ctx.decided(Decision{
+		Kind:        TrialClosedDecision,
+		RequestID:   requestID,
+		Rung:        rungIndex,
+		Metric:      s.reportedMetric(rungIndex, requestID),
+		TriggeredBy: &trigger,
+	})
+	return []Operation{NewClose(requestID)}
+}
+
+// reportedMetric returns the metric the trial reported in the rung, as reported by the trial but
+// for any metric transform, or nil if it exited early, diverged, or has not reported in the rung.
+func (s *asyncHalvingSearch) reportedMetric(rungIndex int, requestID RequestID) *float64 {
+	for _, trialMetric := range s.Rungs[rungIndex].Metrics {
+		if trialMetric.RequestID != requestID {
+			continue
+		}
+		if trialMetric.Metric == ashaExitedMetricValue {
+			return nil
+		}
+		metric := trialMetric.Metric
+		if _, smallerIsBetter := s.RungMetric(rungIndex); !smallerIsBetter {
+			metric *= -1
+		}
+		return &metric
+	}
+	return nil
+}
+
+// progress is the fraction of the training the search is expected to do that it has done: the
+// units trained toward the rungs trials have reported in, out of those units plus the estimate of
+// remainingResource. The training that outstanding trials have reported toward their rungs counts
+// as done too, and is taken out of the estimate, so that progress grows during a long rung rather
+// than only when trials report in it. None of these can be negative, so neither can the progress,
+// however many trials are outstanding.
 func (s *asyncHalvingSearch) progress(unitsCompleted model.Length) float64 {
-	allTrials := len(s.rungs[0].metrics)
-	// Give ourselves an overhead of 20% of maxTrials when calculating progress.
-	progress := float64(allTrials) / (1.2 * float64(s.maxTrials))
-	if allTrials == s.maxTrials {
-		return math.Max(float64(s.trialsCompleted)/float64(s.maxTrials), progress)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	done := 0.0
+	partial := 0.0
+	previousUnits := 0
+	for rungIndex, rung := range s.Rungs[:s.topRung()+1] {
+		width := rung.UnitsNeeded.Units - previousUnits
+		done += float64(len(rung.Metrics)) * float64(width)
+		for _, requestID := range s.outstandingIn(rungIndex) {
+			trained := s.TrialUnits[requestID] - previousUnits
+			partial += float64(max(min(trained, width), 0))
+		}
+		previousUnits = rung.UnitsNeeded.Units
 	}
-	return progress
+	progress := 0.0
+	if expected := done + s.remainingResource(); expected > 0 {
+		progress = (done + partial) / expected
+	}
+	s.Progress = math.Max(s.Progress, math.Min(1, progress))
+	return s.Progress
+}
+
+// RemainingResource estimates the number of units of training that the search has left to do. Each
+// rung is expected to receive the trials it has received so far or, while trials may still be
+// promoted to it, the trials of the rung below divided by the promotion divisor, if that is more;
+// each of those trials that has yet to report in the rung has the difference between the length of
+// the rung and that of the rung below left to train. The estimate never increases and is zero once
+// nothing is left to train.
+func (s *asyncHalvingSearch) RemainingResource() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	estimate := int(math.Round(s.remainingResource()))
+	if s.LowestRemaining != nil && *s.LowestRemaining < estimate {
+		estimate = *s.LowestRemaining
+	}
+	s.LowestRemaining = &estimate
+	return estimate
+}
+
+// remainingResource is the estimate of RemainingResource before it is rounded and kept from
+// increasing.
+func (s *asyncHalvingSearch) remainingResource() float64 {
+	remaining := 0.0
+	expected := float64(s.maxTrials)
+	// finished is whether no more trials will report in the rungs below the current one.
+	finished := true
+	previousUnits := 0
+	for rungIndex, rung := range s.Rungs[:s.topRung()+1] {
+		entered := float64(len(rung.Metrics) + rung.OutstandingTrials)
+		if rungIndex > 0 {
+			expected /= s.promotionDivisor()
+			if finished {
+				expected = entered
+			}
+		}
+		expected = math.Max(expected, entered)
+		remaining += (expected - float64(len(rung.Metrics))) *
+			float64(rung.UnitsNeeded.Units-previousUnits)
+		finished = finished && rung.OutstandingTrials == 0 &&
+			float64(len(rung.Metrics)) >= expected
+		previousUnits = rung.UnitsNeeded.Units
+	}
+	return remaining
+}
+
+// RungProgress describes the trials in a single rung of an asynchronous halving search.
+type RungProgress struct {
+	UnitsNeeded model.Length `json:"units_needed"`
+	// Outstanding is the number of trials that are training toward the rung.
+	Outstanding int `json:"outstanding"`
+	// Completed is the number of trials that have reported a metric in the rung.
+	Completed int `json:"completed"`
+	// Promoted is the number of trials that have been promoted out of the rung.
+	Promoted int `json:"promoted"`
+}
+
+// SearchProgress is a breakdown of the progress of an asynchronous halving search.
+type SearchProgress struct {
+	Rungs           []RungProgress `json:"rungs"`
+	MaxTrials       int            `json:"max_trials"`
+	TrialsCreated   int            `json:"trials_created"`
+	TrialsCompleted int            `json:"trials_completed"`
 }
 
+// ProgressDetail returns a breakdown of the progress of the search by rung.
+func (s *asyncHalvingSearch) ProgressDetail() SearchProgress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.progressDetail()
+}
+
+func (s *asyncHalvingSearch) progressDetail() SearchProgress {
+	detail := SearchProgress{
+		Rungs:           make([]RungProgress, 0, len(s.Rungs)),
+		MaxTrials:       s.maxTrials,
+		TrialsCreated:   len(s.TrialRungs),
+		TrialsCompleted: s.TrialsCompleted,
+	}
+	for rungIndex, rung := range s.Rungs {
+		rungProgress := RungProgress{
+			UnitsNeeded: rung.UnitsNeeded,
+			Outstanding: rung.OutstandingTrials,
+			Completed:   len(rung.Metrics),
+		}
+		for _, trialMetric := range rung.Metrics {
+			if s.TrialRungs[trialMetric.RequestID] > rungIndex {
+				rungProgress.Promoted++
+			}
+		}
+		detail.Rungs = append(detail.Rungs, rungProgress)
+	}
+	return detail
+}
+
+// RungInfo is a copy of the state of a single rung of an asynchronous halving search, as the search
+// keeps it.
+type RungInfo struct {
+	UnitsNeeded   model.Length `json:"units_needed"`
+	StartTrials   int          `json:"start_trials"`
+	PromoteTrials int          `json:"promote_trials"`
+	// OutstandingTrials is the number of trials that are training toward the rung.
+	OutstandingTrials int `json:"outstanding_trials"`
+	// Metrics are the results reported in the rung, best first; each metric is sign-adjusted so
+	// that smaller is better, and is the worst possible value for trials that exited early.
+	Metrics []RungMetricInfo `json:"metrics"`
+}
+
+// RungMetricInfo is a result reported in a rung of an asynchronous halving search.
+type RungMetricInfo struct {
+	RequestID RequestID `json:"request_id"`
+	Metric    float64   `json:"metric"`
+	Promoted  bool      `json:"promoted"`
+}
+
+// WhyNotDone returns the reasons the search has not completed, in human-readable form, e.g., to
+// diagnose a search that seems stuck: whether it is paused, how many trials are yet to be created
+// and whether the concurrency limit holds them back, the rungs with outstanding trials, and the
+// promotions the top rung awaits under MinTopRungTrials. These are the conditions that keep the
+// search from closing out its rungs; it returns no reasons once nothing does.
+func (s *asyncHalvingSearch) WhyNotDone() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var reasons []string
+	if s.Paused {
+		reasons = append(reasons, fmt.Sprintf(
+			"the search is paused with %d deferred operations", len(s.Deferred)))
+	}
+	if created := len(s.TrialRungs) - s.ReplacedTrials; created < s.maxTrials {
+		reasons = append(reasons, fmt.Sprintf("%d/%d trials created", created, s.maxTrials))
+		if s.OutstandingTrials >= s.Concurrency {
+			reasons = append(reasons, fmt.Sprintf(
+				"%d trials outstanding, the most allowed at once", s.OutstandingTrials))
+		}
+	}
+	for i, rung := range s.Rungs {
+		if rung.OutstandingTrials > 0 {
+			reasons = append(reasons, fmt.Sprintf(
+				"rung %d has %d outstanding trials", i, rung.OutstandingTrials))
+		}
+	}
+	if shortfall := s.topRungShortfall(); shortfall > 0 {
+		promotions := "promotions"
+		if shortfall == 1 {
+			promotions = "promotion"
+		}
+		reasons = append(reasons, fmt.Sprintf("top rung awaiting %d %s", shortfall, promotions))
+	}
+	return reasons
+}
+
+// RungInfo returns a copy of the state of each rung, from the bottom up, e.g., for integration
+// tests and external tooling; changing it does not change the search.
+func (s *asyncHalvingSearch) RungInfo() []RungInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	infos := make([]RungInfo, 0, len(s.Rungs))
+	for _, rung := range s.Rungs {
+		info := RungInfo{
+			UnitsNeeded:       rung.UnitsNeeded,
+			StartTrials:       rung.StartTrials,
+			PromoteTrials:     rung.PromoteTrials,
+			OutstandingTrials: rung.OutstandingTrials,
+			Metrics:           make([]RungMetricInfo, 0, len(rung.Metrics)),
+		}
+		for _, trialMetric := range rung.Metrics {
+			info.Metrics = append(info.Metrics, RungMetricInfo{
+				RequestID: trialMetric.RequestID,
+				Metric:    trialMetric.Metric,
+				Promoted:  trialMetric.Promoted,
+			})
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// trialExitedEarly records the worst possible metric for the trial, or closes it as configured by
+// EarlyExitMode. A trial that was preempted or canceled by the user is instead discarded: its
+// metrics are removed from the rungs and a new trial takes its place. So is a trial that errored
+// out, as long as it has been retried fewer than TrialRetries times, except that the new trial
+// uses the same hyperparameters.
 func (s *asyncHalvingSearch) trialExitedEarly(
+	ctx context, requestID RequestID, reason ExitedReason,
+) ([]Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.CancelledTrials[requestID] || s.DiscardedTrials[requestID] {
+		// The trial was already recorded as if it exited early when it was cancelled, or it was
+		// already discarded.
+		return nil, nil
+	}
+	original, isRetry := s.RetryOf[requestID]
+	if !isRetry {
+		original = requestID
+	}
+	if reason == Errored && s.Retries[original] < s.TrialRetries {
+		s.Retries[original]++
+		s.discard(requestID)
+		retry := s.createRetry(ctx, requestID, original)
+		ops, err := s.closeEarlyExit(ctx, requestID)
+		return s.emit(append(retry, ops...), err)
+	}
+	if reason.discardsResult() {
+		s.discard(requestID)
+		return s.emit(s.closeEarlyExit(ctx, requestID))
+	}
+	s.EarlyExitTrials[requestID] = true
+	s.ClosedTrials[requestID] = true
+	s.TrialsCompleted++
+	if s.EarlyExitMode == model.CloseEarlyExitMode {
+		return s.emit(s.closeEarlyExit(ctx, requestID))
+	}
+	rungIndex := s.TrialRungs[requestID]
+	if validatedRung, ok := s.ValidatedRungs[requestID]; ok && validatedRung >= rungIndex {
+		// The trial exited after reporting in its rung, so the metric it reported stands. If it is
+		// promoted later, the promotion records it as having exited in the next rung.
+		return nil, nil
+	}
+	return s.emit(s.promoteAsync(ctx, requestID, ashaExitedMetricValue))
+}
+
+// discard removes the metrics of the trial from the rungs and closes it.
+func (s *asyncHalvingSearch) discard(requestID RequestID) {
+	s.DiscardedTrials[requestID] = true
+	s.ClosedTrials[requestID] = true
+	for _, rung := range s.Rungs {
+		rung.removeMetric(requestID)
+	}
+}
+
+// createRetry creates a trial with the hyperparameters of the trial that errored out, which starts
+// over from the bottom rung.
+func (s *asyncHalvingSearch) createRetry(
+	ctx context, requestID RequestID, original RequestID,
+) []Operation {
+	create := NewCreate(ctx.rand, s.TrialHparams[requestID], model.TrialWorkloadSequencerType)
+	s.TrialRungs[create.RequestID] = 0
+	s.TrialHparams[create.RequestID] = create.Hparams
+	s.RetryOf[create.RequestID] = original
+	s.OutstandingTrials++
+	ctx.decided(Decision{Kind: TrialCreatedDecision, RequestID: create.RequestID})
+	return append([]Operation{create}, s.trainToward(create.RequestID, 0)...)
+}
+
+// closeEarlyExit drops a trial that exited early from the search without recording a metric for
+// it, freeing its place for another trial.
+func (s *asyncHalvingSearch) closeEarlyExit(
 	ctx context, requestID RequestID,
 ) ([]Operation, error) {
-	s.earlyExitTrials[requestID] = true
-	s.closedTrials[requestID] = true
-	s.trialsCompleted++
-	return s.promoteAsync(ctx, requestID, ashaExitedMetricValue), nil
+	rungIndex := s.TrialRungs[requestID]
+	validatedRung, validated := s.ValidatedRungs[requestID]
+	if !validated || validatedRung < rungIndex {
+		// The trial exited while training for its rung.
+		s.Rungs[rungIndex].OutstandingTrials--
+		s.OutstandingTrials--
+	}
+	if !validated || s.DiscardedTrials[requestID] {
+		// The trial never reported in the bottom rung or its report was removed, so a new trial
+		// takes its place there.
+		s.ReplacedTrials++
+	}
+
+	ops, err := s.backfill(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.Rungs[0].Metrics) == s.maxTrials {
+		ops = append(ops, s.closeOutRungs(ctx, requestID)...)
+	}
+	return ops, nil
+}
+
+// RungPromotionStats counts the promotions out of a single rung of an asynchronous halving search,
+// e.g., to check that trials are promoted fairly.
+type RungPromotionStats struct {
+	// Granted is the number of trials promoted out of the rung and trained toward the next one.
+	Granted int `json:"granted"`
+	// SkippedAlreadyPromoted is the number of times the trial that was due to be promoted as more
+	// trials reported in the rung had already been promoted, e.g., because it ranked high enough
+	// when it reported.
+	SkippedAlreadyPromoted int `json:"skipped_already_promoted"`
+	// EarlyExitPromotions is the number of trials promoted out of the rung after they had exited
+	// early, which are passed on through the next rung as if they had reported the worst metric.
+	EarlyExitPromotions int `json:"early_exit_promotions"`
+}
+
+// Stats returns the promotion counters of each rung.
+func (s *asyncHalvingSearch) Stats() []RungPromotionStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]RungPromotionStats{}, s.PromotionStats...)
+}
+
+// TrialSummary describes the standing of a single trial in an asynchronous halving search.
+type TrialSummary struct {
+	RequestID RequestID `json:"request_id"`
+	// Metric is the last metric the trial reported, as reported by the trial (i.e., not negated for
+	// searches where larger is better) but for any metric transform.
+	Metric      float64 `json:"metric"`
+	Rung        int     `json:"rung"`
+	Promoted    bool    `json:"promoted"`
+	EarlyExited bool    `json:"early_exited"`
+}
+
+// BestTrials returns up to n of the best trials so far. Trials that have reached higher rungs rank
+// ahead of those in lower rungs; within a rung, trials are ranked by the last metric they reported.
+func (s *asyncHalvingSearch) BestTrials(n int) []TrialSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// A trial's standing is the rung it has been promoted to along with the metric from the highest
+	// rung in which it has reported one.
+	var ranked []trialMetric
+	reportedRungs := make(map[RequestID]int)
+	for rungIndex := len(s.Rungs) - 1; rungIndex >= 0; rungIndex-- {
+		for _, trialMetric := range s.Rungs[rungIndex].Metrics {
+			if _, ok := reportedRungs[trialMetric.RequestID]; !ok {
+				reportedRungs[trialMetric.RequestID] = rungIndex
+				ranked = append(ranked, trialMetric)
+			}
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		iRung, jRung := s.TrialRungs[ranked[i].RequestID], s.TrialRungs[ranked[j].RequestID]
+		switch {
+		case iRung != jRung:
+			return iRung > jRung
+		case s.comparator.Less(ranked[i].value(), ranked[j].value()):
+			return true
+		case s.comparator.Less(ranked[j].value(), ranked[i].value()):
+			return false
+		default:
+			return ranked[i].RequestID.Before(ranked[j].RequestID)
+		}
+	})
+	if len(ranked) > n {
+		ranked = ranked[:n]
+	}
+
+	summaries := make([]TrialSummary, 0, len(ranked))
+	for _, trialMetric := range ranked {
+		metric := trialMetric.Metric
+		if _, smallerIsBetter := s.RungMetric(reportedRungs[trialMetric.RequestID]); !smallerIsBetter {
+			metric *= -1
+		}
+		summaries = append(summaries, TrialSummary{
+			RequestID:   trialMetric.RequestID,
+			Metric:      metric,
+			Rung:        s.TrialRungs[trialMetric.RequestID],
+			Promoted:    trialMetric.Promoted,
+			EarlyExited: s.EarlyExitTrials[trialMetric.RequestID],
+		})
+	}
+	return summaries
+}
+
+// PromotionEdge records the promotion of a trial from one rung to the next.
+type PromotionEdge struct {
+	RequestID RequestID `json:"request_id"`
+	FromRung  int       `json:"from_rung"`
+	ToRung    int       `json:"to_rung"`
+	// Metric is the metric the trial reported in the rung it was promoted from, as reported by the
+	// trial; it is nil if the trial exited early or diverged.
+	Metric *float64 `json:"metric"`
+}
+
+// PromotionGraph returns an edge for every promotion made so far, ordered by the rung promoted from
+// and then by the metric reported in that rung.
+func (s *asyncHalvingSearch) PromotionGraph() []PromotionEdge {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var edges []PromotionEdge
+	for rungIndex, rung := range s.Rungs {
+		for _, trialMetric := range rung.Metrics {
+			// Promotions of trials that were closed are forfeited, so only trials that actually moved
+			// to a higher rung count.
+			if s.TrialRungs[trialMetric.RequestID] <= rungIndex {
+				continue
+			}
+			edges = append(edges, PromotionEdge{
+				RequestID: trialMetric.RequestID,
+				FromRung:  rungIndex,
+				ToRung:    rungIndex + 1,
+				Metric:    s.reportedMetric(rungIndex, trialMetric.RequestID),
+			})
+		}
+	}
+	return edges
+}
+
+// Merge adds the rung metrics and trials of another asynchronous halving search with the same
+// metrics and rungs into this one, e.g., to rank the trials of several shards of a large search
+// together with BestTrials. The trials of the other search are ranked in each rung as if they had
+// reported there, but they are marked closed and are never promoted or trained by this search; a
+// merged search is meant to be read from rather than driven any further. The metrics, training and
+// promotion counters recorded for each trial and rung are merged as well, so that ProgressDetail,
+// RungInfo, Stats and Snapshot cover the trials of both searches; the state that only drives a
+// search, i.e., the outstanding trials, concurrency, timeouts, max_trials and convergence, stays
+// that of this search.
+func (s *asyncHalvingSearch) Merge(other SearchMethod) error {
+	shard, ok := other.(*asyncHalvingSearch)
+	if !ok {
+		return errors.WithStack(ConfigError{errors.Errorf(
+			"cannot merge a %T into an asynchronous halving search", other)})
+	}
+	if shard == s {
+		return errors.WithStack(ConfigError{
+			errors.New("cannot merge an asynchronous halving search into itself")})
+	}
+	if err := s.checkMergeable(shard.AsyncHalvingConfig); err != nil {
+		return errors.WithStack(ConfigError{err})
+	}
+	// Work on a copy of the state of the other search so that the two locks are never held at once.
+	snapshot, err := shard.Snapshot()
+	if err != nil {
+		return err
+	}
+	var state asyncHalvingSearchState
+	if err := json.Unmarshal(snapshot, &state); err != nil {
+		return errors.Wrap(err, "failed to copy asynchronous halving search state")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for requestID := range state.TrialRungs {
+		if _, ok := s.TrialRungs[requestID]; ok {
+			return errors.WithStack(ConfigError{
+				errors.Errorf("trial %s is in both searches being merged", requestID)})
+		}
+	}
+	for rungIndex, rung := range state.Rungs {
+		for _, trialMetric := range rung.Metrics {
+			insertIndex := s.Rungs[rungIndex].insertMetric(
+				s.comparator, trialMetric.RequestID, trialMetric.value())
+			s.Rungs[rungIndex].Metrics[insertIndex].Promoted = trialMetric.Promoted
+		}
+	}
+	for requestID, rungIndex := range state.TrialRungs {
+		s.TrialRungs[requestID] = rungIndex
+		s.ClosedTrials[requestID] = true
+	}
+	for requestID, rungIndex := range state.ValidatedRungs {
+		s.ValidatedRungs[requestID] = rungIndex
+	}
+	for requestID := range state.EarlyExitTrials {
+		s.EarlyExitTrials[requestID] = true
+	}
+	for requestID, hparams := range state.TrialHparams {
+		s.TrialHparams[requestID] = hparams
+	}
+	for requestID := range state.DiscardedTrials {
+		s.DiscardedTrials[requestID] = true
+	}
+	for requestID, retries := range state.Retries {
+		s.Retries[requestID] = retries
+	}
+	for requestID, original := range state.RetryOf {
+		s.RetryOf[requestID] = original
+	}
+	for requestID, metric := range state.SmoothedMetrics {
+		s.SmoothedMetrics[requestID] = metric
+	}
+	for requestID, metric := range state.BestMetrics {
+		s.BestMetrics[requestID] = metric
+	}
+	for requestID, history := range state.MetricHistories {
+		s.MetricHistories[requestID] = history
+	}
+	for requestID, units := range state.TrialUnits {
+		s.TrialUnits[requestID] = units
+	}
+	for requestID, cost := range state.TrialCosts {
+		s.TrialCosts[requestID] = cost
+	}
+	for rungIndex, stats := range state.PromotionStats {
+		if rungIndex >= len(s.PromotionStats) {
+			break
+		}
+		s.PromotionStats[rungIndex].Granted += stats.Granted
+		s.PromotionStats[rungIndex].SkippedAlreadyPromoted += stats.SkippedAlreadyPromoted
+		s.PromotionStats[rungIndex].EarlyExitPromotions += stats.EarlyExitPromotions
+	}
+	s.TrialsCompleted += state.TrialsCompleted
+	s.ReplacedTrials += state.ReplacedTrials
+	return nil
+}
+
+// checkMergeable returns an error if the trials of a search with the given configuration cannot be
+// ranked together with those of this search, i.e., if the rungs or the metrics differ.
+func (s *asyncHalvingSearch) checkMergeable(config model.AsyncHalvingConfig) error {
+	if config.Unit() != s.Unit() {
+		return errors.Errorf("cannot merge a search in %s into a search in %s", config.Unit(), s.Unit())
+	}
+	units, otherUnits := s.RungUnits(), config.RungUnits()
+	if len(units) != len(otherUnits) {
+		return errors.Errorf(
+			"cannot merge a search with %d rungs into a search with %d rungs",
+			len(otherUnits), len(units))
+	}
+	for rungIndex := range units {
+		if units[rungIndex] != otherUnits[rungIndex] {
+			return errors.Errorf(
+				"cannot merge a search whose rung %d needs %d units into one whose rung %d needs %d",
+				rungIndex, otherUnits[rungIndex], rungIndex, units[rungIndex])
+		}
+		metric, smallerIsBetter := s.RungMetric(rungIndex)
+		otherMetric, otherSmallerIsBetter := config.RungMetric(rungIndex)
+		if metric != otherMetric || smallerIsBetter != otherSmallerIsBetter {
+			return errors.Errorf(
+				"cannot merge a search that ranks rung %d by %s into one that ranks it by %s",
+				rungIndex, otherMetric, metric)
+		}
+	}
+	if !reflect.DeepEqual(config.TiebreakMetrics, s.TiebreakMetrics) ||
+		config.TiebreakByResource != s.TiebreakByResource {
+		return errors.New("cannot merge searches with different tiebreak metrics")
+	}
+	if !reflect.DeepEqual(config.Penalties, s.Penalties) {
+		return errors.New("cannot merge searches with different penalties")
+	}
+	return nil
+}
+
+// Snapshot implements the SearchMethod interface.
+func (s *asyncHalvingSearch) Snapshot() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return json.Marshal(s.asyncHalvingSearchState)
+}
+
+// Restore implements the SearchMethod interface.
+func (s *asyncHalvingSearch) Restore(state []byte) error {
+	var restored asyncHalvingSearchState
+	if err := json.Unmarshal(state, &restored); err != nil {
+		return errors.Wrap(err, "failed to restore asynchronous halving search state")
+	}
+	if len(restored.Rungs) != s.NumRungs {
+		return errors.Errorf(
+			"cannot restore %d rungs into a search configured with %d rungs",
+			len(restored.Rungs), s.NumRungs)
+	}
+	if restored.ValidatedRungs == nil {
+		restored.ValidatedRungs = make(map[RequestID]int)
+	}
+	if restored.CancelledTrials == nil {
+		restored.CancelledTrials = make(map[RequestID]bool)
+	}
+	if restored.DiscardedTrials == nil {
+		restored.DiscardedTrials = make(map[RequestID]bool)
+	}
+	if restored.Retries == nil {
+		restored.Retries = make(map[RequestID]int)
+	}
+	if restored.RetryOf == nil {
+		restored.RetryOf = make(map[RequestID]RequestID)
+	}
+	if restored.SmoothedMetrics == nil {
+		restored.SmoothedMetrics = make(map[RequestID]float64)
+	}
+	if restored.BestMetrics == nil {
+		restored.BestMetrics = make(map[RequestID]float64)
+	}
+	if restored.TrialHparams == nil {
+		restored.TrialHparams = make(map[RequestID]hparamSample)
+	}
+	if restored.TrialStarted == nil {
+		restored.TrialStarted = make(map[RequestID]time.Time)
+	}
+	if restored.TrialCosts == nil {
+		restored.TrialCosts = make(map[RequestID]trialCost)
+	}
+	if restored.TrialUnits == nil {
+		restored.TrialUnits = make(map[RequestID]int)
+	}
+	if restored.IntermediateValidations == nil {
+		restored.IntermediateValidations = make(map[RequestID]int)
+	}
+	if restored.MetricHistories == nil {
+		restored.MetricHistories = make(map[RequestID]*metricHistory)
+	}
+	if restored.Concurrency == 0 {
+		restored.Concurrency = s.defaultConcurrency()
+	}
+	if len(restored.PromotionStats) != len(restored.Rungs) {
+		restored.PromotionStats = make([]RungPromotionStats, len(restored.Rungs))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.asyncHalvingSearchState = restored
+	if restored.ExtendedMaxTrials > 0 {
+		s.maxTrials = restored.ExtendedMaxTrials
+	}
+	return nil
 }
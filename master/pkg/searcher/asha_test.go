@@ -1,9 +1,18 @@
 package searcher
 
 import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
 	"testing"
+	"time"
+
+	"gotest.tools/assert"
 
 	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/nprand"
 )
 
 func TestASHASearcherRecords(t *testing.T) {
@@ -63,6 +72,93 @@ func TestASHASearcherEpochs(t *testing.T) {
 	checkSimulation(t, newAsyncHalvingSearch(actual), nil, ConstantValidation, expected)
 }
 
+func TestASHASearcherEpochRungBatches(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric: defaultMetric, NumRungs: 3,
+		MaxLength: model.NewLengthInEpochs(27),
+		Divisor:   3,
+		MaxTrials: 9,
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	// 6400 records per epoch at a global batch size of 64 is 100 batches per epoch.
+	ctx := model.NewUnitContext(model.Epochs, 64, 6400)
+
+	expectedEpochs := []int{3, 9, 27}
+	expectedBatches := []int{300, 900, 2700}
+	assert.Equal(t, len(search.Rungs), len(expectedEpochs))
+	for i, rung := range search.Rungs {
+		assert.Equal(t, rung.UnitsNeeded, model.NewLengthInEpochs(expectedEpochs[i]))
+		assert.Equal(t, rung.UnitsNeeded.ToNearestBatch(ctx), expectedBatches[i])
+	}
+}
+
+func TestASHASearcherRungResources(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:          defaultMetric,
+		SmallerIsBetter: true,
+		NumRungs:        4,
+		MaxLength:       model.NewLengthInBatches(30),
+		Divisor:         3,
+		MaxTrials:       27,
+	}
+	rungUnits := func(config model.AsyncHalvingConfig) []int {
+		var units []int
+		for _, rung := range newAsyncHalvingSearch(config).(*asyncHalvingSearch).Rungs {
+			assert.Equal(t, rung.UnitsNeeded.Unit, model.Batches)
+			units = append(units, rung.UnitsNeeded.Units)
+		}
+		return units
+	}
+	assert.DeepEqual(t, rungUnits(config), []int{1, 3, 10, 30})
+
+	config.RungResources = []int{1, 3, 9, 30}
+	assert.DeepEqual(t, rungUnits(config), []int{1, 3, 9, 30})
+
+	// Promoted trials train only for the difference between the rungs.
+	search := newAsyncHalvingSearch(config)
+	method := &recordingMethod{SearchMethod: search}
+	driver, err := newQueueDriver(method, nil, func(trialIndex, validations int) float64 {
+		return float64(trialIndex)
+	})
+	assert.NilError(t, err)
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+	lengths := make(map[int][]int)
+	for _, op := range method.ops {
+		if train, ok := op.(Train); ok {
+			trialIndex := driver.trialIndex[train.RequestID]
+			lengths[trialIndex] = append(lengths[trialIndex], train.Length.Units)
+		}
+	}
+	assert.DeepEqual(t, lengths[0], []int{1, 2, 6, 21})
+}
+
+func TestASHASearcherRungRounding(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:          defaultMetric,
+		SmallerIsBetter: true,
+		NumRungs:        4,
+		MaxLength:       model.NewLengthInBatches(50),
+		Divisor:         3,
+		MaxTrials:       27,
+	}
+	rungUnits := func(rounding model.RoundingPolicy) []int {
+		config.RungRounding = rounding
+		var units []int
+		for _, rung := range newAsyncHalvingSearch(config).(*asyncHalvingSearch).Rungs {
+			units = append(units, rung.UnitsNeeded.Units)
+		}
+		return units
+	}
+	// The exact lengths are 50/27, 50/9, 50/3, and 50.
+	assert.DeepEqual(t, rungUnits(""), []int{1, 5, 16, 50})
+	assert.DeepEqual(t, rungUnits(model.FloorRounding), []int{1, 5, 16, 50})
+	assert.DeepEqual(t, rungUnits(model.RoundRounding), []int{2, 6, 17, 50})
+	assert.DeepEqual(t, rungUnits(model.CeilRounding), []int{2, 6, 17, 50})
+}
+
 func TestASHASearchMethod(t *testing.T) {
 	maxConcurrentTrials := 3
 	testCases := []valueSimulationTestCase{
@@ -213,3 +309,3119 @@ func TestASHASearchMethod(t *testing.T) {
 
 	runValueSimulationTestCases(t, testCases)
 }
+
+func TestASHASearcherInvalidConfig(t *testing.T) {
+	valid := model.AsyncHalvingConfig{
+		Metric:    defaultMetric,
+		NumRungs:  3,
+		MaxLength: model.NewLengthInBatches(900),
+		Divisor:   3,
+		MaxTrials: 9,
+	}
+	_, err := NewSearchMethod(model.SearcherConfig{AsyncHalvingConfig: &valid})
+	assert.NilError(t, err)
+
+	cases := []struct {
+		name     string
+		mutate   func(*model.AsyncHalvingConfig)
+		expected string
+	}{
+		{"no rungs", func(c *model.AsyncHalvingConfig) { c.NumRungs = 0 }, "num_rungs"},
+		{"negative rungs", func(c *model.AsyncHalvingConfig) { c.NumRungs = -1 }, "num_rungs"},
+		{"divisor of one", func(c *model.AsyncHalvingConfig) { c.Divisor = 1 }, "divisor"},
+		{"no divisor", func(c *model.AsyncHalvingConfig) { c.Divisor = 0 }, "divisor"},
+		{"no length", func(c *model.AsyncHalvingConfig) {
+			c.MaxLength = model.NewLengthInBatches(0)
+		}, "max_length"},
+		{"no trials", func(c *model.AsyncHalvingConfig) { c.MaxTrials = 0 }, "max_trials"},
+		{"negative concurrency", func(c *model.AsyncHalvingConfig) {
+			c.MaxConcurrentTrials = -1
+		}, "max_concurrent_trials"},
+	}
+	for _, c := range cases {
+		config := valid
+		c.mutate(&config)
+		_, err := NewSearchMethod(model.SearcherConfig{AsyncHalvingConfig: &config})
+		assert.ErrorContains(t, err, c.expected, c.name)
+	}
+}
+
+func TestASHASearcherWarmStart(t *testing.T) {
+	warmStart := []map[string]interface{}{
+		{"lr": 0.01, "layers": 4.0},
+		{"lr": 0.001, "layers": 2.0},
+	}
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(200),
+		Divisor:             2,
+		MaxTrials:           6,
+		MaxConcurrentTrials: 3,
+		WarmStart:           warmStart,
+	}
+	hparams := model.Hyperparameters{
+		"lr": model.Hyperparameter{
+			DoubleHyperparameter: &model.DoubleHyperparameter{Minval: 0.1, Maxval: 1},
+		},
+		"layers": model.Hyperparameter{
+			IntHyperparameter: &model.IntHyperparameter{Minval: 8, Maxval: 16},
+		},
+	}
+	method := &recordingMethod{SearchMethod: newAsyncHalvingSearch(config)}
+	driver, err := newQueueDriver(method, hparams, func(trialIndex, validations int) float64 {
+		return float64(trialIndex)
+	})
+	assert.NilError(t, err)
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+
+	var creates []Create
+	for _, op := range method.ops {
+		if create, ok := op.(Create); ok {
+			creates = append(creates, create)
+		}
+	}
+	assert.Equal(t, len(creates), config.MaxTrials)
+	for i, create := range creates {
+		if i < len(warmStart) {
+			assert.DeepEqual(t, map[string]interface{}(create.Hparams), warmStart[i])
+		} else {
+			// The remaining trials are sampled from the hyperparameter space, which excludes the
+			// warm start points.
+			assert.Assert(t, create.Hparams["lr"].(float64) >= 0.1)
+		}
+	}
+
+	// The warm start points are the best trials, so they are the ones promoted.
+	trains := make(map[int]int)
+	for _, op := range method.ops {
+		if train, ok := op.(Train); ok {
+			trains[driver.trialIndex[train.RequestID]]++
+		}
+	}
+	assert.Equal(t, trains[0], 2)
+	assert.Equal(t, trains[1], 2)
+	assert.Equal(t, len(method.closeCounts()), config.MaxTrials)
+}
+
+func TestASHASearcherConcurrentCalls(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           64,
+		MaxConcurrentTrials: 64,
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	ops, err := search.initialOperations(context{rand: nprand.New(0)})
+	assert.NilError(t, err)
+	var requestIDs []RequestID
+	for _, op := range ops {
+		if create, ok := op.(Create); ok {
+			requestIDs = append(requestIDs, create.RequestID)
+		}
+	}
+	assert.Equal(t, len(requestIDs), config.MaxTrials)
+
+	// Every trial reports from its own goroutine while others poll the progress of the search; run
+	// with -race to check that the search synchronizes them. Each goroutine has its own random
+	// state, since that is owned by the caller rather than the search.
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					progress := search.progress(model.Length{})
+					assert.Assert(t, progress >= 0 && progress <= 1)
+					search.BestTrials(3)
+				}
+			}
+		}()
+	}
+	var trials sync.WaitGroup
+	for i, requestID := range requestIDs {
+		trials.Add(1)
+		go func(i int, requestID RequestID) {
+			defer trials.Done()
+			ctx := context{rand: nprand.New(uint32(i))}
+			_, err := search.trialCreated(ctx, requestID)
+			assert.NilError(t, err)
+			if i%4 == 0 {
+				_, err = search.trialExitedEarly(ctx, requestID, Errored)
+			} else {
+				_, err = search.validationCompleted(ctx, requestID, NewValidate(requestID),
+					ValidationMetrics{Metrics: map[string]interface{}{defaultMetric: float64(i)}})
+			}
+			assert.NilError(t, err)
+		}(i, requestID)
+	}
+	trials.Wait()
+	close(done)
+	wg.Wait()
+
+	assert.Equal(t, len(search.Rungs[0].Metrics), config.MaxTrials)
+	assert.Equal(t, search.TrialsCompleted, config.MaxTrials/4)
+	seen := make(map[RequestID]bool)
+	for _, trialMetric := range search.Rungs[0].Metrics {
+		assert.Assert(t, !seen[trialMetric.RequestID])
+		seen[trialMetric.RequestID] = true
+	}
+}
+
+func TestASHASearcherEarlyExitMode(t *testing.T) {
+	run := func(mode model.EarlyExitMode) (*asyncHalvingSearch, *recordingMethod) {
+		config := model.AsyncHalvingConfig{
+			Metric:              defaultMetric,
+			SmallerIsBetter:     true,
+			NumRungs:            3,
+			MaxLength:           model.NewLengthInBatches(900),
+			Divisor:             3,
+			MaxTrials:           9,
+			MaxConcurrentTrials: 3,
+			EarlyExitMode:       mode,
+		}
+		search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+		method := &recordingMethod{SearchMethod: search}
+		driver, err := newQueueDriver(method, nil, func(trialIndex, validations int) float64 {
+			return float64(trialIndex)
+		})
+		assert.NilError(t, err)
+		// The first two trials exit early in the bottom rung, and the best trial exits early once it
+		// is promoted to the middle rung.
+		driver.exitFn = func(trialIndex, validations int) bool {
+			return (trialIndex == 1 || trialIndex == 2) || (trialIndex == 0 && validations == 1)
+		}
+		for len(driver.pending) > 0 {
+			_, err = driver.step()
+			assert.NilError(t, err)
+		}
+		return search, method
+	}
+	exited := func(rung *rung) (count int) {
+		for _, trialMetric := range rung.Metrics {
+			if trialMetric.Metric == ashaExitedMetricValue {
+				count++
+			}
+		}
+		return count
+	}
+	creates := func(method *recordingMethod) (count int) {
+		for _, op := range method.ops {
+			if _, ok := op.(Create); ok {
+				count++
+			}
+		}
+		return count
+	}
+
+	participate, participateOps := run(model.ParticipateEarlyExitMode)
+	assert.Equal(t, creates(participateOps), 9)
+	assert.Equal(t, len(participate.Rungs[0].Metrics), 9)
+	assert.Equal(t, exited(participate.Rungs[0]), 2)
+	assert.Equal(t, exited(participate.Rungs[1]), 1)
+
+	closed, closedOps := run(model.CloseEarlyExitMode)
+	// The two trials that never reported are replaced; the one that exited in the middle rung has
+	// already taken its place in the bottom rung.
+	assert.Equal(t, creates(closedOps), 11)
+	assert.Equal(t, len(closed.Rungs[0].Metrics), 9)
+	assert.Equal(t, len(closed.Rungs[1].Metrics), 2)
+	for _, rung := range closed.Rungs {
+		assert.Equal(t, exited(rung), 0)
+		assert.Equal(t, rung.OutstandingTrials, 0)
+	}
+	assert.Equal(t, closed.OutstandingTrials, 0)
+	assert.Equal(t, len(closedOps.closeCounts())+len(closed.EarlyExitTrials), 11)
+	for _, count := range closedOps.closeCounts() {
+		assert.Equal(t, count, 1)
+	}
+}
+
+func TestASHASearcherClosesTrialsOnce(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           12,
+		MaxConcurrentTrials: 3,
+	}
+	method := &recordingMethod{SearchMethod: newAsyncHalvingSearch(config)}
+	actual, err := Simulate(NewSearcher(0, method, nil, nil), new(int64), RandomValidation, true,
+		defaultMetric)
+	assert.NilError(t, err)
+
+	closes := method.closeCounts()
+	assert.Equal(t, len(closes), len(actual.Results))
+	for requestID, count := range closes {
+		assert.Equal(t, count, 1, "trial %s closed %d times", requestID, count)
+	}
+}
+
+func TestASHASearcherEarlyExitCompletedOnce(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:    defaultMetric,
+		NumRungs:  2,
+		MaxLength: model.NewLengthInBatches(200),
+		Divisor:   2,
+		MaxTrials: 2,
+	}
+	ctx := context{rand: nprand.New(0)}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	ops, err := search.initialOperations(ctx)
+	assert.NilError(t, err)
+	requestID := ops[0].(Create).RequestID
+
+	_, err = search.trialCreated(ctx, requestID)
+	assert.NilError(t, err)
+	_, err = search.trialExitedEarly(ctx, requestID, Errored)
+	assert.NilError(t, err)
+	// The master closes the trial after it exits early; this must not count it a second time.
+	_, err = search.trialClosed(ctx, requestID)
+	assert.NilError(t, err)
+	assert.Equal(t, search.TrialsCompleted, 1)
+}
+
+func TestASHASearcherDuplicateValidations(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           9,
+		MaxConcurrentTrials: 3,
+	}
+	metricFn := func(trialIndex, validations int) float64 {
+		return float64((trialIndex * 5) % 9)
+	}
+
+	run := func(duplicate bool) (*asyncHalvingSearch, []Operation) {
+		search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+		method := &recordingMethod{SearchMethod: search}
+		driver, err := newQueueDriver(method, nil, metricFn)
+		assert.NilError(t, err)
+		for len(driver.pending) > 0 {
+			next := driver.pending[0]
+			_, err = driver.step()
+			assert.NilError(t, err)
+			if validate, ok := next.(Validate); duplicate && ok {
+				ops, err := search.validationCompleted(driver.ctx, validate.RequestID, validate,
+					ValidationMetrics{Metrics: map[string]interface{}{
+						defaultMetric: metricFn(driver.trialIndex[validate.RequestID], 0),
+					}})
+				assert.NilError(t, err)
+				assert.Equal(t, len(ops), 0)
+			}
+		}
+		return search, method.ops
+	}
+
+	expectedSearch, expectedOps := run(false)
+	actualSearch, actualOps := run(true)
+	assert.DeepEqual(t, actualOps, expectedOps)
+	for i, rung := range actualSearch.Rungs {
+		assert.Equal(t, len(rung.Metrics), len(expectedSearch.Rungs[i].Metrics))
+	}
+	assert.Equal(t, len(actualSearch.Rungs[0].Metrics), config.MaxTrials)
+}
+
+func TestASHASearcherPromotionRatio(t *testing.T) {
+	promotions := func(promotionRatio *float64) ([]int, []model.Length) {
+		config := model.AsyncHalvingConfig{
+			Metric:              defaultMetric,
+			SmallerIsBetter:     true,
+			NumRungs:            3,
+			MaxLength:           model.NewLengthInBatches(900),
+			Divisor:             3,
+			MaxTrials:           16,
+			MaxConcurrentTrials: 4,
+			PromotionRatio:      promotionRatio,
+		}
+		search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+		method := &recordingMethod{SearchMethod: search}
+		driver, err := newQueueDriver(method, nil, func(trialIndex, validations int) float64 {
+			return float64(trialIndex)
+		})
+		assert.NilError(t, err)
+		for len(driver.pending) > 0 {
+			_, err = driver.step()
+			assert.NilError(t, err)
+		}
+
+		rungCounts := make([]int, config.NumRungs)
+		var unitsNeeded []model.Length
+		for _, rung := range search.Rungs {
+			unitsNeeded = append(unitsNeeded, rung.UnitsNeeded)
+		}
+		trains := make(map[RequestID]int)
+		for _, op := range method.ops {
+			if train, ok := op.(Train); ok {
+				rungCounts[trains[train.RequestID]]++
+				trains[train.RequestID]++
+			}
+		}
+		return rungCounts, unitsNeeded
+	}
+
+	coupled, coupledUnits := promotions(nil)
+	assert.DeepEqual(t, coupled, []int{16, 5, 1})
+
+	// Promoting half of each rung promotes more trials but leaves the rungs where they were.
+	half := 0.5
+	decoupled, decoupledUnits := promotions(&half)
+	assert.DeepEqual(t, decoupled, []int{16, 8, 4})
+	assert.DeepEqual(t, decoupledUnits, coupledUnits)
+
+	// A ratio of exactly 1/Divisor behaves as if no ratio were set.
+	third := 1.0 / 3
+	explicit, _ := promotions(&third)
+	assert.DeepEqual(t, explicit, coupled)
+}
+
+func TestASHASearcherSnapshotRestore(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           9,
+		MaxConcurrentTrials: 3,
+	}
+	metricFn := func(trialIndex, _ int) float64 { return float64((trialIndex * 7) % 9) }
+
+	original, err := newQueueDriver(newAsyncHalvingSearch(config), nil, metricFn)
+	assert.NilError(t, err)
+	for i := 0; i < 20; i++ {
+		_, err = original.step()
+		assert.NilError(t, err)
+	}
+
+	snapshot, err := original.method.Snapshot()
+	assert.NilError(t, err)
+	restoredMethod := newAsyncHalvingSearch(config)
+	assert.NilError(t, restoredMethod.Restore(snapshot))
+	restoredSnapshot, err := restoredMethod.Snapshot()
+	assert.NilError(t, err)
+	assert.Equal(t, string(restoredSnapshot), string(snapshot))
+
+	restored := original.clone(restoredMethod)
+	for len(original.pending) > 0 {
+		expected, expectedErr := original.step()
+		assert.NilError(t, expectedErr)
+		actual, actualErr := restored.step()
+		assert.NilError(t, actualErr)
+		assert.DeepEqual(t, actual, expected)
+	}
+	assert.Equal(t, len(restored.pending), 0)
+}
+
+func TestASHASearcherRestoreMismatchedRungs(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:    defaultMetric,
+		NumRungs:  3,
+		MaxLength: model.NewLengthInBatches(900),
+		Divisor:   3,
+		MaxTrials: 9,
+	}
+	snapshot, err := newAsyncHalvingSearch(config).Snapshot()
+	assert.NilError(t, err)
+
+	config.NumRungs = 2
+	assert.ErrorContains(t, newAsyncHalvingSearch(config).Restore(snapshot), "rungs")
+}
+
+func TestASHASearcherNonFiniteMetrics(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           9,
+		MaxConcurrentTrials: 3,
+	}
+
+	for _, nonFinite := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		for _, smallerIsBetter := range []bool{true, false} {
+			config.SmallerIsBetter = smallerIsBetter
+			// The first trial is the best one, until it diverges in the middle rung.
+			metricFn := func(trialIndex, validations int) float64 {
+				if trialIndex == 0 && validations == 1 {
+					return nonFinite
+				}
+				if smallerIsBetter {
+					return float64(trialIndex)
+				}
+				return float64(-trialIndex)
+			}
+			method := &recordingMethod{SearchMethod: newAsyncHalvingSearch(config)}
+			driver, err := newQueueDriver(method, nil, metricFn)
+			assert.NilError(t, err)
+			for len(driver.pending) > 0 {
+				_, err = driver.step()
+				assert.NilError(t, err)
+			}
+
+			trains := make(map[int]int)
+			for _, op := range method.ops {
+				if train, ok := op.(Train); ok {
+					trains[driver.trialIndex[train.RequestID]]++
+				}
+			}
+			assert.Equal(t, trains[0], 2, "diverged trial was promoted to the top rung")
+			topRung := 0
+			for _, count := range trains {
+				if count == config.NumRungs {
+					topRung++
+				}
+			}
+			assert.Equal(t, topRung, 1)
+			assert.Equal(t, len(method.closeCounts()), config.MaxTrials)
+		}
+	}
+}
+
+func TestASHASearcherFailOnNonFiniteMetric(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:                defaultMetric,
+		NumRungs:              3,
+		MaxLength:             model.NewLengthInBatches(900),
+		Divisor:               3,
+		MaxTrials:             9,
+		FailOnNonFiniteMetric: true,
+	}
+	driver, err := newQueueDriver(newAsyncHalvingSearch(config), nil, func(int, int) float64 {
+		return math.NaN()
+	})
+	assert.NilError(t, err)
+	for err == nil && len(driver.pending) > 0 {
+		_, err = driver.step()
+	}
+	assert.ErrorContains(t, err, "non-finite")
+}
+
+func TestASHASearcherMetricAggregation(t *testing.T) {
+	for _, smallerIsBetter := range []bool{true, false} {
+		config := model.AsyncHalvingConfig{
+			Metric:          defaultMetric,
+			SmallerIsBetter: smallerIsBetter,
+			NumRungs:        2,
+			MaxLength:       model.NewLengthInBatches(200),
+			Divisor:         2,
+			MaxTrials:       2,
+			MetricAggregation: &model.MetricAggregationConfig{
+				Mode:    model.MaxAggregation,
+				Metrics: []string{"loss_a", "loss_b"},
+			},
+		}
+		ctx := context{rand: nprand.New(0)}
+		search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+		ops, err := search.initialOperations(ctx)
+		assert.NilError(t, err)
+		requestID := ops[0].(Create).RequestID
+		_, err = search.trialCreated(ctx, requestID)
+		assert.NilError(t, err)
+
+		// The aggregate is taken before negating metrics where larger is better, so the trial is
+		// ranked by max(-1, 3) = 3 rather than by max(1, -3) = 1.
+		_, err = search.validationCompleted(ctx, requestID, Validate{}, ValidationMetrics{
+			Metrics: map[string]interface{}{"loss_a": -1.0, "loss_b": 3.0},
+		})
+		assert.NilError(t, err)
+		expected := 3.0
+		if !smallerIsBetter {
+			expected = -3.0
+		}
+		assert.Equal(t, search.Rungs[0].Metrics[0].Metric, expected)
+
+		otherID := ops[3].(Create).RequestID
+		_, err = search.trialCreated(ctx, otherID)
+		assert.NilError(t, err)
+		_, err = search.validationCompleted(ctx, otherID, Validate{}, ValidationMetrics{
+			Metrics: map[string]interface{}{"loss_a": 1.0, defaultMetric: 0.0},
+		})
+		assert.ErrorContains(t, err, "[loss_b] missing")
+	}
+}
+
+func TestASHASearcherMetricTransform(t *testing.T) {
+	// The logarithm of the negative metrics is not finite, so those trials rank last under it.
+	metrics := []float64{-0.5, -2, 4, 8}
+	for transform, best := range map[model.MetricTransform]int{
+		"":                        1,
+		model.IdentityTransform:   1,
+		model.NegateTransform:     3,
+		model.ReciprocalTransform: 0,
+		model.LogTransform:        2,
+	} {
+		config := model.AsyncHalvingConfig{
+			Metric:              defaultMetric,
+			SmallerIsBetter:     true,
+			NumRungs:            2,
+			MaxLength:           model.NewLengthInBatches(400),
+			Divisor:             4,
+			MaxTrials:           len(metrics),
+			MaxConcurrentTrials: len(metrics),
+			MetricTransform:     transform,
+		}
+		search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+		driver, err := newQueueDriver(search, nil, func(trialIndex, _ int) float64 {
+			return metrics[trialIndex]
+		})
+		assert.NilError(t, err)
+		for len(driver.pending) > 0 {
+			_, err = driver.step()
+			assert.NilError(t, err)
+		}
+
+		var promoted []int
+		for requestID, rung := range search.TrialRungs {
+			if rung == 1 {
+				promoted = append(promoted, driver.trialIndex[requestID])
+			}
+		}
+		assert.DeepEqual(t, promoted, []int{best})
+	}
+}
+
+func TestASHASearcherEarlyExitNeverPromoted(t *testing.T) {
+	for _, smallerIsBetter := range []bool{true, false} {
+		config := model.AsyncHalvingConfig{
+			Metric:              defaultMetric,
+			SmallerIsBetter:     smallerIsBetter,
+			NumRungs:            2,
+			MaxLength:           model.NewLengthInBatches(900),
+			Divisor:             3,
+			MaxTrials:           3,
+			MaxConcurrentTrials: 3,
+		}
+		// The trials that do report use the worst finite metric for the search direction.
+		worst := 1e300
+		if !smallerIsBetter {
+			worst = -1e300
+		}
+
+		method := newAsyncHalvingSearch(config)
+		ctx := context{rand: nprand.New(0)}
+		ops, err := method.initialOperations(ctx)
+		assert.NilError(t, err)
+		var creates []Create
+		for _, op := range ops {
+			if create, ok := op.(Create); ok {
+				creates = append(creates, create)
+				_, err = method.trialCreated(ctx, create.RequestID)
+				assert.NilError(t, err)
+			}
+		}
+		assert.Equal(t, len(creates), 3)
+
+		exited := creates[0].RequestID
+		_, err = method.trialExitedEarly(ctx, exited, Errored)
+		assert.NilError(t, err)
+
+		var promoted []Operation
+		for _, create := range creates[1:] {
+			metrics := ValidationMetrics{Metrics: map[string]interface{}{defaultMetric: worst}}
+			ops, err = method.validationCompleted(
+				ctx, create.RequestID, NewValidate(create.RequestID), metrics)
+			assert.NilError(t, err)
+			promoted = append(promoted, ops...)
+		}
+
+		trains := 0
+		for _, op := range promoted {
+			if train, ok := op.(Train); ok {
+				assert.Assert(t, train.RequestID != exited, "early-exited trial was promoted")
+				trains++
+			}
+		}
+		assert.Equal(t, trains, 1)
+	}
+}
+
+func TestASHASearcherTieBreaking(t *testing.T) {
+	requestIDs := []RequestID{
+		MustParse("00000000-0000-0000-0000-000000000001"),
+		MustParse("00000000-0000-0000-0000-000000000002"),
+		MustParse("00000000-0000-0000-0000-000000000003"),
+	}
+	orders := [][]int{{0, 1, 2}, {0, 2, 1}, {1, 0, 2}, {1, 2, 0}, {2, 0, 1}, {2, 1, 0}}
+	for _, order := range orders {
+		r := &rung{}
+		var promoted []RequestID
+		for _, i := range order {
+			promotions, _, _ := r.promotionsAsync(
+				scalarComparator{}, requestIDs[i], TrialMetricValue{Metric: 0.5}, 3, 0)
+			promoted = append(promoted, promotions...)
+		}
+		assert.DeepEqual(t, promoted, []RequestID{requestIDs[0]})
+		for i, trialMetric := range r.Metrics {
+			assert.Equal(t, trialMetric.RequestID, requestIDs[i])
+		}
+	}
+}
+
+func TestRungPromoteTies(t *testing.T) {
+	requestID := func(i int) RequestID {
+		return MustParse(fmt.Sprintf("00000000-0000-0000-0000-%012d", i))
+	}
+	type report struct {
+		id     int
+		metric float64
+		// promoted and promotedWithTies are the trials promoted on the report without and with
+		// PromoteTies.
+		promoted         []int
+		promotedWithTies []int
+	}
+	for _, c := range []struct {
+		name    string
+		reports []report
+	}{
+		{
+			name: "tie ranked after the reporting trial",
+			reports: []report{
+				{3, 0.2, nil, nil},
+				{2, 0.5, nil, nil},
+				// numPromote becomes 1 and the trial ranks first, ahead of 3 by request ID.
+				{1, 0.2, []int{1}, []int{1, 3}},
+				{4, 0.9, nil, nil},
+				{5, 0.9, nil, nil},
+				// numPromote becomes 2, bringing 3 inside the cutoff; it was promoted for the tie.
+				{6, 0.9, []int{3}, nil},
+			},
+		},
+		{
+			name: "reporting trial ranked after the tie",
+			reports: []report{
+				{1, 0.2, nil, nil},
+				{2, 0.5, nil, nil},
+				// numPromote becomes 1, which promotes 1, and 3 ties with it.
+				{3, 0.2, []int{1}, []int{1, 3}},
+			},
+		},
+		{
+			name: "no tie at the cutoff",
+			reports: []report{
+				{1, 0.2, nil, nil},
+				{2, 0.5, nil, nil},
+				{3, 0.3, []int{1}, []int{1}},
+				{4, 0.5, nil, nil},
+				{5, 0.6, nil, nil},
+				// numPromote becomes 2 with 3 at the cutoff; 2 and 4 tie with each other but not it.
+				{6, 0.7, []int{3}, []int{3}},
+			},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			plain, withTies := &rung{}, &rung{}
+			for _, report := range c.reports {
+				value := TrialMetricValue{Metric: report.metric}
+				promotions, _, _ := plain.promotionsAsync(
+					scalarComparator{}, requestID(report.id), value, 3, 0)
+				var expected []RequestID
+				for _, id := range report.promoted {
+					expected = append(expected, requestID(id))
+				}
+				assert.DeepEqual(t, promotions, expected)
+
+				promotions, _, _ = withTies.promotionsAsync(
+					scalarComparator{}, requestID(report.id), value, 3, 0)
+				promotions = append(promotions, withTies.promoteTies(scalarComparator{}, 3, 0)...)
+				expected = nil
+				for _, id := range report.promotedWithTies {
+					expected = append(expected, requestID(id))
+				}
+				assert.DeepEqual(t, promotions, expected)
+			}
+		})
+	}
+
+	// Trials that exited early all rank equal, but are not promoted for it.
+	r := &rung{}
+	for i := 1; i <= 3; i++ {
+		r.insertMetric(scalarComparator{}, requestID(i), TrialMetricValue{Metric: ashaExitedMetricValue})
+	}
+	assert.Equal(t, len(r.promoteTies(scalarComparator{}, 3, 0)), 0)
+}
+
+func TestASHASearcherPromoteTies(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           3,
+		MaxConcurrentTrials: 3,
+	}
+	topRungTrials := func(config model.AsyncHalvingConfig) int {
+		search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+		driver, err := newQueueDriver(search, nil, func(int, int) float64 { return 0.5 })
+		assert.NilError(t, err)
+		for len(driver.pending) > 0 {
+			_, err = driver.step()
+			assert.NilError(t, err)
+		}
+		return len(search.Rungs[1].Metrics)
+	}
+	// With every trial reporting the same metric, only the one ordered first makes the cutoff,
+	// unless ties are promoted.
+	assert.Equal(t, topRungTrials(config), 1)
+	config.PromoteTies = true
+	assert.Equal(t, topRungTrials(config), 3)
+}
+
+func TestRungPromotionSkipReasons(t *testing.T) {
+	type report struct {
+		metric   float64
+		promoted int
+		skipped  int
+		reason   promotionSkipReason
+	}
+	for _, c := range []struct {
+		name      string
+		divisor   float64
+		minTrials int
+		reports   []report
+	}{
+		{
+			name:    "promotions as trials report",
+			divisor: 2,
+			reports: []report{
+				{0.5, 0, 0, skippedNotGoodEnough},
+				{0.4, 1, 0, notSkipped},
+				{0.3, 1, 0, notSkipped},
+				// The trial with 0.4 is now due for promotion again, but it was promoted.
+				{0.9, 0, 1, skippedAlreadyPromoted},
+				{1.0, 0, 0, skippedNotGoodEnough},
+			},
+		},
+		{
+			name:      "waiting for min trials",
+			divisor:   2,
+			minTrials: 3,
+			reports: []report{
+				{0.1, 0, 0, skippedBelowMinTrials},
+				{0.2, 0, 0, skippedBelowMinTrials},
+				{0.3, 1, 0, notSkipped},
+			},
+		},
+		{
+			name:      "none due at min trials",
+			divisor:   3,
+			minTrials: 2,
+			reports: []report{
+				{0.1, 0, 0, skippedBelowMinTrials},
+				{0.2, 0, 0, skippedNotGoodEnough},
+			},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r := &rung{}
+			for i, report := range c.reports {
+				requestID := MustParse(fmt.Sprintf("00000000-0000-0000-0000-%012d", i+1))
+				promotions, skipped, reason := r.promotionsAsync(
+					scalarComparator{}, requestID, TrialMetricValue{Metric: report.metric},
+					c.divisor, c.minTrials)
+				assert.Equal(t, len(promotions), report.promoted, "report %d", i)
+				assert.Equal(t, skipped, report.skipped, "report %d", i)
+				assert.Equal(t, reason, report.reason, "report %d", i)
+			}
+		})
+	}
+}
+
+func TestRungPromoteBatch(t *testing.T) {
+	random := rand.New(rand.NewSource(0))
+	var metrics []trialMetric
+	for i := 0; i < 30; i++ {
+		metrics = append(metrics, trialMetric{
+			RequestID: MustParse(fmt.Sprintf("00000000-0000-0000-0000-%012d", i+1)),
+			Metric:    float64(random.Intn(20)),
+		})
+	}
+	promotedIDs := func(r *rung) map[RequestID]bool {
+		promoted := make(map[RequestID]bool)
+		for _, trialMetric := range r.Metrics {
+			if trialMetric.Promoted {
+				promoted[trialMetric.RequestID] = true
+			}
+		}
+		return promoted
+	}
+	for _, c := range []struct {
+		name      string
+		earlier   int
+		minTrials int
+	}{
+		{name: "empty rung"},
+		{name: "after incremental reports", earlier: 10},
+		{name: "reaching min trials", earlier: 4, minTrials: 12},
+		{name: "below min trials", earlier: 4, minTrials: 40},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			batch, incremental := &rung{}, &rung{}
+			for _, trialMetric := range metrics[:c.earlier] {
+				for _, r := range []*rung{batch, incremental} {
+					r.promotionsAsync(scalarComparator{}, trialMetric.RequestID, trialMetric.value(), 3,
+						c.minTrials)
+				}
+			}
+
+			rest := append([]trialMetric{}, metrics[c.earlier:]...)
+			promotions, _ := batch.promoteBatch(scalarComparator{}, rest, 3, c.minTrials)
+			sort.SliceStable(rest, func(i, j int) bool { return rest[i].Metric < rest[j].Metric })
+			var expected []RequestID
+			for _, trialMetric := range rest {
+				ids, _, _ := incremental.promotionsAsync(
+					scalarComparator{}, trialMetric.RequestID, trialMetric.value(), 3, c.minTrials)
+				expected = append(expected, ids...)
+			}
+			assert.DeepEqual(t, batch.Metrics, incremental.Metrics)
+			sort.Slice(promotions, func(i, j int) bool { return promotions[i].Before(promotions[j]) })
+			sort.Slice(expected, func(i, j int) bool { return expected[i].Before(expected[j]) })
+			assert.DeepEqual(t, promotions, expected)
+
+			// Reporting the metrics one at a time in any other order promotes at least the same trials,
+			// and possibly some that were among the best when they reported but are no longer.
+			unordered := &rung{}
+			for _, trialMetric := range metrics {
+				unordered.promotionsAsync(
+					scalarComparator{}, trialMetric.RequestID, trialMetric.value(), 3, c.minTrials)
+			}
+			unorderedPromoted := promotedIDs(unordered)
+			for requestID := range promotedIDs(batch) {
+				assert.Assert(t, unorderedPromoted[requestID], requestID)
+			}
+
+			// Either path carries on the same from there.
+			for i := 0; i < 5; i++ {
+				requestID := MustParse(fmt.Sprintf("00000000-0000-0000-0001-%012d", i+1))
+				value := TrialMetricValue{Metric: float64(random.Intn(20))}
+				batchIDs, batchSkipped, batchReason := batch.promotionsAsync(
+					scalarComparator{}, requestID, value, 3, c.minTrials)
+				ids, skipped, reason := incremental.promotionsAsync(
+					scalarComparator{}, requestID, value, 3, c.minTrials)
+				assert.DeepEqual(t, batchIDs, ids)
+				assert.Equal(t, batchSkipped, skipped)
+				assert.Equal(t, batchReason, reason)
+			}
+		})
+	}
+}
+
+func TestASHASearcherBestTrials(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           3,
+		MaxConcurrentTrials: 3,
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+		assert.Equal(t, len(search.BestTrials(3)), 0)
+	})
+
+	t.Run("single rung", func(t *testing.T) {
+		singleRung := config
+		singleRung.NumRungs = 1
+		singleRung.SmallerIsBetter = true
+		search := newAsyncHalvingSearch(singleRung).(*asyncHalvingSearch)
+		driver, err := newQueueDriver(search, nil, func(trialIndex, _ int) float64 {
+			return float64(trialIndex)
+		})
+		assert.NilError(t, err)
+		for len(driver.pending) > 0 {
+			_, err = driver.step()
+			assert.NilError(t, err)
+		}
+		best := search.BestTrials(2)
+		assert.Equal(t, len(best), 2)
+		for i, summary := range best {
+			assert.Equal(t, summary.Rung, 0)
+			assert.Equal(t, summary.Metric, float64(i))
+			assert.Assert(t, !summary.Promoted)
+		}
+	})
+
+	for _, smallerIsBetter := range []bool{true, false} {
+		config.SmallerIsBetter = smallerIsBetter
+		sign := 1.0
+		if !smallerIsBetter {
+			sign = -1.0
+		}
+		search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+		driver, err := newQueueDriver(search, nil, func(trialIndex, _ int) float64 {
+			return sign * float64(trialIndex)
+		})
+		assert.NilError(t, err)
+
+		// Run until every trial has reported in the bottom rung and the best one was promoted.
+		for len(search.Rungs[0].Metrics) < config.MaxTrials {
+			_, err = driver.step()
+			assert.NilError(t, err)
+		}
+		best := search.BestTrials(2)
+		assert.Equal(t, len(best), 2)
+		assert.Equal(t, best[0].Rung, 1)
+		assert.Equal(t, best[0].Metric, 0.0)
+		assert.Assert(t, best[0].Promoted)
+		assert.Equal(t, best[1].Metric, sign)
+		assert.Assert(t, !best[1].Promoted)
+
+		assert.Equal(t, best[1].Rung, 0)
+
+		// Running the search to completion does not change the ranking.
+		for len(driver.pending) > 0 {
+			_, err = driver.step()
+			assert.NilError(t, err)
+		}
+		best = search.BestTrials(5)
+		assert.Equal(t, len(best), config.MaxTrials)
+		assert.Equal(t, best[0].Rung, 1)
+		assert.Equal(t, best[0].Metric, 0.0)
+		for i, summary := range best[1:] {
+			assert.Equal(t, summary.Rung, 0)
+			assert.Equal(t, summary.Metric, sign*float64(i+1))
+			assert.Assert(t, !summary.EarlyExited)
+		}
+	}
+}
+
+func TestASHASearcherMaxTime(t *testing.T) {
+	maxTime := model.Duration(time.Hour)
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           27,
+		MaxConcurrentTrials: 3,
+		MaxTime:             &maxTime,
+	}
+	method := &recordingMethod{SearchMethod: newAsyncHalvingSearch(config)}
+	driver, err := newQueueDriver(method, nil, func(trialIndex, _ int) float64 {
+		return float64(trialIndex)
+	})
+	assert.NilError(t, err)
+	// The search started when the driver requested its initial operations.
+	now := method.SearchMethod.(*asyncHalvingSearch).StartTime
+	driver.ctx.clock = func() time.Time { return now }
+
+	for i := 0; i < 30; i++ {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+	now = now.Add(2 * time.Hour)
+	created := len(driver.trialIndex)
+	assert.Assert(t, created < config.MaxTrials)
+
+	for len(driver.pending) > 0 {
+		ops, stepErr := driver.step()
+		assert.NilError(t, stepErr)
+		for _, op := range ops {
+			_, isCreate := op.(Create)
+			assert.Assert(t, !isCreate, "trial created after the time budget was exceeded")
+			_, isTrain := op.(Train)
+			assert.Assert(t, !isTrain, "trial promoted after the time budget was exceeded")
+		}
+	}
+
+	// Every trial, including those created but not yet started at the cutoff, is closed once.
+	closes := method.closeCounts()
+	assert.Equal(t, len(closes), len(driver.trialIndex))
+	for _, count := range closes {
+		assert.Equal(t, count, 1)
+	}
+}
+
+func TestASHASearcherProgress(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           27,
+		MaxConcurrentTrials: 5,
+	}
+	method := newAsyncHalvingSearch(config)
+	driver, err := newQueueDriver(method, nil, func(trialIndex, _ int) float64 {
+		return float64(trialIndex % 7)
+	})
+	assert.NilError(t, err)
+
+	unitsCompleted := model.NewLengthInBatches(0)
+	last := method.progress(unitsCompleted)
+	for len(driver.pending) > 0 {
+		if train, ok := driver.pending[0].(Train); ok {
+			unitsCompleted = unitsCompleted.Add(train.Length)
+		}
+		_, err = driver.step()
+		assert.NilError(t, err)
+		progress := method.progress(unitsCompleted)
+		assert.Assert(t, progress >= 0 && progress <= 1, "progress out of range: %f", progress)
+		assert.Assert(t, progress >= last, "progress decreased from %f to %f", last, progress)
+		last = progress
+	}
+	assert.Equal(t, last, 1.0)
+}
+
+func TestASHASearcherProgressStart(t *testing.T) {
+	// With every trial outstanding in the bottom rung at once, the progress starts at zero and only
+	// grows with the training that trials have reported.
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           27,
+		MaxConcurrentTrials: 27,
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	driver, err := newQueueDriver(search, nil, func(trialIndex, _ int) float64 {
+		return float64(trialIndex % 7)
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, search.progress(model.Length{}), 0.0)
+	for len(search.Rungs[0].Metrics) == 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+		progress := search.progress(model.Length{})
+		assert.Assert(t, progress >= 0, "negative progress: %f", progress)
+	}
+
+	// One trial has trained for 100 of the 27*100 + 9*200 + 3*600 batches the search expects.
+	assert.Equal(t, search.progress(model.Length{}), 100.0/6300)
+}
+
+func TestASHASearcherProgressDetail(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           27,
+		MaxConcurrentTrials: 5,
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	driver, err := newQueueDriver(search, nil, func(trialIndex, _ int) float64 {
+		return float64(trialIndex % 7)
+	})
+	assert.NilError(t, err)
+
+	// Keep track of the expected counts from the operations alone.
+	created := len(driver.pending) / 3
+	completed := 0
+	rungs := make([]RungProgress, config.NumRungs)
+	for i, units := range []int{100, 300, 900} {
+		rungs[i].UnitsNeeded = model.NewLengthInBatches(units)
+	}
+	for steps := 0; len(driver.pending) > 0; steps++ {
+		switch op := driver.pending[0].(type) {
+		case Create:
+			rungs[0].Outstanding++
+		case Validate:
+			rung := search.TrialRungs[op.RequestID]
+			rungs[rung].Outstanding--
+			rungs[rung].Completed++
+		case Close:
+			completed++
+		}
+		ops, stepErr := driver.step()
+		assert.NilError(t, stepErr)
+		for _, op := range ops {
+			switch op := op.(type) {
+			case Create:
+				created++
+			case Train:
+				// Trains for trials beyond the bottom rung are promotions.
+				if rung := search.TrialRungs[op.RequestID]; rung > 0 {
+					rungs[rung-1].Promoted++
+					rungs[rung].Outstanding++
+				}
+			}
+		}
+
+		if steps%10 == 0 || len(driver.pending) == 0 {
+			assert.DeepEqual(t, search.ProgressDetail(), SearchProgress{
+				Rungs:           rungs,
+				MaxTrials:       config.MaxTrials,
+				TrialsCreated:   created,
+				TrialsCompleted: completed,
+			})
+		}
+	}
+	assert.Equal(t, search.ProgressDetail().Rungs[0].Completed, config.MaxTrials)
+	assert.Equal(t, search.progress(model.NewLengthInBatches(0)), 1.0)
+}
+
+func TestASHASearcherSetMaxConcurrency(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           9,
+		MaxConcurrentTrials: 3,
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	driver, err := newQueueDriver(search, nil, func(trialIndex, _ int) float64 {
+		return float64(trialIndex)
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(driver.pending), 9)
+
+	// Trials with outstanding work are those with a pending train or validation.
+	outstanding := func() int {
+		trials := make(map[RequestID]bool)
+		for _, op := range driver.pending {
+			switch op := op.(type) {
+			case Train:
+				trials[op.RequestID] = true
+			case Validate:
+				trials[op.RequestID] = true
+			}
+		}
+		return len(trials)
+	}
+
+	search.SetMaxConcurrency(1)
+	for len(driver.pending) > 0 && len(driver.trialIndex) < 6 {
+		before := outstanding()
+		ops, stepErr := driver.step()
+		assert.NilError(t, stepErr)
+		for _, op := range ops {
+			if _, ok := op.(Create); ok {
+				// New trials are only created once no other trial has outstanding work.
+				assert.Equal(t, before, 1)
+				assert.Equal(t, outstanding(), 1)
+			}
+		}
+	}
+	assert.Equal(t, len(driver.trialIndex), 6)
+
+	// Raising the limit backfills new trials at the next report.
+	search.SetMaxConcurrency(3)
+	for outstanding() < 3 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+		assert.Assert(t, outstanding() <= 3)
+	}
+	assert.Equal(t, len(driver.trialIndex), config.MaxTrials)
+}
+
+func TestASHASearcherExtendMaxTrials(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           3,
+		MaxConcurrentTrials: 3,
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	method := &recordingMethod{SearchMethod: search}
+	driver, err := newQueueDriver(method, nil, func(trialIndex, _ int) float64 {
+		return float64(trialIndex)
+	})
+	assert.NilError(t, err)
+
+	// Run until the bottom rung has been closed out while the promoted trial is still training.
+	for len(method.closeCounts()) == 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+	assert.Equal(t, len(method.closeCounts()), 2)
+	assert.Assert(t, len(driver.pending) > 0)
+
+	assert.ErrorContains(t, search.ExtendMaxTrials(2), "below the 3 trials already created")
+	assert.NilError(t, search.ExtendMaxTrials(6))
+	closed := method.closeCounts()
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+	assert.Equal(t, len(driver.trialIndex), 6)
+	assert.Equal(t, len(search.Rungs[0].Metrics), 6)
+	// Every trial is closed exactly once, and trials that were closed out are never trained again.
+	for requestID, count := range method.closeCounts() {
+		assert.Equal(t, count, 1)
+		if closed[requestID] > 0 {
+			assert.Equal(t, search.TrialRungs[requestID], 0)
+		}
+	}
+	assert.Equal(t, len(method.closeCounts()), 6)
+	assert.Equal(t, search.progress(model.NewLengthInBatches(0)), 1.0)
+
+	// The extended target survives a restore.
+	snapshot, err := search.Snapshot()
+	assert.NilError(t, err)
+	restored := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	assert.NilError(t, restored.Restore(snapshot))
+	assert.Equal(t, restored.maxTrials, 6)
+}
+
+func TestASHASearcherCancelStragglers(t *testing.T) {
+	// run drives a search, holding back the training and validations that stalled selects, until
+	// only those are left. It returns the indexes of the trials closed by then, along with a function
+	// that finishes the search and returns the number of times each trial was closed.
+	run := func(
+		config model.AsyncHalvingConfig, stalled func(trialIndex, rung int) bool,
+	) (map[int]bool, func() map[RequestID]int) {
+		search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+		method := &recordingMethod{SearchMethod: search}
+		driver, err := newQueueDriver(method, nil, func(trialIndex, _ int) float64 {
+			return float64(trialIndex)
+		})
+		assert.NilError(t, err)
+		var held []Operation
+		for len(driver.pending) > 0 {
+			var requestID *RequestID
+			switch op := driver.pending[0].(type) {
+			case Train:
+				requestID = &op.RequestID
+			case Validate:
+				requestID = &op.RequestID
+			}
+			if requestID != nil &&
+				stalled(driver.trialIndex[*requestID], search.TrialRungs[*requestID]) {
+				held = append(held, driver.pending[0])
+				driver.pending = driver.pending[1:]
+				continue
+			}
+			_, err = driver.step()
+			assert.NilError(t, err)
+		}
+		closed := make(map[int]bool)
+		for requestID := range method.closeCounts() {
+			closed[driver.trialIndex[requestID]] = true
+		}
+		return closed, func() map[RequestID]int {
+			driver.pending = held
+			for len(driver.pending) > 0 {
+				_, err = driver.step()
+				assert.NilError(t, err)
+			}
+			return method.closeCounts()
+		}
+	}
+	threshold := func(fraction float64) *float64 { return &fraction }
+
+	// The best trial stalls after it is promoted to the middle rung, from which nothing can be
+	// promoted with only four trials in the search.
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           4,
+		MaxConcurrentTrials: 4,
+	}
+	stallBest := func(trialIndex, rung int) bool { return trialIndex == 0 && rung == 1 }
+	closed, finish := run(config, stallBest)
+	assert.DeepEqual(t, closed, map[int]bool{1: true, 2: true, 3: true})
+	assert.Equal(t, len(finish()), config.MaxTrials)
+
+	config.CancelStragglers = threshold(0.5)
+	closed, finish = run(config, stallBest)
+	assert.DeepEqual(t, closed, map[int]bool{0: true, 1: true, 2: true, 3: true})
+	// The stalled trial's validation arriving after it was cancelled is ignored.
+	for _, count := range finish() {
+		assert.Equal(t, count, 1)
+	}
+
+	// The last trial stalls in the bottom rung, where it could still be promoted, so it is never
+	// cancelled; only the trials it cannot keep from being promoted are.
+	config = model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           9,
+		MaxConcurrentTrials: 9,
+	}
+	stallLast := func(trialIndex, _ int) bool { return trialIndex == 8 }
+	closed, finish = run(config, stallLast)
+	assert.DeepEqual(t, closed, map[int]bool{0: true, 1: true})
+	assert.Equal(t, len(finish()), config.MaxTrials)
+
+	config.CancelStragglers = threshold(0.2)
+	closed, finish = run(config, stallLast)
+	assert.DeepEqual(t, closed, map[int]bool{
+		0: true, 1: true, 3: true, 4: true, 5: true, 6: true, 7: true,
+	})
+	closeCounts := finish()
+	assert.Equal(t, len(closeCounts), config.MaxTrials)
+	for _, count := range closeCounts {
+		assert.Equal(t, count, 1)
+	}
+}
+
+func TestASHASearcherPromotionGraph(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           27,
+		MaxConcurrentTrials: 5,
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	method := &recordingMethod{SearchMethod: search}
+	driver, err := newQueueDriver(method, nil, func(trialIndex, _ int) float64 {
+		return float64(trialIndex % 7)
+	})
+	assert.NilError(t, err)
+
+	promotions := 0
+	verify := func() {
+		edges := search.PromotionGraph()
+		assert.Equal(t, len(edges), promotions)
+		incoming := make(map[RequestID][]int)
+		for _, edge := range edges {
+			assert.Equal(t, edge.ToRung, edge.FromRung+1)
+			assert.Assert(t, edge.Metric != nil)
+			assert.Equal(t, *edge.Metric, float64(driver.trialIndex[edge.RequestID]%7))
+			incoming[edge.RequestID] = append(incoming[edge.RequestID], edge.ToRung)
+		}
+		// Every promoted trial has exactly one incoming edge into each rung it has reached.
+		for requestID, rung := range search.TrialRungs {
+			var expected []int
+			for r := 1; r <= rung; r++ {
+				expected = append(expected, r)
+			}
+			assert.DeepEqual(t, incoming[requestID], expected)
+		}
+	}
+	for steps := 0; len(driver.pending) > 0; steps++ {
+		ops, stepErr := driver.step()
+		assert.NilError(t, stepErr)
+		for _, op := range ops {
+			// Trains for trials beyond the bottom rung are promotions.
+			if train, ok := op.(Train); ok && search.TrialRungs[train.RequestID] > 0 {
+				promotions++
+			}
+		}
+		if steps%10 == 0 {
+			verify()
+		}
+	}
+	verify()
+	assert.Assert(t, promotions >= 9+3)
+}
+
+func TestASHASearcherMinTrialsPerRung(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           9,
+		MaxConcurrentTrials: 9,
+		MinTrialsPerRung:    6,
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	driver, err := newQueueDriver(search, nil, func(trialIndex, _ int) float64 {
+		return float64(trialIndex)
+	})
+	assert.NilError(t, err)
+
+	// Without the minimum, the best trial would be promoted after the third report and the second
+	// best after the sixth; instead, both are promoted together after the sixth.
+	expected := map[int][]int{1: nil, 3: nil, 5: nil, 6: {0, 1}, 8: {0, 1}, 9: {0, 1, 2}}
+	for len(search.Rungs[0].Metrics) < config.MaxTrials {
+		_, err = driver.step()
+		assert.NilError(t, err)
+		if promoted, ok := expected[len(search.Rungs[0].Metrics)]; ok {
+			var actual []int
+			for requestID, rung := range search.TrialRungs {
+				if rung == 1 {
+					actual = append(actual, driver.trialIndex[requestID])
+				}
+			}
+			sort.Ints(actual)
+			assert.DeepEqual(t, actual, promoted)
+		}
+	}
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+	assert.Equal(t, len(search.Rungs[1].Metrics), 3)
+}
+
+func TestASHASearcherCloseOrder(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           27,
+		MaxConcurrentTrials: 9,
+	}
+	// run returns the trials closed by each operation that closed any, in order.
+	run := func() [][]RequestID {
+		search := newAsyncHalvingSearch(config)
+		driver, err := newQueueDriver(search, nil, func(trialIndex, _ int) float64 {
+			return float64((trialIndex * 5) % 11)
+		})
+		assert.NilError(t, err)
+		var batches [][]RequestID
+		for len(driver.pending) > 0 {
+			ops, stepErr := driver.step()
+			assert.NilError(t, stepErr)
+			var batch []RequestID
+			for _, op := range ops {
+				if op, ok := op.(Close); ok {
+					batch = append(batch, op.RequestID)
+				}
+			}
+			if len(batch) > 0 {
+				batches = append(batches, batch)
+			}
+		}
+		return batches
+	}
+
+	batches := run()
+	assert.DeepEqual(t, run(), batches)
+	closed := make(map[RequestID]bool)
+	for _, batch := range batches {
+		// Trials closed together are closed in ascending request ID order.
+		assert.Assert(t, sort.SliceIsSorted(batch, func(i, j int) bool {
+			return batch[i].Before(batch[j])
+		}))
+		for _, requestID := range batch {
+			assert.Assert(t, !closed[requestID], "trial %s closed twice", requestID)
+			closed[requestID] = true
+		}
+	}
+	assert.Equal(t, len(closed), config.MaxTrials)
+}
+
+func TestASHASearcherRemainingResource(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           27,
+		MaxConcurrentTrials: 5,
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	driver, err := newQueueDriver(search, nil, func(trialIndex, _ int) float64 {
+		return float64(trialIndex % 7)
+	})
+	assert.NilError(t, err)
+
+	// Of the 27 trials, 9 are promoted to the middle rung and 3 to the top one, and each trains for
+	// 100, 200 more, and 600 more batches in the rungs it reaches.
+	last := search.RemainingResource()
+	assert.Equal(t, last, 27*100+9*200+3*600)
+	trained := 0
+	for len(driver.pending) > 0 {
+		if train, ok := driver.pending[0].(Train); ok {
+			trained += train.Length.Units
+		}
+		_, err = driver.step()
+		assert.NilError(t, err)
+		remaining := search.RemainingResource()
+		assert.Assert(t, remaining <= last, "estimate increased from %d to %d", last, remaining)
+		last = remaining
+	}
+	assert.Equal(t, last, 0)
+	assert.Equal(t, trained, 27*100+9*200+3*600)
+}
+
+func TestASHASearcherSmoothing(t *testing.T) {
+	// Trial 0 improves steadily, while trial 1 is poor in the bottom rung and then reports a lucky
+	// spike in the middle one. Trials 2 and 3 are poor throughout, so both of the first two trials
+	// reach the middle rung, where only the best of the two is promoted to the top.
+	metrics := [][]float64{{0.5, 0.4, 0.3}, {1.0, 0.1, 1.0}, {2, 2, 2}, {2, 2, 2}}
+	// promoted returns the trial that reaches the top rung.
+	promoted := func(smallerIsBetter bool, smoothingFactor *float64) int {
+		config := model.AsyncHalvingConfig{
+			Metric:              defaultMetric,
+			SmallerIsBetter:     smallerIsBetter,
+			NumRungs:            3,
+			MaxLength:           model.NewLengthInBatches(400),
+			Divisor:             2,
+			MaxTrials:           4,
+			MaxConcurrentTrials: 4,
+			MinTrialsPerRung:    2,
+			SmoothingFactor:     smoothingFactor,
+		}
+		search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+		driver, err := newQueueDriver(search, nil, func(trialIndex, validations int) float64 {
+			if !smallerIsBetter {
+				return -metrics[trialIndex][validations]
+			}
+			return metrics[trialIndex][validations]
+		})
+		assert.NilError(t, err)
+		for len(driver.pending) > 0 {
+			_, err = driver.step()
+			assert.NilError(t, err)
+		}
+		assert.Equal(t, len(search.Rungs[2].Metrics), 1)
+		return driver.trialIndex[search.Rungs[2].Metrics[0].RequestID]
+	}
+
+	factor := 0.5
+	for _, smallerIsBetter := range []bool{true, false} {
+		// Without smoothing, the spike wins; with it, trial 1 averages 0.55 against trial 0's 0.45.
+		assert.Equal(t, promoted(smallerIsBetter, nil), 1)
+		assert.Equal(t, promoted(smallerIsBetter, &factor), 0)
+	}
+}
+
+func TestASHASearcherPromoteOnBest(t *testing.T) {
+	// Trial 0 peaks in the bottom rung and then degrades, while trial 1 improves steadily. Trials 2
+	// and 3 are poor throughout, so both of the first two trials reach the middle rung, where only
+	// the better of the two is promoted to the top once both have reported.
+	metrics := [][]float64{{0.1, 0.9, 0.9}, {0.6, 0.4, 0.3}, {2, 2, 2}, {2, 2, 2}}
+	// promoted returns the trial that reaches the top rung.
+	promoted := func(smallerIsBetter, promoteOnBest bool) int {
+		config := model.AsyncHalvingConfig{
+			Metric:              defaultMetric,
+			SmallerIsBetter:     smallerIsBetter,
+			NumRungs:            3,
+			MaxLength:           model.NewLengthInBatches(400),
+			Divisor:             2,
+			MaxTrials:           4,
+			MaxConcurrentTrials: 4,
+			MinTrialsPerRung:    2,
+			PromoteOnBest:       promoteOnBest,
+		}
+		search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+		driver, err := newQueueDriver(search, nil, func(trialIndex, validations int) float64 {
+			if !smallerIsBetter {
+				return -metrics[trialIndex][validations]
+			}
+			return metrics[trialIndex][validations]
+		})
+		assert.NilError(t, err)
+		for len(driver.pending) > 0 {
+			_, err = driver.step()
+			assert.NilError(t, err)
+		}
+		assert.Equal(t, len(search.Rungs[2].Metrics), 1)
+		return driver.trialIndex[search.Rungs[2].Metrics[0].RequestID]
+	}
+
+	for _, smallerIsBetter := range []bool{true, false} {
+		// In the middle rung, trial 0 loses on its latest metric of 0.9 but wins on its best of 0.1.
+		assert.Equal(t, promoted(smallerIsBetter, false), 1)
+		assert.Equal(t, promoted(smallerIsBetter, true), 0)
+	}
+}
+
+func TestASHASearcherMaxPromotionRung(t *testing.T) {
+	for _, maxRung := range []int{0, 1, 2} {
+		maxRung := maxRung
+		config := model.AsyncHalvingConfig{
+			Metric:              defaultMetric,
+			SmallerIsBetter:     true,
+			NumRungs:            4,
+			MaxLength:           model.NewLengthInBatches(2700),
+			Divisor:             3,
+			MaxTrials:           27,
+			MaxConcurrentTrials: 5,
+			MaxPromotionRung:    &maxRung,
+		}
+		search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+		method := &recordingMethod{SearchMethod: search}
+		driver, err := newQueueDriver(method, nil, func(trialIndex, _ int) float64 {
+			return float64((trialIndex * 5) % 11)
+		})
+		assert.NilError(t, err)
+		for len(driver.pending) > 0 {
+			_, err = driver.step()
+			assert.NilError(t, err)
+		}
+
+		for requestID, rung := range search.TrialRungs {
+			assert.Assert(t, rung <= maxRung, "trial %s reached rung %d", requestID, rung)
+		}
+		assert.Assert(t, len(search.Rungs[maxRung].Metrics) > 0)
+		for _, rung := range search.Rungs[maxRung+1:] {
+			assert.Equal(t, len(rung.Metrics), 0)
+		}
+		// Every trial is still closed exactly once, including those in the capped rung.
+		closeCounts := method.closeCounts()
+		assert.Equal(t, len(closeCounts), config.MaxTrials)
+		for _, count := range closeCounts {
+			assert.Equal(t, count, 1)
+		}
+		assert.Equal(t, search.RemainingResource(), 0)
+	}
+}
+
+// countingSampler is a batch sampler that records the counts it is asked for and samples each
+// point independently.
+type countingSampler struct {
+	counts []int
+}
+
+func (c *countingSampler) sampleBatch(ctx context, count int) ([]hparamSample, error) {
+	c.counts = append(c.counts, count)
+	var samples []hparamSample
+	for i := 0; i < count; i++ {
+		sample, err := sampleAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		sample["batch"] = true
+		samples = append(samples, sample)
+	}
+	return samples, nil
+}
+
+func TestASHASearcherBatchSampler(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           27,
+		MaxConcurrentTrials: 6,
+		WarmStart:           []map[string]interface{}{{"warm": true}, {"warm": true}},
+		InitialDesign:       model.SobolDesign,
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	assert.Equal(t, search.sampler, batchSampler(sobolSampler{}))
+	sampler := &countingSampler{}
+	search.sampler = sampler
+	driver, err := newQueueDriver(search, nil, func(trialIndex, _ int) float64 {
+		return float64(trialIndex % 7)
+	})
+	assert.NilError(t, err)
+
+	// The sampler is asked once for the initial trials that the warm start does not cover, and the
+	// trials use its samples after the warm start ones.
+	assert.DeepEqual(t, sampler.counts, []int{4})
+	var creates []Create
+	for _, op := range driver.pending {
+		if create, ok := op.(Create); ok {
+			creates = append(creates, create)
+		}
+	}
+	assert.Equal(t, len(creates), 6)
+	for i, create := range creates {
+		_, warm := create.Hparams["warm"]
+		_, batch := create.Hparams["batch"]
+		assert.Equal(t, warm, i < 2)
+		assert.Equal(t, batch, i >= 2)
+	}
+
+	// Later trials are sampled independently.
+	for len(driver.pending) > 0 {
+		ops, stepErr := driver.step()
+		assert.NilError(t, stepErr)
+		for _, op := range ops {
+			if create, ok := op.(Create); ok {
+				_, batch := create.Hparams["batch"]
+				assert.Assert(t, !batch)
+			}
+		}
+	}
+	assert.DeepEqual(t, sampler.counts, []int{4})
+}
+
+func TestASHASearcherTrialValidationTimeout(t *testing.T) {
+	timeout := model.Duration(time.Hour)
+	config := model.AsyncHalvingConfig{
+		Metric:                 defaultMetric,
+		SmallerIsBetter:        true,
+		NumRungs:               2,
+		MaxLength:              model.NewLengthInBatches(900),
+		Divisor:                3,
+		MaxTrials:              9,
+		MaxConcurrentTrials:    3,
+		TrialValidationTimeout: &timeout,
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	method := &recordingMethod{SearchMethod: search}
+	driver, err := newQueueDriver(method, nil, func(trialIndex, _ int) float64 {
+		return float64(trialIndex)
+	})
+	assert.NilError(t, err)
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	driver.ctx.clock = func() time.Time { return now }
+
+	// The second trial hangs: it never completes any of its work.
+	var hung RequestID
+	drain := func() {
+		for len(driver.pending) > 0 {
+			switch op := driver.pending[0].(type) {
+			case Train, Validate:
+				if driver.trialIndex[op.(Requested).GetRequestID()] == 1 {
+					hung = op.(Requested).GetRequestID()
+					driver.pending = driver.pending[1:]
+					continue
+				}
+			}
+			_, stepErr := driver.step()
+			assert.NilError(t, stepErr)
+		}
+	}
+	drain()
+	// Without the timeout, the outstanding trial keeps the bottom rung from being closed out.
+	assert.Equal(t, len(search.Rungs[0].Metrics), config.MaxTrials-1)
+	assert.Assert(t, len(method.closeCounts()) < config.MaxTrials)
+	ops, err := method.record(search.checkTimeouts(driver.ctx))
+	assert.NilError(t, err)
+	assert.Equal(t, len(ops), 0)
+
+	now = now.Add(time.Hour)
+	ops, err = method.record(search.checkTimeouts(driver.ctx))
+	assert.NilError(t, err)
+	assert.Assert(t, len(ops) > 0)
+	assert.DeepEqual(t, ops[0], Operation(NewClose(hung)))
+	driver.pending = append(driver.pending, ops...)
+	drain()
+
+	assert.Equal(t, len(search.Rungs[0].Metrics), config.MaxTrials)
+	closeCounts := method.closeCounts()
+	assert.Equal(t, len(closeCounts), config.MaxTrials)
+	for _, count := range closeCounts {
+		assert.Equal(t, count, 1)
+	}
+	assert.Equal(t, search.progress(model.Length{}), 1.0)
+
+	// A late report from the trial that timed out changes nothing.
+	ops, err = search.trialExitedEarly(driver.ctx, hung, Errored)
+	assert.NilError(t, err)
+	assert.Equal(t, len(ops), 0)
+}
+
+func TestASHASearcherRungMetrics(t *testing.T) {
+	smaller, larger := true, false
+	config := model.AsyncHalvingConfig{
+		Metric:              "real",
+		SmallerIsBetter:     false,
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           9,
+		MaxConcurrentTrials: 9,
+		MinTrialsPerRung:    9,
+		RungMetrics: []model.RungMetric{
+			{Metric: "proxy", SmallerIsBetter: &smaller},
+			{Metric: "real", SmallerIsBetter: &larger},
+		},
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	driver, err := newQueueDriver(search, nil, nil)
+	assert.NilError(t, err)
+	// The proxy is smallest for the first trials, and the real metric is largest for the last ones.
+	driver.metricsFn = func(trialIndex, _ int) map[string]interface{} {
+		return map[string]interface{}{"proxy": float64(trialIndex), "real": float64(trialIndex)}
+	}
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+
+	order := func(rung *rung) []int {
+		var indexes []int
+		for _, trialMetric := range rung.Metrics {
+			indexes = append(indexes, driver.trialIndex[trialMetric.RequestID])
+		}
+		return indexes
+	}
+	assert.DeepEqual(t, order(search.Rungs[0]), []int{0, 1, 2, 3, 4, 5, 6, 7, 8})
+	assert.DeepEqual(t, order(search.Rungs[1]), []int{2, 1, 0})
+
+	best := search.BestTrials(1)
+	assert.Equal(t, len(best), 1)
+	assert.Equal(t, driver.trialIndex[best[0].RequestID], 2)
+	assert.Equal(t, best[0].Metric, 2.0)
+	edges := search.PromotionGraph()
+	assert.Equal(t, len(edges), 3)
+	assert.Equal(t, *edges[0].Metric, 0.0)
+}
+
+func TestASHASearcherCheckpointBeforePromotion(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		config := model.AsyncHalvingConfig{
+			Metric:                    defaultMetric,
+			SmallerIsBetter:           true,
+			NumRungs:                  3,
+			MaxLength:                 model.NewLengthInBatches(900),
+			Divisor:                   3,
+			MaxTrials:                 27,
+			MaxConcurrentTrials:       5,
+			CheckpointBeforePromotion: enabled,
+		}
+		search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+		driver, err := newQueueDriver(search, nil, func(trialIndex, _ int) float64 {
+			return float64(trialIndex % 7)
+		})
+		assert.NilError(t, err)
+
+		promotions, checkpoints := 0, 0
+		for len(driver.pending) > 0 {
+			ops, stepErr := driver.step()
+			assert.NilError(t, stepErr)
+			for i, op := range ops {
+				switch op := op.(type) {
+				case Checkpoint:
+					checkpoints++
+					// The checkpoint is immediately followed by the training for the new rung.
+					assert.Assert(t, i+1 < len(ops))
+					train, ok := ops[i+1].(Train)
+					assert.Assert(t, ok, "checkpoint followed by %v", ops[i+1])
+					assert.Equal(t, train.RequestID, op.RequestID)
+				case Train:
+					if search.TrialRungs[op.RequestID] > 0 {
+						promotions++
+						if enabled {
+							assert.Assert(t, i > 0, "promotion of %s not checkpointed", op.RequestID)
+							_, ok := ops[i-1].(Checkpoint)
+							assert.Assert(t, ok, "promotion of %s not checkpointed", op.RequestID)
+						}
+					}
+				}
+			}
+		}
+		assert.Assert(t, promotions >= 9+3)
+		if enabled {
+			assert.Equal(t, checkpoints, promotions)
+		} else {
+			assert.Equal(t, checkpoints, 0)
+		}
+	}
+}
+
+func TestASHASearcherPromotionStats(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(400),
+		Divisor:             2,
+		MaxTrials:           4,
+		MaxConcurrentTrials: 1,
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	metrics := []float64{1, 0.5, 0.1, 2}
+	driver, err := newQueueDriver(search, nil, func(trialIndex, _ int) float64 {
+		return metrics[trialIndex]
+	})
+	assert.NilError(t, err)
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+	// The second and third trials are promoted as soon as they report. Once the fourth reports, two
+	// trials are due to be promoted, but the second best has been promoted already.
+	assert.DeepEqual(t, search.Stats(), []RungPromotionStats{
+		{Granted: 2, SkippedAlreadyPromoted: 1}, {},
+	})
+}
+
+func TestASHASearcherEarlyExitPromotionStats(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           9,
+		MaxConcurrentTrials: 9,
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	driver, err := newQueueDriver(search, nil, func(trialIndex, _ int) float64 {
+		return float64(trialIndex)
+	})
+	assert.NilError(t, err)
+	// Every trial but the last exits early in the bottom rung. The exited trials all rank as the
+	// worst, so the ones promoted out of the bottom rung besides the last trial are promoted on
+	// ties, and pass on through the middle rung right away.
+	driver.exitFn = func(trialIndex, _ int) bool { return trialIndex < config.MaxTrials-1 }
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+	stats := search.Stats()
+	assert.Equal(t, stats[0].Granted, 1)
+	assert.Assert(t, stats[0].EarlyExitPromotions > 0)
+	for rungIndex, rung := range search.Rungs {
+		granted, exited := 0, 0
+		for _, trialMetric := range rung.Metrics {
+			switch {
+			case !trialMetric.Promoted:
+			case search.EarlyExitTrials[trialMetric.RequestID]:
+				exited++
+			default:
+				granted++
+			}
+		}
+		assert.Equal(t, stats[rungIndex].Granted, granted, "rung %d", rungIndex)
+		assert.Equal(t, stats[rungIndex].EarlyExitPromotions, exited, "rung %d", rungIndex)
+	}
+
+	// The counters survive a snapshot.
+	snapshot, err := search.Snapshot()
+	assert.NilError(t, err)
+	restored := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	assert.NilError(t, restored.Restore(snapshot))
+	assert.DeepEqual(t, restored.Stats(), stats)
+}
+
+func TestASHASearcherTrialExitedEarlyReasons(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(200),
+		Divisor:             2,
+		MaxTrials:           3,
+		MaxConcurrentTrials: 2,
+	}
+	creates := func(ops []Operation) []RequestID {
+		var requestIDs []RequestID
+		for _, op := range ops {
+			if create, ok := op.(Create); ok {
+				requestIDs = append(requestIDs, create.RequestID)
+			}
+		}
+		return requestIDs
+	}
+	rungTrials := func(rung *rung) []RequestID {
+		var requestIDs []RequestID
+		for _, trialMetric := range rung.Metrics {
+			requestIDs = append(requestIDs, trialMetric.RequestID)
+		}
+		return requestIDs
+	}
+
+	for _, reason := range []ExitedReason{Errored, Diverged, Preempted, UserCanceled} {
+		t.Run(string(reason), func(t *testing.T) {
+			ctx := context{rand: nprand.New(0)}
+			search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+			ops, err := search.initialOperations(ctx)
+			assert.NilError(t, err)
+			initial := creates(ops)
+			assert.Equal(t, len(initial), 2)
+			exited, reported := initial[0], initial[1]
+			for _, requestID := range initial {
+				_, err = search.trialCreated(ctx, requestID)
+				assert.NilError(t, err)
+			}
+			// One trial reports and is replaced in the bottom rung by the last of the three trials.
+			ops, err = search.validationCompleted(ctx, reported, Validate{}, ValidationMetrics{
+				Metrics: map[string]interface{}{defaultMetric: 1.0},
+			})
+			assert.NilError(t, err)
+			last := creates(ops)
+			assert.Equal(t, len(last), 1)
+			_, err = search.trialCreated(ctx, last[0])
+			assert.NilError(t, err)
+
+			ops, err = search.trialExitedEarly(ctx, exited, reason)
+			assert.NilError(t, err)
+			if !reason.discardsResult() {
+				// The trial ranks last in the bottom rung, which is then large enough to promote the
+				// trial that reported; all three trials have been created, so none replaces it.
+				assert.DeepEqual(t, rungTrials(search.Rungs[0]), []RequestID{reported, exited})
+				assert.Equal(t, search.TrialRungs[reported], 1)
+				assert.Equal(t, search.Rungs[0].OutstandingTrials, 1)
+				assert.Equal(t, search.Rungs[1].OutstandingTrials, 1)
+				assert.Equal(t, search.OutstandingTrials, 2)
+				assert.Equal(t, len(creates(ops)), 0)
+				assert.Equal(t, search.TrialsCompleted, 1)
+				return
+			}
+			// The trial leaves no trace in the rungs, so nothing is promoted, and a new trial takes
+			// its place.
+			assert.DeepEqual(t, rungTrials(search.Rungs[0]), []RequestID{reported})
+			assert.Equal(t, search.TrialRungs[reported], 0)
+			assert.Equal(t, search.Rungs[0].OutstandingTrials, 1)
+			assert.Equal(t, search.Rungs[1].OutstandingTrials, 0)
+			assert.Equal(t, search.OutstandingTrials, 2)
+			replacement := creates(ops)
+			assert.Equal(t, len(replacement), 1)
+			_, err = search.trialCreated(ctx, replacement[0])
+			assert.NilError(t, err)
+			assert.Equal(t, search.Rungs[0].OutstandingTrials, 2)
+			assert.Equal(t, search.TrialsCompleted, 0)
+
+			// Discarding a trial that has reported removes its metric and replaces it as well, and
+			// neither of the discarded trials counts as completed once closed.
+			ops, err = search.trialExitedEarly(ctx, reported, reason)
+			assert.NilError(t, err)
+			assert.Equal(t, len(search.Rungs[0].Metrics), 0)
+			assert.Equal(t, search.Rungs[0].OutstandingTrials, 2)
+			assert.Equal(t, search.OutstandingTrials, 2)
+			assert.Equal(t, len(creates(ops)), 0)
+			assert.Equal(t, search.ReplacedTrials, 2)
+			for _, requestID := range []RequestID{exited, reported} {
+				_, err = search.trialClosed(ctx, requestID)
+				assert.NilError(t, err)
+			}
+			assert.Equal(t, search.TrialsCompleted, 0)
+		})
+	}
+}
+
+func TestASHASearcherCostAware(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(200),
+		Divisor:             2,
+		MaxTrials:           2,
+		MaxConcurrentTrials: 2,
+	}
+	// promote runs both trials through the bottom rung, reporting the same metric for each but
+	// taking ten times as long to train the one at index expensive, and returns the trial that is
+	// promoted along with the expensive one.
+	promote := func(config model.AsyncHalvingConfig, expensive int) (promoted, costly RequestID) {
+		searcher := NewSearcher(0, newAsyncHalvingSearch(config), nil, nil)
+		ops, err := searcher.InitialOperations()
+		assert.NilError(t, err)
+		creates := []Create{ops[0].(Create), ops[3].(Create)}
+		var promotions []RequestID
+		for i, create := range creates {
+			trialID := i + 1
+			_, err = searcher.TrialCreated(create, trialID)
+			assert.NilError(t, err)
+			seconds := time.Second
+			if i == expensive {
+				seconds *= 10
+			}
+			start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+			searcher.WorkloadCompleted(CompletedMessage{
+				Workload:  Workload{Kind: RunStep, TrialID: trialID, NumBatches: 100},
+				StartTime: start,
+				EndTime:   start.Add(seconds),
+			}, model.NewLengthInBatches(100))
+			_, err = searcher.OperationCompleted(trialID, ops[3*i+1].(Train), nil)
+			assert.NilError(t, err)
+		}
+		for i, create := range creates {
+			ops, err = searcher.OperationCompleted(i+1, NewValidate(create.RequestID),
+				&ValidationMetrics{Metrics: map[string]interface{}{defaultMetric: 1.0}})
+			assert.NilError(t, err)
+			for _, op := range ops {
+				if train, ok := op.(Train); ok {
+					promotions = append(promotions, train.RequestID)
+				}
+			}
+		}
+		assert.Equal(t, len(promotions), 1)
+		return promotions[0], creates[expensive].RequestID
+	}
+
+	// The tie is broken by request ID, whichever trial is the expensive one, unless cost is taken
+	// into account, in which case the cheaper trial is promoted.
+	first, _ := promote(config, 0)
+	second, _ := promote(config, 1)
+	assert.Equal(t, first, second)
+	config.CostAware = &model.CostAwareConfig{MetricPerSecond: 0.1}
+	for expensive := 0; expensive < 2; expensive++ {
+		promoted, costly := promote(config, expensive)
+		assert.Assert(t, promoted != costly, "the expensive trial was promoted")
+	}
+}
+
+func TestASHASearcherConcurrencyAboveMaxTrials(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(200),
+		Divisor:             2,
+		MaxTrials:           5,
+		MaxConcurrentTrials: 10,
+	}
+	creates := func(ops []Operation) int {
+		count := 0
+		for _, op := range ops {
+			if _, ok := op.(Create); ok {
+				count++
+			}
+		}
+		return count
+	}
+
+	// Every trial is created right away, and no more are created later.
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	method := &recordingMethod{SearchMethod: search}
+	driver, err := newQueueDriver(method, nil, func(trialIndex, _ int) float64 {
+		return float64(trialIndex)
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, creates(driver.pending), config.MaxTrials)
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+	assert.Equal(t, creates(method.ops), config.MaxTrials)
+	assert.Equal(t, len(method.closeCounts()), config.MaxTrials)
+
+	// Once the number of trials is extended, the search uses its full concurrency for the new
+	// trials rather than being held to the original number.
+	search = newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	driver, err = newQueueDriver(search, nil, func(trialIndex, _ int) float64 {
+		return float64(trialIndex)
+	})
+	assert.NilError(t, err)
+	assert.NilError(t, search.ExtendMaxTrials(8))
+	for _, ok := driver.pending[0].(Validate); !ok; _, ok = driver.pending[0].(Validate) {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+	ops, err := driver.step()
+	assert.NilError(t, err)
+	assert.Equal(t, creates(ops), 3)
+}
+
+func TestASHASearcherAdversarialConcurrency(t *testing.T) {
+	ratio := 2.0
+	for _, config := range []model.AsyncHalvingConfig{
+		// divisor^(num_rungs-1) overflows.
+		{NumRungs: 3, Divisor: 1e300, MaxTrials: 1},
+		// A promotion ratio above 1, which validation rejects, makes the power less than 1.
+		{NumRungs: 3, Divisor: 4, PromotionRatio: &ratio, MaxTrials: 2},
+	} {
+		config.Metric = defaultMetric
+		config.SmallerIsBetter = true
+		config.MaxLength = model.NewLengthInBatches(900)
+		search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+		driver, err := newQueueDriver(search, nil, func(int, int) float64 { return 0 })
+		assert.NilError(t, err)
+		creates := 0
+		for _, op := range driver.pending {
+			if _, ok := op.(Create); ok {
+				creates++
+			}
+		}
+		assert.Assert(t, creates >= 1, "divisor %v: no trials created", config.Divisor)
+		for len(driver.pending) > 0 {
+			_, err = driver.step()
+			assert.NilError(t, err)
+		}
+		assert.Equal(t, len(search.TrialRungs), config.MaxTrials)
+	}
+}
+
+func TestASHASearcherTrialRetries(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           3,
+		MaxConcurrentTrials: 3,
+		TrialRetries:        2,
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	method := &recordingMethod{SearchMethod: search}
+	driver, err := newQueueDriver(method, listHyperparameters(), nil)
+	assert.NilError(t, err)
+	creates := func() []Create {
+		var creates []Create
+		for _, op := range method.ops {
+			if create, ok := op.(Create); ok {
+				creates = append(creates, create)
+			}
+		}
+		return creates
+	}
+	first := driver.pending[0].(Create)
+	faulty := func(trialIndex int) bool {
+		return creates()[trialIndex].Hparams["lr"] == first.Hparams["lr"]
+	}
+	// The first trial is the best, so it is promoted every time, and errors out in the top rung
+	// every time.
+	driver.metricFn = func(trialIndex, _ int) float64 {
+		if faulty(trialIndex) {
+			return 0
+		}
+		return float64(trialIndex + 1)
+	}
+	driver.exitFn = func(trialIndex, validations int) bool {
+		return faulty(trialIndex) && validations == 1
+	}
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+
+	// The first trial is retried twice with the same hyperparameters, after which it is treated as
+	// having exited early.
+	var attempts []RequestID
+	for i, create := range creates() {
+		if faulty(i) {
+			assert.DeepEqual(t, create.Hparams, first.Hparams)
+			attempts = append(attempts, create.RequestID)
+		}
+	}
+	assert.Equal(t, len(attempts), config.TrialRetries+1)
+	assert.Equal(t, len(creates()), config.MaxTrials+config.TrialRetries)
+	assert.Equal(t, search.Retries[first.RequestID], config.TrialRetries)
+	for _, retry := range attempts[1:] {
+		assert.Equal(t, search.RetryOf[retry], first.RequestID)
+	}
+	for _, attempt := range attempts[:config.TrialRetries] {
+		assert.Assert(t, search.DiscardedTrials[attempt])
+		assert.Assert(t, !search.EarlyExitTrials[attempt])
+	}
+	last := attempts[config.TrialRetries]
+	assert.Assert(t, search.EarlyExitTrials[last])
+	// Only the last attempt and the other two trials remain in the rungs, and the last attempt has
+	// the worst metric in the top rung.
+	assert.Equal(t, len(search.Rungs[0].Metrics), config.MaxTrials)
+	for _, rung := range search.Rungs {
+		for _, trialMetric := range rung.Metrics {
+			assert.Assert(t, !search.DiscardedTrials[trialMetric.RequestID])
+		}
+	}
+	top := search.Rungs[1].Metrics
+	assert.DeepEqual(t, top[len(top)-1], trialMetric{RequestID: last, Metric: ashaExitedMetricValue})
+
+	// A trial that diverged is not retried.
+	diverged := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	driver, err = newQueueDriver(diverged, listHyperparameters(), func(int, int) float64 { return 0 })
+	assert.NilError(t, err)
+	driver.exitFn = func(trialIndex, _ int) bool { return trialIndex == 0 }
+	driver.exitReason = Diverged
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+	assert.Equal(t, len(diverged.TrialRungs), config.MaxTrials)
+	assert.Equal(t, len(diverged.Retries), 0)
+}
+
+func TestASHASearcherExitAfterValidation(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(400),
+		Divisor:             2,
+		MaxTrials:           4,
+		MaxConcurrentTrials: 4,
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	method := &recordingMethod{SearchMethod: search}
+	driver, err := newQueueDriver(method, nil, func(trialIndex, _ int) float64 {
+		return float64(trialIndex)
+	})
+	assert.NilError(t, err)
+	first := driver.pending[0].(Create).RequestID
+	// The first trial reports the best metric of the bottom rung but is not promoted yet, since it
+	// is the only one to have reported, and then exits before it is closed.
+	for {
+		op := driver.pending[0]
+		_, err = driver.step()
+		assert.NilError(t, err)
+		if _, ok := op.(Validate); ok {
+			break
+		}
+	}
+	assert.Equal(t, search.TrialRungs[first], 0)
+	ops, err := search.trialExitedEarly(driver.ctx, first, Errored)
+	assert.NilError(t, err)
+	assert.Equal(t, len(ops), 0)
+	assert.Equal(t, len(search.Rungs[0].Metrics), 1)
+
+	// Its metric stands, so it is promoted once the next trial reports and recorded as having
+	// exited in the top rung, exactly once.
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+	for rungIndex, rung := range search.Rungs {
+		seen := make(map[RequestID]bool)
+		for _, trialMetric := range rung.Metrics {
+			assert.Assert(t, !seen[trialMetric.RequestID], "rung %d: %s", rungIndex, trialMetric.RequestID)
+			seen[trialMetric.RequestID] = true
+		}
+	}
+	assert.Equal(t, len(search.Rungs[0].Metrics), config.MaxTrials)
+	assert.Equal(t, search.TrialRungs[first], 1)
+	assert.Assert(t, search.Rungs[1].hasMetric(first))
+	assert.Equal(t, search.OutstandingTrials, 0)
+
+	// Recording a second metric for a trial in a rung is an internal error.
+	_, err = search.promoteAsync(driver.ctx, first, ashaExitedMetricValue)
+	assert.ErrorContains(t, err, "already has a metric in rung 1")
+}
+
+func TestASHASearcherCanBePromoted(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           9,
+		MaxConcurrentTrials: 9,
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	driver, err := newQueueDriver(search, nil, func(trialIndex, _ int) float64 {
+		return float64(trialIndex)
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, !search.CanBePromoted(RequestID{}))
+
+	// Once the predicate says a trial is dead, it is never promoted again.
+	dead := make(map[RequestID]int)
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+		for requestID, rungIndex := range search.TrialRungs {
+			if deadRung, ok := dead[requestID]; ok {
+				assert.Equal(t, rungIndex, deadRung, "trial %d", driver.trialIndex[requestID])
+			} else if !search.CanBePromoted(requestID) {
+				dead[requestID] = rungIndex
+			}
+		}
+
+		if len(search.Rungs[0].Metrics) < config.MaxTrials {
+			// Until every trial has reported in the bottom rung, any trial there may still be promoted.
+			for requestID, rungIndex := range search.TrialRungs {
+				if rungIndex == 0 {
+					assert.Assert(t, search.CanBePromoted(requestID))
+				}
+			}
+		}
+	}
+
+	// The best trial reached the top rung, the next two were promoted once, and the rest were not.
+	for requestID, rungIndex := range search.TrialRungs {
+		assert.Assert(t, !search.CanBePromoted(requestID))
+		switch trialIndex := driver.trialIndex[requestID]; {
+		case trialIndex == 0:
+			assert.Equal(t, rungIndex, search.topRung())
+		case trialIndex < 3:
+			assert.Equal(t, rungIndex, 1)
+		default:
+			assert.Equal(t, rungIndex, 0)
+		}
+	}
+	assert.Equal(t, len(dead), config.MaxTrials)
+}
+
+func TestASHASearcherCanBePromotedDecidedRung(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(400),
+		Divisor:             2,
+		MaxTrials:           2,
+		MaxConcurrentTrials: 2,
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	promoted, unpromoted := RequestID{1}, RequestID{2}
+	search.TrialRungs[promoted] = 1
+	search.TrialRungs[unpromoted] = 0
+	search.Rungs[0].Metrics = []trialMetric{
+		{RequestID: promoted, Metric: 1, Promoted: true},
+		{RequestID: unpromoted, Metric: 2},
+	}
+	search.Rungs[1].OutstandingTrials = 1
+
+	// The unpromoted trial is dead even before closeOutRungs has closed it, since no trial will
+	// ever report in its rung again.
+	assert.Assert(t, !search.CanBePromoted(unpromoted))
+	// The promoted trial is in the top rung.
+	assert.Assert(t, !search.CanBePromoted(promoted))
+
+	// While a trial is still training toward the bottom rung, the unpromoted trial may yet be.
+	search.Rungs[0].OutstandingTrials = 1
+	assert.Assert(t, search.CanBePromoted(unpromoted))
+}
+
+func TestASHASearcherReplacementRungThreshold(t *testing.T) {
+	run := func(threshold int) (creates, churn int) {
+		config := model.AsyncHalvingConfig{
+			Metric:                   defaultMetric,
+			SmallerIsBetter:          true,
+			NumRungs:                 2,
+			MaxLength:                model.NewLengthInBatches(400),
+			Divisor:                  2,
+			MaxTrials:                16,
+			MaxConcurrentTrials:      4,
+			ReplacementRungThreshold: threshold,
+		}
+		search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+		driver, err := newQueueDriver(search, nil, func(trialIndex, _ int) float64 {
+			return float64(trialIndex % 5)
+		})
+		assert.NilError(t, err)
+		creates = len(driver.pending) / 3
+		for len(driver.pending) > 0 {
+			op := driver.pending[0]
+			rungIndex := -1
+			if validate, ok := op.(Validate); ok {
+				rungIndex = search.TrialRungs[validate.RequestID]
+			}
+			ops, stepErr := driver.step()
+			assert.NilError(t, stepErr)
+			for _, op := range ops {
+				if _, ok := op.(Create); ok {
+					creates++
+					// Trials created when a trial reports in the bottom rung are bottom-rung churn.
+					if rungIndex == 0 {
+						churn++
+					}
+				}
+			}
+		}
+		assert.Equal(t, len(search.Rungs[0].Metrics), config.MaxTrials)
+		assert.Equal(t, search.OutstandingTrials, 0)
+		return creates, churn
+	}
+
+	creates, churn := run(0)
+	thresholdCreates, thresholdChurn := run(1)
+	// Every trial is still created, but fewer of them as soon as a bottom-rung trial finishes.
+	assert.Equal(t, creates, 16)
+	assert.Equal(t, thresholdCreates, 16)
+	assert.Assert(t, thresholdChurn < churn, "%d >= %d", thresholdChurn, churn)
+}
+
+func TestASHASearcherProgressWithinRung(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(1000),
+		Divisor:             2,
+		MaxTrials:           2,
+		MaxConcurrentTrials: 2,
+	}
+	searcher := NewSearcher(0, newAsyncHalvingSearch(config), nil, nil)
+	ops, err := searcher.InitialOperations()
+	assert.NilError(t, err)
+	creates := []Create{ops[0].(Create), ops[3].(Create)}
+	step := func(trialID int) {
+		searcher.WorkloadCompleted(CompletedMessage{
+			Workload: Workload{Kind: RunStep, TrialID: trialID, NumBatches: 100},
+		}, model.NewLengthInBatches(100))
+	}
+
+	// Each step of training raises the progress, not just the reports at the end of each rung.
+	last := searcher.Progress()
+	assert.Equal(t, last, 0.0)
+	for i, create := range creates {
+		_, err = searcher.TrialCreated(create, i+1)
+		assert.NilError(t, err)
+	}
+	for batches := 0; batches < 500; batches += 100 {
+		for trialID := 1; trialID <= 2; trialID++ {
+			step(trialID)
+			progress := searcher.Progress()
+			assert.Assert(t, progress > last, "progress did not grow from %f", last)
+			last = progress
+		}
+	}
+	var promoted Train
+	for i, create := range creates {
+		_, err = searcher.OperationCompleted(i+1, ops[3*i+1].(Train), nil)
+		assert.NilError(t, err)
+		ops, err := searcher.OperationCompleted(i+1, NewValidate(create.RequestID),
+			&ValidationMetrics{Metrics: map[string]interface{}{defaultMetric: float64(i)}})
+		assert.NilError(t, err)
+		for _, op := range ops {
+			if train, ok := op.(Train); ok {
+				promoted = train
+			}
+		}
+	}
+	// Both trials have trained the 500 batches of the bottom rung, 1000 of the 1500 batches in all.
+	assert.Equal(t, last, 1000.0/1500)
+	assert.Equal(t, searcher.Progress(), 1000.0/1500)
+
+	// The promoted trial trains for the 500 batches of the top rung.
+	trialID, _ := searcher.TrialID(promoted.RequestID)
+	for batches := 0; batches < 500; batches += 100 {
+		step(trialID)
+		progress := searcher.Progress()
+		assert.Assert(t, progress > last, "progress did not grow from %f", last)
+		last = progress
+	}
+	assert.Equal(t, last, 1.0)
+}
+
+func TestASHASearcherValidationsPerRung(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           9,
+		MaxConcurrentTrials: 3,
+		ValidationsPerRung:  4,
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	driver, err := newQueueDriver(search, nil, func(trialIndex, validations int) float64 {
+		// Intermediate validations would rank the trials the other way around.
+		if validations%config.ValidationsPerRung != config.ValidationsPerRung-1 {
+			return -float64(trialIndex)
+		}
+		return float64(trialIndex)
+	})
+	assert.NilError(t, err)
+
+	type trialRung struct {
+		requestID RequestID
+		rung      int
+	}
+	validations := make(map[trialRung]int)
+	trained := make(map[trialRung]int)
+	for len(driver.pending) > 0 {
+		op := driver.pending[0]
+		var key trialRung
+		var boundary bool
+		switch op := op.(type) {
+		case Train:
+			key = trialRung{op.RequestID, search.TrialRungs[op.RequestID]}
+			trained[key] += op.Length.Units
+		case Validate:
+			key = trialRung{op.RequestID, search.TrialRungs[op.RequestID]}
+			validations[key]++
+			boundary = search.IntermediateValidations[op.RequestID] == 0
+		}
+		metrics := len(search.Rungs[key.rung].Metrics)
+		ops, stepErr := driver.step()
+		assert.NilError(t, stepErr)
+		if _, ok := op.(Validate); ok && !boundary {
+			// Intermediate validations promote, create, and close nothing, and are not recorded.
+			assert.Equal(t, len(ops), 0)
+			assert.Equal(t, len(search.Rungs[key.rung].Metrics), metrics)
+		}
+	}
+
+	// Every trial validated four times toward each rung it trained toward, and trained exactly up
+	// to the rung boundaries.
+	previousUnits := 0
+	for rungIndex, rung := range search.Rungs {
+		for _, trialMetric := range rung.Metrics {
+			key := trialRung{trialMetric.RequestID, rungIndex}
+			assert.Equal(t, validations[key], config.ValidationsPerRung)
+			assert.Equal(t, trained[key], rung.UnitsNeeded.Units-previousUnits)
+		}
+		previousUnits = rung.UnitsNeeded.Units
+	}
+	// Promotions follow the metrics at the rung boundaries only.
+	for requestID, rungIndex := range search.TrialRungs {
+		switch trialIndex := driver.trialIndex[requestID]; {
+		case trialIndex == 0:
+			assert.Equal(t, rungIndex, 2)
+		case trialIndex < 3:
+			assert.Equal(t, rungIndex, 1)
+		default:
+			assert.Equal(t, rungIndex, 0)
+		}
+	}
+}
+
+func TestASHASearcherSpaceFilling(t *testing.T) {
+	hparams := model.Hyperparameters{
+		"x": {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: 0, Maxval: 1}},
+		"y": {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: 0, Maxval: 1}},
+	}
+	// run returns the hyperparameters of the trials the search creates, in order, and the number of
+	// cells of a 4x4 grid over the space that they fall in.
+	run := func(spaceFilling bool) ([]hparamSample, int) {
+		config := model.AsyncHalvingConfig{
+			Metric:              defaultMetric,
+			SmallerIsBetter:     true,
+			NumRungs:            3,
+			MaxLength:           model.NewLengthInBatches(900),
+			Divisor:             3,
+			MaxTrials:           16,
+			MaxConcurrentTrials: 4,
+			SpaceFilling:        spaceFilling,
+		}
+		search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+		driver, err := newQueueDriver(search, hparams, func(trialIndex, _ int) float64 {
+			return float64(trialIndex % 7)
+		})
+		assert.NilError(t, err)
+		var samples []hparamSample
+		cells := make(map[[2]int]bool)
+		for i := 0; len(driver.pending) > 0; i++ {
+			if create, ok := driver.pending[0].(Create); ok {
+				samples = append(samples, create.Hparams)
+				cells[[2]int{
+					int(4 * create.Hparams["x"].(float64)), int(4 * create.Hparams["y"].(float64)),
+				}] = true
+			}
+			_, err = driver.step()
+			assert.NilError(t, err)
+			// The sequence carries over to a restored search.
+			if i == 20 {
+				snapshot, snapshotErr := search.Snapshot()
+				assert.NilError(t, snapshotErr)
+				restored := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+				assert.NilError(t, restored.Restore(snapshot))
+				search = restored
+				driver.method = restored
+			}
+		}
+		assert.Equal(t, len(samples), 16)
+		return samples, len(cells)
+	}
+
+	// Each of the 16 trials falls in a different cell of the grid, since the first 16 points of the
+	// Sobol sequence are spread evenly; independent samples leave some cells empty.
+	samples, covered := run(true)
+	_, independent := run(false)
+	assert.Equal(t, covered, 16)
+	assert.Assert(t, independent < covered, "%d >= %d", independent, covered)
+
+	// The same seed gives the same trials.
+	again, _ := run(true)
+	assert.DeepEqual(t, again, samples)
+}
+
+func TestASHASearcherMerge(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           3,
+		MaxConcurrentTrials: 3,
+	}
+	// shard runs a search to completion in which the metric of each trial is its index plus offset.
+	// Each shard is seeded differently, as the shards of a real search would be, so that their
+	// request IDs differ.
+	seed := uint32(0)
+	shard := func(config model.AsyncHalvingConfig, offset float64) *asyncHalvingSearch {
+		seed++
+		search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+		driver := &queueDriver{
+			ctx:    context{rand: nprand.New(seed)},
+			method: search,
+			metricFn: func(trialIndex, _ int) float64 {
+				return float64(trialIndex) + offset
+			},
+			trialIndex:  make(map[RequestID]int),
+			validations: make(map[RequestID]int),
+		}
+		var err error
+		driver.pending, err = search.initialOperations(driver.ctx)
+		assert.NilError(t, err)
+		for len(driver.pending) > 0 {
+			_, err = driver.step()
+			assert.NilError(t, err)
+		}
+		return search
+	}
+
+	first, second := shard(config, 0), shard(config, 0.5)
+	firstBest, secondBest := first.BestTrials(3), second.BestTrials(3)
+	firstStats, secondStats := first.Stats(), second.Stats()
+	firstProgress, secondProgress := first.ProgressDetail(), second.ProgressDetail()
+	assert.NilError(t, first.Merge(second))
+
+	// The best trial of each shard reached the top rung, so they lead the merged leaderboard, and
+	// the trials of both shards in the bottom rung follow, interleaved by metric.
+	best := first.BestTrials(6)
+	expected := []TrialSummary{
+		firstBest[0], secondBest[0], firstBest[1], secondBest[1], firstBest[2], secondBest[2],
+	}
+	assert.DeepEqual(t, best, expected)
+	assert.Equal(t, len(first.Rungs[0].Metrics), 6)
+	for i := 1; i < len(first.Rungs[0].Metrics); i++ {
+		assert.Assert(t, first.Rungs[0].Metrics[i-1].Metric <= first.Rungs[0].Metrics[i].Metric)
+	}
+	assert.Equal(t, first.TrialsCompleted, 6)
+
+	// The readers of the per-trial and per-rung state see the trials of both searches.
+	for requestID, units := range second.TrialUnits {
+		assert.Equal(t, first.TrialUnits[requestID], units)
+	}
+	stats := first.Stats()
+	progress := first.ProgressDetail()
+	assert.Equal(t, progress.TrialsCreated, firstProgress.TrialsCreated+secondProgress.TrialsCreated)
+	for rungIndex := range stats {
+		assert.Equal(t, stats[rungIndex].Granted,
+			firstStats[rungIndex].Granted+secondStats[rungIndex].Granted)
+		assert.Equal(t, progress.Rungs[rungIndex].Completed,
+			firstProgress.Rungs[rungIndex].Completed+secondProgress.Rungs[rungIndex].Completed)
+		assert.Equal(t, progress.Rungs[rungIndex].Promoted,
+			firstProgress.Rungs[rungIndex].Promoted+secondProgress.Rungs[rungIndex].Promoted)
+	}
+	assert.Equal(t, len(first.RungInfo()[1].Metrics), 2)
+	// The state that drives the search stays that of this search.
+	assert.Equal(t, first.Concurrency, 3)
+	assert.Equal(t, first.maxTrials, 3)
+
+	t.Run("metrics", func(t *testing.T) {
+		smoothing := 0.5
+		ranked := config
+		ranked.PromoteOnBest = true
+		ranked.SmoothingFactor = &smoothing
+		first, second := shard(ranked, 0), shard(ranked, 0.5)
+		assert.NilError(t, first.Merge(second))
+		assert.Equal(t, len(second.BestMetrics), 3)
+		for requestID, metric := range second.BestMetrics {
+			assert.Equal(t, first.BestMetrics[requestID], metric)
+		}
+		assert.Equal(t, len(second.SmoothedMetrics), 3)
+		for requestID, metric := range second.SmoothedMetrics {
+			assert.Equal(t, first.SmoothedMetrics[requestID], metric)
+		}
+	})
+
+	t.Run("incompatible", func(t *testing.T) {
+		search := shard(config, 0)
+		fewerRungs := config
+		fewerRungs.NumRungs = 3
+		largerIsBetter := config
+		largerIsBetter.SmallerIsBetter = false
+		for _, other := range []SearchMethod{
+			shard(fewerRungs, 0),
+			shard(largerIsBetter, 0),
+			newRandomSearch(model.RandomConfig{MaxTrials: 1, MaxLength: model.NewLengthInBatches(1)}),
+			search,
+		} {
+			err := search.Merge(other)
+			assert.Assert(t, err != nil)
+			kind, ok := ErrorKindOf(err)
+			assert.Assert(t, ok)
+			assert.Equal(t, kind, ConfigErrorKind)
+		}
+		assert.Equal(t, len(search.BestTrials(6)), 3)
+	})
+
+	t.Run("overlapping", func(t *testing.T) {
+		assert.ErrorContains(t, first.Merge(second), "in both searches")
+	})
+}
+
+func TestASHASearcherMinTopRungTrials(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           9,
+		MaxConcurrentTrials: 3,
+	}
+	// topRungTrials returns the number of trials that reported a metric in the top rung.
+	topRungTrials := func(search *asyncHalvingSearch) int {
+		return len(search.Rungs[len(search.Rungs)-1].Metrics)
+	}
+	// Later trials are better in one run and worse in the other, so that the trials created past
+	// max_trials are promoted in one and have to outnumber the closed out trials in the other.
+	for _, sign := range []float64{-1, 1} {
+		search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+		driver, err := newQueueDriver(search, nil, func(trialIndex, _ int) float64 {
+			return sign * float64(trialIndex)
+		})
+		assert.NilError(t, err)
+		for len(driver.pending) > 0 {
+			_, err = driver.step()
+			assert.NilError(t, err)
+		}
+		assert.Equal(t, len(search.TrialRungs), 9)
+		baseline := topRungTrials(search)
+
+		// Ask for two more trials in the top rung than the search normally brings there.
+		minTop := config
+		minTop.MinTopRungTrials = baseline + 2
+		search = newAsyncHalvingSearch(minTop).(*asyncHalvingSearch)
+		driver, err = newQueueDriver(search, nil, func(trialIndex, _ int) float64 {
+			return sign * float64(trialIndex)
+		})
+		assert.NilError(t, err)
+		for steps := 0; len(driver.pending) > 0; steps++ {
+			assert.Assert(t, steps < 10000, "the search did not complete")
+			_, err = driver.step()
+			assert.NilError(t, err)
+			// The search has work left until enough trials have reached the top rung.
+			if topRungTrials(search) < minTop.MinTopRungTrials {
+				assert.Assert(t, len(driver.pending) > 0)
+			}
+		}
+		assert.Assert(t, topRungTrials(search) >= minTop.MinTopRungTrials,
+			"sign %v: %d < %d", sign, topRungTrials(search), minTop.MinTopRungTrials)
+		assert.Assert(t, len(search.TrialRungs) > 9)
+		// Every trial is closed once the search completes.
+		for requestID := range search.TrialRungs {
+			assert.Assert(t, search.ClosedTrials[requestID])
+		}
+	}
+}
+
+func TestASHASearcherConvergenceStop(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           100,
+		MaxConcurrentTrials: 1,
+		ConvergenceStop:     &model.ConvergenceStopConfig{Window: 4, MinImprovement: 0.5},
+	}
+	// The metric improves by 1 with every trial up to the eighth, and then plateaus. With a single
+	// trial training at a time, trials report in the bottom rung in the order they are created.
+	metric := func(trialIndex, _ int) float64 {
+		return math.Max(10-float64(trialIndex), 3)
+	}
+	run := func(config model.AsyncHalvingConfig) *asyncHalvingSearch {
+		search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+		driver, err := newQueueDriver(search, nil, metric)
+		assert.NilError(t, err)
+		for len(driver.pending) > 0 {
+			_, err = driver.step()
+			assert.NilError(t, err)
+		}
+		return search
+	}
+
+	// The best metric is 3 from the eighth report on, so the twelfth is the first after which it has
+	// improved by less than 0.5 over the last four; the eleventh has it improve from 4 to 3.
+	search := run(config)
+	assert.Assert(t, search.Converged)
+	assert.Equal(t, len(search.TrialRungs), 12)
+	assert.Equal(t, len(search.Rungs[0].Metrics), 12)
+	for requestID := range search.TrialRungs {
+		assert.Assert(t, search.ClosedTrials[requestID])
+	}
+
+	// A longer window waits for more reports without improvement.
+	longer := config
+	longer.ConvergenceStop = &model.ConvergenceStopConfig{Window: 6, MinImprovement: 0.5}
+	assert.Equal(t, len(run(longer).TrialRungs), 14)
+
+	// max_trials still bounds the search if the metric has yet to plateau.
+	ceiling := config
+	ceiling.MaxTrials = 6
+	search = run(ceiling)
+	assert.Assert(t, !search.Converged)
+	assert.Equal(t, len(search.TrialRungs), 6)
+}
+
+func TestASHASearcherRungInfo(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           9,
+		MaxConcurrentTrials: 3,
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	driver, err := newQueueDriver(search, nil, func(trialIndex, _ int) float64 {
+		return float64(trialIndex % 4)
+	})
+	assert.NilError(t, err)
+
+	// matches checks that the info matches the rungs of the search.
+	matches := func(infos []RungInfo) {
+		assert.Equal(t, len(infos), len(search.Rungs))
+		for i, rung := range search.Rungs {
+			assert.Equal(t, infos[i].UnitsNeeded, rung.UnitsNeeded)
+			assert.Equal(t, infos[i].StartTrials, rung.StartTrials)
+			assert.Equal(t, infos[i].PromoteTrials, rung.PromoteTrials)
+			assert.Equal(t, infos[i].OutstandingTrials, rung.OutstandingTrials)
+			assert.Equal(t, len(infos[i].Metrics), len(rung.Metrics))
+			for j, trialMetric := range rung.Metrics {
+				assert.DeepEqual(t, infos[i].Metrics[j], RungMetricInfo{
+					RequestID: trialMetric.RequestID,
+					Metric:    trialMetric.Metric,
+					Promoted:  trialMetric.Promoted,
+				})
+			}
+		}
+	}
+
+	infos := search.RungInfo()
+	matches(infos)
+	assert.Equal(t, len(infos[0].Metrics), 0)
+	assert.DeepEqual(t, infos[2].UnitsNeeded, model.NewLengthInBatches(900))
+	for steps := 0; len(driver.pending) > 0; steps++ {
+		_, err = driver.step()
+		assert.NilError(t, err)
+		if steps%5 == 0 {
+			matches(search.RungInfo())
+		}
+	}
+	infos = search.RungInfo()
+	matches(infos)
+	assert.Equal(t, len(infos[0].Metrics), 9)
+
+	// The info is a copy, so changing it leaves the search as it was.
+	infos[0].OutstandingTrials = 100
+	infos[0].Metrics[0].Promoted = !infos[0].Metrics[0].Promoted
+	infos[0].Metrics = infos[0].Metrics[:0]
+	matches(search.RungInfo())
+	assert.Equal(t, len(search.Rungs[0].Metrics), 9)
+	assert.Equal(t, search.Rungs[0].OutstandingTrials, 0)
+}
+
+func TestASHASearcherTrialResumed(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           9,
+		MaxConcurrentTrials: 3,
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	driver, err := newQueueDriver(search, nil, func(trialIndex, _ int) float64 {
+		return float64(trialIndex)
+	})
+	assert.NilError(t, err)
+	create := driver.pending[0].(Create)
+	requestID := create.RequestID
+
+	// A trial preempted in the bottom rung only trains from its checkpoint to the rung boundary.
+	ops, err := search.trialResumed(driver.ctx, requestID, model.NewLengthInBatches(40))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, ops, []Operation{
+		NewTrain(requestID, model.NewLengthInBatches(60)),
+		NewValidate(requestID),
+	})
+	assert.Equal(t, search.TrialUnits[requestID], 40)
+
+	// One that had already trained to the boundary only validates.
+	ops, err = search.trialResumed(driver.ctx, requestID, model.NewLengthInBatches(100))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, ops, []Operation{NewValidate(requestID)})
+
+	_, err = search.trialResumed(driver.ctx, requestID, model.NewLengthInRecords(40))
+	assert.ErrorContains(t, err, "in a search in batches")
+	kind, ok := ErrorKindOf(err)
+	assert.Assert(t, ok)
+	assert.Equal(t, kind, ConfigErrorKind)
+
+	_, err = search.trialResumed(driver.ctx, RequestID{}, model.NewLengthInBatches(40))
+	kind, ok = ErrorKindOf(err)
+	assert.Assert(t, ok)
+	assert.Equal(t, kind, InternalErrorKind)
+
+	// A paused search lets the trial carry on with the operations it already has.
+	search.Paused = true
+	ops, err = search.trialResumed(driver.ctx, requestID, model.NewLengthInBatches(40))
+	assert.NilError(t, err)
+	assert.Assert(t, ops == nil)
+	search.Paused = false
+
+	// Intermediate validations that the trial already passed are not repeated.
+	config.ValidationsPerRung = 4
+	search = newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	driver, err = newQueueDriver(search, nil, func(trialIndex, _ int) float64 {
+		return float64(trialIndex)
+	})
+	assert.NilError(t, err)
+	requestID = driver.pending[0].(Create).RequestID
+	ops, err = search.trialResumed(driver.ctx, requestID, model.NewLengthInBatches(60))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, ops, []Operation{
+		NewTrain(requestID, model.NewLengthInBatches(15)),
+		NewValidate(requestID),
+		NewTrain(requestID, model.NewLengthInBatches(25)),
+		NewValidate(requestID),
+	})
+	assert.Equal(t, search.IntermediateValidations[requestID], 1)
+
+	// Trials waiting to be promoted are not training, so there is nothing to resume.
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+	for requestID := range search.TrialRungs {
+		ops, err = search.trialResumed(driver.ctx, requestID, model.NewLengthInBatches(0))
+		assert.NilError(t, err)
+		assert.Equal(t, len(ops), 0)
+	}
+}
+
+func TestASHASearcherWhyNotDone(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           9,
+		MaxConcurrentTrials: 3,
+		MinTopRungTrials:    1,
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	driver, err := newQueueDriver(search, nil, func(trialIndex, _ int) float64 {
+		return float64(trialIndex)
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, search.WhyNotDone(), []string{
+		"3/9 trials created",
+		"3 trials outstanding, the most allowed at once",
+		"top rung awaiting 1 promotion",
+	})
+
+	// A trial that never reports holds up its rung.
+	_, err = driver.step()
+	assert.NilError(t, err)
+	search.pause()
+	assert.DeepEqual(t, search.WhyNotDone(), []string{
+		"the search is paused with 0 deferred operations",
+		"3/9 trials created",
+		"3 trials outstanding, the most allowed at once",
+		"rung 0 has 1 outstanding trials",
+		"top rung awaiting 1 promotion",
+	})
+
+	ops, err := search.resume(driver.ctx)
+	assert.NilError(t, err)
+	driver.pending = append(driver.pending, ops...)
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+	assert.Equal(t, len(search.WhyNotDone()), 0)
+}
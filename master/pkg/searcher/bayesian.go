@@ -0,0 +1,362 @@
+package searcher
+
+import (
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// bayesianSearch implements Bayesian optimization with a Gaussian process surrogate. The first
+// NumStartupTrials trials are sampled randomly. After that, a Gaussian process is fit to the
+// metrics of the completed trials over their normalized hyperparameters, and each new trial uses
+// the candidate, out of NumCandidates random samples, that maximizes the expected improvement on
+// the best metric so far. Trials that are still running are treated as if they had reached the
+// mean of the observed metrics (the "constant liar" heuristic), so that trials proposed while
+// others are running do not all crowd around the same point. A candidate that is worse than the
+// best trial so far in the value of a Monotone hyperparameter takes the value of the best trial
+// instead, so that no trial goes against the known trend.
+type bayesianSearch struct {
+	*modelBasedSearch
+	model.BayesianConfig
+}
+
+func newBayesianSearch(config model.BayesianConfig) SearchMethod {
+	s := &bayesianSearch{
+		modelBasedSearch: newModelBasedSearch(modelBasedConfig{
+			Name:                "Bayesian",
+			Metric:              config.Metric,
+			SmallerIsBetter:     config.SmallerIsBetter,
+			MaxLength:           config.MaxLength,
+			MaxTrials:           config.MaxTrials,
+			MaxConcurrentTrials: config.MaxConcurrentTrials,
+			PriorWeight:         config.PriorWeight,
+		}),
+		BayesianConfig: config,
+	}
+	s.propose = s.proposeTrial
+	return s
+}
+
+func (s *bayesianSearch) initialOperations(ctx context) ([]Operation, error) {
+	for name := range s.Monotone {
+		param, ok := ctx.hparams[name]
+		if !ok {
+			return nil, errors.Errorf("unknown monotone hyperparameter %s", name)
+		}
+		if param.IntHyperparameter == nil && param.DoubleHyperparameter == nil &&
+			param.LogHyperparameter == nil {
+			return nil, errors.Errorf("monotone hyperparameter %s is not numeric", name)
+		}
+	}
+	return s.modelBasedSearch.initialOperations(ctx)
+}
+
+// pending returns the hyperparameters of the trials that have been created but not yet completed,
+// in order of request ID.
+func (s *bayesianSearch) pending() []hparamSample {
+	var requestIDs []RequestID
+	for requestID := range s.TrialParams {
+		if !s.ClosedTrials[requestID] {
+			requestIDs = append(requestIDs, requestID)
+		}
+	}
+	sort.Slice(requestIDs, func(i, j int) bool {
+		return requestIDs[i].Before(requestIDs[j])
+	})
+	samples := make([]hparamSample, 0, len(requestIDs))
+	for _, requestID := range requestIDs {
+		samples = append(samples, s.TrialParams[requestID])
+	}
+	return samples
+}
+
+// proposeTrial returns the hyperparameters of the next trial: a random sample until enough trials
+// have been observed, and the candidate with the highest expected improvement afterwards, unless
+// the trial is a random restart. It also returns whether the trial is a restart.
+func (s *bayesianSearch) proposeTrial(ctx context) (hparamSample, bool, error) {
+	if observedWeight(s.Observations) < float64(s.NumStartupTrials) {
+		hparams, err := sampleAll(ctx)
+		return hparams, false, err
+	}
+	if restart(ctx, s.RestartProbability) {
+		hparams, err := sampleAll(ctx)
+		return hparams, true, err
+	}
+	hparams, err := s.maximizeImprovement(ctx)
+	return hparams, false, err
+}
+
+// maximizeImprovement returns the candidate with the highest expected improvement under a Gaussian
+// process fit to the observations and the pending trials.
+func (s *bayesianSearch) maximizeImprovement(ctx context) (hparamSample, error) {
+	// Diverged trials tell the model nothing, and are no longer observed, but a search restored
+	// from before that may still hold some.
+	var observations []modelObservation
+	for _, observation := range s.Observations {
+		if !math.IsNaN(observation.Metric) && !math.IsInf(observation.Metric, 0) {
+			observations = append(observations, observation)
+		}
+	}
+	if len(observations) == 0 {
+		return sampleAll(ctx)
+	}
+
+	// Standardize the metrics so that the prior variance of the process is one.
+	var mean, variance float64
+	for _, observation := range observations {
+		mean += observation.Metric
+	}
+	mean /= float64(len(observations))
+	for _, observation := range observations {
+		variance += (observation.Metric - mean) * (observation.Metric - mean)
+	}
+	std := math.Sqrt(variance / float64(len(observations)))
+	if std == 0 {
+		std = 1
+	}
+
+	var points [][]float64
+	var targets, noise []float64
+	best := math.Inf(1)
+	var bestHparams hparamSample
+	for _, observation := range observations {
+		target := (observation.Metric - mean) / std
+		points = append(points, normalizeHparams(ctx.hparams, observation.Hparams))
+		targets = append(targets, target)
+		// An observation loaded as a prior gets an extra noise variance of 1/weight - 1 in units of
+		// the prior variance, e.g., as much as the metrics vary for a weight of one half.
+		noise = append(noise, 1/observation.weight()-1)
+		if target < best {
+			best, bestHparams = target, observation.Hparams
+		}
+	}
+	// The standardized mean of the observed metrics is zero.
+	for _, hparams := range s.pending() {
+		points = append(points, normalizeHparams(ctx.hparams, hparams))
+		targets = append(targets, 0)
+		noise = append(noise, 0)
+	}
+	gp, err := fitGaussianProcess(s.kernel(), s.LengthScale, s.Jitter, points, targets, noise)
+	if err != nil {
+		return nil, err
+	}
+
+	var bestCandidate hparamSample
+	bestImprovement := math.Inf(-1)
+	for candidate := 0; candidate < s.NumCandidates; candidate++ {
+		hparams, sampleErr := sampleAll(ctx)
+		if sampleErr != nil {
+			return nil, sampleErr
+		}
+		hparams = s.followMonotone(hparams, bestHparams)
+		mu, sigma := gp.predict(normalizeHparams(ctx.hparams, hparams))
+		improvement := expectedImprovement(mu, sigma, best)
+		if bestCandidate == nil || improvement > bestImprovement {
+			bestCandidate, bestImprovement = hparams, improvement
+		}
+	}
+	return bestCandidate, nil
+}
+
+// followMonotone returns the candidate with the value of each Monotone hyperparameter that is
+// worse than that of the best observation replaced by the value of the best observation. Inactive
+// hyperparameters are left alone.
+func (s *bayesianSearch) followMonotone(candidate, best hparamSample) hparamSample {
+	for name, monotonicity := range s.Monotone {
+		value, active := candidate[name]
+		bestValue, bestActive := best[name]
+		if !active || !bestActive {
+			continue
+		}
+		gain := hparamFloat(value) - hparamFloat(bestValue)
+		if monotonicity == model.DecreasingMonotonicity {
+			gain = -gain
+		}
+		if gain < 0 {
+			candidate[name] = bestValue
+		}
+	}
+	return candidate
+}
+
+// kernel returns the configured covariance function, which takes the distance between two points
+// in units of the length scale.
+func (s *bayesianSearch) kernel() func(r float64) float64 {
+	switch s.Kernel {
+	case model.RBFKernel:
+		return func(r float64) float64 { return math.Exp(-r * r / 2) }
+	default:
+		return func(r float64) float64 {
+			return (1 + math.Sqrt(5)*r + 5*r*r/3) * math.Exp(-math.Sqrt(5)*r)
+		}
+	}
+}
+
+// expectedImprovement returns how much a point whose metric is normally distributed with the given
+// mean and standard deviation is expected to improve on the best metric, where smaller is better.
+func expectedImprovement(mu, sigma, best float64) float64 {
+	if sigma == 0 {
+		return math.Max(best-mu, 0)
+	}
+	z := (best - mu) / sigma
+	return (best-mu)*normalCDF(z) + sigma*math.Exp(-z*z/2)/math.Sqrt(2*math.Pi)
+}
+
+// normalizeHparams maps a hyperparameter sample to a point in the unit hypercube, with a coordinate
+// for each numeric hyperparameter and for each value of each categorical hyperparameter. Log
+// hyperparameters are normalized by their exponents, and categorical ones are one-hot encoded.
+// The coordinates of inactive conditional hyperparameters are zero.
+func normalizeHparams(h model.Hyperparameters, sample hparamSample) []float64 {
+	var point []float64
+	h.Each(func(name string, param model.Hyperparameter) {
+		value, active := sample[name]
+		switch {
+		case param.IntHyperparameter != nil:
+			p := param.IntHyperparameter
+			x := 0.0
+			if active {
+				step, size := float64(p.StepSize()), float64(intLatticeSize(*p))
+				x = (hparamFloat(value) + step/2 - float64(p.Minval)) / (step * size)
+			}
+			point = append(point, x)
+		case param.DoubleHyperparameter != nil:
+			p := param.DoubleHyperparameter
+			x := 0.0
+			if active {
+				x = (hparamFloat(value) - p.Minval) / (p.Maxval - p.Minval)
+			}
+			point = append(point, x)
+		case param.LogHyperparameter != nil:
+			p := param.LogHyperparameter
+			x := 0.0
+			if active {
+				x = (math.Log(hparamFloat(value))/math.Log(p.Base) - p.Minval) / (p.Maxval - p.Minval)
+			}
+			point = append(point, x)
+		case param.CategoricalHyperparameter != nil:
+			for _, val := range param.CategoricalHyperparameter.Vals {
+				if active && hparamValuesEqual(value, val) {
+					point = append(point, 1)
+				} else {
+					point = append(point, 0)
+				}
+			}
+		}
+	})
+	return point
+}
+
+// gaussianProcess is a Gaussian process with zero prior mean and unit prior variance conditioned
+// on a set of observations.
+type gaussianProcess struct {
+	kernel      func(r float64) float64
+	lengthScale float64
+	points      [][]float64
+	// cholesky is the lower triangular Cholesky factor of the covariance matrix of the points, and
+	// alpha is the covariance matrix inverse times the targets.
+	cholesky [][]float64
+	alpha    []float64
+}
+
+// fitGaussianProcess conditions a Gaussian process on the targets at the points, each with a noise
+// variance of jitter plus its entry in noise.
+func fitGaussianProcess(
+	kernel func(r float64) float64, lengthScale, jitter float64, points [][]float64,
+	targets, noise []float64,
+) (*gaussianProcess, error) {
+	gp := &gaussianProcess{kernel: kernel, lengthScale: lengthScale, points: points}
+	n := len(points)
+	covariance := make([][]float64, n)
+	for i := range points {
+		covariance[i] = make([]float64, n)
+		for j := range points {
+			covariance[i][j] = gp.covariance(points[i], points[j])
+		}
+		covariance[i][i] += jitter + noise[i]
+	}
+	cholesky, err := choleskyDecompose(covariance)
+	if err != nil {
+		return nil, err
+	}
+	gp.cholesky = cholesky
+	gp.alpha = choleskySolve(cholesky, targets)
+	return gp, nil
+}
+
+func (gp *gaussianProcess) covariance(a, b []float64) float64 {
+	distance := 0.0
+	for i := range a {
+		distance += (a[i] - b[i]) * (a[i] - b[i])
+	}
+	return gp.kernel(math.Sqrt(distance) / gp.lengthScale)
+}
+
+// predict returns the posterior mean and standard deviation of the process at the point.
+func (gp *gaussianProcess) predict(point []float64) (mean, std float64) {
+	covariances := make([]float64, len(gp.points))
+	for i, other := range gp.points {
+		covariances[i] = gp.covariance(point, other)
+		mean += covariances[i] * gp.alpha[i]
+	}
+	v := forwardSubstitute(gp.cholesky, covariances)
+	variance := gp.kernel(0)
+	for _, x := range v {
+		variance -= x * x
+	}
+	return mean, math.Sqrt(math.Max(variance, 0))
+}
+
+// choleskyDecompose returns the lower triangular matrix L such that L L^T is the symmetric matrix.
+func choleskyDecompose(matrix [][]float64) ([][]float64, error) {
+	n := len(matrix)
+	lower := make([][]float64, n)
+	for i := range matrix {
+		lower[i] = make([]float64, n)
+		for j := 0; j <= i; j++ {
+			sum := matrix[i][j]
+			for k := 0; k < j; k++ {
+				sum -= lower[i][k] * lower[j][k]
+			}
+			if i == j {
+				if sum <= 0 {
+					return nil, errors.New(
+						"Gaussian process covariance matrix is not positive definite; increase jitter")
+				}
+				lower[i][i] = math.Sqrt(sum)
+			} else {
+				lower[i][j] = sum / lower[j][j]
+			}
+		}
+	}
+	return lower, nil
+}
+
+// forwardSubstitute solves L x = b for x, where L is lower triangular.
+func forwardSubstitute(lower [][]float64, b []float64) []float64 {
+	x := make([]float64, len(b))
+	for i := range b {
+		sum := b[i]
+		for k := 0; k < i; k++ {
+			sum -= lower[i][k] * x[k]
+		}
+		x[i] = sum / lower[i][i]
+	}
+	return x
+}
+
+// choleskySolve solves L L^T x = b for x, where L is lower triangular.
+func choleskySolve(lower [][]float64, b []float64) []float64 {
+	y := forwardSubstitute(lower, b)
+	x := make([]float64, len(y))
+	for i := len(y) - 1; i >= 0; i-- {
+		sum := y[i]
+		for k := i + 1; k < len(y); k++ {
+			sum -= lower[k][i] * x[k]
+		}
+		x[i] = sum / lower[i][i]
+	}
+	return x
+}
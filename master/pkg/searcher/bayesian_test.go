@@ -0,0 +1,371 @@
+package searcher
+
+import (
+	"math"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/nprand"
+)
+
+// searchBowl runs the search method to completion on a two-dimensional bowl with its minimum of 0
+// at (1, -0.5) and returns the best metric found after each trial, in order of creation.
+func searchBowl(t *testing.T, search SearchMethod) []float64 {
+	hparams := model.Hyperparameters{
+		"x": {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: -5, Maxval: 5}},
+		"y": {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: -5, Maxval: 5}},
+	}
+	bowl := func(create Create) float64 {
+		x, y := create.Hparams["x"].(float64), create.Hparams["y"].(float64)
+		return (x-1)*(x-1) + 2*(y+0.5)*(y+0.5)
+	}
+	method := &recordingMethod{SearchMethod: search}
+	driver, err := newQueueDriver(method, hparams, nil)
+	assert.NilError(t, err)
+	creates := func() []Create {
+		var creates []Create
+		for _, op := range method.ops {
+			if create, ok := op.(Create); ok {
+				creates = append(creates, create)
+			}
+		}
+		return creates
+	}
+	driver.metricsFn = func(trialIndex, validations int) map[string]interface{} {
+		return map[string]interface{}{defaultMetric: bowl(creates()[trialIndex])}
+	}
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+
+	var regrets []float64
+	best := math.Inf(1)
+	for _, create := range creates() {
+		best = math.Min(best, bowl(create))
+		regrets = append(regrets, best)
+	}
+	return regrets
+}
+
+func TestBayesianSearcherRegret(t *testing.T) {
+	const maxTrials = 30
+	for _, kernel := range []model.KernelType{model.RBFKernel, model.Matern52Kernel} {
+		bayesian := searchBowl(t, newBayesianSearch(model.BayesianConfig{
+			Metric:              defaultMetric,
+			SmallerIsBetter:     true,
+			MaxLength:           model.NewLengthInBatches(100),
+			MaxTrials:           maxTrials,
+			MaxConcurrentTrials: 2,
+			NumStartupTrials:    5,
+			NumCandidates:       500,
+			Kernel:              kernel,
+			LengthScale:         0.25,
+			Jitter:              1e-4,
+		}))
+		random := searchBowl(t, newRandomSearch(model.RandomConfig{
+			Metric:          defaultMetric,
+			SmallerIsBetter: true,
+			MaxLength:       model.NewLengthInBatches(100),
+			MaxTrials:       maxTrials,
+		}))
+		assert.Equal(t, len(bayesian), maxTrials)
+		assert.Equal(t, len(random), maxTrials)
+
+		// The regret keeps decreasing once the Gaussian process takes over, ending well below that
+		// of random search.
+		assert.Assert(t, bayesian[maxTrials-1] < bayesian[10]/2,
+			"%s: %v", kernel, bayesian)
+		assert.Assert(t, bayesian[maxTrials-1] < random[maxTrials-1]/10,
+			"%s: bayesian %v, random %v", kernel, bayesian[maxTrials-1], random[maxTrials-1])
+	}
+}
+
+func TestBayesianSearcherPendingTrials(t *testing.T) {
+	// Without any pending trials to lie about, every concurrent proposal would be the same point.
+	search := newBayesianSearch(model.BayesianConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		MaxLength:           model.NewLengthInBatches(100),
+		MaxTrials:           4,
+		MaxConcurrentTrials: 4,
+		NumStartupTrials:    1,
+		NumCandidates:       100,
+		Kernel:              model.Matern52Kernel,
+		LengthScale:         0.25,
+		Jitter:              1e-4,
+	}).(*bayesianSearch)
+	hparams := model.Hyperparameters{
+		"x": {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: 0, Maxval: 1}},
+	}
+	search.Observations = []modelObservation{
+		{Hparams: hparamSample{"x": 0.1}, Metric: 0}, {Hparams: hparamSample{"x": 0.9}, Metric: 1},
+	}
+	driver, err := newQueueDriver(search, hparams, nil)
+	assert.NilError(t, err)
+	var xs []float64
+	for _, op := range driver.pending {
+		if create, ok := op.(Create); ok {
+			xs = append(xs, create.Hparams["x"].(float64))
+		}
+	}
+	assert.Equal(t, len(xs), 4)
+	for i := range xs {
+		for j := i + 1; j < len(xs); j++ {
+			assert.Assert(t, math.Abs(xs[i]-xs[j]) > 0.01, "proposals %v", xs)
+		}
+	}
+}
+
+func TestBayesianSearcherObserveExternal(t *testing.T) {
+	const maxTrials = 5
+	search := newBayesianSearch(model.BayesianConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		MaxLength:           model.NewLengthInBatches(100),
+		MaxTrials:           maxTrials,
+		MaxConcurrentTrials: 1,
+		NumStartupTrials:    5,
+		NumCandidates:       500,
+		Kernel:              model.Matern52Kernel,
+		LengthScale:         0.25,
+		Jitter:              1e-4,
+	}).(*bayesianSearch)
+	assert.ErrorContains(t, search.ObserveExternal(
+		map[string]interface{}{"x": 0.0, "y": 0.0}, math.Inf(1)), "non-finite")
+	// The best point of the grid has a metric of 1.5.
+	for x := -4.0; x <= 4; x += 2 {
+		for y := -4.0; y <= 4; y += 2 {
+			assert.NilError(t, search.ObserveExternal(
+				map[string]interface{}{"x": x, "y": y}, (x-1)*(x-1)+2*(y+0.5)*(y+0.5)))
+		}
+	}
+	assert.Equal(t, search.TrialsCreated, 0)
+
+	// With the grid already observed, the search goes straight to improving on it, without any
+	// random startup trials and without the grid using up any of its trials.
+	regrets := searchBowl(t, search)
+	assert.Equal(t, len(regrets), maxTrials)
+	assert.Assert(t, regrets[maxTrials-1] < 1.5, "regrets %v", regrets)
+}
+
+func TestBayesianSearcherRestarts(t *testing.T) {
+	config := model.BayesianConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		MaxLength:           model.NewLengthInBatches(100),
+		MaxTrials:           10,
+		MaxConcurrentTrials: 1,
+		NumStartupTrials:    2,
+		NumCandidates:       10,
+		Kernel:              model.Matern52Kernel,
+		LengthScale:         0.25,
+		Jitter:              1e-4,
+	}
+	for _, probability := range []float64{0, 0.2, 0.75} {
+		config.RestartProbability = probability
+		search := newBayesianSearch(config).(*bayesianSearch)
+		search.Observations = []modelObservation{
+			{Hparams: hparamSample{"x": -2.0}, Metric: 9},
+			{Hparams: hparamSample{"x": 3.0}, Metric: 4},
+		}
+		frequency := restartFrequency(t, search.propose)
+		assert.Assert(t, math.Abs(frequency-probability) < 0.03,
+			"restart probability %v, frequency %v", probability, frequency)
+	}
+
+	config.RestartProbability = 1
+	search := newBayesianSearch(config).(*bayesianSearch)
+	regrets := searchBowl(t, search)
+	assert.Equal(t, len(regrets), config.MaxTrials)
+	assert.Equal(t, len(search.Restarts), config.MaxTrials-config.NumStartupTrials)
+}
+
+func TestBayesianSearcherMonotone(t *testing.T) {
+	// The metric improves as x increases, although not by much compared to y, so that the search
+	// would otherwise also propose smaller values of x.
+	hparams := model.Hyperparameters{
+		"x": {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: 0, Maxval: 1}},
+		"y": {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: 0, Maxval: 1}},
+	}
+	metric := func(create Create) float64 {
+		x, y := create.Hparams["x"].(float64), create.Hparams["y"].(float64)
+		return 0.2*(1-x) + (y-0.3)*(y-0.3)
+	}
+	config := model.BayesianConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		MaxLength:           model.NewLengthInBatches(100),
+		MaxTrials:           30,
+		MaxConcurrentTrials: 1,
+		NumStartupTrials:    5,
+		NumCandidates:       100,
+		Kernel:              model.Matern52Kernel,
+		LengthScale:         0.25,
+		Jitter:              1e-4,
+	}
+	// search returns how many proposals had a smaller x than the best trial before them.
+	search := func(config model.BayesianConfig) int {
+		method := &recordingMethod{SearchMethod: newBayesianSearch(config)}
+		driver, err := newQueueDriver(method, hparams, nil)
+		assert.NilError(t, err)
+		creates := func() []Create {
+			var creates []Create
+			for _, op := range method.ops {
+				if create, ok := op.(Create); ok {
+					creates = append(creates, create)
+				}
+			}
+			return creates
+		}
+		driver.metricsFn = func(trialIndex, validations int) map[string]interface{} {
+			return map[string]interface{}{defaultMetric: metric(creates()[trialIndex])}
+		}
+		for len(driver.pending) > 0 {
+			_, err = driver.step()
+			assert.NilError(t, err)
+		}
+		assert.Equal(t, len(creates()), config.MaxTrials)
+
+		violations := 0
+		best := creates()[0]
+		for i, create := range creates() {
+			if i >= config.NumStartupTrials &&
+				create.Hparams["x"].(float64) < best.Hparams["x"].(float64) {
+				violations++
+			}
+			if metric(create) < metric(best) {
+				best = create
+			}
+		}
+		return violations
+	}
+
+	assert.Assert(t, search(config) > 0)
+	config.Monotone = map[string]model.Monotonicity{"x": model.IncreasingMonotonicity}
+	assert.Equal(t, search(config), 0)
+}
+
+func TestBayesianSearcherMonotoneCategorical(t *testing.T) {
+	search := newBayesianSearch(model.BayesianConfig{
+		Metric:           defaultMetric,
+		MaxLength:        model.NewLengthInBatches(100),
+		MaxTrials:        1,
+		NumStartupTrials: 1,
+		NumCandidates:    1,
+		Kernel:           model.Matern52Kernel,
+		LengthScale:      0.25,
+		Jitter:           1e-4,
+		Monotone:         map[string]model.Monotonicity{"optimizer": model.IncreasingMonotonicity},
+	})
+	_, err := newQueueDriver(search, listHyperparameters(), nil)
+	assert.ErrorContains(t, err, "monotone hyperparameter optimizer is not numeric")
+}
+
+func TestBayesianSearcherLoadPrior(t *testing.T) {
+	config := model.BayesianConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		MaxLength:           model.NewLengthInBatches(100),
+		MaxTrials:           5,
+		MaxConcurrentTrials: 1,
+		NumStartupTrials:    5,
+		NumCandidates:       500,
+		Kernel:              model.Matern52Kernel,
+		LengthScale:         0.25,
+		Jitter:              1e-4,
+		PriorWeight:         0.5,
+	}
+	var prior []Observation
+	for x := -4.0; x <= 4; x += 2 {
+		for y := -4.0; y <= 4; y += 2 {
+			prior = append(prior, Observation{
+				Hparams: map[string]interface{}{"x": x, "y": y},
+				Metric:  (x-1)*(x-1) + 2*(y+0.5)*(y+0.5),
+			})
+		}
+	}
+	coldStart := searchBowl(t, newBayesianSearch(config))
+
+	search := newBayesianSearch(config).(*bayesianSearch)
+	assert.ErrorContains(t, search.LoadPrior([]Observation{
+		{Hparams: map[string]interface{}{"x": 0.0, "y": 0.0}, Metric: math.Inf(-1)},
+	}), "non-finite")
+	assert.NilError(t, search.LoadPrior(prior))
+	for _, observation := range search.Observations {
+		assert.Equal(t, observation.Weight, 0.5)
+	}
+	warmStart := searchBowl(t, search)
+
+	// The 25 prior observations count for more than the startup trials, so the search improves on
+	// them from its first trial rather than sampling randomly.
+	assert.Equal(t, len(warmStart), config.MaxTrials)
+	assert.Assert(t, warmStart[0] != coldStart[0])
+	assert.Assert(t, warmStart[config.MaxTrials-1] < coldStart[config.MaxTrials-1],
+		"warm start: %v, cold start: %v", warmStart, coldStart)
+
+	// A down-weighted observation leaves the process less certain at its point than a trial would.
+	point := [][]float64{{0.5, 0.5}}
+	trial, err := fitGaussianProcess(search.kernel(), 0.25, 1e-4, point, []float64{1}, []float64{0})
+	assert.NilError(t, err)
+	weighted, err := fitGaussianProcess(search.kernel(), 0.25, 1e-4, point, []float64{1}, []float64{1})
+	assert.NilError(t, err)
+	trialMean, trialStd := trial.predict(point[0])
+	weightedMean, weightedStd := weighted.predict(point[0])
+	assert.Assert(t, weightedStd > trialStd, "%v <= %v", weightedStd, trialStd)
+	assert.Assert(t, math.Abs(weightedMean) < math.Abs(trialMean), "%v >= %v", weightedMean, trialMean)
+}
+
+func TestBayesianSearcherNonFiniteMetrics(t *testing.T) {
+	config := model.BayesianConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		MaxLength:           model.NewLengthInBatches(100),
+		MaxTrials:           12,
+		MaxConcurrentTrials: 1,
+		NumStartupTrials:    2,
+		NumCandidates:       10,
+		Kernel:              model.Matern52Kernel,
+		LengthScale:         0.25,
+		Jitter:              1e-4,
+	}
+	hparams := model.Hyperparameters{
+		"x": {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: 0, Maxval: 1}},
+	}
+	search := newBayesianSearch(config).(*bayesianSearch)
+	// Every other trial diverges, alternating between NaN and infinite metrics.
+	driver, err := newQueueDriver(search, hparams, func(trialIndex, _ int) float64 {
+		switch trialIndex % 4 {
+		case 1:
+			return math.NaN()
+		case 3:
+			return math.Inf(-1)
+		}
+		return float64(trialIndex)
+	})
+	assert.NilError(t, err)
+	for len(driver.pending) > 0 {
+		ops, stepErr := driver.step()
+		assert.NilError(t, stepErr)
+		for _, op := range ops {
+			if create, ok := op.(Create); ok {
+				assert.Assert(t, create.Hparams != nil)
+			}
+		}
+	}
+	assert.Equal(t, search.TrialsCompleted, config.MaxTrials)
+	assert.Equal(t, len(search.Observations), config.MaxTrials/2)
+
+	// A search restored with a non-finite observation still proposes candidates.
+	search = newBayesianSearch(config).(*bayesianSearch)
+	search.Observations = []modelObservation{
+		{Hparams: hparamSample{"x": 0.1}, Metric: math.NaN()},
+		{Hparams: hparamSample{"x": 0.5}, Metric: 1},
+		{Hparams: hparamSample{"x": 0.9}, Metric: 2},
+	}
+	proposal, err := search.maximizeImprovement(context{rand: nprand.New(0), hparams: hparams})
+	assert.NilError(t, err)
+	assert.Assert(t, proposal != nil)
+}
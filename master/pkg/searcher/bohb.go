@@ -0,0 +1,226 @@
+package searcher
+
+import (
+	"encoding/json"
+	"math"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// bohbSearch implements BOHB, which combines the bracketed successive halving of adaptive ASHA with
+// model-based proposals. See https://arxiv.org/abs/1807.01774 for details. Training is allocated
+// between trials exactly as by an adaptive ASHA search, but once NumStartupTrials trials have
+// reported a metric after training for the same length, new trials are proposed by TPE from the
+// metrics reported after the longest such length instead of being sampled randomly. As in the
+// paper, the densities of TPE are kernel density estimates without a prior, since the halving
+// already explores by starting many trials.
+type bohbSearch struct {
+	*tournamentSearch
+	model.BOHBConfig
+	bohbSearchState
+
+	// mu guards bohbSearchState and the brackets. The brackets' own locks do not cover their
+	// proposals, which read bohbSearchState, and ObserveExternal writes it from other goroutines.
+	mu sync.Mutex
+}
+
+// bohbTrial is what a BOHB search tracks about each trial: its hyperparameters, how long it has
+// trained, and how long it had trained when it last reported a metric.
+type bohbTrial struct {
+	Hparams  hparamSample `json:"hparams"`
+	Trained  int          `json:"trained"`
+	Observed int          `json:"observed"`
+}
+
+type bohbSearchState struct {
+	Trials map[RequestID]*bohbTrial `json:"trials"`
+	// Observations holds the metrics trials reported after training for each length, in units.
+	Observations map[int][]modelObservation `json:"observations"`
+}
+
+func newBOHBSearch(config model.BOHBConfig) SearchMethod {
+	brackets := adaptiveASHABrackets(config.AdaptiveASHAConfig())
+	s := &bohbSearch{
+		tournamentSearch: newTournamentSearch(brackets...),
+		BOHBConfig:       config,
+		bohbSearchState: bohbSearchState{
+			Trials:       make(map[RequestID]*bohbTrial),
+			Observations: make(map[int][]modelObservation),
+		},
+	}
+	for _, bracket := range brackets {
+		bracket.(*asyncHalvingSearch).propose = s.propose
+	}
+	return s
+}
+
+// propose returns the hyperparameters of a new trial in any bracket.
+func (s *bohbSearch) propose(ctx context) (hparamSample, error) {
+	budget := -1
+	for units, observations := range s.Observations {
+		if len(observations) >= s.NumStartupTrials && units > budget {
+			budget = units
+		}
+	}
+	if budget < 0 {
+		return sampleAll(ctx)
+	}
+	return proposeTPE(
+		ctx, kernelDensityEstimator, s.Observations[budget], s.Gamma, s.NumCandidates)
+}
+
+// record starts tracking the trials created by the operations.
+func (s *bohbSearch) record(ops []Operation, err error) ([]Operation, error) {
+	for _, op := range ops {
+		if create, ok := op.(Create); ok {
+			s.Trials[create.RequestID] = &bohbTrial{Hparams: create.Hparams}
+		}
+	}
+	return ops, err
+}
+
+func (s *bohbSearch) initialOperations(ctx context) ([]Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.record(s.tournamentSearch.initialOperations(ctx))
+}
+
+func (s *bohbSearch) trialCreated(ctx context, requestID RequestID) ([]Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.record(s.tournamentSearch.trialCreated(ctx, requestID))
+}
+
+func (s *bohbSearch) trainCompleted(
+	ctx context, requestID RequestID, train Train,
+) ([]Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if trial, ok := s.Trials[requestID]; ok {
+		trial.Trained += train.Length.Units
+	}
+	return s.record(s.tournamentSearch.trainCompleted(ctx, requestID, train))
+}
+
+func (s *bohbSearch) checkpointCompleted(
+	ctx context, requestID RequestID, checkpoint Checkpoint, metrics CheckpointMetrics,
+) ([]Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.record(s.tournamentSearch.checkpointCompleted(ctx, requestID, checkpoint, metrics))
+}
+
+func (s *bohbSearch) validationCompleted(
+	ctx context, requestID RequestID, validate Validate, metrics ValidationMetrics,
+) ([]Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ops, err := s.tournamentSearch.validationCompleted(ctx, requestID, validate, metrics)
+	if err != nil {
+		return nil, err
+	}
+	// Each trial is observed once per length it trains for, however often it validates then.
+	if trial, ok := s.Trials[requestID]; ok && trial.Trained > trial.Observed {
+		metric, metricErr := metrics.Metric(s.Metric)
+		if metricErr != nil {
+			return nil, metricErr
+		}
+		if !s.SmallerIsBetter {
+			metric *= -1
+		}
+		trial.Observed = trial.Trained
+		// Diverged trials tell the model nothing about where good hyperparameters are.
+		if !math.IsNaN(metric) && !math.IsInf(metric, 0) {
+			s.Observations[trial.Trained] = append(s.Observations[trial.Trained],
+				modelObservation{Hparams: trial.Hparams, Metric: metric})
+		}
+	}
+	return s.record(ops, nil)
+}
+
+func (s *bohbSearch) trialClosed(ctx context, requestID RequestID) ([]Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.record(s.tournamentSearch.trialClosed(ctx, requestID))
+}
+
+func (s *bohbSearch) trialExitedEarly(
+	ctx context, requestID RequestID, reason ExitedReason,
+) ([]Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.record(s.tournamentSearch.trialExitedEarly(ctx, requestID, reason))
+}
+
+func (s *bohbSearch) progress(unitsCompleted model.Length) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tournamentSearch.progress(unitsCompleted)
+}
+
+// ObserveExternal adds the result of a trial evaluated outside of the search, e.g., on another
+// cluster, to the observations that new trials are proposed from, as if the trial had trained for
+// MaxLength. The trial does not count as one of the MaxTrials trials of the search. It is safe to
+// call concurrently with the rest of the search.
+func (s *bohbSearch) ObserveExternal(hparams map[string]interface{}, metric float64) error {
+	if math.IsNaN(metric) || math.IsInf(metric, 0) {
+		return errors.Errorf("cannot observe a non-finite metric: %f", metric)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sample := make(hparamSample, len(hparams))
+	for name, value := range hparams {
+		sample[name] = value
+	}
+	if !s.SmallerIsBetter {
+		metric *= -1
+	}
+	units := s.MaxLength.Units
+	s.Observations[units] = append(s.Observations[units],
+		modelObservation{Hparams: sample, Metric: metric})
+	return nil
+}
+
+// Unit implements the model.InUnits interface.
+func (s *bohbSearch) Unit() model.Unit {
+	return s.BOHBConfig.Unit()
+}
+
+// bohbSnapshot is the serialized form of a bohbSearch.
+type bohbSnapshot struct {
+	Tournament json.RawMessage `json:"tournament"`
+	bohbSearchState
+}
+
+func (s *bohbSearch) Snapshot() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tournament, err := s.tournamentSearch.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(bohbSnapshot{Tournament: tournament, bohbSearchState: s.bohbSearchState})
+}
+
+func (s *bohbSearch) Restore(state []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var snapshot bohbSnapshot
+	if err := json.Unmarshal(state, &snapshot); err != nil {
+		return errors.Wrap(err, "failed to restore BOHB search state")
+	}
+	if err := s.tournamentSearch.Restore(snapshot.Tournament); err != nil {
+		return err
+	}
+	s.bohbSearchState = snapshot.bohbSearchState
+	if s.Trials == nil {
+		s.Trials = make(map[RequestID]*bohbTrial)
+	}
+	if s.Observations == nil {
+		s.Observations = make(map[int][]modelObservation)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package searcher
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// searchQuadraticWithBudget runs the search method to completion on a one-dimensional quadratic
+// with its minimum at x = 1, offset by how long the trial has trained, and returns the recorded
+// search method along with the value of x that each trial used, in order of creation.
+func searchQuadraticWithBudget(t *testing.T, search SearchMethod) (*recordingMethod, []float64) {
+	hparams := model.Hyperparameters{
+		"x": {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: -5, Maxval: 5}},
+	}
+	method := &recordingMethod{SearchMethod: search}
+	driver, err := newQueueDriver(method, hparams, nil)
+	assert.NilError(t, err)
+	xs := func() []float64 {
+		var xs []float64
+		for _, op := range method.ops {
+			if create, ok := op.(Create); ok {
+				xs = append(xs, create.Hparams["x"].(float64))
+			}
+		}
+		return xs
+	}
+	driver.metricsFn = func(trialIndex, validations int) map[string]interface{} {
+		x := xs()[trialIndex]
+		return map[string]interface{}{defaultMetric: (x-1)*(x-1) + 1/float64(validations+1)}
+	}
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+	return method, xs()
+}
+
+func TestBOHBSearcher(t *testing.T) {
+	config := model.BOHBConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		MaxLength:           model.NewLengthInBatches(256),
+		MaxTrials:           64,
+		Divisor:             4,
+		Mode:                model.StandardMode,
+		MaxRungs:            3,
+		MaxConcurrentTrials: 4,
+		NumStartupTrials:    10,
+		Gamma:               0.25,
+		NumCandidates:       24,
+	}
+	bohbMethod, bohb := searchQuadraticWithBudget(t, newBOHBSearch(config))
+	ashaMethod, asha := searchQuadraticWithBudget(
+		t, newAdaptiveASHASearch(config.AdaptiveASHAConfig()))
+	assert.Equal(t, len(bohb), config.MaxTrials)
+	assert.Equal(t, len(asha), config.MaxTrials)
+
+	// Until enough trials have reported, trials are sampled just as by adaptive ASHA.
+	for i := 0; i < config.MaxConcurrentTrials; i++ {
+		assert.Equal(t, bohb[i], asha[i])
+	}
+
+	// Afterwards, new trials concentrate near the optimum.
+	distance := func(xs []float64) float64 {
+		total := 0.0
+		for _, x := range xs {
+			total += math.Abs(x - 1)
+		}
+		return total / float64(len(xs))
+	}
+	late := config.MaxTrials / 2
+	assert.Assert(t, distance(bohb[late:]) < distance(asha[late:])/2,
+		"bohb: %v, adaptive ASHA: %v", distance(bohb[late:]), distance(asha[late:]))
+
+	// The halving structure is unchanged: each trial trains through a prefix of the rungs of one of
+	// the brackets, and every trial is closed once.
+	var rungUnits [][]int
+	for _, bracket := range adaptiveASHABrackets(config.AdaptiveASHAConfig()) {
+		rungUnits = append(rungUnits, bracket.(*asyncHalvingSearch).RungUnits())
+	}
+	for _, method := range []*recordingMethod{bohbMethod, ashaMethod} {
+		trained := make(map[RequestID][]int)
+		for _, op := range method.ops {
+			if train, ok := op.(Train); ok {
+				previous := 0
+				if units := trained[train.RequestID]; len(units) > 0 {
+					previous = units[len(units)-1]
+				}
+				trained[train.RequestID] = append(
+					trained[train.RequestID], previous+train.Length.Units)
+			}
+		}
+		assert.Equal(t, len(trained), config.MaxTrials)
+		for requestID, units := range trained {
+			found := false
+			for _, rungs := range rungUnits {
+				found = found ||
+					(len(units) <= len(rungs) && reflect.DeepEqual(units, rungs[:len(units)]))
+			}
+			assert.Assert(t, found, "trial %s trained for %v", requestID, units)
+		}
+		assert.Equal(t, len(method.closeCounts()), config.MaxTrials)
+		for _, count := range method.closeCounts() {
+			assert.Equal(t, count, 1)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package searcher
+
+import (
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// TrialMetricValue is the result a trial reported in a rung: its searcher metric, sign-adjusted so
+// that smaller is better, and the values of any tie-break metrics, as reported, followed by the
+// units the trial had trained when it reported, if it is to break ties by resource.
+type TrialMetricValue struct {
+	Metric     float64
+	Objectives []float64
+}
+
+// MetricComparator ranks the results of trials in a rung.
+type MetricComparator interface {
+	// Less returns whether a ranks ahead of b.
+	Less(a, b TrialMetricValue) bool
+}
+
+// scalarComparator ranks results by their searcher metric alone.
+type scalarComparator struct{}
+
+func (scalarComparator) Less(a, b TrialMetricValue) bool {
+	return a.Metric < b.Metric
+}
+
+// objectiveComparator ranks results by the value of one of their tie-break metrics, smaller first.
+// Results that lack the value, like those of trials that exited early, rank neither ahead of nor
+// behind any other.
+type objectiveComparator int
+
+func (c objectiveComparator) Less(a, b TrialMetricValue) bool {
+	i := int(c)
+	if i >= len(a.Objectives) || i >= len(b.Objectives) {
+		return false
+	}
+	return a.Objectives[i] < b.Objectives[i]
+}
+
+// negatedComparator reverses the ranking of another comparator.
+type negatedComparator struct {
+	MetricComparator
+}
+
+func (c negatedComparator) Less(a, b TrialMetricValue) bool {
+	return c.MetricComparator.Less(b, a)
+}
+
+// lexicographicComparator ranks results by the first of its comparators that tells them apart.
+type lexicographicComparator []MetricComparator
+
+func (c lexicographicComparator) Less(a, b TrialMetricValue) bool {
+	for _, comparator := range c {
+		switch {
+		case comparator.Less(a, b):
+			return true
+		case comparator.Less(b, a):
+			return false
+		}
+	}
+	return false
+}
+
+// newMetricComparator returns the comparator that ranks trials as configured: by the searcher
+// metric, then by each of the tie-break metrics in turn.
+func newMetricComparator(tiebreaks []model.Objective) MetricComparator {
+	if len(tiebreaks) == 0 {
+		return scalarComparator{}
+	}
+	comparators := lexicographicComparator{scalarComparator{}}
+	for i, tiebreak := range tiebreaks {
+		var comparator MetricComparator = objectiveComparator(i)
+		if !tiebreak.SmallerIsBetter {
+			comparator = negatedComparator{comparator}
+		}
+		comparators = append(comparators, comparator)
+	}
+	return comparators
+}
+
+// resourceComparator ranks results by the units trained, the objective after those of the tie-break
+// metrics, more first.
+func resourceComparator(tiebreaks []model.Objective) MetricComparator {
+	return negatedComparator{objectiveComparator(len(tiebreaks))}
+}
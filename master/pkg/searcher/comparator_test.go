@@ -0,0 +1,238 @@
+package searcher
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func TestMetricComparators(t *testing.T) {
+	a := TrialMetricValue{Metric: 0.1, Objectives: []float64{5}}
+	b := TrialMetricValue{Metric: 0.2, Objectives: []float64{1}}
+	exited := TrialMetricValue{Metric: ashaExitedMetricValue}
+
+	assert.Assert(t, scalarComparator{}.Less(a, b))
+	assert.Assert(t, !scalarComparator{}.Less(b, a))
+	assert.Assert(t, negatedComparator{scalarComparator{}}.Less(b, a))
+	assert.Assert(t, objectiveComparator(0).Less(b, a))
+	// A result without the value is neither ahead of nor behind any other.
+	assert.Assert(t, !objectiveComparator(0).Less(a, exited))
+	assert.Assert(t, !objectiveComparator(0).Less(exited, a))
+
+	lexicographic := lexicographicComparator{objectiveComparator(0), scalarComparator{}}
+	assert.Assert(t, lexicographic.Less(b, a))
+	tied := TrialMetricValue{Metric: 0.05, Objectives: []float64{5}}
+	assert.Assert(t, lexicographic.Less(tied, a))
+	assert.Assert(t, !lexicographic.Less(a, a))
+}
+
+func TestRungPromotionsLexicographic(t *testing.T) {
+	// The tuples in the order they rank: by the searcher metric, then larger accuracy first, then
+	// smaller latency first.
+	ranked := []TrialMetricValue{
+		{Metric: 0.1, Objectives: []float64{0.5, 3}},
+		{Metric: 0.2, Objectives: []float64{0.9, 2}},
+		{Metric: 0.2, Objectives: []float64{0.8, 1}},
+		{Metric: 0.2, Objectives: []float64{0.8, 2}},
+		{Metric: 0.3, Objectives: []float64{0.9, 1}},
+		{Metric: ashaExitedMetricValue},
+	}
+	less := newMetricComparator([]model.Objective{
+		{Metric: "accuracy", SmallerIsBetter: false},
+		{Metric: "latency", SmallerIsBetter: true},
+	})
+	r := &rung{}
+	var promoted []RequestID
+	// Report the tuples out of order and promote every trial once all of them are in, so that the
+	// promotions come in the order of the rung.
+	for _, i := range []int{3, 5, 0, 4, 2, 1} {
+		requestID := MustParse(fmt.Sprintf("00000000-0000-0000-0000-%012d", 6-i))
+		promotions, _, _ := r.promotionsAsync(less, requestID, ranked[i], 1, len(ranked))
+		promoted = append(promoted, promotions...)
+	}
+	var expected []RequestID
+	for i := range ranked {
+		expected = append(expected, MustParse(fmt.Sprintf("00000000-0000-0000-0000-%012d", 6-i)))
+	}
+	assert.DeepEqual(t, promoted, expected)
+	for i, trialMetric := range r.Metrics {
+		assert.DeepEqual(t, trialMetric.value(), ranked[i])
+	}
+}
+
+func TestASHASearcherTiebreakMetrics(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           3,
+		MaxConcurrentTrials: 3,
+		MinTrialsPerRung:    3,
+		TiebreakMetrics:     []model.Objective{{Metric: "accuracy", SmallerIsBetter: false}},
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	driver, err := newQueueDriver(search, nil, nil)
+	assert.NilError(t, err)
+	// Every trial reports the same searcher metric, so the accuracy decides which is promoted.
+	driver.metricsFn = func(trialIndex, _ int) map[string]interface{} {
+		return map[string]interface{}{defaultMetric: 0.5, "accuracy": float64(trialIndex)}
+	}
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+
+	var order []int
+	for _, trialMetric := range search.Rungs[0].Metrics {
+		order = append(order, driver.trialIndex[trialMetric.RequestID])
+	}
+	assert.DeepEqual(t, order, []int{2, 1, 0})
+	assert.Equal(t, len(search.Rungs[1].Metrics), 1)
+	assert.Equal(t, driver.trialIndex[search.Rungs[1].Metrics[0].RequestID], 2)
+	best := search.BestTrials(1)
+	assert.Equal(t, driver.trialIndex[best[0].RequestID], 2)
+
+	// A trial that does not report a tie-break metric fails the search, like a missing metric.
+	search = newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	driver, err = newQueueDriver(search, nil, func(int, int) float64 { return 0.5 })
+	assert.NilError(t, err)
+	for err == nil && len(driver.pending) > 0 {
+		_, err = driver.step()
+	}
+	assert.ErrorContains(t, err, "accuracy")
+}
+
+func TestASHASearcherTiebreakByResource(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           3,
+		MaxConcurrentTrials: 3,
+		MinTrialsPerRung:    3,
+		TiebreakByResource:  true,
+	}
+	// Every trial reports the same metric, having trained the units given by units by then, so the
+	// trials are ranked by the units they trained and the one that trained the most is promoted,
+	// whatever the order of their request IDs.
+	for _, c := range []struct {
+		units func(trialIndex int) int
+		order []int
+	}{
+		{func(trialIndex int) int { return 100 * (trialIndex + 1) }, []int{2, 1, 0}},
+		{func(trialIndex int) int { return 100 * (3 - trialIndex) }, []int{0, 1, 2}},
+	} {
+		search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+		driver, err := newQueueDriver(search, nil, nil)
+		assert.NilError(t, err)
+		units := c.units
+		driver.metricsFn = func(trialIndex, _ int) map[string]interface{} {
+			for requestID, index := range driver.trialIndex {
+				if index == trialIndex {
+					search.recordStep(requestID, model.NewLengthInBatches(units(trialIndex)))
+				}
+			}
+			return map[string]interface{}{defaultMetric: 0.5}
+		}
+		for len(driver.pending) > 0 {
+			_, err = driver.step()
+			assert.NilError(t, err)
+		}
+
+		var order []int
+		for _, trialMetric := range search.Rungs[0].Metrics {
+			order = append(order, driver.trialIndex[trialMetric.RequestID])
+		}
+		assert.DeepEqual(t, order, c.order)
+		assert.Equal(t, len(search.Rungs[1].Metrics), 1)
+		assert.Equal(t, driver.trialIndex[search.Rungs[1].Metrics[0].RequestID], c.order[0])
+	}
+
+	// Without the tie-break, ties are ranked by request ID.
+	config.TiebreakByResource = false
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	driver, err := newQueueDriver(search, nil, func(int, int) float64 { return 0.5 })
+	assert.NilError(t, err)
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+	metrics := search.Rungs[0].Metrics
+	for i := 1; i < len(metrics); i++ {
+		assert.Assert(t, metrics[i-1].RequestID.Before(metrics[i].RequestID))
+	}
+}
+
+func TestASHASearcherPenalties(t *testing.T) {
+	smallerIsBetter := true
+	config := model.AsyncHalvingConfig{
+		Metric:              "accuracy",
+		SmallerIsBetter:     false,
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           3,
+		MaxConcurrentTrials: 3,
+		MinTrialsPerRung:    3,
+	}
+	// The most accurate trial is far over the latency target of 10.
+	metricsFn := func(trialIndex, _ int) map[string]interface{} {
+		return map[string]interface{}{
+			"accuracy": []float64{0.9, 0.8, 0.7}[trialIndex],
+			"latency":  []float64{30, 5, 10}[trialIndex],
+		}
+	}
+	run := func(config model.AsyncHalvingConfig) ([]int, []float64) {
+		search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+		driver, err := newQueueDriver(search, nil, nil)
+		assert.NilError(t, err)
+		driver.metricsFn = metricsFn
+		for len(driver.pending) > 0 {
+			_, err = driver.step()
+			assert.NilError(t, err)
+		}
+		var order []int
+		var scores []float64
+		for _, trialMetric := range search.Rungs[0].Metrics {
+			order = append(order, driver.trialIndex[trialMetric.RequestID])
+			scores = append(scores, trialMetric.Metric)
+		}
+		assert.Equal(t, len(search.Rungs[1].Metrics), 1)
+		assert.Equal(t, driver.trialIndex[search.Rungs[1].Metrics[0].RequestID], order[0])
+		return order, scores
+	}
+
+	order, _ := run(config)
+	assert.DeepEqual(t, order, []int{0, 1, 2})
+
+	// Each unit of latency over the target costs 0.02 of accuracy, so the first trial scores 0.5 and
+	// ranks last; the third trial is right at the target and is not penalized.
+	config.Penalties = []model.MetricPenalty{
+		{Metric: "latency", Target: 10, SmallerIsBetter: &smallerIsBetter, Weight: 0.02},
+	}
+	order, scores := run(config)
+	assert.DeepEqual(t, order, []int{1, 2, 0})
+	for i, expected := range []float64{-0.8, -0.7, -0.5} {
+		assert.Assert(t, math.Abs(scores[i]-expected) < 1e-9, scores)
+	}
+
+	// A trial that does not report the constrained metric fails the search, like a missing metric.
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	driver, err := newQueueDriver(search, nil, nil)
+	assert.NilError(t, err)
+	driver.metricsFn = func(int, int) map[string]interface{} {
+		return map[string]interface{}{"accuracy": 0.5}
+	}
+	for err == nil && len(driver.pending) > 0 {
+		_, err = driver.step()
+	}
+	assert.ErrorContains(t, err, "latency")
+}
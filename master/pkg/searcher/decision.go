@@ -0,0 +1,32 @@
+package searcher
+
+// DecisionKind is the kind of decision a search method made about a trial.
+type DecisionKind string
+
+const (
+	// TrialCreatedDecision is made when a search method creates a trial.
+	TrialCreatedDecision DecisionKind = "TRIAL_CREATED"
+	// TrialPromotedDecision is made when a search method promotes a trial to a higher rung.
+	TrialPromotedDecision DecisionKind = "TRIAL_PROMOTED"
+	// TrialClosedDecision is made when a search method closes a trial.
+	TrialClosedDecision DecisionKind = "TRIAL_CLOSED"
+)
+
+// Decision describes a single decision a search method made about a trial. The decisions of a
+// search, in order, are enough to reconstruct which trials reached which rungs and why.
+type Decision struct {
+	Kind      DecisionKind `json:"kind"`
+	RequestID RequestID    `json:"request_id"`
+	// Rung is the rung a trial was created in or promoted to, or the rung it was in when closed.
+	Rung int `json:"rung"`
+	// Metric is the metric the trial reported in the rung it was promoted from or closed in, as
+	// reported by the trial. It is nil for created trials and trials that exited early or diverged.
+	Metric *float64 `json:"metric,omitempty"`
+	// TriggeredBy is the trial whose validation or early exit prompted the decision, if any.
+	TriggeredBy *RequestID `json:"triggered_by,omitempty"`
+}
+
+// EventSink receives the decisions of a search method as it makes them.
+type EventSink interface {
+	Decided(Decision)
+}
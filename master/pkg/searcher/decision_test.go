@@ -0,0 +1,77 @@
+package searcher
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+type recordingSink struct {
+	decisions []Decision
+}
+
+func (r *recordingSink) Decided(decision Decision) {
+	r.decisions = append(r.decisions, decision)
+}
+
+func TestASHASearcherDecisions(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:          defaultMetric,
+		SmallerIsBetter: false,
+		NumRungs:        2,
+		MaxLength:       model.NewLengthInBatches(200),
+		Divisor:         2,
+		MaxTrials:       2,
+	}
+	sink := &recordingSink{}
+	searcher := NewSearcher(0, newAsyncHalvingSearch(config), nil, nil)
+	searcher.SetEventSink(sink)
+
+	ops, err := searcher.InitialOperations()
+	assert.NilError(t, err)
+	first, second := ops[0].(Create), ops[3].(Create)
+	_, err = searcher.TrialCreated(first, 1)
+	assert.NilError(t, err)
+	_, err = searcher.TrialCreated(second, 2)
+	assert.NilError(t, err)
+
+	validate := func(trialID int, requestID RequestID, metric float64) {
+		_, err := searcher.OperationCompleted(trialID, NewValidate(requestID), &ValidationMetrics{
+			Metrics: map[string]interface{}{defaultMetric: metric},
+		})
+		assert.NilError(t, err)
+	}
+	// The second trial is better; once both have reported, it is promoted and the first is closed.
+	validate(1, first.RequestID, 0.5)
+	validate(2, second.RequestID, 0.8)
+	validate(2, second.RequestID, 0.9)
+
+	firstMetric, secondMetric, topMetric := 0.5, 0.8, 0.9
+	assert.DeepEqual(t, sink.decisions, []Decision{
+		{Kind: TrialCreatedDecision, RequestID: first.RequestID},
+		{Kind: TrialCreatedDecision, RequestID: second.RequestID},
+		{
+			Kind:        TrialPromotedDecision,
+			RequestID:   second.RequestID,
+			Rung:        1,
+			Metric:      &secondMetric,
+			TriggeredBy: &second.RequestID,
+		},
+		{
+			Kind:        TrialClosedDecision,
+			RequestID:   first.RequestID,
+			Rung:        0,
+			Metric:      &firstMetric,
+			TriggeredBy: &second.RequestID,
+		},
+		{
+			Kind:        TrialClosedDecision,
+			RequestID:   second.RequestID,
+			Rung:        1,
+			Metric:      &topMetric,
+			TriggeredBy: &second.RequestID,
+		},
+	})
+}
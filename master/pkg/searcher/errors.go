@@ -0,0 +1,78 @@
+package searcher
+
+// ErrorKind classifies the failures of the searcher by who is to fix them.
+type ErrorKind string
+
+const (
+	// ConfigErrorKind is a mistake in the searcher configuration of the experiment.
+	ConfigErrorKind ErrorKind = "config"
+	// MetricErrorKind is a validation metric a trial reported that the searcher cannot use, e.g.,
+	// because it is missing or not a number; it is usually a mistake in the model's validation code
+	// or in the experiment configuration.
+	MetricErrorKind ErrorKind = "metric"
+	// InternalErrorKind is a violation of an invariant of the searcher, i.e., a bug.
+	InternalErrorKind ErrorKind = "internal"
+)
+
+// Error is implemented by the errors the searcher returns for failures of a known kind, so that the
+// master can tell a user error apart from an internal failure, e.g., to decide what state to leave
+// the experiment in.
+type Error interface {
+	error
+	Kind() ErrorKind
+}
+
+// ConfigError is returned when the searcher configuration cannot be used.
+type ConfigError struct {
+	Err error
+}
+
+func (e ConfigError) Error() string { return e.Err.Error() }
+
+// Cause returns the underlying error.
+func (e ConfigError) Cause() error { return e.Err }
+
+// Kind implements the Error interface.
+func (ConfigError) Kind() ErrorKind { return ConfigErrorKind }
+
+// MetricError is returned when a trial reports a validation metric that the searcher cannot use.
+type MetricError struct {
+	Err error
+}
+
+func (e MetricError) Error() string { return e.Err.Error() }
+
+// Cause returns the underlying error.
+func (e MetricError) Cause() error { return e.Err }
+
+// Kind implements the Error interface.
+func (MetricError) Kind() ErrorKind { return MetricErrorKind }
+
+// InternalError is returned when the searcher finds one of its invariants violated.
+type InternalError struct {
+	Err error
+}
+
+func (e InternalError) Error() string { return "internal error: " + e.Err.Error() }
+
+// Cause returns the underlying error.
+func (e InternalError) Cause() error { return e.Err }
+
+// Kind implements the Error interface.
+func (InternalError) Kind() ErrorKind { return InternalErrorKind }
+
+// ErrorKindOf returns the kind of the outermost Error in the chain of causes of the error, or false
+// if there is none.
+func ErrorKindOf(err error) (ErrorKind, bool) {
+	for err != nil {
+		if searcherErr, ok := err.(Error); ok {
+			return searcherErr.Kind(), true
+		}
+		causer, ok := err.(interface{ Cause() error })
+		if !ok {
+			break
+		}
+		err = causer.Cause()
+	}
+	return "", false
+}
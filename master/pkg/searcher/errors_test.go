@@ -0,0 +1,109 @@
+package searcher
+
+import (
+	"math"
+	"testing"
+
+	"github.com/pkg/errors"
+	"gotest.tools/assert"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func assertErrorKind(t *testing.T, err error, expected ErrorKind) {
+	t.Helper()
+	assert.Assert(t, err != nil)
+	kind, ok := ErrorKindOf(err)
+	assert.Assert(t, ok, "error has no kind: %v", err)
+	assert.Equal(t, kind, expected, "error: %v", err)
+}
+
+func TestErrorKindOf(t *testing.T) {
+	_, ok := ErrorKindOf(nil)
+	assert.Assert(t, !ok)
+	_, ok = ErrorKindOf(errors.New("plain"))
+	assert.Assert(t, !ok)
+
+	// The kind survives the context callers add, and the message is unchanged but for the prefix
+	// of internal errors.
+	err := errors.Wrap(errors.WithStack(MetricError{Err: errors.New("bad metric")}), "context")
+	assertErrorKind(t, err, MetricErrorKind)
+	assert.Error(t, err, "context: bad metric")
+	assert.Error(t, InternalError{Err: errors.New("broken")}, "internal error: broken")
+}
+
+func TestConfigErrors(t *testing.T) {
+	_, err := NewSearchMethod(model.SearcherConfig{})
+	assertErrorKind(t, err, ConfigErrorKind)
+
+	_, err = NewSearchMethod(model.SearcherConfig{
+		AsyncHalvingConfig: &model.AsyncHalvingConfig{Metric: defaultMetric, NumRungs: 2},
+	})
+	assertErrorKind(t, err, ConfigErrorKind)
+
+	_, err = transformMetric("square", 1)
+	assertErrorKind(t, err, ConfigErrorKind)
+}
+
+func TestMetricErrors(t *testing.T) {
+	metrics := ValidationMetrics{Metrics: map[string]interface{}{"loss": 0.5, "label": "cat"}}
+	_, err := metrics.Metric("accuracy")
+	assertErrorKind(t, err, MetricErrorKind)
+	// A missing metric is still recognizable as such.
+	assert.Assert(t, IsMissingMetric(err))
+
+	_, err = metrics.Metric("label")
+	assertErrorKind(t, err, MetricErrorKind)
+	assert.Assert(t, !IsMissingMetric(err))
+
+	_, err = metrics.AggregateMetric(model.MetricAggregationConfig{
+		Mode: model.MeanAggregation, Metrics: []string{"loss", "accuracy"},
+	})
+	assertErrorKind(t, err, MetricErrorKind)
+
+	config := model.AsyncHalvingConfig{
+		Metric:                defaultMetric,
+		NumRungs:              2,
+		MaxLength:             model.NewLengthInBatches(200),
+		Divisor:               2,
+		MaxTrials:             2,
+		FailOnNonFiniteMetric: true,
+	}
+	searcher := NewSearcher(0, newAsyncHalvingSearch(config), nil, nil)
+	ops, err := searcher.InitialOperations()
+	assert.NilError(t, err)
+	create := ops[0].(Create)
+	_, err = searcher.TrialCreated(create, 1)
+	assert.NilError(t, err)
+	_, err = searcher.OperationCompleted(1, NewValidate(create.RequestID), &ValidationMetrics{
+		Metrics: map[string]interface{}{defaultMetric: math.Inf(1)},
+	})
+	assertErrorKind(t, err, MetricErrorKind)
+}
+
+func TestInternalErrors(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:    defaultMetric,
+		NumRungs:  2,
+		MaxLength: model.NewLengthInBatches(200),
+		Divisor:   2,
+		MaxTrials: 2,
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	searcher := NewSearcher(0, search, nil, nil)
+	ops, err := searcher.InitialOperations()
+	assert.NilError(t, err)
+	create := ops[0].(Create)
+
+	_, err = searcher.OperationCompleted(7, NewValidate(create.RequestID), &ValidationMetrics{})
+	assertErrorKind(t, err, InternalErrorKind)
+	_, err = searcher.TrialExitedEarly(7, Errored)
+	assertErrorKind(t, err, InternalErrorKind)
+
+	// Recording a second metric for a trial in a rung breaks an invariant of the search.
+	_, err = searcher.TrialCreated(create, 1)
+	assert.NilError(t, err)
+	search.Rungs[0].insertMetric(search.comparator, create.RequestID, TrialMetricValue{Metric: 1})
+	_, err = search.promoteAsync(searcher.context(), create.RequestID, 1)
+	assertErrorKind(t, err, InternalErrorKind)
+}
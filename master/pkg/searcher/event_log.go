@@ -33,6 +33,8 @@ type EventLog struct {
 	TrialsClosed    int
 	TrialIDs        map[RequestID]int
 	RequestIDs      map[int]RequestID
+	// TrialMetadata is the metadata of the Create operation of each trial that had any.
+	TrialMetadata map[RequestID]map[string]string
 }
 
 // NewEventLog initializes an empty event log.
@@ -45,15 +47,19 @@ func NewEventLog(unit model.Unit) *EventLog {
 		TrialsClosed:        0,
 		TrialIDs:            map[RequestID]int{},
 		RequestIDs:          map[int]RequestID{},
+		TrialMetadata:       map[RequestID]map[string]string{},
 	}
 }
 
 // OperationsCreated records that the provided operations have been created by the searcher.
 func (el *EventLog) OperationsCreated(operations ...Operation) {
 	for _, operation := range operations {
-		switch operation.(type) {
+		switch operation := operation.(type) {
 		case Create:
 			el.TrialsRequested++
+			if len(operation.Metadata) > 0 {
+				el.TrialMetadata[operation.RequestID] = operation.Metadata
+			}
 		case Shutdown:
 			el.Shutdown = true
 		}
@@ -0,0 +1,149 @@
+package searcher
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// TrialOutcome is how a trial of an asynchronous halving search ended, if it has.
+type TrialOutcome string
+
+const (
+	// RunningOutcome is the outcome of trials that are still in the search.
+	RunningOutcome TrialOutcome = "running"
+	// CompletedOutcome is the outcome of trials that reported a metric in the top rung, or in the
+	// highest rung trials are promoted to if it is capped.
+	CompletedOutcome TrialOutcome = "completed"
+	// StoppedOutcome is the outcome of trials that were closed without being promoted.
+	StoppedOutcome TrialOutcome = "stopped"
+	// ExitedEarlyOutcome is the outcome of trials that exited early.
+	ExitedEarlyOutcome TrialOutcome = "exited_early"
+	// CancelledOutcome is the outcome of trials that were closed while they were still training,
+	// once they could no longer be promoted.
+	CancelledOutcome TrialOutcome = "cancelled"
+)
+
+// TrialResult is everything an asynchronous halving search knows about one of its trials.
+type TrialResult struct {
+	RequestID RequestID              `json:"request_id"`
+	Hparams   map[string]interface{} `json:"hparams"`
+	// Metric is the metric the trial reported in the highest rung it reported in, as reported by
+	// the trial but for any metric transform; it is nil if the trial has not reported a metric or
+	// exited early or diverged there.
+	Metric  *float64     `json:"metric"`
+	Rung    int          `json:"rung"`
+	Outcome TrialOutcome `json:"outcome"`
+}
+
+// Results returns the results of every trial created so far, in request ID order.
+func (s *asyncHalvingSearch) Results() []TrialResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.results()
+}
+
+func (s *asyncHalvingSearch) results() []TrialResult {
+	metrics := make(map[RequestID]*float64)
+	completed := make(map[RequestID]bool)
+	for rungIndex := len(s.Rungs) - 1; rungIndex >= 0; rungIndex-- {
+		for _, trialMetric := range s.Rungs[rungIndex].Metrics {
+			requestID := trialMetric.RequestID
+			if _, ok := metrics[requestID]; !ok {
+				metrics[requestID] = s.reportedMetric(rungIndex, requestID)
+			}
+			if rungIndex == s.topRung() {
+				completed[requestID] = true
+			}
+		}
+	}
+
+	results := make([]TrialResult, 0, len(s.TrialRungs))
+	for requestID, rungIndex := range s.TrialRungs {
+		result := TrialResult{
+			RequestID: requestID,
+			Hparams:   s.TrialHparams[requestID],
+			Metric:    metrics[requestID],
+			Rung:      rungIndex,
+			Outcome:   RunningOutcome,
+		}
+		switch {
+		case s.CancelledTrials[requestID]:
+			result.Outcome = CancelledOutcome
+		case s.EarlyExitTrials[requestID]:
+			result.Outcome = ExitedEarlyOutcome
+		case completed[requestID]:
+			result.Outcome = CompletedOutcome
+		case s.ClosedTrials[requestID]:
+			result.Outcome = StoppedOutcome
+		}
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].RequestID.Before(results[j].RequestID)
+	})
+	return results
+}
+
+// ExportResults writes the results of every trial created so far to w, for offline analysis. The
+// format is either "json", an array of TrialResult, or "csv", with one row per trial and one
+// column per hyperparameter after the request_id, rung, outcome, and metric columns.
+func (s *asyncHalvingSearch) ExportResults(w io.Writer, format string) error {
+	results := s.Results()
+	switch format {
+	case "json":
+		return json.NewEncoder(w).Encode(results)
+	case "csv":
+		return writeResultsCSV(w, results)
+	default:
+		return errors.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// writeResultsCSV writes the results as CSV. Hyperparameter columns are sorted by name and are
+// empty for trials without a value for them, e.g., because of a conditional hyperparameter.
+func writeResultsCSV(w io.Writer, results []TrialResult) error {
+	names := make(map[string]bool)
+	for _, result := range results {
+		for name := range result.Hparams {
+			names[name] = true
+		}
+	}
+	hparamNames := make([]string, 0, len(names))
+	for name := range names {
+		hparamNames = append(hparamNames, name)
+	}
+	sort.Strings(hparamNames)
+
+	writer := csv.NewWriter(w)
+	header := append([]string{"request_id", "rung", "outcome", "metric"}, hparamNames...)
+	if err := writer.Write(header); err != nil {
+		return errors.Wrap(err, "failed to write results header")
+	}
+	for _, result := range results {
+		metric := ""
+		if result.Metric != nil {
+			metric = strconv.FormatFloat(*result.Metric, 'g', -1, 64)
+		}
+		record := []string{
+			result.RequestID.String(), strconv.Itoa(result.Rung), string(result.Outcome), metric,
+		}
+		for _, name := range hparamNames {
+			value := ""
+			if hparam, ok := result.Hparams[name]; ok {
+				value = fmt.Sprint(hparam)
+			}
+			record = append(record, value)
+		}
+		if err := writer.Write(record); err != nil {
+			return errors.Wrapf(err, "failed to write results of trial %s", result.RequestID)
+		}
+	}
+	writer.Flush()
+	return errors.Wrap(writer.Error(), "failed to write results")
+}
@@ -0,0 +1,97 @@
+package searcher
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// exportedSearch runs a small asynchronous halving search where larger is better to completion.
+func exportedSearch(t *testing.T) *asyncHalvingSearch {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     false,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           9,
+		MaxConcurrentTrials: 3,
+	}
+	hparams := model.Hyperparameters{
+		"lr":     {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: 0.001, Maxval: 0.1}},
+		"layers": {IntHyperparameter: &model.IntHyperparameter{Minval: 1, Maxval: 4}},
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	driver, err := newQueueDriver(search, hparams, func(trialIndex, _ int) float64 {
+		return float64(trialIndex + 1)
+	})
+	assert.NilError(t, err)
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+	return search
+}
+
+func TestASHASearcherExportJSON(t *testing.T) {
+	search := exportedSearch(t)
+	var buf bytes.Buffer
+	assert.NilError(t, search.ExportResults(&buf, "json"))
+
+	var results []map[string]interface{}
+	assert.NilError(t, json.Unmarshal(buf.Bytes(), &results))
+	assert.Equal(t, len(results), 9)
+	outcomes := make(map[string]int)
+	for _, result := range results {
+		for _, field := range []string{"request_id", "hparams", "metric", "rung", "outcome"} {
+			_, ok := result[field]
+			assert.Assert(t, ok, "missing %s in %v", field, result)
+		}
+		hparams := result["hparams"].(map[string]interface{})
+		assert.Equal(t, len(hparams), 2)
+		// Metrics are reported in the direction of the trials, not negated.
+		assert.Assert(t, result["metric"].(float64) >= 1, "metric %v", result["metric"])
+		outcomes[result["outcome"].(string)]++
+	}
+	// Every trial that reported in the top rung completed, and the rest were stopped below it.
+	completed := len(search.Rungs[2].Metrics)
+	assert.DeepEqual(t, outcomes, map[string]int{
+		string(CompletedOutcome): completed, string(StoppedOutcome): 9 - completed,
+	})
+
+	// The round trip preserves the results.
+	var decoded []TrialResult
+	assert.NilError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	expected := search.Results()
+	assert.Equal(t, len(decoded), len(expected))
+	for i := range decoded {
+		assert.Equal(t, decoded[i].RequestID, expected[i].RequestID)
+		assert.Equal(t, *decoded[i].Metric, *expected[i].Metric)
+		assert.Equal(t, decoded[i].Rung, expected[i].Rung)
+		assert.Equal(t, decoded[i].Outcome, expected[i].Outcome)
+	}
+}
+
+func TestASHASearcherExportCSV(t *testing.T) {
+	search := exportedSearch(t)
+	var buf bytes.Buffer
+	assert.NilError(t, search.ExportResults(&buf, "csv"))
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	assert.NilError(t, err)
+	assert.Equal(t, len(records), 1+9)
+	assert.DeepEqual(t, records[0],
+		[]string{"request_id", "rung", "outcome", "metric", "layers", "lr"})
+	for _, record := range records[1:] {
+		for _, value := range record {
+			assert.Assert(t, value != "", "record %v", record)
+		}
+	}
+
+	assert.ErrorContains(t, search.ExportResults(&buf, "xml"), "unsupported export format")
+}
@@ -0,0 +1,122 @@
+package searcher
+
+import (
+	"time"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/nprand"
+)
+
+// SearchContext is what a search method implemented outside of this package is given with each of
+// its callbacks.
+type SearchContext struct {
+	ctx context
+}
+
+// Rand returns the random state that the search method should draw from, e.g., to create request
+// IDs with NewCreate, so that the search is reproducible from the seed of the experiment.
+func (c SearchContext) Rand() *nprand.State {
+	return c.ctx.rand
+}
+
+// Hyperparameters returns the hyperparameters of the experiment.
+func (c SearchContext) Hyperparameters() model.Hyperparameters {
+	return c.ctx.hparams
+}
+
+// SampleHyperparameters samples a value of every active hyperparameter of the experiment that
+// satisfies the constraints of the experiment, as random search does.
+func (c SearchContext) SampleHyperparameters() (map[string]interface{}, error) {
+	return sampleAll(c.ctx)
+}
+
+// Now returns the current time, as the searcher tells it.
+func (c SearchContext) Now() time.Time {
+	return c.ctx.now()
+}
+
+// ExternalSearchMethod is the exported counterpart of SearchMethod, which search methods outside
+// of this package implement to be registered with RegisterSearchMethod through AdaptSearchMethod.
+// Each method corresponds to the SearchMethod callback of the same name.
+type ExternalSearchMethod interface {
+	InitialOperations(ctx SearchContext) ([]Operation, error)
+	TrialCreated(ctx SearchContext, requestID RequestID) ([]Operation, error)
+	TrainCompleted(ctx SearchContext, requestID RequestID, train Train) ([]Operation, error)
+	CheckpointCompleted(
+		ctx SearchContext, requestID RequestID, checkpoint Checkpoint, metrics CheckpointMetrics,
+	) ([]Operation, error)
+	ValidationCompleted(
+		ctx SearchContext, requestID RequestID, validate Validate, metrics ValidationMetrics,
+	) ([]Operation, error)
+	TrialClosed(ctx SearchContext, requestID RequestID) ([]Operation, error)
+	TrialExitedEarly(
+		ctx SearchContext, requestID RequestID, reason ExitedReason,
+	) ([]Operation, error)
+	Progress(totalUnitsCompleted model.Length) float64
+	Snapshot() ([]byte, error)
+	Restore(state []byte) error
+	model.InUnits
+}
+
+// AdaptSearchMethod returns a SearchMethod that relays each of its callbacks to the external
+// search method, e.g., for a SearchMethodFactory to return.
+func AdaptSearchMethod(method ExternalSearchMethod) SearchMethod {
+	return &externalSearch{method: method}
+}
+
+// externalSearch adapts an ExternalSearchMethod to the SearchMethod interface.
+type externalSearch struct {
+	method ExternalSearchMethod
+}
+
+func (s *externalSearch) initialOperations(ctx context) ([]Operation, error) {
+	return s.method.InitialOperations(SearchContext{ctx})
+}
+
+func (s *externalSearch) trialCreated(ctx context, requestID RequestID) ([]Operation, error) {
+	return s.method.TrialCreated(SearchContext{ctx}, requestID)
+}
+
+func (s *externalSearch) trainCompleted(
+	ctx context, requestID RequestID, train Train,
+) ([]Operation, error) {
+	return s.method.TrainCompleted(SearchContext{ctx}, requestID, train)
+}
+
+func (s *externalSearch) checkpointCompleted(
+	ctx context, requestID RequestID, checkpoint Checkpoint, metrics CheckpointMetrics,
+) ([]Operation, error) {
+	return s.method.CheckpointCompleted(SearchContext{ctx}, requestID, checkpoint, metrics)
+}
+
+func (s *externalSearch) validationCompleted(
+	ctx context, requestID RequestID, validate Validate, metrics ValidationMetrics,
+) ([]Operation, error) {
+	return s.method.ValidationCompleted(SearchContext{ctx}, requestID, validate, metrics)
+}
+
+func (s *externalSearch) trialClosed(ctx context, requestID RequestID) ([]Operation, error) {
+	return s.method.TrialClosed(SearchContext{ctx}, requestID)
+}
+
+func (s *externalSearch) trialExitedEarly(
+	ctx context, requestID RequestID, reason ExitedReason,
+) ([]Operation, error) {
+	return s.method.TrialExitedEarly(SearchContext{ctx}, requestID, reason)
+}
+
+func (s *externalSearch) progress(totalUnitsCompleted model.Length) float64 {
+	return s.method.Progress(totalUnitsCompleted)
+}
+
+func (s *externalSearch) Snapshot() ([]byte, error) {
+	return s.method.Snapshot()
+}
+
+func (s *externalSearch) Restore(state []byte) error {
+	return s.method.Restore(state)
+}
+
+func (s *externalSearch) Unit() model.Unit {
+	return s.method.Unit()
+}
@@ -0,0 +1,197 @@
+package searcher
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// fallbackSearch runs an asynchronous halving search unless, once it sees the hyperparameters of
+// the experiment, the space is so small that halving would mostly spend trials on configurations
+// that random search with early stopping could tell apart just as well; it then runs that random
+// search instead. All callbacks are sent to whichever of the two it decided on.
+type fallbackSearch struct {
+	model.AsyncHalvingConfig
+	halving SearchMethod
+	random  SearchMethod
+	fallbackSearchState
+}
+
+type fallbackSearchState struct {
+	Decided  bool `json:"decided"`
+	FellBack bool `json:"fell_back"`
+}
+
+func newFallbackSearch(config model.AsyncHalvingConfig) SearchMethod {
+	return &fallbackSearch{
+		AsyncHalvingConfig: config,
+		halving:            newAsyncHalvingSearch(config),
+		random:             newRandomSearch(config.AutoFallback.RandomConfig(config)),
+	}
+}
+
+// fallsBack returns whether the hyperparameter space is small enough to fall back to random
+// search: every hyperparameter that is not constant is continuous, and there are no more of them
+// than the configured number of dimensions.
+func (s *fallbackSearch) fallsBack(hparams model.Hyperparameters) bool {
+	dimensions := 0
+	for _, hparam := range hparams {
+		switch {
+		case hparam.ConstHyperparameter != nil:
+			continue
+		case hparam.DoubleHyperparameter != nil, hparam.LogHyperparameter != nil:
+			dimensions++
+		default:
+			return false
+		}
+	}
+	return dimensions <= s.AutoFallback.MaxDimensions
+}
+
+func (s *fallbackSearch) active() SearchMethod {
+	if s.FellBack {
+		return s.random
+	}
+	return s.halving
+}
+
+func (s *fallbackSearch) initialOperations(ctx context) ([]Operation, error) {
+	if !s.Decided {
+		s.Decided = true
+		s.FellBack = s.fallsBack(ctx.hparams)
+		if s.FellBack {
+			log.Warnf("falling back to random search: the %d hyperparameters are all constant "+
+				"or continuous, with at most %d that are not constant",
+				len(ctx.hparams), s.AutoFallback.MaxDimensions)
+		} else {
+			log.Debugf("not falling back to random search over %d hyperparameters", len(ctx.hparams))
+		}
+	}
+	return s.active().initialOperations(ctx)
+}
+
+func (s *fallbackSearch) trialCreated(ctx context, requestID RequestID) ([]Operation, error) {
+	return s.active().trialCreated(ctx, requestID)
+}
+
+func (s *fallbackSearch) trainCompleted(
+	ctx context, requestID RequestID, train Train,
+) ([]Operation, error) {
+	return s.active().trainCompleted(ctx, requestID, train)
+}
+
+func (s *fallbackSearch) checkpointCompleted(
+	ctx context, requestID RequestID, checkpoint Checkpoint, metrics CheckpointMetrics,
+) ([]Operation, error) {
+	return s.active().checkpointCompleted(ctx, requestID, checkpoint, metrics)
+}
+
+func (s *fallbackSearch) validationCompleted(
+	ctx context, requestID RequestID, validate Validate, metrics ValidationMetrics,
+) ([]Operation, error) {
+	return s.active().validationCompleted(ctx, requestID, validate, metrics)
+}
+
+func (s *fallbackSearch) trialClosed(ctx context, requestID RequestID) ([]Operation, error) {
+	return s.active().trialClosed(ctx, requestID)
+}
+
+func (s *fallbackSearch) trialExitedEarly(
+	ctx context, requestID RequestID, reason ExitedReason,
+) ([]Operation, error) {
+	return s.active().trialExitedEarly(ctx, requestID, reason)
+}
+
+func (s *fallbackSearch) progress(totalUnitsCompleted model.Length) float64 {
+	return s.active().progress(totalUnitsCompleted)
+}
+
+// recordCost implements the costRecorder interface.
+func (s *fallbackSearch) recordCost(requestID RequestID, duration time.Duration, batches int) {
+	if recorder, ok := s.active().(costRecorder); ok {
+		recorder.recordCost(requestID, duration, batches)
+	}
+}
+
+// recordStep implements the stepRecorder interface.
+func (s *fallbackSearch) recordStep(requestID RequestID, length model.Length) {
+	if recorder, ok := s.active().(stepRecorder); ok {
+		recorder.recordStep(requestID, length)
+	}
+}
+
+// checkTimeouts implements the timeoutChecker interface.
+func (s *fallbackSearch) checkTimeouts(ctx context) ([]Operation, error) {
+	if checker, ok := s.active().(timeoutChecker); ok {
+		return checker.checkTimeouts(ctx)
+	}
+	return nil, nil
+}
+
+// pause implements the pauser interface. Random search cannot be paused, so once the search has
+// fallen back to it, pause does nothing and paused keeps returning false.
+func (s *fallbackSearch) pause() {
+	if p, ok := s.active().(pauser); ok {
+		p.pause()
+	}
+}
+
+// resume implements the pauser interface.
+func (s *fallbackSearch) resume(ctx context) ([]Operation, error) {
+	if p, ok := s.active().(pauser); ok {
+		return p.resume(ctx)
+	}
+	return nil, nil
+}
+
+func (s *fallbackSearch) paused() bool {
+	p, ok := s.active().(pauser)
+	return ok && p.paused()
+}
+
+// trialResumed implements the trialResumer interface.
+func (s *fallbackSearch) trialResumed(
+	ctx context, requestID RequestID, completed model.Length,
+) ([]Operation, error) {
+	if resumer, ok := s.active().(trialResumer); ok {
+		return resumer.trialResumed(ctx, requestID, completed)
+	}
+	return nil, nil
+}
+
+// fallbackSearchSnapshot is the serialized form of a fallbackSearch, holding the state of the
+// search method it decided on.
+type fallbackSearchSnapshot struct {
+	fallbackSearchState
+	State json.RawMessage `json:"state"`
+}
+
+// Snapshot implements the SearchMethod interface.
+func (s *fallbackSearch) Snapshot() ([]byte, error) {
+	state, err := s.active().Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(fallbackSearchSnapshot{
+		fallbackSearchState: s.fallbackSearchState,
+		State:               state,
+	})
+}
+
+// Restore implements the SearchMethod interface.
+func (s *fallbackSearch) Restore(state []byte) error {
+	var restored fallbackSearchSnapshot
+	if err := json.Unmarshal(state, &restored); err != nil {
+		return errors.Wrap(err, "failed to restore fallback search state")
+	}
+	s.fallbackSearchState = restored.fallbackSearchState
+	return s.active().Restore(restored.State)
+}
+
+func (s *fallbackSearch) Unit() model.Unit {
+	return s.MaxLength.Unit
+}
@@ -0,0 +1,198 @@
+package searcher
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func fallbackConfig() model.AsyncHalvingConfig {
+	return model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           9,
+		MaxConcurrentTrials: 3,
+		AutoFallback: &model.AutoFallbackConfig{
+			MaxDimensions:    1,
+			ValidationPeriod: model.NewLengthInBatches(300),
+			Patience:         1,
+		},
+	}
+}
+
+// trainLengths runs the search to completion and returns the length of each Train operation.
+func trainLengths(t *testing.T, driver *queueDriver) map[int]int {
+	lengths := make(map[int]int)
+	for _, op := range driver.pending {
+		if train, ok := op.(Train); ok {
+			lengths[train.Length.Units]++
+		}
+	}
+	for len(driver.pending) > 0 {
+		ops, err := driver.step()
+		assert.NilError(t, err)
+		for _, op := range ops {
+			if train, ok := op.(Train); ok {
+				lengths[train.Length.Units]++
+			}
+		}
+	}
+	return lengths
+}
+
+func TestFallbackSearchTinySpace(t *testing.T) {
+	config := fallbackConfig()
+	method, err := NewSearchMethod(model.SearcherConfig{
+		Metric:             defaultMetric,
+		SmallerIsBetter:    true,
+		AsyncHalvingConfig: &config,
+	})
+	assert.NilError(t, err)
+	hparams := model.Hyperparameters{
+		"x":          {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: 0, Maxval: 1}},
+		"batch_size": {ConstHyperparameter: &model.ConstHyperparameter{Val: 32}},
+	}
+	// Every trial gets worse after its first validation, so early stopping closes it after the
+	// second.
+	driver, err := newQueueDriver(method, hparams, func(trialIndex, validations int) float64 {
+		return float64(trialIndex + validations)
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, method.(*fallbackSearch).FellBack)
+
+	// Trials train in validation periods, as random search does, rather than to rung lengths.
+	assert.DeepEqual(t, trainLengths(t, driver), map[int]int{300: 18})
+	assert.Equal(t, len(driver.trialIndex), 9)
+	assert.Equal(t, method.progress(model.NewLengthInBatches(0)), 1.0)
+}
+
+func TestFallbackSearchRichSpace(t *testing.T) {
+	config := fallbackConfig()
+	method := newFallbackSearch(config)
+	hparams := model.Hyperparameters{
+		"x": {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: 0, Maxval: 1}},
+		"y": {LogHyperparameter: &model.LogHyperparameter{Base: 10, Minval: -4, Maxval: -1}},
+		"optimizer": {CategoricalHyperparameter: &model.CategoricalHyperparameter{
+			Vals: []interface{}{"sgd", "adam"},
+		}},
+	}
+	driver, err := newQueueDriver(method, hparams, func(trialIndex, _ int) float64 {
+		return float64(trialIndex)
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, !method.(*fallbackSearch).FellBack)
+
+	// The bottom rung trains for 100 batches, which random search never does.
+	lengths := trainLengths(t, driver)
+	assert.Equal(t, lengths[100], 9, lengths)
+	assert.Equal(t, lengths[300], 0, lengths)
+
+	// Two continuous hyperparameters are still too many for a single dimension.
+	delete(hparams, "optimizer")
+	method = newFallbackSearch(config)
+	_, err = newQueueDriver(method, hparams, func(int, int) float64 { return 0 })
+	assert.NilError(t, err)
+	assert.Assert(t, !method.(*fallbackSearch).FellBack)
+}
+
+func TestFallbackSearchSnapshotRestore(t *testing.T) {
+	config := fallbackConfig()
+	hparams := model.Hyperparameters{
+		"x": {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: 0, Maxval: 1}},
+	}
+	metricFn := func(trialIndex, validations int) float64 { return float64(trialIndex - validations) }
+	original, err := newQueueDriver(newFallbackSearch(config), hparams, metricFn)
+	assert.NilError(t, err)
+	for i := 0; i < 10; i++ {
+		_, err = original.step()
+		assert.NilError(t, err)
+	}
+
+	snapshot, err := original.method.Snapshot()
+	assert.NilError(t, err)
+	restoredMethod := newFallbackSearch(config)
+	assert.NilError(t, restoredMethod.Restore(snapshot))
+	assert.Assert(t, restoredMethod.(*fallbackSearch).FellBack)
+
+	restored := original.clone(restoredMethod)
+	for len(original.pending) > 0 {
+		expected, expectedErr := original.step()
+		assert.NilError(t, expectedErr)
+		actual, actualErr := restored.step()
+		assert.NilError(t, actualErr)
+		assert.DeepEqual(t, actual, expected)
+	}
+}
+
+func TestFallbackSearchTimeoutsAndPausing(t *testing.T) {
+	timeout := model.Duration(time.Hour)
+	config := fallbackConfig()
+	config.TrialValidationTimeout = &timeout
+	hparams := model.Hyperparameters{
+		"x": {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: 0, Maxval: 1}},
+		"optimizer": {CategoricalHyperparameter: &model.CategoricalHyperparameter{
+			Vals: []interface{}{"sgd", "adam"},
+		}},
+	}
+	method := newFallbackSearch(config)
+	driver, err := newQueueDriver(method, hparams, func(trialIndex, _ int) float64 {
+		return float64(trialIndex)
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, !method.(*fallbackSearch).FellBack)
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	driver.ctx.clock = func() time.Time { return now }
+	first := driver.pending[0].(Create).RequestID
+	for _, ok := driver.pending[0].(Create); ok; _, ok = driver.pending[0].(Create) {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+
+	// Timeouts are checked on the halving search the wrapper decided on.
+	checker := method.(timeoutChecker)
+	ops, err := checker.checkTimeouts(driver.ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, len(ops), 0)
+	now = now.Add(time.Hour)
+	ops, err = checker.checkTimeouts(driver.ctx)
+	assert.NilError(t, err)
+	assert.Assert(t, len(ops) > 0)
+	assert.DeepEqual(t, ops[0], Operation(NewClose(first)))
+
+	// So is pausing: the operations that trials earn while paused are held back until resuming.
+	p := method.(pauser)
+	p.pause()
+	assert.Assert(t, p.paused())
+	driver.pending = append(driver.pending, ops...)
+	for len(driver.pending) > 0 {
+		ops, err = driver.step()
+		assert.NilError(t, err)
+		assert.Equal(t, len(ops), 0)
+	}
+	ops, err = p.resume(driver.ctx)
+	assert.NilError(t, err)
+	assert.Assert(t, len(ops) > 0)
+	assert.Assert(t, !p.paused())
+}
+
+func TestFallbackSearchPauseAfterFallingBack(t *testing.T) {
+	hparams := model.Hyperparameters{
+		"x": {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: 0, Maxval: 1}},
+	}
+	searcher := NewSearcher(0, newFallbackSearch(fallbackConfig()), hparams, nil)
+	_, err := searcher.InitialOperations()
+	assert.NilError(t, err)
+	assert.Assert(t, searcher.method.(*fallbackSearch).FellBack)
+
+	// Random search cannot be paused, nor can the search that fell back to it.
+	assert.ErrorContains(t, searcher.Pause(), "cannot be paused")
+	ops, err := searcher.CheckTimeouts()
+	assert.NilError(t, err)
+	assert.Equal(t, len(ops), 0)
+}
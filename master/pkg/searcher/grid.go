@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"math"
 
+	"github.com/pkg/errors"
+
 	"github.com/determined-ai/determined/master/pkg/model"
 )
 
@@ -21,7 +23,22 @@ func newGridSearch(config model.GridConfig) SearchMethod {
 
 func (s *gridSearch) initialOperations(ctx context) ([]Operation, error) {
 	var ops []Operation
-	grid := newHyperparameterGrid(ctx.hparams)
+	var grid []hparamSample
+	// Points on the grid that violate the constraints are skipped rather than redrawn.
+	for _, params := range newHyperparameterGrid(ctx.hparams) {
+		switch ok, err := satisfiesConstraints(ctx.constraints, params); {
+		case err != nil:
+			return nil, err
+		case ok:
+			grid = append(grid, params)
+		}
+	}
+	if len(ctx.constraints) > 0 && len(grid) == 0 {
+		return nil, errors.New("no points on the grid satisfy the constraints")
+	}
+	if s.MaxTrials != nil && len(grid) > *s.MaxTrials {
+		grid = grid[:*s.MaxTrials]
+	}
 	s.trials = len(grid)
 	for _, params := range grid {
 		create := NewCreate(ctx.rand, params, model.TrialWorkloadSequencerType)
@@ -39,7 +56,7 @@ func (s *gridSearch) progress(unitsCompleted model.Length) float64 {
 
 // trialExitedEarly does nothing since grid does not take actions based on
 // search status or progress.
-func (s *gridSearch) trialExitedEarly(context, RequestID) ([]Operation, error) {
+func (s *gridSearch) trialExitedEarly(context, RequestID, ExitedReason) ([]Operation, error) {
 	return nil, nil
 }
 
@@ -88,6 +105,9 @@ func grid(h model.Hyperparameter) []interface{} {
 		return []interface{}{p.Val}
 	case h.IntHyperparameter != nil:
 		p := *h.IntHyperparameter
+		if p.Step != nil {
+			return intLatticeGrid(p)
+		}
 		// Dereferencing is okay because initialization of GridSearch has checked p.Count is non-nil.
 		count := *p.Count
 
@@ -139,3 +159,23 @@ func grid(h model.Hyperparameter) []interface{} {
 		panic(fmt.Sprintf("unexpected hyperparameter type %+v", h))
 	}
 }
+
+// intLatticeGrid returns count values spread evenly over the values of the form minval + k * step
+// between minval and maxval, or all of those values if count is unset or greater than their
+// number. No value is returned twice.
+func intLatticeGrid(p model.IntHyperparameter) []interface{} {
+	size := p.GridSize()
+	count := size
+	if p.Count != nil {
+		count = min(*p.Count, size)
+	}
+	vals := make([]interface{}, count)
+	for i := range vals {
+		k := (size - 1) / 2
+		if count > 1 {
+			k = int(math.Round(float64(i*(size-1)) / float64(count-1)))
+		}
+		vals[i] = p.Minval + p.StepSize()*k
+	}
+	return vals
+}
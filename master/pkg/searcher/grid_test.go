@@ -1,12 +1,14 @@
 package searcher
 
 import (
+	"fmt"
 	"strconv"
 	"testing"
 
 	"gotest.tools/assert"
 
 	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/nprand"
 )
 
 func intP(x int) *int {
@@ -77,6 +79,69 @@ func TestGrid(t *testing.T) {
 	assert.DeepEqual(t, actual, expected)
 }
 
+func TestGridSearcherMaxTrials(t *testing.T) {
+	hparams := model.Hyperparameters{
+		"1": model.Hyperparameter{
+			IntHyperparameter: &model.IntHyperparameter{Maxval: 20, Count: intP(3)}},
+		"2": model.Hyperparameter{
+			IntHyperparameter: &model.IntHyperparameter{Maxval: 10, Count: intP(3)}},
+	}
+	points := func(config model.GridConfig) []hparamSample {
+		ops, err := newGridSearch(config).initialOperations(
+			context{rand: nprand.New(0), hparams: hparams})
+		assert.NilError(t, err)
+		var points []hparamSample
+		for _, op := range ops {
+			if create, ok := op.(Create); ok {
+				points = append(points, create.Hparams)
+			}
+		}
+		return points
+	}
+
+	// Every point of the grid is created exactly once, in the same order every time.
+	config := model.GridConfig{MaxLength: model.NewLengthInBatches(100)}
+	all := points(config)
+	assert.DeepEqual(t, all, newHyperparameterGrid(hparams))
+	assert.DeepEqual(t, points(config), all)
+	seen := make(map[string]bool)
+	for _, point := range all {
+		key := fmt.Sprint(point)
+		assert.Assert(t, !seen[key], "duplicate point %s", key)
+		seen[key] = true
+	}
+	assert.Equal(t, len(seen), 9)
+
+	config.MaxTrials = intP(4)
+	assert.DeepEqual(t, points(config), all[:4])
+	config.MaxTrials = intP(20)
+	assert.DeepEqual(t, points(config), all)
+}
+
+func TestGridSearcherConstraints(t *testing.T) {
+	// The grid for each parameter is {-1, -0.5, 0, 0.5, 1}.
+	grid := generateHyperparameters([]int{5, 5})
+	ctx := context{
+		rand:        nprand.New(0),
+		hparams:     model.Hyperparameters{"x": grid["0"], "y": grid["1"]},
+		constraints: []model.HyperparameterConstraint{"x + y <= 0"},
+	}
+	ops, err := newGridSearch(model.GridConfig{}).initialOperations(ctx)
+	assert.NilError(t, err)
+	var creates int
+	for _, op := range ops {
+		if create, ok := op.(Create); ok {
+			creates++
+			assert.Assert(t, create.Hparams["x"].(float64)+create.Hparams["y"].(float64) <= 0)
+		}
+	}
+	assert.Equal(t, creates, 15)
+
+	ctx.constraints = []model.HyperparameterConstraint{"x > 1"}
+	_, err = newGridSearch(model.GridConfig{}).initialOperations(ctx)
+	assert.ErrorContains(t, err, "no points on the grid satisfy the constraints")
+}
+
 func TestGridIntCount(t *testing.T) {
 	hparams := model.Hyperparameters{
 		"1": model.Hyperparameter{
@@ -107,6 +172,24 @@ func TestGridIntCountNegative(t *testing.T) {
 	assert.DeepEqual(t, actual, expected)
 }
 
+func TestGridIntStep(t *testing.T) {
+	p := model.IntHyperparameter{Minval: 8, Maxval: 64, Step: intP(8)}
+	param := model.Hyperparameter{IntHyperparameter: &p}
+	// Without a count, every value of the lattice is on the grid, once.
+	assert.DeepEqual(t, grid(param), []interface{}{8, 16, 24, 32, 40, 48, 56, 64})
+	p.Count = intP(20)
+	assert.DeepEqual(t, grid(param), []interface{}{8, 16, 24, 32, 40, 48, 56, 64})
+	// With fewer, they are spread over the lattice.
+	p.Count = intP(3)
+	assert.DeepEqual(t, grid(param), []interface{}{8, 40, 64})
+	p.Count = intP(1)
+	assert.DeepEqual(t, grid(param), []interface{}{32})
+
+	// Maxval need not be on the lattice.
+	p = model.IntHyperparameter{Minval: 0, Maxval: 10, Step: intP(4)}
+	assert.DeepEqual(t, grid(param), []interface{}{0, 4, 8})
+}
+
 func TestGridSearcherRecords(t *testing.T) {
 	actual := model.GridConfig{MaxLength: model.NewLengthInRecords(19200)}
 	params := generateHyperparameters([]int{2, 1, 3})
@@ -3,6 +3,9 @@ package searcher
 import (
 	"fmt"
 	"math"
+	"reflect"
+
+	"github.com/pkg/errors"
 
 	"github.com/determined-ai/determined/master/pkg/model"
 	"github.com/determined-ai/determined/master/pkg/nprand"
@@ -21,14 +24,102 @@ func (h hparamSample) GlobalBatchSize() int {
 	return h[model.GlobalBatchSize].(int)
 }
 
-func sampleAll(h model.Hyperparameters, rand *nprand.State) hparamSample {
+// maxSampleAttempts bounds how many times hyperparameters are redrawn to satisfy the constraints.
+const maxSampleAttempts = 1000
+
+// sampleAll samples a value for every active hyperparameter, redrawing the whole sample until it
+// satisfies all of the constraints. While the hyperparameters are pinned, it returns a copy of the
+// pinned point instead.
+func sampleAll(ctx context) (hparamSample, error) {
+	if ctx.pinned != nil {
+		sample := make(hparamSample, len(ctx.pinned))
+		for name, value := range ctx.pinned {
+			sample[name] = value
+		}
+		return sample, nil
+	}
+	for attempt := 0; attempt < maxSampleAttempts; attempt++ {
+		sample := sampleUnconstrained(ctx.hparams, ctx.rand)
+		switch ok, err := satisfiesConstraints(ctx.constraints, sample); {
+		case err != nil:
+			return nil, err
+		case ok:
+			return sample, nil
+		}
+	}
+	return nil, errors.Errorf(
+		"failed to sample hyperparameters satisfying the constraints after %d attempts",
+		maxSampleAttempts)
+}
+
+func sampleUnconstrained(h model.Hyperparameters, rand *nprand.State) hparamSample {
 	results := make(hparamSample)
 	h.Each(func(name string, param model.Hyperparameter) {
 		results[name] = sampleOne(param, rand)
 	})
+	return pruneInactive(h, results)
+}
+
+func satisfiesConstraints(
+	constraints []model.HyperparameterConstraint, sample hparamSample,
+) (bool, error) {
+	for _, constraint := range constraints {
+		if ok, err := constraint.Satisfied(sample); err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// pruneInactive removes the conditional hyperparameters whose conditions do not hold from the
+// sample. Every parameter is sampled regardless of its condition so that the random state advances
+// the same way no matter which parameters end up active.
+func pruneInactive(h model.Hyperparameters, sample hparamSample) hparamSample {
+	active := make(map[string]bool)
+	var isActive func(name string) bool
+	isActive = func(name string) bool {
+		if result, ok := active[name]; ok {
+			return result
+		}
+		// Conditions are validated to be acyclic, so this recursion terminates.
+		condition := h[name].Condition
+		result := true
+		if condition != nil {
+			result = false
+			if _, ok := sample[condition.Parent]; ok && isActive(condition.Parent) {
+				for _, val := range condition.Vals {
+					if hparamValuesEqual(sample[condition.Parent], val) {
+						result = true
+						break
+					}
+				}
+			}
+		}
+		active[name] = result
+		return result
+	}
+
+	results := make(hparamSample)
+	for name, val := range sample {
+		if isActive(name) {
+			results[name] = val
+		}
+	}
 	return results
 }
 
+// hparamValuesEqual compares a sampled value to a value from the experiment config, where all
+// numbers are parsed as floats but int hyperparameters are sampled as ints.
+func hparamValuesEqual(sampled, configured interface{}) bool {
+	if i, ok := sampled.(int); ok {
+		sampled = float64(i)
+	}
+	if i, ok := configured.(int); ok {
+		configured = float64(i)
+	}
+	return reflect.DeepEqual(sampled, configured)
+}
+
 func sampleOne(h model.Hyperparameter, rand *nprand.State) interface{} {
 	switch {
 	case h.ConstHyperparameter != nil:
@@ -36,7 +127,7 @@ func sampleOne(h model.Hyperparameter, rand *nprand.State) interface{} {
 		return p.Val
 	case h.IntHyperparameter != nil:
 		p := h.IntHyperparameter
-		return p.Minval + rand.Intn(p.Maxval-p.Minval)
+		return p.Minval + p.StepSize()*rand.Intn(intLatticeSize(*p))
 	case h.DoubleHyperparameter != nil:
 		p := h.DoubleHyperparameter
 		return rand.Uniform(p.Minval, p.Maxval)
@@ -45,12 +136,96 @@ func sampleOne(h model.Hyperparameter, rand *nprand.State) interface{} {
 		return math.Pow(p.Base, rand.Uniform(p.Minval, p.Maxval))
 	case h.CategoricalHyperparameter != nil:
 		p := h.CategoricalHyperparameter
+		if p.Weights != nil {
+			return p.Vals[weightedIndex(p.Weights, rand)]
+		}
 		return p.Vals[rand.Intn(len(p.Vals))]
 	default:
 		panic(fmt.Sprintf("unexpected hyperparameter type: %+v", h))
 	}
 }
 
+// intLatticeSize returns the number of values an int hyperparameter is sampled from: those of the
+// form minval + k * step below maxval.
+func intLatticeSize(p model.IntHyperparameter) int {
+	step := p.StepSize()
+	return (p.Maxval - p.Minval + step - 1) / step
+}
+
+// snapInt returns the value of the form minval + k * step of the int hyperparameter next to x,
+// rounding down if down is set and up otherwise, clamped to the values between minval and maxval.
+func snapInt(p model.IntHyperparameter, x float64, down bool) int {
+	k := (x - float64(p.Minval)) / float64(p.StepSize())
+	if down {
+		k = math.Floor(k)
+	} else {
+		k = math.Ceil(k)
+	}
+	return p.Minval + p.StepSize()*intClamp(int(k), 0, p.GridSize()-1)
+}
+
+// sampleNearbyCategorical samples a value of the categorical hyperparameter other than the current
+// one. For ordinal categoricals, each value is chosen with probability proportional to
+// exp(-distance/temperature), where distance is how many places away from the current value it is
+// in the list of values, so lower temperatures favor the closest values more strongly; other
+// categoricals treat all values as equally close. Any weights of the values also apply. If the
+// current value is not one of the values, a value is sampled as usual.
+func sampleNearbyCategorical(
+	p model.CategoricalHyperparameter, current interface{}, temperature float64,
+	rand *nprand.State,
+) interface{} {
+	currentIndex := -1
+	for i, val := range p.Vals {
+		if hparamValuesEqual(current, val) {
+			currentIndex = i
+			break
+		}
+	}
+	if currentIndex < 0 || len(p.Vals) == 1 {
+		return sampleOne(model.Hyperparameter{CategoricalHyperparameter: &p}, rand)
+	}
+
+	weights := make([]float64, len(p.Vals))
+	for i := range p.Vals {
+		switch {
+		case i == currentIndex:
+			continue
+		case p.Ordinal:
+			distance := math.Abs(float64(i - currentIndex))
+			weights[i] = math.Exp(-distance / temperature)
+		default:
+			weights[i] = 1
+		}
+		if p.Weights != nil {
+			weights[i] *= p.Weights[i]
+		}
+	}
+	return p.Vals[weightedIndex(weights, rand)]
+}
+
+// weightedIndex returns a random index into weights, chosen with probability proportional to the
+// weight at that index.
+func weightedIndex(weights []float64, rand *nprand.State) int {
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
+	}
+	target := rand.UnitInterval() * total
+	for i, weight := range weights {
+		if target < weight {
+			return i
+		}
+		target -= weight
+	}
+	// Floating point error can leave a tiny remainder; fall back to the last nonzero weight.
+	for i := len(weights) - 1; i > 0; i-- {
+		if weights[i] > 0 {
+			return i
+		}
+	}
+	return 0
+}
+
 func intClamp(val, minval, maxval int) int {
 	switch {
 	case val < minval:
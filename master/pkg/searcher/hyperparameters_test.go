@@ -1,6 +1,8 @@
 package searcher
 
 import (
+	"encoding/json"
+	"math"
 	"testing"
 
 	"gotest.tools/assert"
@@ -26,8 +28,8 @@ func TestSamplingReproducibility(t *testing.T) {
 		rand1 := nprand.New(seed)
 		rand2 := nprand.New(seed)
 
-		sample1 := sampleAll(spec, rand1)
-		sample2 := sampleAll(spec, rand2)
+		sample1 := sampleUnconstrained(spec, rand1)
+		sample2 := sampleUnconstrained(spec, rand2)
 
 		assert.Equal(t, 5, len(sample1))
 		assert.Equal(t, 5, len(sample2))
@@ -42,3 +44,193 @@ func TestSamplingReproducibility(t *testing.T) {
 		assert.Equal(t, rand1.Bits64(), rand2.Bits64())
 	}
 }
+
+func TestLogUniformSampling(t *testing.T) {
+	param := model.Hyperparameter{
+		LogHyperparameter: &model.LogHyperparameter{Base: 10, Minval: -4, Maxval: 0},
+	}
+	rand := nprand.New(0)
+	const draws = 40000
+	// Each unit interval of the exponent should receive a quarter of the samples.
+	buckets := make([]int, 4)
+	for i := 0; i < draws; i++ {
+		exponent := math.Log10(sampleOne(param, rand).(float64))
+		assert.Assert(t, exponent >= -4 && exponent <= 0)
+		buckets[min(int(exponent+4), len(buckets)-1)]++
+	}
+	for _, count := range buckets {
+		assert.Assert(t, math.Abs(float64(count)/draws-0.25) < 0.01, "bucket count %d", count)
+	}
+}
+
+func TestIntStepSampling(t *testing.T) {
+	p := model.IntHyperparameter{Minval: 8, Maxval: 64, Step: intP(8)}
+	param := model.Hyperparameter{IntHyperparameter: &p}
+	onLattice := func(value int) bool {
+		return value >= 8 && value < 64 && value%8 == 0
+	}
+	rand := nprand.New(0)
+	seen := make(map[int]bool)
+	for i := 0; i < 1000; i++ {
+		value := sampleOne(param, rand).(int)
+		assert.Assert(t, onLattice(value), "sampled %d", value)
+		seen[value] = true
+	}
+	assert.Equal(t, len(seen), 7)
+	for i := 0; i < 64; i++ {
+		value := quantileOne(param, float64(i)/64).(int)
+		assert.Assert(t, onLattice(value), "quantile %d of 64 is %d", i, value)
+	}
+
+	// Perturbed values are moved onto the lattice in the direction of the perturbation, within the
+	// bounds of the range.
+	assert.Equal(t, snapInt(p, 30, true), 24)
+	assert.Equal(t, snapInt(p, 30, false), 32)
+	assert.Equal(t, snapInt(p, 100, false), 64)
+	assert.Equal(t, snapInt(p, -5, true), 8)
+}
+
+func TestWeightedCategoricalSampling(t *testing.T) {
+	weights := []float64{1, 0, 3, 6}
+	param := model.Hyperparameter{
+		CategoricalHyperparameter: &model.CategoricalHyperparameter{
+			Vals:    []interface{}{"sgd", "momentum", "adam", "rmsprop"},
+			Weights: weights,
+		},
+	}
+	rand := nprand.New(0)
+	const draws = 40000
+	counts := make(map[interface{}]int)
+	for i := 0; i < draws; i++ {
+		counts[sampleOne(param, rand)]++
+	}
+	assert.Equal(t, counts["momentum"], 0)
+	for i, val := range param.CategoricalHyperparameter.Vals {
+		expected := weights[i] / 10
+		assert.Assert(t, math.Abs(float64(counts[val])/draws-expected) < 0.01,
+			"%v sampled %d times", val, counts[val])
+	}
+}
+
+func TestSampledHyperparametersRoundTrip(t *testing.T) {
+	spec := model.Hyperparameters{
+		"cat": {CategoricalHyperparameter: &model.CategoricalHyperparameter{
+			Vals: []interface{}{"a", "b", "c"}, Weights: []float64{1, 2, 3}}},
+		"log": {LogHyperparameter: &model.LogHyperparameter{Base: 10, Minval: -5, Maxval: -1}},
+	}
+	expected := sampleUnconstrained(spec, nprand.New(1))
+	create := NewCreate(
+		nprand.New(0), sampleUnconstrained(spec, nprand.New(1)), model.TrialWorkloadSequencerType)
+	assert.DeepEqual(t, create.Hparams, expected)
+
+	bytes, err := json.Marshal(create)
+	assert.NilError(t, err)
+	var rebuilt Create
+	assert.NilError(t, json.Unmarshal(bytes, &rebuilt))
+	assert.DeepEqual(t, rebuilt.Hparams, expected)
+}
+
+func TestConditionalSampling(t *testing.T) {
+	spec := model.Hyperparameters{
+		"optimizer": {CategoricalHyperparameter: &model.CategoricalHyperparameter{
+			Vals: []interface{}{"sgd", "adam"}}},
+		"momentum": {
+			CategoricalHyperparameter: &model.CategoricalHyperparameter{
+				Vals: []interface{}{0.0, 0.9}},
+			Condition: &model.HyperparameterCondition{
+				Parent: "optimizer", Vals: []interface{}{"sgd"}},
+		},
+		"nesterov": {
+			ConstHyperparameter: &model.ConstHyperparameter{Val: true},
+			Condition: &model.HyperparameterCondition{
+				Parent: "momentum", Vals: []interface{}{0.9}},
+		},
+		"layers": {IntHyperparameter: &model.IntHyperparameter{Minval: 1, Maxval: 3}},
+		"width": {
+			IntHyperparameter: &model.IntHyperparameter{Minval: 8, Maxval: 64},
+			// Config values are parsed as floats even when the parent is an int.
+			Condition: &model.HyperparameterCondition{Parent: "layers", Vals: []interface{}{2.0}},
+		},
+	}
+
+	seen := make(map[string]bool)
+	for seed := uint32(0); seed < 200; seed++ {
+		sample := sampleUnconstrained(spec, nprand.New(seed))
+		_, hasMomentum := sample["momentum"]
+		_, hasNesterov := sample["nesterov"]
+		_, hasWidth := sample["width"]
+		assert.Equal(t, hasMomentum, sample["optimizer"] == "sgd")
+		assert.Equal(t, hasNesterov, hasMomentum && sample["momentum"] == 0.9)
+		assert.Equal(t, hasWidth, sample["layers"] == 2)
+		for name := range sample {
+			seen[name] = true
+		}
+	}
+	assert.Equal(t, len(seen), len(spec))
+}
+
+func TestConstrainedSampling(t *testing.T) {
+	spec := model.Hyperparameters{
+		"batch_size": {IntHyperparameter: &model.IntHyperparameter{Minval: 1, Maxval: 128}},
+		"seq_len":    {IntHyperparameter: &model.IntHyperparameter{Minval: 1, Maxval: 512}},
+	}
+	constraint := model.HyperparameterConstraint("batch_size * seq_len <= 4096")
+	ctx := context{
+		rand:        nprand.New(0),
+		hparams:     spec,
+		constraints: []model.HyperparameterConstraint{constraint},
+	}
+	for i := 0; i < 100; i++ {
+		sample, err := sampleAll(ctx)
+		assert.NilError(t, err)
+		assert.Assert(t, sample["batch_size"].(int)*sample["seq_len"].(int) <= 4096)
+	}
+}
+
+func TestUnsatisfiableConstraints(t *testing.T) {
+	ctx := context{
+		rand: nprand.New(0),
+		hparams: model.Hyperparameters{
+			"batch_size": {IntHyperparameter: &model.IntHyperparameter{Minval: 1, Maxval: 128}},
+		},
+		constraints: []model.HyperparameterConstraint{"batch_size > 1000"},
+	}
+	_, err := sampleAll(ctx)
+	assert.ErrorContains(t, err,
+		"failed to sample hyperparameters satisfying the constraints after 1000 attempts")
+}
+
+func TestNearbyCategoricalSampling(t *testing.T) {
+	param := model.CategoricalHyperparameter{Vals: []interface{}{2, 4, 8, 16, 32}, Ordinal: true}
+	draws := func(temperature float64) map[interface{}]int {
+		rand := nprand.New(0)
+		counts := make(map[interface{}]int)
+		for i := 0; i < 40000; i++ {
+			counts[sampleNearbyCategorical(param, 8, temperature, rand)]++
+		}
+		return counts
+	}
+
+	// At a temperature of 1, each adjacent value is e times as likely as each value two places away.
+	counts := draws(1)
+	assert.Equal(t, counts[8], 0)
+	expected := map[interface{}]float64{4: math.E, 16: math.E, 2: 1, 32: 1}
+	for val, weight := range expected {
+		probability := weight / (2*math.E + 2)
+		assert.Assert(t, math.Abs(float64(counts[val])/40000-probability) < 0.01,
+			"%v sampled %d times", val, counts[val])
+	}
+
+	// Higher temperatures flatten the distribution, but still favor adjacent values.
+	counts = draws(4)
+	ratio := float64(counts[4]+counts[16]) / float64(counts[2]+counts[32])
+	assert.Assert(t, math.Abs(ratio-math.Exp(0.25)) < 0.05, "ratio %f", ratio)
+
+	// Non-ordinal categoricals draw every other value equally.
+	param.Ordinal = false
+	counts = draws(1)
+	for _, val := range []interface{}{2, 4, 16, 32} {
+		assert.Assert(t, math.Abs(float64(counts[val])/40000-0.25) < 0.01,
+			"%v sampled %d times", val, counts[val])
+	}
+}
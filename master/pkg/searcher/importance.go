@@ -0,0 +1,125 @@
+package searcher
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// HParamImportance estimates how much of the variation in the results of the trials so far is due
+// to each hyperparameter, as the fraction of the variance of the metrics the trials reported in the
+// bottom rung that is explained by the value of the hyperparameter alone, i.e., the first-order
+// effect of a functional ANOVA. The bottom rung is used because every trial reports there after the
+// same amount of training. The scores are normalized to sum to 1; the map is empty if fewer than
+// two trials have reported a metric or they all reported the same one.
+func (s *asyncHalvingSearch) HParamImportance() map[string]float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var samples []hparamSample
+	var metrics []float64
+	for _, trialMetric := range s.Rungs[0].Metrics {
+		hparams, ok := s.TrialHparams[trialMetric.RequestID]
+		if !ok || trialMetric.Metric == ashaExitedMetricValue {
+			continue
+		}
+		samples = append(samples, hparams)
+		metrics = append(metrics, trialMetric.Metric)
+	}
+	return hparamImportance(samples, metrics)
+}
+
+// hparamImportance returns the normalized first-order importance of each hyperparameter of the
+// samples to the metrics reported for them.
+func hparamImportance(samples []hparamSample, metrics []float64) map[string]float64 {
+	importance := make(map[string]float64)
+	if len(metrics) < 2 {
+		return importance
+	}
+	var mean float64
+	for _, metric := range metrics {
+		mean += metric
+	}
+	mean /= float64(len(metrics))
+	var variance float64
+	for _, metric := range metrics {
+		variance += (metric - mean) * (metric - mean)
+	}
+	if variance == 0 {
+		return importance
+	}
+
+	names := make(map[string]bool)
+	for _, hparams := range samples {
+		for name := range hparams {
+			names[name] = true
+		}
+	}
+	var total float64
+	for name := range names {
+		// The explained variance is that of the mean metric of each group of trials with the same
+		// value of the hyperparameter, weighted by the size of the group.
+		var explained float64
+		for _, group := range hparamGroups(samples, name) {
+			var groupMean float64
+			for _, i := range group {
+				groupMean += metrics[i]
+			}
+			groupMean /= float64(len(group))
+			explained += float64(len(group)) * (groupMean - mean) * (groupMean - mean)
+		}
+		importance[name] = explained / variance
+		total += importance[name]
+	}
+	if total > 0 {
+		for name := range importance {
+			importance[name] /= total
+		}
+	}
+	return importance
+}
+
+// hparamGroups partitions the indices of the samples by the value of the hyperparameter. Since
+// trials rarely share the value of a continuous hyperparameter, samples whose values are all
+// floats are instead split by rank into about the square root of their number of groups of equal
+// size.
+func hparamGroups(samples []hparamSample, name string) [][]int {
+	continuous := true
+	for _, hparams := range samples {
+		if _, ok := hparams[name].(float64); !ok {
+			continuous = false
+			break
+		}
+	}
+
+	if continuous {
+		order := make([]int, len(samples))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return samples[order[i]][name].(float64) < samples[order[j]][name].(float64)
+		})
+		numGroups := max(int(math.Sqrt(float64(len(samples)))), 1)
+		groups := make([][]int, numGroups)
+		for rank, i := range order {
+			group := rank * numGroups / len(samples)
+			groups[group] = append(groups[group], i)
+		}
+		return groups
+	}
+
+	var keys []string
+	byValue := make(map[string][]int)
+	for i, hparams := range samples {
+		key := fmt.Sprint(hparams[name])
+		if _, ok := byValue[key]; !ok {
+			keys = append(keys, key)
+		}
+		byValue[key] = append(byValue[key], i)
+	}
+	groups := make([][]int, 0, len(keys))
+	for _, key := range keys {
+		groups = append(groups, byValue[key])
+	}
+	return groups
+}
@@ -0,0 +1,87 @@
+package searcher
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func TestHParamImportance(t *testing.T) {
+	// The metric is determined by x alone; noise and optimizer are unrelated to it.
+	random := rand.New(rand.NewSource(0))
+	var samples []hparamSample
+	var metrics []float64
+	for i := 0; i < 100; i++ {
+		x := random.Float64()
+		samples = append(samples, hparamSample{
+			"x":         x,
+			"noise":     random.Float64(),
+			"optimizer": []string{"sgd", "adam", "rmsprop"}[random.Intn(3)],
+		})
+		metrics = append(metrics, 10*x*x)
+	}
+	importance := hparamImportance(samples, metrics)
+	assert.Equal(t, len(importance), 3)
+	var total float64
+	for _, score := range importance {
+		total += score
+	}
+	assert.Assert(t, math.Abs(total-1) < 1e-9, total)
+	assert.Assert(t, importance["x"] > 0.8, importance)
+	assert.Assert(t, importance["x"] > 5*importance["noise"], importance)
+	assert.Assert(t, importance["x"] > 5*importance["optimizer"], importance)
+
+	// A categorical hyperparameter that determines the metric dominates just the same.
+	for i, hparams := range samples {
+		metrics[i] = map[string]float64{"sgd": 0, "adam": 1, "rmsprop": 3}[hparams["optimizer"].(string)]
+	}
+	importance = hparamImportance(samples, metrics)
+	assert.Assert(t, importance["optimizer"] > 0.8, importance)
+
+	// Nothing can be said without variation in the metric.
+	for i := range metrics {
+		metrics[i] = 1
+	}
+	assert.Equal(t, len(hparamImportance(samples, metrics)), 0)
+	assert.Equal(t, len(hparamImportance(samples[:1], metrics[:1])), 0)
+}
+
+func TestASHASearcherHParamImportance(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           40,
+		MaxConcurrentTrials: 4,
+	}
+	hparams := model.Hyperparameters{
+		"x":     {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: 0, Maxval: 1}},
+		"noise": {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: 0, Maxval: 1}},
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	assert.Equal(t, len(search.HParamImportance()), 0)
+
+	var driver *queueDriver
+	driver, err := newQueueDriver(search, hparams, func(trialIndex, _ int) float64 {
+		for requestID, index := range driver.trialIndex {
+			if index == trialIndex {
+				return search.TrialHparams[requestID]["x"].(float64)
+			}
+		}
+		panic("unknown trial")
+	})
+	assert.NilError(t, err)
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+	importance := search.HParamImportance()
+	assert.Assert(t, importance["x"] > 0.8, importance)
+	assert.Assert(t, math.Abs(importance["x"]+importance["noise"]-1) < 1e-9, importance)
+}
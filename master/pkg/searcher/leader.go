@@ -0,0 +1,172 @@
+package searcher
+
+import (
+	"math"
+)
+
+// recordHistory adds the sign-adjusted metric the trial reported in its current rung to its metric
+// history. A trial validates once per rung, so the history holds a point for every rung.
+func (s *asyncHalvingSearch) recordHistory(requestID RequestID, metric float64) {
+	history, ok := s.MetricHistories[requestID]
+	if !ok {
+		history = &metricHistory{}
+		s.MetricHistories[requestID] = history
+	}
+	rung := s.Rungs[s.TrialRungs[requestID]]
+	history.add(len(s.Rungs), metricPoint{Units: rung.UnitsNeeded.Units, Metric: metric})
+}
+
+// LeaderConfidence returns the confidence that the leading trial of the highest rung in which at
+// least two trials have reported a metric is better than the runner-up: one minus the one-sided
+// p-value of Welch's t-test between the metric histories of the two trials, restricted to the
+// rungs ranked by the same metric as that rung. It is 0 if there is no such rung or either trial
+// has reported fewer than two metrics, since nothing can be said about the noise of its metric
+// then.
+func (s *asyncHalvingSearch) LeaderConfidence() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for rungIndex := len(s.Rungs) - 1; rungIndex >= 0; rungIndex-- {
+		var leaders []RequestID
+		for _, trialMetric := range s.Rungs[rungIndex].Metrics {
+			if trialMetric.Metric != ashaExitedMetricValue && len(leaders) < 2 {
+				leaders = append(leaders, trialMetric.RequestID)
+			}
+		}
+		if len(leaders) < 2 {
+			continue
+		}
+		leader := s.comparableHistory(rungIndex, leaders[0])
+		runnerUp := s.comparableHistory(rungIndex, leaders[1])
+		if len(leader) < 2 || len(runnerUp) < 2 {
+			return 0
+		}
+		// Metrics are sign-adjusted so that smaller is better, so the leader is better if the
+		// mean of its metrics is smaller.
+		return welchConfidence(runnerUp, leader)
+	}
+	return 0
+}
+
+// comparableHistory returns the metrics in the history of the trial that it reported in rungs
+// ranked by the same metric as the given rung.
+func (s *asyncHalvingSearch) comparableHistory(rungIndex int, requestID RequestID) []float64 {
+	history, ok := s.MetricHistories[requestID]
+	if !ok {
+		return nil
+	}
+	name, _ := s.RungMetric(rungIndex)
+	comparable := make(map[int]bool)
+	for i, rung := range s.Rungs {
+		if rungName, _ := s.RungMetric(i); rungName == name {
+			comparable[rung.UnitsNeeded.Units] = true
+		}
+	}
+	var metrics []float64
+	for _, point := range history.recent() {
+		if comparable[point.Units] {
+			metrics = append(metrics, point.Metric)
+		}
+	}
+	return metrics
+}
+
+// welchConfidence returns the probability under Welch's t-test that the mean of the population a
+// is sampled from is greater than that of b, i.e., one minus the one-sided p-value of the null
+// hypothesis that it is not. Each sample must have at least two values.
+func welchConfidence(a, b []float64) float64 {
+	meanA, varA := meanVariance(a)
+	meanB, varB := meanVariance(b)
+	seA, seB := varA/float64(len(a)), varB/float64(len(b))
+	if seA+seB == 0 {
+		switch {
+		case meanA > meanB:
+			return 1
+		case meanA < meanB:
+			return 0
+		default:
+			return 0.5
+		}
+	}
+	t := (meanA - meanB) / math.Sqrt(seA+seB)
+	// The Welch-Satterthwaite approximation of the degrees of freedom.
+	df := (seA + seB) * (seA + seB) /
+		(seA*seA/float64(len(a)-1) + seB*seB/float64(len(b)-1))
+	return studentTCDF(t, df)
+}
+
+// meanVariance returns the mean and the unbiased sample variance of the values.
+func meanVariance(values []float64) (mean, variance float64) {
+	for _, value := range values {
+		mean += value
+	}
+	mean /= float64(len(values))
+	for _, value := range values {
+		variance += (value - mean) * (value - mean)
+	}
+	return mean, variance / float64(len(values)-1)
+}
+
+// studentTCDF returns the cumulative distribution function of Student's t-distribution with df
+// degrees of freedom at t.
+func studentTCDF(t, df float64) float64 {
+	tail := regularizedIncompleteBeta(df/2, 0.5, df/(df+t*t)) / 2
+	if t > 0 {
+		return 1 - tail
+	}
+	return tail
+}
+
+// regularizedIncompleteBeta returns the regularized incomplete beta function I_x(a, b), evaluated
+// by its continued fraction, which converges quickly for x < (a+1)/(a+b+2); the symmetry
+// I_x(a, b) = 1 - I_{1-x}(b, a) covers the other values of x.
+func regularizedIncompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	lgammaA, _ := math.Lgamma(a)
+	lgammaB, _ := math.Lgamma(b)
+	lgammaAB, _ := math.Lgamma(a + b)
+	front := math.Exp(lgammaAB - lgammaA - lgammaB + a*math.Log(x) + b*math.Log(1-x))
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(a, b, x) / a
+	}
+	return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+}
+
+// betaContinuedFraction evaluates the continued fraction of the incomplete beta function by the
+// modified Lentz method.
+func betaContinuedFraction(a, b, x float64) float64 {
+	const (
+		maxIterations = 200
+		epsilon       = 1e-14
+		tiny          = 1e-300
+	)
+	clamp := func(v float64) float64 {
+		if math.Abs(v) < tiny {
+			return tiny
+		}
+		return v
+	}
+	c, d := 1.0, 1/clamp(1-(a+b)*x/(a+1))
+	h := d
+	for m := 1.0; m <= maxIterations; m++ {
+		// The even step of the recurrence.
+		numerator := m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m))
+		d = 1 / clamp(1+numerator*d)
+		c = clamp(1 + numerator/c)
+		h *= d * c
+		// The odd step.
+		numerator = -(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1))
+		d = 1 / clamp(1+numerator*d)
+		c = clamp(1 + numerator/c)
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+	return h
+}
@@ -0,0 +1,106 @@
+package searcher
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+	"gotest.tools/assert"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func TestStudentTCDF(t *testing.T) {
+	// Critical values from a table of the t-distribution.
+	for _, c := range []struct{ t, df, cdf float64 }{
+		{0, 5, 0.5},
+		{2.228, 10, 0.975},
+		{-2.228, 10, 0.025},
+		{1.833, 9, 0.95},
+		{12.706, 1, 0.975},
+	} {
+		assert.Assert(t, math.Abs(studentTCDF(c.t, c.df)-c.cdf) < 1e-3,
+			"t=%v df=%v: %v", c.t, c.df, studentTCDF(c.t, c.df))
+	}
+}
+
+func TestASHASearcherLeaderConfidence(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            4,
+		MaxLength:           model.NewLengthInBatches(800),
+		Divisor:             2,
+		MaxTrials:           8,
+		MaxConcurrentTrials: 8,
+	}
+	run := func(metricFn func(trialIndex, step int) float64) (*asyncHalvingSearch, *queueDriver) {
+		search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+		driver, err := newQueueDriver(search, nil, metricFn)
+		assert.NilError(t, err)
+		assert.Equal(t, search.LeaderConfidence(), 0.0)
+		for len(driver.pending) > 0 {
+			_, err = driver.step()
+			assert.NilError(t, err)
+		}
+		return search, driver
+	}
+
+	// Each trial reports a metric in every rung it reaches.
+	search, _ := run(func(trialIndex, step int) float64 { return float64(trialIndex) })
+	for requestID, history := range search.MetricHistories {
+		assert.Equal(t, len(history.Points), search.TrialRungs[requestID]+1)
+	}
+
+	// The metrics of the trials are noisy, but the best two are far apart.
+	search, _ = run(func(trialIndex, step int) float64 {
+		return 10*float64(trialIndex) + float64(step%3)
+	})
+	assert.Assert(t, search.LeaderConfidence() > 0.99, search.LeaderConfidence())
+
+	// The noise dwarfs the difference between the best two.
+	search, _ = run(func(trialIndex, step int) float64 {
+		return 0.1*float64(trialIndex) + 10*float64(step%3)
+	})
+	assert.Assert(t, search.LeaderConfidence() < 0.75, search.LeaderConfidence())
+}
+
+func TestASHASearcherLeaderConfidenceSynthetic(t *testing.T) {
+	search := newAsyncHalvingSearch(model.AsyncHalvingConfig{
+		Metric:          defaultMetric,
+		SmallerIsBetter: true,
+		NumRungs:        3,
+		MaxLength:       model.NewLengthInBatches(900),
+		Divisor:         3,
+		MaxTrials:       9,
+	}).(*asyncHalvingSearch)
+	leader, runnerUp := RequestID(uuid.New()), RequestID(uuid.New())
+	setHistories := func(leaderMetrics, runnerUpMetrics []float64) {
+		for requestID, metrics := range map[RequestID][]float64{
+			leader: leaderMetrics, runnerUp: runnerUpMetrics,
+		} {
+			history := &metricHistory{}
+			for i, metric := range metrics {
+				history.add(3, metricPoint{
+					Units: search.Rungs[i].UnitsNeeded.Units, Metric: metric,
+				})
+			}
+			search.MetricHistories[requestID] = history
+		}
+		search.Rungs[2].Metrics = []trialMetric{
+			{RequestID: leader, Metric: leaderMetrics[2]},
+			{RequestID: runnerUp, Metric: runnerUpMetrics[2]},
+		}
+	}
+
+	setHistories([]float64{1.0, 1.1, 0.9}, []float64{5.0, 5.2, 4.9})
+	assert.Assert(t, search.LeaderConfidence() > 0.99, search.LeaderConfidence())
+
+	setHistories([]float64{1.0, 3.0, 0.9}, []float64{2.8, 0.5, 1.0})
+	confidence := search.LeaderConfidence()
+	assert.Assert(t, confidence > 0.3 && confidence < 0.7, confidence)
+
+	// A runner-up that exited early is not compared against.
+	search.Rungs[2].Metrics[1].Metric = ashaExitedMetricValue
+	assert.Equal(t, search.LeaderConfidence(), 0.0)
+}
@@ -0,0 +1,123 @@
+package searcher
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// listSearch trains one trial for each of a fixed list of hyperparameter points, in the order they
+// are listed and each for the full length, with at most MaxConcurrentTrials of them at a time.
+type listSearch struct {
+	defaultSearchMethod
+	model.ListConfig
+	listSearchState
+}
+
+type listSearchState struct {
+	// TrialsCreated is also the index of the next point to create a trial for.
+	TrialsCreated   int                `json:"trials_created"`
+	TrialsCompleted int                `json:"trials_completed"`
+	ClosedTrials    map[RequestID]bool `json:"closed_trials"`
+}
+
+func newListSearch(config model.ListConfig) SearchMethod {
+	return &listSearch{
+		ListConfig:      config,
+		listSearchState: listSearchState{ClosedTrials: make(map[RequestID]bool)},
+	}
+}
+
+func (s *listSearch) initialOperations(ctx context) ([]Operation, error) {
+	concurrency := s.MaxConcurrentTrials
+	if concurrency <= 0 || concurrency > len(s.Points) {
+		concurrency = len(s.Points)
+	}
+	var ops []Operation
+	for trial := 0; trial < concurrency; trial++ {
+		created, err := s.createTrial(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, created...)
+	}
+	return ops, nil
+}
+
+// createTrial creates a trial for the next point and trains and validates it.
+func (s *listSearch) createTrial(ctx context) ([]Operation, error) {
+	hparams, err := listPoint(ctx.hparams, s.Points[s.TrialsCreated])
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid point %d of list search", s.TrialsCreated)
+	}
+	create := NewCreate(ctx.rand, hparams, model.TrialWorkloadSequencerType)
+	s.TrialsCreated++
+	return []Operation{
+		create,
+		NewTrain(create.RequestID, s.MaxLength),
+		NewValidate(create.RequestID),
+		NewClose(create.RequestID),
+	}, nil
+}
+
+// listPoint returns the hyperparameters of a trial for the point. Since numbers in the experiment
+// configuration are parsed as floats, the values of int hyperparameters are converted to ints, as
+// if they had been sampled.
+func listPoint(h model.Hyperparameters, point map[string]interface{}) (hparamSample, error) {
+	if err := h.CheckPoint(point); err != nil {
+		return nil, err
+	}
+	hparams := make(hparamSample, len(point))
+	for name, value := range point {
+		if h[name].IntHyperparameter != nil {
+			value = int(hparamFloat(value))
+		}
+		hparams[name] = value
+	}
+	return hparams, nil
+}
+
+// complete marks the trial as done and creates a trial for the next point, if any.
+func (s *listSearch) complete(ctx context, requestID RequestID) ([]Operation, error) {
+	if s.ClosedTrials[requestID] {
+		return nil, nil
+	}
+	s.ClosedTrials[requestID] = true
+	s.TrialsCompleted++
+	if s.TrialsCreated >= len(s.Points) {
+		return nil, nil
+	}
+	return s.createTrial(ctx)
+}
+
+func (s *listSearch) trialClosed(ctx context, requestID RequestID) ([]Operation, error) {
+	return s.complete(ctx, requestID)
+}
+
+// trialExitedEarly moves on to the next point; a point is not retried.
+func (s *listSearch) trialExitedEarly(
+	ctx context, requestID RequestID, _ ExitedReason,
+) ([]Operation, error) {
+	return s.complete(ctx, requestID)
+}
+
+// progress is the fraction of the points whose trials have completed.
+func (s *listSearch) progress(model.Length) float64 {
+	return float64(s.TrialsCompleted) / float64(len(s.Points))
+}
+
+func (s *listSearch) Snapshot() ([]byte, error) {
+	return json.Marshal(s.listSearchState)
+}
+
+func (s *listSearch) Restore(state []byte) error {
+	if err := json.Unmarshal(state, &s.listSearchState); err != nil {
+		return errors.Wrap(err, "failed to restore list search state")
+	}
+	if s.ClosedTrials == nil {
+		s.ClosedTrials = make(map[RequestID]bool)
+	}
+	return nil
+}
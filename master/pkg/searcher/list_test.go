@@ -0,0 +1,119 @@
+package searcher
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func listHyperparameters() model.Hyperparameters {
+	return model.Hyperparameters{
+		"lr":     {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: 0, Maxval: 1}},
+		"layers": {IntHyperparameter: &model.IntHyperparameter{Minval: 1, Maxval: 8}},
+		"optimizer": {CategoricalHyperparameter: &model.CategoricalHyperparameter{
+			Vals: []interface{}{"adam", "sgd"},
+		}},
+	}
+}
+
+func TestListSearch(t *testing.T) {
+	// Numbers are floats, as parsed from the experiment configuration.
+	points := []map[string]interface{}{
+		{"lr": 0.1, "layers": 2.0, "optimizer": "adam"},
+		{"lr": 0.1, "layers": 4.0, "optimizer": "adam"},
+		{"lr": 0.01, "layers": 2.0, "optimizer": "sgd"},
+		{"lr": 0.5, "layers": 8.0, "optimizer": "adam"},
+		{"lr": 0.1, "layers": 2.0, "optimizer": "adam"},
+	}
+	config := model.ListConfig{
+		MaxLength:           model.NewLengthInBatches(500),
+		MaxConcurrentTrials: 2,
+		Points:              points,
+	}
+	search := newListSearch(config)
+	method := &recordingMethod{SearchMethod: search}
+	driver, err := newQueueDriver(method, listHyperparameters(), func(int, int) float64 {
+		return 0
+	})
+	assert.NilError(t, err)
+	creates := 0
+	for _, op := range driver.pending {
+		if _, ok := op.(Create); ok {
+			creates++
+		}
+	}
+	assert.Equal(t, creates, config.MaxConcurrentTrials)
+
+	for i := 0; len(driver.pending) > 0; i++ {
+		_, err = driver.step()
+		assert.NilError(t, err)
+		// A restored search carries on the same way.
+		if i == 10 {
+			snapshot, snapshotErr := search.Snapshot()
+			assert.NilError(t, snapshotErr)
+			search = newListSearch(config)
+			assert.NilError(t, search.Restore(snapshot))
+			method.SearchMethod = search
+		}
+	}
+	assert.Equal(t, search.progress(model.NewLengthInBatches(0)), 1.0)
+
+	// Every point is trained for the full length once, in the order listed, even if it repeats an
+	// earlier point.
+	var trials []hparamSample
+	for _, op := range method.ops {
+		switch op := op.(type) {
+		case Create:
+			trials = append(trials, op.Hparams)
+		case Train:
+			assert.DeepEqual(t, op.Length, config.MaxLength)
+		}
+	}
+	assert.DeepEqual(t, trials, []hparamSample{
+		{"lr": 0.1, "layers": 2, "optimizer": "adam"},
+		{"lr": 0.1, "layers": 4, "optimizer": "adam"},
+		{"lr": 0.01, "layers": 2, "optimizer": "sgd"},
+		{"lr": 0.5, "layers": 8, "optimizer": "adam"},
+		{"lr": 0.1, "layers": 2, "optimizer": "adam"},
+	})
+	assert.Equal(t, len(method.closeCounts()), len(points))
+	for _, count := range method.closeCounts() {
+		assert.Equal(t, count, 1)
+	}
+}
+
+func TestListSearchTrialExitedEarly(t *testing.T) {
+	config := model.ListConfig{
+		MaxLength:           model.NewLengthInBatches(500),
+		MaxConcurrentTrials: 1,
+		Points: []map[string]interface{}{
+			{"lr": 0.1, "layers": 2.0, "optimizer": "adam"},
+			{"lr": 0.2, "layers": 3.0, "optimizer": "sgd"},
+		},
+	}
+	search := newListSearch(config)
+	driver, err := newQueueDriver(search, listHyperparameters(), nil)
+	assert.NilError(t, err)
+	// The first trial exits early; the search moves on to the second point without retrying it.
+	driver.exitFn = func(trialIndex, _ int) bool { return trialIndex == 0 }
+	driver.metricFn = func(int, int) float64 { return 0 }
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+	assert.Equal(t, len(driver.trialIndex), 2)
+	assert.Equal(t, search.progress(model.NewLengthInBatches(0)), 1.0)
+}
+
+func TestListSearchInvalidPoint(t *testing.T) {
+	search := newListSearch(model.ListConfig{
+		MaxLength: model.NewLengthInBatches(500),
+		Points:    []map[string]interface{}{{"lr": 0.1, "layers": 2.5, "optimizer": "adam"}},
+	})
+	_, err := newQueueDriver(search, listHyperparameters(), nil)
+	assert.ErrorContains(t, err,
+		"invalid point 0 of list search: invalid value for hyperparameter layers: "+
+			"2.5 is not an integer")
+}
@@ -2,10 +2,16 @@ package searcher
 
 import (
 	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
+
+	"github.com/determined-ai/determined/master/pkg/model"
 )
 
 // CompletedMessage is the wrapping message returned by the trial runner when a workload
@@ -64,19 +70,129 @@ type ValidationMetrics struct {
 	Metrics   map[string]interface{} `json:"validation_metrics"`
 }
 
+// MissingMetricError is returned when a validation metric that the searcher needs was not reported
+// by the trial. This is usually a mistake in the experiment configuration or in the model's
+// validation code rather than an internal failure, so the error lists the metrics that were
+// reported to help the user correct it.
+type MissingMetricError struct {
+	Name      string
+	Available []string
+}
+
+func (e MissingMetricError) Error() string {
+	available := "none"
+	if len(e.Available) > 0 {
+		available = strings.Join(e.Available, ", ")
+	}
+	return fmt.Sprintf(
+		"'%s' could not be found in validation metrics (reported metrics: %s); make sure the "+
+			"searcher metric in the experiment configuration names a metric that the model returns "+
+			"from validation", e.Name, available)
+}
+
+// IsMissingMetric returns whether the cause of the error is a MissingMetricError.
+func IsMissingMetric(err error) bool {
+	_, ok := errors.Cause(err).(MissingMetricError)
+	return ok
+}
+
 // Metric returns the requested validation metric value from the set of validation metrics.
 func (metrics ValidationMetrics) Metric(name string) (float64, error) {
 	rawMetric, ok := metrics.Metrics[name]
 	if !ok {
-		return 0, errors.Errorf("'%s' could not be found in validation metrics", name)
+		available := make([]string, 0, len(metrics.Metrics))
+		for metricName := range metrics.Metrics {
+			available = append(available, metricName)
+		}
+		sort.Strings(available)
+		return 0, errors.WithStack(MetricError{
+			Err: MissingMetricError{Name: name, Available: available},
+		})
 	}
 	metric, ok := rawMetric.(float64)
 	if !ok {
-		return 0, errors.Errorf("'%s' is not a scalar float value", name)
+		return 0, errors.WithStack(MetricError{
+			Err: errors.Errorf("'%s' is not a scalar float value", name),
+		})
 	}
 	return metric, nil
 }
 
+// AggregateMetric combines the metrics named by the aggregation config into a single value. It
+// returns an error naming every metric that is missing or not a scalar float value.
+func (metrics ValidationMetrics) AggregateMetric(
+	config model.MetricAggregationConfig,
+) (float64, error) {
+	values := make([]float64, 0, len(config.Metrics))
+	var missing []string
+	for _, name := range config.Metrics {
+		metric, err := metrics.Metric(name)
+		if err != nil {
+			missing = append(missing, name)
+			continue
+		}
+		values = append(values, metric)
+	}
+	if len(missing) > 0 {
+		return 0, errors.WithStack(MetricError{Err: errors.Errorf(
+			"cannot compute %s of validation metrics: %v missing or not scalar float values",
+			config.Mode, missing)})
+	}
+	if len(values) == 0 {
+		return 0, errors.WithStack(MetricError{Err: errors.New("no validation metrics to aggregate")})
+	}
+
+	switch config.Mode {
+	case model.MeanAggregation, model.WeightedMeanAggregation:
+		var sum, totalWeight float64
+		for i, value := range values {
+			weight := 1.0
+			if config.Mode == model.WeightedMeanAggregation {
+				weight = config.Weights[i]
+			}
+			sum += weight * value
+			totalWeight += weight
+		}
+		return sum / totalWeight, nil
+	case model.MaxAggregation:
+		result := values[0]
+		for _, value := range values[1:] {
+			result = math.Max(result, value)
+		}
+		return result, nil
+	case model.MinAggregation:
+		result := values[0]
+		for _, value := range values[1:] {
+			result = math.Min(result, value)
+		}
+		return result, nil
+	default:
+		return 0, errors.WithStack(ConfigError{
+			Err: errors.Errorf("unknown metric aggregation mode: %s", config.Mode),
+		})
+	}
+}
+
+// transformMetric applies the named transform to a metric; no transform leaves it unchanged.
+// Transforms that are undefined for the metric, e.g., the logarithm of a negative number, return a
+// non-finite value.
+func transformMetric(transform model.MetricTransform, metric float64) (float64, error) {
+	switch transform {
+	case "", model.IdentityTransform:
+		return metric, nil
+	case model.NegateTransform:
+		return -metric, nil
+	case model.ReciprocalTransform:
+		return 1 / metric, nil
+	case model.LogTransform:
+		return math.Log(metric), nil
+	default:
+		return 0, errors.WithStack(ConfigError{
+			Err: errors.Errorf("unknown metric transform: %s", transform),
+		})
+	}
+}
+
 // ExitedReason defines why a workload exited early.
 type ExitedReason string
 
@@ -85,4 +201,16 @@ const (
 	Errored ExitedReason = "ERRORED"
 	// UserCanceled signals the searcher that the user requested a cancelation.
 	UserCanceled ExitedReason = "USER_CANCELED"
+	// Diverged signals the searcher that the trial stopped because its training diverged.
+	Diverged ExitedReason = "DIVERGED"
+	// Preempted signals the searcher that the trial was stopped through no fault of its own, e.g.,
+	// because the spot instance it ran on was reclaimed.
+	Preempted ExitedReason = "PREEMPTED"
 )
+
+// discardsResult returns whether a trial that exited early for this reason says nothing about its
+// hyperparameters, so that search methods should drop its partial result and replace it rather
+// than treat it as the worst possible result.
+func (r ExitedReason) discardsResult() bool {
+	return r == UserCanceled || r == Preempted
+}
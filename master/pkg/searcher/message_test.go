@@ -2,11 +2,14 @@ package searcher
 
 import (
 	"encoding/json"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"gotest.tools/assert"
+
+	"github.com/determined-ai/determined/master/pkg/model"
 )
 
 func roundTrip(t *testing.T, original, shell interface{}) interface{} {
@@ -89,3 +92,84 @@ func TestCompletedValidationMarshaling(t *testing.T) {
 	assert.Assert(t, rebuilt.RunMetrics == nil)
 	assert.Assert(t, rebuilt.CheckpointMetrics == nil)
 }
+
+func TestAggregateMetric(t *testing.T) {
+	metrics := ValidationMetrics{Metrics: map[string]interface{}{
+		"loss_a": 1.0,
+		"loss_b": 2.0,
+		"loss_c": 6.0,
+		"label":  "not a float",
+	}}
+	names := []string{"loss_a", "loss_b", "loss_c"}
+	cases := []struct {
+		config   model.MetricAggregationConfig
+		expected float64
+	}{
+		{model.MetricAggregationConfig{Mode: model.MeanAggregation, Metrics: names}, 3},
+		{model.MetricAggregationConfig{Mode: model.MaxAggregation, Metrics: names}, 6},
+		{model.MetricAggregationConfig{Mode: model.MinAggregation, Metrics: names}, 1},
+		{model.MetricAggregationConfig{
+			Mode: model.WeightedMeanAggregation, Metrics: names, Weights: []float64{2, 1, 1},
+		}, 2.5},
+	}
+	for _, c := range cases {
+		actual, err := metrics.AggregateMetric(c.config)
+		assert.NilError(t, err)
+		assert.Equal(t, actual, c.expected, "mode %s", c.config.Mode)
+	}
+
+	for _, mode := range []model.AggregationMode{
+		model.MeanAggregation, model.MaxAggregation, model.MinAggregation,
+	} {
+		_, err := metrics.AggregateMetric(model.MetricAggregationConfig{
+			Mode: mode, Metrics: []string{"loss_a", "loss_d", "label"},
+		})
+		assert.ErrorContains(t, err, "[loss_d label] missing or not scalar float values")
+	}
+}
+
+func TestMissingMetric(t *testing.T) {
+	metrics := ValidationMetrics{Metrics: map[string]interface{}{
+		"val_loss":     0.5,
+		"val_accuracy": 0.9,
+	}}
+	_, err := metrics.Metric("validation_error")
+	assert.Assert(t, IsMissingMetric(err))
+	assert.ErrorContains(t, err,
+		"'validation_error' could not be found in validation metrics "+
+			"(reported metrics: val_accuracy, val_loss)")
+
+	_, err = ValidationMetrics{}.Metric("validation_error")
+	assert.ErrorContains(t, err, "(reported metrics: none)")
+
+	// A metric that is present but not a float is not a missing metric.
+	metrics.Metrics["label"] = "cat"
+	_, err = metrics.Metric("label")
+	assert.Assert(t, !IsMissingMetric(err))
+}
+
+func TestSearcherMissingMetric(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:          defaultMetric,
+		SmallerIsBetter: true,
+		NumRungs:        2,
+		MaxLength:       model.NewLengthInBatches(200),
+		Divisor:         2,
+		MaxTrials:       2,
+	}
+	searcher := NewSearcher(0, newAsyncHalvingSearch(config), nil, nil)
+	ops, err := searcher.InitialOperations()
+	assert.NilError(t, err)
+	create := ops[0].(Create)
+	_, err = searcher.TrialCreated(create, 1)
+	assert.NilError(t, err)
+
+	_, err = searcher.OperationCompleted(1, NewValidate(create.RequestID), &ValidationMetrics{
+		Metrics: map[string]interface{}{"loss": 0.5, "accuracy": 0.8},
+	})
+	// The error still says which metric is missing after the searcher adds its own context.
+	assert.Assert(t, IsMissingMetric(err))
+	assert.ErrorContains(t, err, fmt.Sprintf(
+		"'%s' could not be found in validation metrics (reported metrics: accuracy, loss)",
+		defaultMetric))
+}
@@ -0,0 +1,33 @@
+package searcher
+
+// metricPoint is a validation metric reported by a trial, along with how long the trial had trained
+// for, in the unit of the search, when it reported it.
+type metricPoint struct {
+	Units  int     `json:"units"`
+	Metric float64 `json:"metric"`
+}
+
+// metricHistory holds the most recent metrics reported by a trial in a ring buffer, so that the
+// memory it takes is bounded no matter how many times the trial validates.
+type metricHistory struct {
+	Points []metricPoint `json:"points"`
+	// Next is the index of the oldest point, which the next point overwrites, once Points is full.
+	Next int `json:"next"`
+}
+
+// add records a point, evicting the oldest one if the history already holds window points.
+func (h *metricHistory) add(window int, point metricPoint) {
+	if len(h.Points) < window {
+		h.Points = append(h.Points, point)
+		return
+	}
+	h.Points[h.Next] = point
+	h.Next = (h.Next + 1) % len(h.Points)
+}
+
+// recent returns the points in the history from oldest to newest.
+func (h *metricHistory) recent() []metricPoint {
+	points := make([]metricPoint, 0, len(h.Points))
+	points = append(points, h.Points[h.Next:]...)
+	return append(points, h.Points[:h.Next]...)
+}
@@ -0,0 +1,33 @@
+package searcher
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestMetricHistory(t *testing.T) {
+	var history metricHistory
+	assert.Equal(t, len(history.recent()), 0)
+
+	points := func(units ...int) []metricPoint {
+		var result []metricPoint
+		for _, u := range units {
+			result = append(result, metricPoint{Units: u, Metric: float64(u) / 10})
+		}
+		return result
+	}
+	for units := 1; units <= 3; units++ {
+		history.add(3, points(units)[0])
+	}
+	assert.DeepEqual(t, history.recent(), points(1, 2, 3))
+
+	// Once full, each new point evicts the oldest one.
+	history.add(3, points(4)[0])
+	assert.DeepEqual(t, history.recent(), points(2, 3, 4))
+	for units := 5; units <= 9; units++ {
+		history.add(3, points(units)[0])
+	}
+	assert.DeepEqual(t, history.recent(), points(7, 8, 9))
+	assert.Equal(t, len(history.Points), 3)
+}
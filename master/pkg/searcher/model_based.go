@@ -0,0 +1,274 @@
+package searcher
+
+import (
+	"encoding/json"
+	"math"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// modelBasedSearch is the trial bookkeeping shared by the model-based search methods, TPE and
+// Bayesian optimization. It creates up to MaxConcurrentTrials trials at once and MaxTrials in
+// all, trains each for MaxLength and validates it once, and records the metric it reaches as an
+// observation; the search methods that embed it only decide, in propose, on the hyperparameters
+// of each new trial from the observations.
+type modelBasedSearch struct {
+	defaultSearchMethod
+	config modelBasedConfig
+	modelBasedSearchState
+
+	// propose returns the hyperparameters of the next trial and whether the trial is a random
+	// restart. It is called with mu held.
+	propose func(ctx context) (hparamSample, bool, error)
+
+	// mu guards modelBasedSearchState, since ObserveExternal and LoadPrior may be called
+	// concurrently with the rest of the search. Every callback holds it for its whole duration; the
+	// helpers they call assume it is held.
+	mu sync.Mutex
+}
+
+// modelBasedConfig is the part of the configuration of a model-based search method that its
+// trial bookkeeping depends on. Name names the search method in errors.
+type modelBasedConfig struct {
+	Name                string
+	Metric              string
+	SmallerIsBetter     bool
+	MaxLength           model.Length
+	MaxTrials           int
+	MaxConcurrentTrials int
+	PriorWeight         float64
+}
+
+// modelObservation is the hyperparameters of a completed trial and the metric it reached, negated
+// if larger is better so that smaller is always better. Weight is how much an observation loaded as
+// a prior counts for; the other observations leave it zero, and count in full.
+type modelObservation struct {
+	Hparams hparamSample `json:"hparams"`
+	Metric  float64      `json:"metric"`
+	Weight  float64      `json:"weight,omitempty"`
+}
+
+func (o modelObservation) weight() float64 {
+	if o.Weight == 0 {
+		return 1
+	}
+	return o.Weight
+}
+
+// observedWeight returns the total weight of the observations, i.e., the number of trials they
+// count for.
+func observedWeight(observations []modelObservation) float64 {
+	total := 0.0
+	for _, observation := range observations {
+		total += observation.weight()
+	}
+	return total
+}
+
+type modelBasedSearchState struct {
+	TrialParams     map[RequestID]hparamSample `json:"trial_params"`
+	Observations    []modelObservation         `json:"observations"`
+	TrialsCreated   int                        `json:"trials_created"`
+	TrialsCompleted int                        `json:"trials_completed"`
+	ClosedTrials    map[RequestID]bool         `json:"closed_trials"`
+	// Restarts records the trials that were sampled randomly after the startup trials because of
+	// RestartProbability, so that their contribution to the search can be told apart.
+	Restarts map[RequestID]bool `json:"restarts,omitempty"`
+}
+
+func newModelBasedSearch(config modelBasedConfig) *modelBasedSearch {
+	return &modelBasedSearch{
+		config: config,
+		modelBasedSearchState: modelBasedSearchState{
+			TrialParams:  make(map[RequestID]hparamSample),
+			ClosedTrials: make(map[RequestID]bool),
+			Restarts:     make(map[RequestID]bool),
+		},
+	}
+}
+
+func (s *modelBasedSearch) initialOperations(ctx context) ([]Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	concurrency := s.config.MaxConcurrentTrials
+	if concurrency <= 0 || concurrency > s.config.MaxTrials {
+		concurrency = s.config.MaxTrials
+	}
+	var ops []Operation
+	for trial := 0; trial < concurrency; trial++ {
+		created, err := s.createTrial(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, created...)
+	}
+	return ops, nil
+}
+
+// createTrial proposes the hyperparameters of a new trial and trains and validates it.
+func (s *modelBasedSearch) createTrial(ctx context) ([]Operation, error) {
+	hparams, restart, err := s.propose(ctx)
+	if err != nil {
+		return nil, err
+	}
+	create := NewCreate(ctx.rand, hparams, model.TrialWorkloadSequencerType)
+	s.TrialsCreated++
+	s.TrialParams[create.RequestID] = hparams
+	if restart {
+		s.Restarts[create.RequestID] = true
+	}
+	return []Operation{
+		create, NewTrain(create.RequestID, s.config.MaxLength), NewValidate(create.RequestID),
+	}, nil
+}
+
+// backfill creates a new trial if the search has not yet created all of its trials.
+func (s *modelBasedSearch) backfill(ctx context) ([]Operation, error) {
+	if s.TrialsCreated >= s.config.MaxTrials {
+		return nil, nil
+	}
+	return s.createTrial(ctx)
+}
+
+// complete marks the trial as done for the purposes of progress reporting, and as no longer
+// pending for the purposes of proposing new trials.
+func (s *modelBasedSearch) complete(requestID RequestID) {
+	if !s.ClosedTrials[requestID] {
+		s.ClosedTrials[requestID] = true
+		s.TrialsCompleted++
+	}
+}
+
+func (s *modelBasedSearch) validationCompleted(
+	ctx context, requestID RequestID, validate Validate, metrics ValidationMetrics,
+) ([]Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hparams, ok := s.TrialParams[requestID]
+	if !ok || s.ClosedTrials[requestID] {
+		return nil, nil
+	}
+	metric, err := metrics.Metric(s.config.Metric)
+	if err != nil {
+		return nil, err
+	}
+	if !s.config.SmallerIsBetter {
+		metric *= -1
+	}
+	// Diverged trials tell the model nothing about where good hyperparameters are, and a NaN would
+	// break the ordering and the statistics of the observations.
+	if !math.IsNaN(metric) && !math.IsInf(metric, 0) {
+		s.Observations = append(s.Observations, modelObservation{Hparams: hparams, Metric: metric})
+	}
+	s.complete(requestID)
+
+	ops := []Operation{NewClose(requestID)}
+	created, err := s.backfill(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return append(ops, created...), nil
+}
+
+func (s *modelBasedSearch) trialClosed(ctx context, requestID RequestID) ([]Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.complete(requestID)
+	return nil, nil
+}
+
+// trialExitedEarly replaces the trial with a new one; the trial is not observed, since it never
+// reported a metric.
+func (s *modelBasedSearch) trialExitedEarly(
+	ctx context, requestID RequestID, _ ExitedReason,
+) ([]Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ClosedTrials[requestID] {
+		return nil, nil
+	}
+	s.complete(requestID)
+	return s.backfill(ctx)
+}
+
+func (s *modelBasedSearch) progress(unitsCompleted model.Length) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	maxTrials := s.config.MaxTrials
+	progress := float64(unitsCompleted.Units) / float64(s.config.MaxLength.MultInt(maxTrials).Units)
+	if trials := float64(s.TrialsCompleted) / float64(maxTrials); trials > progress {
+		progress = trials
+	}
+	if progress > 1 {
+		return 1
+	}
+	return progress
+}
+
+// ObserveExternal adds the result of a trial evaluated outside of the search, e.g., on another
+// cluster, to the observations that new trials are proposed from. The trial does not count as one
+// of the MaxTrials trials of the search. It is safe to call concurrently with the rest of the
+// search.
+func (s *modelBasedSearch) ObserveExternal(hparams map[string]interface{}, metric float64) error {
+	if math.IsNaN(metric) || math.IsInf(metric, 0) {
+		return errors.Errorf("cannot observe a non-finite metric: %f", metric)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sample := make(hparamSample, len(hparams))
+	for name, value := range hparams {
+		sample[name] = value
+	}
+	if !s.config.SmallerIsBetter {
+		metric *= -1
+	}
+	s.Observations = append(s.Observations, modelObservation{Hparams: sample, Metric: metric})
+	return nil
+}
+
+// LoadPrior adds the observations, e.g., the results of the trials of a previous experiment over a
+// similar space, to those that new trials are proposed from, each counting for PriorWeight of a
+// trial of the search, both in the model and toward NumStartupTrials. Enough of them let the model
+// guide the first trials of the search, and the trials of the search outweigh them as they
+// complete. It is safe to call concurrently with the rest of the search.
+func (s *modelBasedSearch) LoadPrior(observations []Observation) error {
+	samples, metrics, err := priorSamples(observations, s.config.SmallerIsBetter)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, sample := range samples {
+		s.Observations = append(s.Observations, modelObservation{
+			Hparams: sample, Metric: metrics[i], Weight: s.config.PriorWeight,
+		})
+	}
+	return nil
+}
+
+func (s *modelBasedSearch) Snapshot() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return json.Marshal(s.modelBasedSearchState)
+}
+
+func (s *modelBasedSearch) Restore(state []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := json.Unmarshal(state, &s.modelBasedSearchState); err != nil {
+		return errors.Wrapf(err, "failed to restore %s search state", s.config.Name)
+	}
+	if s.TrialParams == nil {
+		s.TrialParams = make(map[RequestID]hparamSample)
+	}
+	if s.ClosedTrials == nil {
+		s.ClosedTrials = make(map[RequestID]bool)
+	}
+	if s.Restarts == nil {
+		s.Restarts = make(map[RequestID]bool)
+	}
+	return nil
+}
@@ -0,0 +1,371 @@
+package searcher
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// multiObjectiveSearch implements asynchronous successive halving over several objectives. Rather
+// than sorting each rung by a single metric, trials are ranked by how many other trials in the rung
+// dominate them, so that trials on the Pareto front of a rung are promoted first.
+type multiObjectiveSearch struct {
+	defaultSearchMethod
+	model.MultiObjectiveConfig
+	multiObjectiveSearchState
+}
+
+// multiObjectiveSearchState holds all of the mutable state of a multiObjectiveSearch; it is what
+// gets persisted by Snapshot and loaded by Restore.
+type multiObjectiveSearchState struct {
+	Rungs      []*paretoRung     `json:"rungs"`
+	TrialRungs map[RequestID]int `json:"trial_rungs"`
+	// EarlyExitTrials contains trials that exited early that are still considered in the search.
+	EarlyExitTrials   map[RequestID]bool `json:"early_exit_trials"`
+	ClosedTrials      map[RequestID]bool `json:"closed_trials"`
+	ValidatedRungs    map[RequestID]int  `json:"validated_rungs"`
+	TrialsCompleted   int                `json:"trials_completed"`
+	OutstandingTrials int                `json:"outstanding_trials"`
+}
+
+// trialObjectives is the value of every objective reported by a trial in a rung, sign-adjusted so
+// that smaller is better for each of them.
+type trialObjectives struct {
+	RequestID  RequestID `json:"request_id"`
+	Objectives []float64 `json:"objectives"`
+	Promoted   bool      `json:"promoted"`
+}
+
+type paretoRung struct {
+	UnitsNeeded       model.Length      `json:"units_needed"`
+	Metrics           []trialObjectives `json:"metrics"`
+	OutstandingTrials int               `json:"outstanding_trials"`
+}
+
+func newMultiObjectiveSearch(config model.MultiObjectiveConfig) SearchMethod {
+	rungs := make([]*paretoRung, 0, config.NumRungs)
+	for id := 0; id < config.NumRungs; id++ {
+		downsamplingRate := math.Pow(config.Divisor, float64(config.NumRungs-id-1))
+		unitsNeeded := max(int(float64(config.MaxLength.Units)/downsamplingRate), 1)
+		rungs = append(rungs, &paretoRung{UnitsNeeded: model.NewLength(config.Unit(), unitsNeeded)})
+	}
+	return &multiObjectiveSearch{
+		MultiObjectiveConfig: config,
+		multiObjectiveSearchState: multiObjectiveSearchState{
+			Rungs:           rungs,
+			TrialRungs:      make(map[RequestID]int),
+			EarlyExitTrials: make(map[RequestID]bool),
+			ClosedTrials:    make(map[RequestID]bool),
+			ValidatedRungs:  make(map[RequestID]int),
+		},
+	}
+}
+
+// dominates returns whether a is at least as good as b in every objective and strictly better in
+// at least one.
+func dominates(a, b []float64) bool {
+	better := false
+	for i := range a {
+		switch {
+		case a[i] > b[i]:
+			return false
+		case a[i] < b[i]:
+			better = true
+		}
+	}
+	return better
+}
+
+// dominanceCounts returns, for each trial in the rung, the number of other trials that dominate it.
+// Trials on the Pareto front of the rung have a count of zero.
+func (r *paretoRung) dominanceCounts() []int {
+	counts := make([]int, len(r.Metrics))
+	for i := range r.Metrics {
+		for j := range r.Metrics {
+			if dominates(r.Metrics[j].Objectives, r.Metrics[i].Objectives) {
+				counts[i]++
+			}
+		}
+	}
+	return counts
+}
+
+// promotionsPareto records the objectives of a trial and returns the trials to promote. The trials
+// in the rung are ranked by dominance count, with ties broken by request ID; every trial ranked
+// within the top 1/divisor of the rung that has not been promoted yet is promoted.
+func (r *paretoRung) promotionsPareto(
+	requestID RequestID, objectives []float64, divisor float64,
+) []RequestID {
+	r.Metrics = append(r.Metrics, trialObjectives{RequestID: requestID, Objectives: objectives})
+	numPromote := int(float64(len(r.Metrics)) / divisor)
+
+	counts := r.dominanceCounts()
+	order := make([]int, len(r.Metrics))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		if counts[order[i]] != counts[order[j]] {
+			return counts[order[i]] < counts[order[j]]
+		}
+		return r.Metrics[order[i]].RequestID.Before(r.Metrics[order[j]].RequestID)
+	})
+
+	var promotions []RequestID
+	for _, i := range order[:numPromote] {
+		if !r.Metrics[i].Promoted {
+			r.Metrics[i].Promoted = true
+			promotions = append(promotions, r.Metrics[i].RequestID)
+		}
+	}
+	return promotions
+}
+
+// worstObjectives returns the objectives recorded for trials that exit early or diverge, which are
+// dominated by any trial that reported finite values.
+func (s *multiObjectiveSearch) worstObjectives() []float64 {
+	objectives := make([]float64, len(s.Objectives))
+	for i := range objectives {
+		objectives[i] = math.MaxFloat64
+	}
+	return objectives
+}
+
+// concurrency is the maximum number of trials with outstanding work at once. As in asynchronous
+// halving, it is not capped at MaxTrials, since backfill never creates more trials than that.
+func (s *multiObjectiveSearch) concurrency() int {
+	if s.MaxConcurrentTrials > 0 {
+		return s.MaxConcurrentTrials
+	}
+	return halvingConcurrency(s.Divisor, s.NumRungs-1)
+}
+
+func (s *multiObjectiveSearch) initialOperations(ctx context) ([]Operation, error) {
+	return s.backfill(ctx)
+}
+
+// backfill creates new trials until the number of trials with outstanding work reaches the
+// concurrency limit, or until the maximum number of trials has been created.
+func (s *multiObjectiveSearch) backfill(ctx context) ([]Operation, error) {
+	var ops []Operation
+	for s.OutstandingTrials < s.concurrency() && len(s.TrialRungs) < s.MaxTrials {
+		hparams, err := sampleAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		create := NewCreate(ctx.rand, hparams, model.TrialWorkloadSequencerType)
+		s.TrialRungs[create.RequestID] = 0
+		s.OutstandingTrials++
+		ops = append(ops, create)
+		ops = append(ops, NewTrain(create.RequestID, s.Rungs[0].UnitsNeeded))
+		ops = append(ops, NewValidate(create.RequestID))
+	}
+	return ops, nil
+}
+
+func (s *multiObjectiveSearch) trialCreated(ctx context, requestID RequestID) ([]Operation, error) {
+	s.Rungs[0].OutstandingTrials++
+	s.TrialRungs[requestID] = 0
+	return nil, nil
+}
+
+func (s *multiObjectiveSearch) trialClosed(ctx context, requestID RequestID) ([]Operation, error) {
+	if !s.EarlyExitTrials[requestID] {
+		s.TrialsCompleted++
+	}
+	s.ClosedTrials[requestID] = true
+	return nil, nil
+}
+
+func (s *multiObjectiveSearch) validationCompleted(
+	ctx context, requestID RequestID, validate Validate, metrics ValidationMetrics,
+) ([]Operation, error) {
+	// As in asynchronous halving, a trial only validates once per rung, so a second validation for
+	// the same rung is a duplicate delivery.
+	rungIndex := s.TrialRungs[requestID]
+	if validatedRung, ok := s.ValidatedRungs[requestID]; ok && validatedRung >= rungIndex {
+		log.WithField("request-id", requestID).Debugf(
+			"ignoring duplicate validation for rung %d", rungIndex)
+		return nil, nil
+	}
+
+	objectives := make([]float64, 0, len(s.Objectives))
+	diverged := false
+	for _, objective := range s.Objectives {
+		metric, err := metrics.Metric(objective.Metric)
+		if err != nil {
+			return nil, err
+		}
+		if math.IsNaN(metric) || math.IsInf(metric, 0) {
+			log.WithField("request-id", requestID).WithField("metric", objective.Metric).Warnf(
+				"treating non-finite metric value %f as the worst possible value", metric)
+			diverged = true
+		}
+		if !objective.SmallerIsBetter {
+			metric *= -1
+		}
+		objectives = append(objectives, metric)
+	}
+	s.ValidatedRungs[requestID] = rungIndex
+	if diverged {
+		return s.promote(ctx, requestID, s.worstObjectives())
+	}
+	return s.promote(ctx, requestID, objectives)
+}
+
+func (s *multiObjectiveSearch) promote(
+	ctx context, requestID RequestID, objectives []float64,
+) ([]Operation, error) {
+	rungIndex := s.TrialRungs[requestID]
+	rung := s.Rungs[rungIndex]
+	rung.OutstandingTrials--
+	s.OutstandingTrials--
+
+	var ops []Operation
+	if rungIndex == s.NumRungs-1 {
+		rung.Metrics = append(rung.Metrics,
+			trialObjectives{RequestID: requestID, Objectives: objectives})
+		if !s.EarlyExitTrials[requestID] {
+			ops = append(ops, NewClose(requestID))
+			s.ClosedTrials[requestID] = true
+		}
+	} else {
+		nextRung := s.Rungs[rungIndex+1]
+		for _, promotionID := range rung.promotionsPareto(requestID, objectives, s.Divisor) {
+			s.TrialRungs[promotionID] = rungIndex + 1
+			nextRung.OutstandingTrials++
+			s.OutstandingTrials++
+			if !s.EarlyExitTrials[promotionID] {
+				unitsNeeded := max(nextRung.UnitsNeeded.Units-rung.UnitsNeeded.Units, 1)
+				ops = append(ops, NewTrain(promotionID, model.NewLength(s.Unit(), unitsNeeded)))
+				ops = append(ops, NewValidate(promotionID))
+			} else {
+				promoteOps, err := s.promote(ctx, promotionID, s.worstObjectives())
+				if err != nil {
+					return nil, err
+				}
+				ops = append(ops, promoteOps...)
+			}
+		}
+	}
+
+	creates, err := s.backfill(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ops = append(ops, creates...)
+
+	if len(s.Rungs[0].Metrics) == s.MaxTrials {
+		ops = append(ops, s.closeOutRungs()...)
+	}
+	return ops, nil
+}
+
+// closeOutRungs closes all remaining unpromoted trials in any rungs that have no more outstanding
+// trials.
+func (s *multiObjectiveSearch) closeOutRungs() []Operation {
+	var ops []Operation
+	for _, rung := range s.Rungs {
+		if rung.OutstandingTrials > 0 {
+			break
+		}
+		for _, trialMetric := range rung.Metrics {
+			requestID := trialMetric.RequestID
+			if !trialMetric.Promoted && !s.ClosedTrials[requestID] && !s.EarlyExitTrials[requestID] {
+				ops = append(ops, NewClose(requestID))
+				s.ClosedTrials[requestID] = true
+			}
+		}
+	}
+	return ops
+}
+
+// progress is the fraction of the expected training that has been done, in units. Each rung is
+// expected to receive the top 1/divisor of the trials of the rung below, starting from MaxTrials in
+// the bottom rung; those are exactly the trials that report in it once the search completes, so
+// progress reaches 1 then.
+func (s *multiObjectiveSearch) progress(unitsCompleted model.Length) float64 {
+	done, expected := 0.0, 0.0
+	expectedTrials, previousUnits := s.MaxTrials, 0
+	for _, rung := range s.Rungs {
+		units := float64(rung.UnitsNeeded.Units - previousUnits)
+		done += float64(len(rung.Metrics)) * units
+		expected += float64(max(expectedTrials, len(rung.Metrics))) * units
+		expectedTrials = int(float64(expectedTrials) / s.Divisor)
+		previousUnits = rung.UnitsNeeded.Units
+	}
+	if expected == 0 {
+		return 0
+	}
+	return math.Min(1, done/expected)
+}
+
+func (s *multiObjectiveSearch) trialExitedEarly(
+	ctx context, requestID RequestID, _ ExitedReason,
+) ([]Operation, error) {
+	s.EarlyExitTrials[requestID] = true
+	s.ClosedTrials[requestID] = true
+	s.TrialsCompleted++
+	return s.promote(ctx, requestID, s.worstObjectives())
+}
+
+// ParetoTrial is a trial on the Pareto front of a multi-objective search.
+type ParetoTrial struct {
+	RequestID RequestID `json:"request_id"`
+	// Objectives maps each objective metric to the value the trial reported for it.
+	Objectives map[string]float64 `json:"objectives"`
+}
+
+// ParetoFront returns the trials that completed the top rung and are not dominated by any other
+// trial that did, ordered by request ID.
+func (s *multiObjectiveSearch) ParetoFront() []ParetoTrial {
+	topRung := s.Rungs[len(s.Rungs)-1]
+	var front []ParetoTrial
+	for i, count := range topRung.dominanceCounts() {
+		trialMetric := topRung.Metrics[i]
+		if count > 0 || s.EarlyExitTrials[trialMetric.RequestID] {
+			continue
+		}
+		objectives := make(map[string]float64, len(s.Objectives))
+		for j, objective := range s.Objectives {
+			value := trialMetric.Objectives[j]
+			if !objective.SmallerIsBetter {
+				value *= -1
+			}
+			objectives[objective.Metric] = value
+		}
+		front = append(front, ParetoTrial{RequestID: trialMetric.RequestID, Objectives: objectives})
+	}
+	sort.Slice(front, func(i, j int) bool {
+		return front[i].RequestID.Before(front[j].RequestID)
+	})
+	return front
+}
+
+// Snapshot implements the SearchMethod interface.
+func (s *multiObjectiveSearch) Snapshot() ([]byte, error) {
+	return json.Marshal(s.multiObjectiveSearchState)
+}
+
+// Restore implements the SearchMethod interface.
+func (s *multiObjectiveSearch) Restore(state []byte) error {
+	var restored multiObjectiveSearchState
+	if err := json.Unmarshal(state, &restored); err != nil {
+		return errors.Wrap(err, "failed to restore multi-objective search state")
+	}
+	if len(restored.Rungs) != s.NumRungs {
+		return errors.Errorf(
+			"cannot restore %d rungs into a search configured with %d rungs",
+			len(restored.Rungs), s.NumRungs)
+	}
+	if restored.ValidatedRungs == nil {
+		restored.ValidatedRungs = make(map[RequestID]int)
+	}
+	s.multiObjectiveSearchState = restored
+	return nil
+}
@@ -0,0 +1,187 @@
+package searcher
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func TestDominates(t *testing.T) {
+	assert.Assert(t, dominates([]float64{1, 2}, []float64{2, 2}))
+	assert.Assert(t, dominates([]float64{1, 1}, []float64{2, 2}))
+	assert.Assert(t, !dominates([]float64{1, 2}, []float64{1, 2}))
+	assert.Assert(t, !dominates([]float64{1, 3}, []float64{2, 2}))
+}
+
+func TestMultiObjectiveSearcherParetoPromotion(t *testing.T) {
+	config := model.MultiObjectiveConfig{
+		Objectives: []model.Objective{
+			{Metric: "accuracy", SmallerIsBetter: false},
+			{Metric: "latency", SmallerIsBetter: true},
+		},
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           9,
+		MaxConcurrentTrials: 9,
+	}
+	// Trials 0, 2, and 4 trade accuracy for latency; every other trial is dominated by one of them.
+	points := [][2]float64{
+		{0.9, 30}, {0.85, 35}, {0.8, 20}, {0.75, 25}, {0.7, 10},
+		{0.65, 15}, {0.6, 40}, {0.8, 30}, {0.7, 20},
+	}
+	front := map[int]bool{0: true, 2: true, 4: true}
+	for i, point := range points {
+		dominated := false
+		for _, other := range points {
+			dominated = dominated ||
+				(other[0] >= point[0] && other[1] <= point[1] && other != point)
+		}
+		assert.Equal(t, !dominated, front[i], "trial %d", i)
+	}
+
+	search := newMultiObjectiveSearch(config).(*multiObjectiveSearch)
+	method := &recordingMethod{SearchMethod: search}
+	driver, err := newQueueDriver(method, nil, nil)
+	assert.NilError(t, err)
+	driver.metricsFn = func(trialIndex, validations int) map[string]interface{} {
+		return map[string]interface{}{
+			"accuracy": points[trialIndex][0],
+			"latency":  points[trialIndex][1],
+		}
+	}
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+
+	trains := make(map[int]int)
+	for _, op := range method.ops {
+		if train, ok := op.(Train); ok {
+			trains[driver.trialIndex[train.RequestID]]++
+		}
+	}
+	var topRung []int
+	for trialIndex, count := range trains {
+		if count > 1 {
+			assert.Assert(t, front[trialIndex], "dominated trial %d was promoted", trialIndex)
+		}
+		if count == config.NumRungs {
+			topRung = append(topRung, trialIndex)
+		}
+	}
+	assert.Equal(t, len(topRung), 1)
+	assert.Equal(t, len(method.closeCounts()), config.MaxTrials)
+	for _, count := range method.closeCounts() {
+		assert.Equal(t, count, 1)
+	}
+
+	paretoFront := search.ParetoFront()
+	assert.Equal(t, len(paretoFront), 1)
+	assert.Equal(t, driver.trialIndex[paretoFront[0].RequestID], topRung[0])
+	assert.DeepEqual(t, paretoFront[0].Objectives, map[string]float64{
+		"accuracy": points[topRung[0]][0],
+		"latency":  points[topRung[0]][1],
+	})
+	assert.Equal(t, search.progress(model.Length{}), 1.0)
+}
+
+func TestMultiObjectiveSearcherMissingObjective(t *testing.T) {
+	config := model.MultiObjectiveConfig{
+		Objectives: []model.Objective{{Metric: "accuracy"}, {Metric: "latency"}},
+		NumRungs:   2,
+		MaxLength:  model.NewLengthInBatches(200),
+		Divisor:    2,
+		MaxTrials:  2,
+	}
+	driver, err := newQueueDriver(newMultiObjectiveSearch(config), nil, nil)
+	assert.NilError(t, err)
+	driver.metricsFn = func(int, int) map[string]interface{} {
+		return map[string]interface{}{"accuracy": 0.5}
+	}
+	for err == nil && len(driver.pending) > 0 {
+		_, err = driver.step()
+	}
+	assert.ErrorContains(t, err, "'latency' could not be found")
+}
+
+func TestMultiObjectiveSearcherProgress(t *testing.T) {
+	for _, tc := range []struct {
+		numRungs  int
+		divisor   float64
+		maxTrials int
+	}{
+		{numRungs: 1, divisor: 4, maxTrials: 5},
+		{numRungs: 2, divisor: 2, maxTrials: 7},
+		{numRungs: 3, divisor: 3, maxTrials: 9},
+		{numRungs: 3, divisor: 2.5, maxTrials: 20},
+		{numRungs: 4, divisor: 4, maxTrials: 16},
+	} {
+		config := model.MultiObjectiveConfig{
+			Objectives: []model.Objective{{Metric: "accuracy"}, {Metric: "latency"}},
+			NumRungs:   tc.numRungs,
+			MaxLength:  model.NewLengthInBatches(1000),
+			Divisor:    tc.divisor,
+			MaxTrials:  tc.maxTrials,
+		}
+		search := newMultiObjectiveSearch(config)
+		driver, err := newQueueDriver(search, nil, nil)
+		assert.NilError(t, err)
+		driver.metricsFn = func(trialIndex, validations int) map[string]interface{} {
+			return map[string]interface{}{
+				"accuracy": float64(trialIndex),
+				"latency":  float64((trialIndex * 7) % tc.maxTrials),
+			}
+		}
+		// Progress never decreases and is exactly 1 once the search completes, whatever the
+		// schedule.
+		last := 0.0
+		for len(driver.pending) > 0 {
+			_, err = driver.step()
+			assert.NilError(t, err)
+			progress := search.progress(model.Length{})
+			assert.Assert(t, progress >= last, "%+v: progress decreased from %f to %f", tc, last,
+				progress)
+			last = progress
+		}
+		assert.Equal(t, last, 1.0, "%+v", tc)
+	}
+}
+
+func TestMultiObjectiveSearcherConcurrencyAboveMaxTrials(t *testing.T) {
+	config := model.MultiObjectiveConfig{
+		Objectives:          []model.Objective{{Metric: "accuracy"}, {Metric: "latency"}},
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(200),
+		Divisor:             2,
+		MaxTrials:           5,
+		MaxConcurrentTrials: 10,
+	}
+	method := &recordingMethod{SearchMethod: newMultiObjectiveSearch(config)}
+	driver, err := newQueueDriver(method, nil, nil)
+	assert.NilError(t, err)
+	driver.metricsFn = func(trialIndex, _ int) map[string]interface{} {
+		return map[string]interface{}{"accuracy": float64(trialIndex), "latency": 1.0}
+	}
+	initial := 0
+	for _, op := range driver.pending {
+		if _, ok := op.(Create); ok {
+			initial++
+		}
+	}
+	assert.Equal(t, initial, config.MaxTrials)
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+	created := 0
+	for _, op := range method.ops {
+		if _, ok := op.(Create); ok {
+			created++
+		}
+	}
+	assert.Equal(t, created, config.MaxTrials)
+	assert.Equal(t, len(method.closeCounts()), config.MaxTrials)
+}
@@ -0,0 +1,382 @@
+package searcher
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// noiseAdaptiveSearch is an adaptive ASHA search that shifts trials between its brackets according
+// to how noisy the metric is. Early stopping only pays off when the metric a trial reports after a
+// short training predicts the one it reports after a longer one, so the less noisy the metric, the
+// more trials the aggressive brackets should get. Each bracket starts with half of the trials that
+// adaptive ASHA would give it, but no fewer than it trains at once; the rest are held back and
+// assigned one at a time, whenever a bracket has run out of trials while it could train more, to
+// the bracket furthest below its share of the held-back trials as weighed by bracketWeights.
+type noiseAdaptiveSearch struct {
+	*tournamentSearch
+	model.AdaptiveASHAConfig
+	noiseAdaptiveSearchState
+
+	brackets []*asyncHalvingSearch
+	// shares is the number of trials that adaptive ASHA would give each bracket.
+	shares []int
+	// mu guards noiseAdaptiveSearchState and the tournament. The brackets' own locks cannot cover
+	// the assignment of held-back trials, which reads the state of every bracket.
+	mu sync.Mutex
+}
+
+// noiseTrial is what a noise adaptive search tracks about each trial: how long it has trained, and
+// how long it had trained when it last reported a metric, along with that metric if it was finite.
+type noiseTrial struct {
+	Trained  int      `json:"trained"`
+	Observed int      `json:"observed"`
+	Metric   *float64 `json:"metric"`
+}
+
+// runningVariance accumulates the mean and variance of a stream of values by Welford's algorithm.
+type runningVariance struct {
+	Count int     `json:"count"`
+	Mean  float64 `json:"mean"`
+	M2    float64 `json:"m2"`
+}
+
+func (v *runningVariance) add(x float64) {
+	v.Count++
+	delta := x - v.Mean
+	v.Mean += delta / float64(v.Count)
+	v.M2 += delta * (x - v.Mean)
+}
+
+// variance returns the sample variance of the values, or zero if there are fewer than two.
+func (v runningVariance) variance() float64 {
+	if v.Count < 2 {
+		return 0
+	}
+	return v.M2 / float64(v.Count-1)
+}
+
+// budgetNoise holds the running variance of the metrics that trials reported after training for
+// one length and that of how much the metrics of those trials changed by their next report.
+type budgetNoise struct {
+	Metrics runningVariance `json:"metrics"`
+	Changes runningVariance `json:"changes"`
+}
+
+type noiseAdaptiveSearchState struct {
+	// HeldBack is the number of trials yet to be assigned to a bracket, and Assigned is the number
+	// of held-back trials assigned to each bracket so far.
+	HeldBack int                       `json:"held_back"`
+	Assigned []int                     `json:"assigned"`
+	Trials   map[RequestID]*noiseTrial `json:"trials"`
+	// Budgets holds the running variances for each length trials have reported after, in units.
+	Budgets map[int]*budgetNoise `json:"budgets"`
+}
+
+func newNoiseAdaptiveSearch(config model.AdaptiveASHAConfig) SearchMethod {
+	configs := adaptiveASHABracketConfigs(config)
+	s := &noiseAdaptiveSearch{
+		AdaptiveASHAConfig: config,
+		noiseAdaptiveSearchState: noiseAdaptiveSearchState{
+			Assigned: make([]int, len(configs)),
+			Trials:   make(map[RequestID]*noiseTrial),
+			Budgets:  make(map[int]*budgetNoise),
+		},
+	}
+	methods := make([]SearchMethod, 0, len(configs))
+	for _, c := range configs {
+		share := c.MaxTrials
+		c.MaxTrials = min(share, max((share+1)/2, c.MaxConcurrentTrials))
+		s.shares = append(s.shares, share)
+		s.HeldBack += share - c.MaxTrials
+		bracket := newAsyncHalvingSearch(c).(*asyncHalvingSearch)
+		s.brackets = append(s.brackets, bracket)
+		methods = append(methods, bracket)
+	}
+	s.tournamentSearch = newTournamentSearch(methods...)
+	return s
+}
+
+// assign assigns a held-back trial to a bracket once every trial assigned so far has been created
+// and some bracket could train another trial, and returns the operations of a callback along with
+// any that the assignment creates.
+func (s *noiseAdaptiveSearch) assign(ctx context, ops []Operation) ([]Operation, error) {
+	if s.HeldBack == 0 {
+		return ops, nil
+	}
+	idle := false
+	for _, bracket := range s.brackets {
+		created, bracketIdle := bracket.trialDemand()
+		if !created {
+			return ops, nil
+		}
+		idle = idle || bracketIdle
+	}
+	if !idle {
+		return ops, nil
+	}
+
+	weights := s.bracketWeights()
+	assigned := 1
+	for _, count := range s.Assigned {
+		assigned += count
+	}
+	chosen, deficit := 0, math.Inf(-1)
+	for i, weight := range weights {
+		if d := weight*float64(assigned) - float64(s.Assigned[i]); d > deficit {
+			chosen, deficit = i, d
+		}
+	}
+	s.HeldBack--
+	s.Assigned[chosen]++
+	creates, err := s.brackets[chosen].addTrials(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	return append(ops, s.markCreates(s.brackets[chosen], creates)...), nil
+}
+
+// bracketWeights returns the fraction of the held-back trials that each bracket should receive.
+// Without an estimate of the noise, that is its share of the trials of adaptive ASHA. An estimate
+// below one half moves weight from the conservative brackets to the aggressive ones, so that with
+// no noise at all the most aggressive bracket gets twice its share and the least none; an estimate
+// above one half moves it the other way, as far again once the metric is pure noise.
+func (s *noiseAdaptiveSearch) bracketWeights() []float64 {
+	lean := 0.0
+	if noise, ok := s.noise(); ok {
+		lean = 1 - 2*noise
+	}
+	weights := make([]float64, len(s.shares))
+	total := 0.0
+	for i, share := range s.shares {
+		// aggressiveness runs from 1 for the first bracket down to -1 for the last.
+		aggressiveness := 0.0
+		if len(s.shares) > 1 {
+			aggressiveness = 1 - 2*float64(i)/float64(len(s.shares)-1)
+		}
+		weights[i] = float64(share) * (1 + lean*aggressiveness)
+		total += weights[i]
+	}
+	for i := range weights {
+		weights[i] /= total
+	}
+	return weights
+}
+
+// noise estimates the fraction of the variance of the metrics that trials report after the same
+// training that is noise, as opposed to differences between their hyperparameters. If each metric
+// is the sum of a term for the trial's hyperparameters, a drift with training that is the same for
+// every trial, and independent noise, then the change between two reports of a trial varies by
+// twice the variance of the noise, while the metrics after the same training vary by that of the
+// noise plus that of the first term. The estimate is half the variance of the changes over that of
+// the metrics, pooled over the lengths that changes have been observed from and capped at one; it
+// is unknown until there are at least two changes from some length.
+func (s *noiseAdaptiveSearch) noise() (float64, bool) {
+	// The lengths are summed over in order so that the estimate does not depend on map order.
+	lengths := make([]int, 0, len(s.Budgets))
+	for units := range s.Budgets {
+		lengths = append(lengths, units)
+	}
+	sort.Ints(lengths)
+	changes, metrics := 0.0, 0.0
+	for _, units := range lengths {
+		budget := s.Budgets[units]
+		if budget.Changes.Count < 2 {
+			continue
+		}
+		weight := float64(budget.Changes.Count)
+		changes += weight * budget.Changes.variance()
+		metrics += weight * budget.Metrics.variance()
+	}
+	switch {
+	case changes == 0 && metrics == 0:
+		return 0, false
+	case metrics == 0:
+		return 1, true
+	default:
+		return math.Min(changes/(2*metrics), 1), true
+	}
+}
+
+// observe adds the metric a trial reported to the running variances of the length it has trained
+// for and, if the trial reported a finite metric before, its change since then to those of the
+// length it had trained for then. Each trial is observed once per length it trains for.
+func (s *noiseAdaptiveSearch) observe(requestID RequestID, metrics ValidationMetrics) error {
+	trial, ok := s.Trials[requestID]
+	if !ok || trial.Trained <= trial.Observed {
+		return nil
+	}
+	metric, err := metrics.Metric(s.Metric)
+	if err != nil {
+		return err
+	}
+	previous := trial.Metric
+	previousUnits := trial.Observed
+	trial.Observed = trial.Trained
+	trial.Metric = nil
+	// Diverged trials tell nothing about the noise of the trials that train normally.
+	if math.IsNaN(metric) || math.IsInf(metric, 0) {
+		return nil
+	}
+	trial.Metric = &metric
+	s.budget(trial.Trained).Metrics.add(metric)
+	if previous != nil {
+		s.budget(previousUnits).Changes.add(metric - *previous)
+	}
+	return nil
+}
+
+// budget returns the running variances of the length, creating them if need be.
+func (s *noiseAdaptiveSearch) budget(units int) *budgetNoise {
+	budget, ok := s.Budgets[units]
+	if !ok {
+		budget = &budgetNoise{}
+		s.Budgets[units] = budget
+	}
+	return budget
+}
+
+func (s *noiseAdaptiveSearch) initialOperations(ctx context) ([]Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ops, err := s.tournamentSearch.initialOperations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.assign(ctx, ops)
+}
+
+func (s *noiseAdaptiveSearch) trialCreated(ctx context, requestID RequestID) ([]Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Trials[requestID] = &noiseTrial{}
+	ops, err := s.tournamentSearch.trialCreated(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	return s.assign(ctx, ops)
+}
+
+func (s *noiseAdaptiveSearch) trainCompleted(
+	ctx context, requestID RequestID, train Train,
+) ([]Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if trial, ok := s.Trials[requestID]; ok {
+		trial.Trained += train.Length.Units
+	}
+	ops, err := s.tournamentSearch.trainCompleted(ctx, requestID, train)
+	if err != nil {
+		return nil, err
+	}
+	return s.assign(ctx, ops)
+}
+
+func (s *noiseAdaptiveSearch) checkpointCompleted(
+	ctx context, requestID RequestID, checkpoint Checkpoint, metrics CheckpointMetrics,
+) ([]Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ops, err := s.tournamentSearch.checkpointCompleted(ctx, requestID, checkpoint, metrics)
+	if err != nil {
+		return nil, err
+	}
+	return s.assign(ctx, ops)
+}
+
+func (s *noiseAdaptiveSearch) validationCompleted(
+	ctx context, requestID RequestID, validate Validate, metrics ValidationMetrics,
+) ([]Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ops, err := s.tournamentSearch.validationCompleted(ctx, requestID, validate, metrics)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.observe(requestID, metrics); err != nil {
+		return nil, err
+	}
+	return s.assign(ctx, ops)
+}
+
+func (s *noiseAdaptiveSearch) trialClosed(ctx context, requestID RequestID) ([]Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ops, err := s.tournamentSearch.trialClosed(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	return s.assign(ctx, ops)
+}
+
+func (s *noiseAdaptiveSearch) trialExitedEarly(
+	ctx context, requestID RequestID, reason ExitedReason,
+) ([]Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ops, err := s.tournamentSearch.trialExitedEarly(ctx, requestID, reason)
+	if err != nil {
+		return nil, err
+	}
+	return s.assign(ctx, ops)
+}
+
+// progress counts the held-back trials as not yet started.
+func (s *noiseAdaptiveSearch) progress(unitsCompleted model.Length) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	assigned := 1 - float64(s.HeldBack)/float64(s.MaxTrials)
+	return s.tournamentSearch.progress(unitsCompleted) * assigned
+}
+
+// Unit implements the model.InUnits interface.
+func (s *noiseAdaptiveSearch) Unit() model.Unit {
+	return s.AdaptiveASHAConfig.Unit()
+}
+
+// noiseAdaptiveSnapshot is the serialized form of a noiseAdaptiveSearch.
+type noiseAdaptiveSnapshot struct {
+	Tournament json.RawMessage `json:"tournament"`
+	noiseAdaptiveSearchState
+}
+
+func (s *noiseAdaptiveSearch) Snapshot() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tournament, err := s.tournamentSearch.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(noiseAdaptiveSnapshot{
+		Tournament: tournament, noiseAdaptiveSearchState: s.noiseAdaptiveSearchState,
+	})
+}
+
+func (s *noiseAdaptiveSearch) Restore(state []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var snapshot noiseAdaptiveSnapshot
+	if err := json.Unmarshal(state, &snapshot); err != nil {
+		return errors.Wrap(err, "failed to restore noise adaptive search state")
+	}
+	if len(snapshot.Assigned) != len(s.brackets) {
+		return errors.Errorf("cannot restore the assignments of %d brackets into a search of %d",
+			len(snapshot.Assigned), len(s.brackets))
+	}
+	if err := s.tournamentSearch.Restore(snapshot.Tournament); err != nil {
+		return err
+	}
+	s.noiseAdaptiveSearchState = snapshot.noiseAdaptiveSearchState
+	if s.Trials == nil {
+		s.Trials = make(map[RequestID]*noiseTrial)
+	}
+	if s.Budgets == nil {
+		s.Budgets = make(map[int]*budgetNoise)
+	}
+	return nil
+}
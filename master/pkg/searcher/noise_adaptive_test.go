@@ -0,0 +1,114 @@
+package searcher
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/nprand"
+)
+
+func TestRunningVariance(t *testing.T) {
+	var v runningVariance
+	assert.Equal(t, v.variance(), 0.0)
+	for _, x := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+		v.add(x)
+	}
+	assert.Equal(t, v.Count, 8)
+	assert.Equal(t, v.Mean, 5.0)
+	assert.Equal(t, v.variance(), 32.0/7)
+}
+
+// noiseAdaptiveConfig returns the configuration of a noise adaptive search with three brackets,
+// which adaptive ASHA would give 34, 16, and 10 of the trials.
+func noiseAdaptiveConfig() model.AdaptiveASHAConfig {
+	return model.AdaptiveASHAConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		MaxLength:           model.NewLengthInBatches(900),
+		MaxTrials:           60,
+		Divisor:             3,
+		Mode:                model.ConservativeMode,
+		MaxRungs:            3,
+		MaxConcurrentTrials: 6,
+		AdaptToNoise:        true,
+	}
+}
+
+func TestNoiseAdaptiveSearchBracketAssignment(t *testing.T) {
+	// run drives a search to completion and returns the number of held-back trials it assigned to
+	// each bracket.
+	run := func(metricFn func(trialIndex, validations int) float64) []int {
+		config := noiseAdaptiveConfig()
+		search := newAdaptiveASHASearch(config).(*noiseAdaptiveSearch)
+		assert.DeepEqual(t, search.shares, []int{34, 16, 10})
+		assert.Equal(t, search.HeldBack, 30)
+		method := &recordingMethod{SearchMethod: search}
+		driver, err := newQueueDriver(method, nil, metricFn)
+		assert.NilError(t, err)
+		for len(driver.pending) > 0 {
+			_, err = driver.step()
+			assert.NilError(t, err)
+		}
+
+		// Every trial is created and closed once, whichever bracket it ends up in.
+		assert.Equal(t, len(driver.trialIndex), config.MaxTrials)
+		assert.Equal(t, len(method.closeCounts()), config.MaxTrials)
+		for _, count := range method.closeCounts() {
+			assert.Equal(t, count, 1)
+		}
+		assert.Equal(t, search.HeldBack, 0)
+		assert.Equal(t, search.progress(model.NewLengthInBatches(0)), 1.0)
+		return search.Assigned
+	}
+
+	// quality is the part of the metric that depends on the trial alone.
+	quality := func(trialIndex int) float64 { return float64((trialIndex * 7) % 11) }
+
+	// When every trial improves by the same amount as it trains, the metric after the shortest
+	// training ranks trials perfectly, and the held-back trials go to the aggressive brackets.
+	lowNoise := run(func(trialIndex, validations int) float64 {
+		return quality(trialIndex) + 1/float64(validations+1)
+	})
+	// When most of the metric is noise, they go to the conservative brackets instead.
+	highNoise := run(func(trialIndex, validations int) float64 {
+		noise := nprand.New(uint32(trialIndex*10+validations)).Uniform(-20, 20)
+		return quality(trialIndex) + noise
+	})
+	assert.Assert(t, lowNoise[0] > highNoise[0], "low: %v, high: %v", lowNoise, highNoise)
+	assert.Assert(t, lowNoise[2] < highNoise[2], "low: %v, high: %v", lowNoise, highNoise)
+	// Without noise, the most aggressive bracket gets more than its share of 34 in 60, and the
+	// least aggressive less than its share of 10 in 60; with mostly noise, the reverse.
+	assert.Assert(t, lowNoise[0]*60 > 34*30 && lowNoise[2]*60 < 10*30, "low: %v", lowNoise)
+	assert.Assert(t, highNoise[0]*60 < 34*30 && highNoise[2]*60 > 10*30, "high: %v", highNoise)
+}
+
+func TestNoiseAdaptiveSearchSnapshotRestore(t *testing.T) {
+	config := noiseAdaptiveConfig()
+	metricFn := func(trialIndex, validations int) float64 {
+		return float64((trialIndex*5)%12) + 1/float64(validations+1)
+	}
+
+	original, err := newQueueDriver(newAdaptiveASHASearch(config), nil, metricFn)
+	assert.NilError(t, err)
+	for i := 0; i < 200; i++ {
+		_, err = original.step()
+		assert.NilError(t, err)
+	}
+	assert.Assert(t, original.method.(*noiseAdaptiveSearch).HeldBack < 30)
+
+	snapshot, err := original.method.Snapshot()
+	assert.NilError(t, err)
+	restoredMethod := newAdaptiveASHASearch(config)
+	assert.NilError(t, restoredMethod.Restore(snapshot))
+
+	restored := original.clone(restoredMethod)
+	for len(original.pending) > 0 {
+		expected, expectedErr := original.step()
+		assert.NilError(t, expectedErr)
+		actual, actualErr := restored.step()
+		assert.NilError(t, actualErr)
+		assert.DeepEqual(t, actual, expected)
+	}
+}
@@ -91,11 +91,31 @@ type Runnable interface {
 // Create a new trial for the search method.
 type Create struct {
 	RequestID RequestID `json:"request_id"`
-	// TrialSeed must be a value between 0 and 2**31 - 1.
-	TrialSeed             uint32                      `json:"trial_seed"`
-	Hparams               hparamSample                `json:"hparams"`
+	// TrialSeed must be a value between 0 and 2**31 - 1. The Searcher replaces it with a seed
+	// derived from the experiment seed before the operation is returned.
+	TrialSeed uint32       `json:"trial_seed"`
+	Hparams   hparamSample `json:"hparams"`
+	// Checkpoint, if set, refers to a Checkpoint operation of another trial that the new trial
+	// should load its initial weights from. The experiment resolves it to the latest checkpoint of
+	// that trial, in place of any experiment-wide source checkpoint, before starting the trial, so
+	// search methods must only emit such a Create after the checkpoint has completed.
 	Checkpoint            *Checkpoint                 `json:"checkpoint"`
 	WorkloadSequencerType model.WorkloadSequencerType `json:"workload_sequencer_type"`
+	// Metadata, if set, tags the trial, e.g., with the bracket or generation it belongs to. The
+	// Searcher keeps it for the trial, so that the search method can look it up by request ID in the
+	// context of any later callback about the trial, e.g., to route its validations.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// WithMetadata returns a copy of the Create operation with the metadata key set to the value.
+func (c Create) WithMetadata(key, value string) Create {
+	metadata := make(map[string]string, len(c.Metadata)+1)
+	for k, v := range c.Metadata {
+		metadata[k] = v
+	}
+	metadata[key] = value
+	c.Metadata = metadata
+	return c
 }
 
 // NewCreate initializes a new Create operation with a new request ID and the given hyperparameters.
@@ -110,7 +130,8 @@ func NewCreate(
 }
 
 // NewCreateFromCheckpoint initializes a new Create operation with a new request ID and the given
-// hyperparameters and checkpoint to initially load from.
+// hyperparameters and checkpoint to initially load from. This is how population-based training
+// copies a well-performing trial into a new trial with perturbed hyperparameters.
 func NewCreateFromCheckpoint(
 	rand *nprand.State, s hparamSample, checkpoint Checkpoint,
 	sequencerType model.WorkloadSequencerType,
@@ -0,0 +1,109 @@
+package searcher
+
+import (
+	"github.com/pkg/errors"
+)
+
+// deferredOperation is an operation that a paused search has decided on but not yet emitted, in a
+// form that can be persisted; exactly one of its fields is set.
+type deferredOperation struct {
+	Create     *Create     `json:"create,omitempty"`
+	Train      *Train      `json:"train,omitempty"`
+	Validate   *Validate   `json:"validate,omitempty"`
+	Checkpoint *Checkpoint `json:"checkpoint,omitempty"`
+	Close      *Close      `json:"close,omitempty"`
+}
+
+func newDeferredOperation(op Operation) (deferredOperation, error) {
+	switch op := op.(type) {
+	case Create:
+		return deferredOperation{Create: &op}, nil
+	case Train:
+		return deferredOperation{Train: &op}, nil
+	case Validate:
+		return deferredOperation{Validate: &op}, nil
+	case Checkpoint:
+		return deferredOperation{Checkpoint: &op}, nil
+	case Close:
+		return deferredOperation{Close: &op}, nil
+	default:
+		return deferredOperation{}, errors.Errorf("cannot defer operation %v", op)
+	}
+}
+
+func (d deferredOperation) operation() Operation {
+	switch {
+	case d.Create != nil:
+		return *d.Create
+	case d.Train != nil:
+		return *d.Train
+	case d.Validate != nil:
+		return *d.Validate
+	case d.Checkpoint != nil:
+		return *d.Checkpoint
+	default:
+		return *d.Close
+	}
+}
+
+// emit returns the operations of a callback, unless the search is paused, in which case it defers
+// them until the search is resumed.
+func (s *asyncHalvingSearch) emit(ops []Operation, err error) ([]Operation, error) {
+	if err != nil || !s.Paused {
+		return ops, err
+	}
+	for _, op := range ops {
+		deferred, deferErr := newDeferredOperation(op)
+		if deferErr != nil {
+			return nil, deferErr
+		}
+		s.Deferred = append(s.Deferred, deferred)
+	}
+	return nil, nil
+}
+
+// pause implements the pauser interface. While paused, the search keeps recording the metrics that
+// trials report in its rungs and deciding on promotions and new trials, but holds back the
+// operations for them.
+func (s *asyncHalvingSearch) pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Paused = true
+}
+
+// resume implements the pauser interface. It returns the deferred operations in the order the
+// search decided on them, so that each trial is created before it trains and trains before it
+// validates. The work deferred for a trial that was closed afterwards, e.g., by cancelStragglers,
+// is dropped, so that it is not started only to be closed. Trials with deferred work count as
+// starting it now for TrialValidationTimeout.
+func (s *asyncHalvingSearch) resume(ctx context) ([]Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lastClose := make(map[RequestID]int)
+	for i, deferred := range s.Deferred {
+		if deferred.Close != nil {
+			lastClose[deferred.Close.RequestID] = i
+		}
+	}
+	ops := make([]Operation, 0, len(s.Deferred))
+	for i, deferred := range s.Deferred {
+		op := deferred.operation()
+		if runnable, ok := op.(Runnable); ok {
+			requestID := runnable.GetRequestID()
+			if closed, ok := lastClose[requestID]; ok && closed > i {
+				continue
+			}
+			s.TrialStarted[requestID] = ctx.now()
+		}
+		ops = append(ops, op)
+	}
+	s.Paused = false
+	s.Deferred = nil
+	return ops, nil
+}
+
+func (s *asyncHalvingSearch) paused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Paused
+}
@@ -0,0 +1,132 @@
+package searcher
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func TestASHASearcherPauseResume(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           6,
+		MaxConcurrentTrials: 3,
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	method := &recordingMethod{SearchMethod: search}
+	driver, err := newQueueDriver(method, nil, func(trialIndex, _ int) float64 {
+		return float64(trialIndex)
+	})
+	assert.NilError(t, err)
+	var initial []RequestID
+	for _, op := range driver.pending {
+		if create, ok := op.(Create); ok {
+			initial = append(initial, create.RequestID)
+		}
+	}
+	assert.Equal(t, len(initial), 3)
+
+	// Pause once the first trial is about to validate, then deliver the validations of all three.
+	for _, ok := driver.pending[0].(Validate); !ok; _, ok = driver.pending[0].(Validate) {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+	search.pause()
+	assert.Assert(t, search.paused())
+	for len(driver.pending) > 0 {
+		ops, stepErr := driver.step()
+		assert.NilError(t, stepErr)
+		assert.Equal(t, len(ops), 0)
+	}
+	// The metrics are in the rung and the first trial is promoted, but nothing is emitted for it.
+	assert.Equal(t, len(search.Rungs[0].Metrics), 3)
+	assert.Equal(t, search.TrialRungs[initial[0]], 1)
+	assert.Equal(t, len(search.Deferred), 8)
+
+	// A restored search resumes with the same operations.
+	snapshot, err := search.Snapshot()
+	assert.NilError(t, err)
+	restored := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	assert.NilError(t, restored.Restore(snapshot))
+	assert.Assert(t, restored.paused())
+
+	ops, err := search.resume(driver.ctx)
+	assert.NilError(t, err)
+	restoredOps, err := restored.resume(driver.ctx)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, restoredOps, ops)
+	assert.Assert(t, !search.paused())
+	assert.Equal(t, len(search.Deferred), 0)
+
+	// The two trials that replaced the first two to validate come first, in the order they were
+	// decided on, followed by the promotion of the first trial.
+	assert.Equal(t, len(ops), 8)
+	for i := 0; i < 2; i++ {
+		create, ok := ops[3*i].(Create)
+		assert.Assert(t, ok, "operation %d: %v", 3*i, ops[3*i])
+		assert.DeepEqual(t, ops[3*i+1], NewTrain(create.RequestID, model.NewLengthInBatches(300)))
+		assert.DeepEqual(t, ops[3*i+2], NewValidate(create.RequestID))
+	}
+	assert.DeepEqual(t, ops[6], NewTrain(initial[0], model.NewLengthInBatches(600)))
+	assert.DeepEqual(t, ops[7], NewValidate(initial[0]))
+
+	// After resuming, the search runs to completion as usual.
+	driver.pending = ops
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+	assert.Equal(t, len(search.TrialRungs), config.MaxTrials)
+	assert.Equal(t, len(method.closeCounts()), config.MaxTrials)
+	for _, count := range method.closeCounts() {
+		assert.Equal(t, count, 1)
+	}
+}
+
+func TestASHASearcherResumeDropsCancelledWork(t *testing.T) {
+	cancel := 0.5
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           3,
+		MaxConcurrentTrials: 3,
+		CancelStragglers:    &cancel,
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	driver, err := newQueueDriver(search, nil, func(trialIndex, _ int) float64 {
+		return float64(trialIndex)
+	})
+	assert.NilError(t, err)
+	best := driver.pending[0].(Create).RequestID
+	// While the search is paused, the first trial reports and the other two exit early, so the
+	// first one is promoted, but it can no longer reach the top rung and is cancelled right away.
+	driver.exitFn = func(trialIndex, _ int) bool { return trialIndex > 0 }
+	search.pause()
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+	assert.Assert(t, search.CancelledTrials[best])
+
+	ops, err := search.resume(driver.ctx)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, ops, []Operation{NewClose(best)})
+}
+
+func TestSearcherPauseUnsupported(t *testing.T) {
+	searcher := NewSearcher(0, newRandomSearch(model.RandomConfig{
+		MaxTrials: 1, MaxLength: model.NewLengthInBatches(100),
+	}), nil, nil)
+	assert.ErrorContains(t, searcher.Pause(), "cannot be paused")
+	_, err := searcher.Resume()
+	assert.ErrorContains(t, err, "cannot be paused")
+}
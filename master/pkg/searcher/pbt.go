@@ -41,8 +41,11 @@ func newPBTSearch(config model.PBTConfig) SearchMethod {
 func (s *pbtSearch) initialOperations(ctx context) ([]Operation, error) {
 	var ops []Operation
 	for trial := 0; trial < s.PopulationSize; trial++ {
-		create := NewCreate(
-			ctx.rand, sampleAll(ctx.hparams, ctx.rand), model.TrialWorkloadSequencerType)
+		hparams, err := sampleAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		create := NewCreate(ctx.rand, hparams, model.TrialWorkloadSequencerType)
 		s.trialParams[create.RequestID] = create.Hparams
 		ops = append(ops, create)
 		ops = append(ops, NewTrain(create.RequestID, s.LengthPerRound))
@@ -128,7 +131,10 @@ func (s *pbtSearch) runNewTrials(ctx context, requestID RequestID) ([]Operation,
 			ops = append(ops, checkpoint)
 
 			origParams := s.trialParams[requestID]
-			newParams := s.exploreParams(ctx, origParams)
+			newParams, err := s.exploreParams(ctx, origParams)
+			if err != nil {
+				return nil, err
+			}
 
 			create := NewCreateFromCheckpoint(
 				ctx.rand, newParams, checkpoint, model.TrialWorkloadSequencerType)
@@ -153,16 +159,33 @@ func (s *pbtSearch) runNewTrials(ctx context, requestID RequestID) ([]Operation,
 	return ops, nil
 }
 
-// exploreParams modifies a hyperparameter sample to produce a different one that is "nearby": it
-// resamples some parameters anew, and perturbs the rest from their previous values by some
-// multiplicative factor.
-func (s *pbtSearch) exploreParams(ctx context, old hparamSample) hparamSample {
+// exploreParams modifies a hyperparameter sample to produce a different one that is "nearby",
+// retrying until the result satisfies all of the constraints.
+func (s *pbtSearch) exploreParams(ctx context, old hparamSample) (hparamSample, error) {
+	for attempt := 0; attempt < maxSampleAttempts; attempt++ {
+		params := s.exploreParamsOnce(ctx, old)
+		switch ok, err := satisfiesConstraints(ctx.constraints, params); {
+		case err != nil:
+			return nil, err
+		case ok:
+			return params, nil
+		}
+	}
+	return nil, errors.Errorf(
+		"failed to explore hyperparameters satisfying the constraints after %d attempts",
+		maxSampleAttempts)
+}
+
+// exploreParamsOnce resamples some parameters anew, and perturbs the rest from their previous
+// values by some multiplicative factor.
+func (s *pbtSearch) exploreParamsOnce(ctx context, old hparamSample) hparamSample {
 	params := make(hparamSample)
 	ctx.hparams.Each(func(name string, sampler model.Hyperparameter) {
-		if ctx.rand.UnitInterval() < s.ResampleProbability {
+		val, ok := old[name]
+		// Conditional parameters that were inactive in the old sample have nothing to perturb.
+		if ctx.rand.UnitInterval() < s.ResampleProbability || !ok {
 			params[name] = sampleOne(sampler, ctx.rand)
 		} else {
-			val := old[name]
 			decrease := ctx.rand.UnitInterval() < .5
 			var multiplier float64
 			if decrease {
@@ -173,11 +196,7 @@ func (s *pbtSearch) exploreParams(ctx context, old hparamSample) hparamSample {
 			switch {
 			case sampler.IntHyperparameter != nil:
 				h := sampler.IntHyperparameter
-				if decrease {
-					val = intClamp(int(math.Floor(float64(val.(int))*multiplier)), h.Minval, h.Maxval)
-				} else {
-					val = intClamp(int(math.Ceil(float64(val.(int))*multiplier)), h.Minval, h.Maxval)
-				}
+				val = snapInt(*h, float64(val.(int))*multiplier, decrease)
 			case sampler.DoubleHyperparameter != nil:
 				h := sampler.DoubleHyperparameter
 				val = doubleClamp(val.(float64)*multiplier, h.Minval, h.Maxval)
@@ -190,7 +209,7 @@ func (s *pbtSearch) exploreParams(ctx context, old hparamSample) hparamSample {
 			params[name] = val
 		}
 	})
-	return params
+	return pruneInactive(ctx.hparams, params)
 }
 
 func (s *pbtSearch) checkpointCompleted(
@@ -206,7 +225,9 @@ func (s *pbtSearch) progress(unitsCompleted model.Length) float64 {
 		s.LengthPerRound.MultInt(s.PopulationSize).MultInt(s.NumRounds).Units)
 }
 
-func (s *pbtSearch) trialExitedEarly(ctx context, requestID RequestID) ([]Operation, error) {
+func (s *pbtSearch) trialExitedEarly(
+	ctx context, requestID RequestID, _ ExitedReason,
+) ([]Operation, error) {
 	s.earlyExitTrials[requestID] = true
 	s.metrics[requestID] = pbtExitedMetricValue
 	return s.runNewTrials(ctx, requestID)
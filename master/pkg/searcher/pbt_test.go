@@ -1,6 +1,7 @@
 package searcher
 
 import (
+	"math"
 	"math/rand"
 	"testing"
 
@@ -245,7 +246,8 @@ func testPBTExploreWithSeed(t *testing.T, seed uint32) {
 	// Test that exploring with no resampling and no perturbing does not change the hyperparameters.
 	{
 		pbt := newPBTSearch(nullConfig).(*pbtSearch)
-		newSample := pbt.exploreParams(ctx, sample)
+		newSample, err := pbt.exploreParams(ctx, sample)
+		assert.NilError(t, err)
 		assert.DeepEqual(t, sample, newSample)
 	}
 
@@ -260,7 +262,8 @@ func testPBTExploreWithSeed(t *testing.T, seed uint32) {
 			invalidSample[name] = nil
 		})
 		pbt := newPBTSearch(nullConfig).(*pbtSearch)
-		newSample := pbt.exploreParams(ctx, sample)
+		newSample, err := pbt.exploreParams(ctx, sample)
+		assert.NilError(t, err)
 
 		assert.Equal(t, len(invalidSample), len(newSample))
 		for name := range invalidSample {
@@ -276,7 +279,8 @@ func testPBTExploreWithSeed(t *testing.T, seed uint32) {
 		perturbingConfig.PerturbFactor = .5
 		pbt := newPBTSearch(perturbingConfig).(*pbtSearch)
 
-		newSample := pbt.exploreParams(ctx, sample)
+		newSample, err := pbt.exploreParams(ctx, sample)
+		assert.NilError(t, err)
 
 		assert.Equal(t, len(sample), len(newSample))
 
@@ -478,3 +482,58 @@ func TestPBTSearchMethod(t *testing.T) {
 
 	runValueSimulationTestCases(t, testCases)
 }
+
+func TestPBTSearcherCreatesFromCheckpoint(t *testing.T) {
+	config := model.PBTConfig{
+		Metric:          defaultMetric,
+		SmallerIsBetter: true,
+		PopulationSize:  4,
+		NumRounds:       2,
+		LengthPerRound:  model.NewLengthInBatches(100),
+		PBTReplaceConfig: model.PBTReplaceConfig{
+			TruncateFraction: .5,
+		},
+		PBTExploreConfig: model.PBTExploreConfig{
+			ResampleProbability: 0,
+			PerturbFactor:       .5,
+		},
+	}
+	hparams := model.Hyperparameters{
+		"x": {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: 0, Maxval: 100}},
+	}
+	// The first two trials created perform best.
+	driver, err := newQueueDriver(newPBTSearch(config), hparams, func(i, _ int) float64 {
+		return float64(i)
+	})
+	assert.NilError(t, err)
+
+	sources := make(map[RequestID]hparamSample)
+	for _, op := range driver.pending {
+		if create, ok := op.(Create); ok {
+			sources[create.RequestID] = create.Hparams
+		}
+	}
+
+	var creates []Create
+	for len(driver.pending) > 0 {
+		ops, stepErr := driver.step()
+		assert.NilError(t, stepErr)
+		for _, op := range ops {
+			if create, ok := op.(Create); ok {
+				creates = append(creates, create)
+			}
+		}
+	}
+
+	assert.Equal(t, len(creates), 2)
+	for _, create := range creates {
+		assert.Assert(t, create.Checkpoint != nil, "replacement trial must start from a checkpoint")
+		source := create.Checkpoint.RequestID
+		assert.Assert(t, driver.trialIndex[source] < 2, "copied from a truncated trial")
+
+		// With no resampling, every hyperparameter is perturbed from the source trial's value.
+		old, perturbed := sources[source]["x"].(float64), create.Hparams["x"].(float64)
+		assert.Assert(t, perturbed == old*.5 || perturbed == math.Min(old*1.5, 100),
+			"%f is not a perturbation of %f", perturbed, old)
+	}
+}
@@ -0,0 +1,42 @@
+package searcher
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// Observation is the result of a trial evaluated outside of a search, e.g., in a previous
+// experiment over a similar space, that LoadPrior seeds the model of a model-based search with.
+// Metric is in the same metric and direction as that of the search.
+type Observation struct {
+	Hparams map[string]interface{} `json:"hparams"`
+	Metric  float64                `json:"metric"`
+}
+
+// priorSamples returns the hyperparameters of each observation and its metric negated if larger is
+// better, so that smaller is always better, or an error if any metric is not finite; in that case,
+// none of the observations should be loaded.
+func priorSamples(
+	observations []Observation, smallerIsBetter bool,
+) ([]hparamSample, []float64, error) {
+	samples := make([]hparamSample, 0, len(observations))
+	metrics := make([]float64, 0, len(observations))
+	for i, observation := range observations {
+		metric := observation.Metric
+		if math.IsNaN(metric) || math.IsInf(metric, 0) {
+			return nil, nil, errors.Errorf(
+				"cannot load prior observation %d with a non-finite metric: %f", i, metric)
+		}
+		if !smallerIsBetter {
+			metric *= -1
+		}
+		sample := make(hparamSample, len(observation.Hparams))
+		for name, value := range observation.Hparams {
+			sample[name] = value
+		}
+		samples = append(samples, sample)
+		metrics = append(metrics, metric)
+	}
+	return samples, metrics, nil
+}
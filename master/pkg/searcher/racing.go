@@ -0,0 +1,210 @@
+package searcher
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// racingSearch races all of its trials against each other: each trial trains and validates in
+// increments of the validation period, and every validation is taken as a noisy sample of how good
+// the trial is. By Hoeffding's inequality, the mean metric of a trial is within
+//
+//	MetricRange * sqrt(ln(2/Delta) / (2n))
+//
+// of the mean of its n validations with probability at least 1 - Delta. A trial is closed as soon
+// as the lower bound on its mean is above the upper bound on that of another trial, since it is
+// then worse than that trial with high probability; the trials that are never closed this way train
+// for the full length.
+type racingSearch struct {
+	defaultSearchMethod
+	model.RacingConfig
+	racingSearchState
+}
+
+// racingTrial is the state of a single trial of a racing search. Metrics are sign-adjusted so that
+// smaller is better.
+type racingTrial struct {
+	Trained     model.Length `json:"trained"`
+	Validations int          `json:"validations"`
+	MetricSum   float64      `json:"metric_sum"`
+}
+
+type racingSearchState struct {
+	Trials          map[RequestID]*racingTrial `json:"trials"`
+	TrialsCompleted int                        `json:"trials_completed"`
+	ClosedTrials    map[RequestID]bool         `json:"closed_trials"`
+	// EliminatedTrials contains the trials that were closed because they lost the race.
+	EliminatedTrials map[RequestID]bool `json:"eliminated_trials"`
+}
+
+func newRacingSearch(config model.RacingConfig) SearchMethod {
+	return &racingSearch{
+		RacingConfig: config,
+		racingSearchState: racingSearchState{
+			Trials:           make(map[RequestID]*racingTrial),
+			ClosedTrials:     make(map[RequestID]bool),
+			EliminatedTrials: make(map[RequestID]bool),
+		},
+	}
+}
+
+// initialOperations creates every trial of the search at once, since they race each other.
+func (s *racingSearch) initialOperations(ctx context) ([]Operation, error) {
+	var ops []Operation
+	for trial := 0; trial < s.MaxTrials; trial++ {
+		hparams, err := sampleAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		create := NewCreate(ctx.rand, hparams, model.TrialWorkloadSequencerType)
+		s.Trials[create.RequestID] = &racingTrial{Trained: model.NewLength(s.Unit(), 0)}
+		ops = append(ops, create)
+		ops = append(ops, s.trainNext(create.RequestID)...)
+	}
+	return ops, nil
+}
+
+// trainNext trains the trial for another validation period, without going past the maximum
+// length, and validates it.
+func (s *racingSearch) trainNext(requestID RequestID) []Operation {
+	trial := s.Trials[requestID]
+	length := s.ValidationPeriod
+	if remaining := s.MaxLength.Units - trial.Trained.Units; length.Units > remaining {
+		length.Units = remaining
+	}
+	trial.Trained = trial.Trained.Add(length)
+	return []Operation{NewTrain(requestID, length), NewValidate(requestID)}
+}
+
+// bounds returns the lower and upper Hoeffding bounds on the mean sign-adjusted metric of the
+// trial, or false if it has not validated yet.
+func (s *racingSearch) bounds(trial *racingTrial) (lower, upper float64, ok bool) {
+	if trial.Validations == 0 {
+		return 0, 0, false
+	}
+	n := float64(trial.Validations)
+	mean := trial.MetricSum / n
+	width := s.MetricRange * math.Sqrt(math.Log(2/s.Delta)/(2*n))
+	return mean - width, mean + width, true
+}
+
+func (s *racingSearch) validationCompleted(
+	ctx context, requestID RequestID, validate Validate, metrics ValidationMetrics,
+) ([]Operation, error) {
+	trial, ok := s.Trials[requestID]
+	if !ok || s.ClosedTrials[requestID] {
+		return nil, nil
+	}
+	metric, err := metrics.Metric(s.Metric)
+	if err != nil {
+		return nil, err
+	}
+	if !s.SmallerIsBetter {
+		metric *= -1
+	}
+	trial.Validations++
+	trial.MetricSum += metric
+
+	ops := s.eliminate()
+	if s.ClosedTrials[requestID] {
+		return ops, nil
+	}
+	if trial.Trained.Units < s.MaxLength.Units {
+		return append(ops, s.trainNext(requestID)...), nil
+	}
+	s.complete(requestID)
+	return append(ops, NewClose(requestID)), nil
+}
+
+// eliminate closes the open trials whose lower bounds are above the lowest upper bound of any
+// trial, in request ID order. The trial with the lowest upper bound is never closed, since its
+// lower bound is below its upper bound.
+func (s *racingSearch) eliminate() []Operation {
+	best := math.Inf(1)
+	for _, trial := range s.Trials {
+		if _, upper, ok := s.bounds(trial); ok && upper < best {
+			best = upper
+		}
+	}
+	var eliminated []RequestID
+	for requestID, trial := range s.Trials {
+		if s.ClosedTrials[requestID] {
+			continue
+		}
+		if lower, _, ok := s.bounds(trial); ok && lower > best {
+			eliminated = append(eliminated, requestID)
+		}
+	}
+	sort.Slice(eliminated, func(i, j int) bool {
+		return eliminated[i].Before(eliminated[j])
+	})
+
+	var ops []Operation
+	for _, requestID := range eliminated {
+		log.WithField("request-id", requestID).Debugf(
+			"closing trial whose metric is worse than another's after %d validations",
+			s.Trials[requestID].Validations)
+		s.EliminatedTrials[requestID] = true
+		s.complete(requestID)
+		ops = append(ops, NewClose(requestID))
+	}
+	return ops
+}
+
+// complete marks the trial as done for the purposes of progress reporting.
+func (s *racingSearch) complete(requestID RequestID) {
+	if !s.ClosedTrials[requestID] {
+		s.ClosedTrials[requestID] = true
+		s.TrialsCompleted++
+	}
+}
+
+func (s *racingSearch) trialClosed(ctx context, requestID RequestID) ([]Operation, error) {
+	s.complete(requestID)
+	return nil, nil
+}
+
+// trialExitedEarly drops the trial from the race; it is not replaced, since a trial that joined
+// late would have too few validations to catch up.
+func (s *racingSearch) trialExitedEarly(
+	ctx context, requestID RequestID, _ ExitedReason,
+) ([]Operation, error) {
+	s.complete(requestID)
+	return nil, nil
+}
+
+// progress is the fraction of the trials that have completed or, if it is more, of the training
+// the search would do if no trial were closed early.
+func (s *racingSearch) progress(unitsCompleted model.Length) float64 {
+	progress := float64(unitsCompleted.Units) / float64(s.MaxLength.MultInt(s.MaxTrials).Units)
+	if trials := float64(s.TrialsCompleted) / float64(s.MaxTrials); trials > progress {
+		progress = trials
+	}
+	return math.Min(progress, 1)
+}
+
+func (s *racingSearch) Snapshot() ([]byte, error) {
+	return json.Marshal(s.racingSearchState)
+}
+
+func (s *racingSearch) Restore(state []byte) error {
+	if err := json.Unmarshal(state, &s.racingSearchState); err != nil {
+		return errors.Wrap(err, "failed to restore racing search state")
+	}
+	if s.Trials == nil {
+		s.Trials = make(map[RequestID]*racingTrial)
+	}
+	if s.ClosedTrials == nil {
+		s.ClosedTrials = make(map[RequestID]bool)
+	}
+	if s.EliminatedTrials == nil {
+		s.EliminatedTrials = make(map[RequestID]bool)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package searcher
+
+import (
+	"math"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// racingMetric is the metric trial trialIndex reports at its validations-th validation: its mean
+// grows with its index, and each validation adds noise within a range of 0.2. The metrics of trials
+// with indexes up to 5 are all between 0 and 1.2.
+func racingMetric(trialIndex, validations int) float64 {
+	noise := 0.1 * math.Sin(float64(trialIndex*31+validations*17))
+	return 0.2*float64(trialIndex) + 0.1 + noise
+}
+
+func TestRacingSearch(t *testing.T) {
+	for _, smallerIsBetter := range []bool{true, false} {
+		config := model.RacingConfig{
+			Metric:           defaultMetric,
+			SmallerIsBetter:  smallerIsBetter,
+			MaxLength:        model.NewLengthInBatches(10000),
+			MaxTrials:        6,
+			ValidationPeriod: model.NewLengthInBatches(100),
+			Delta:            0.05,
+			MetricRange:      1.2,
+		}
+		search := newRacingSearch(config).(*racingSearch)
+		method := &recordingMethod{SearchMethod: search}
+		driver, err := newQueueDriver(method, listHyperparameters(), func(i, v int) float64 {
+			if !smallerIsBetter {
+				// The best trial is still the one at index 0.
+				return -racingMetric(i, v)
+			}
+			return racingMetric(i, v)
+		})
+		assert.NilError(t, err)
+		for i := 0; len(driver.pending) > 0; i++ {
+			_, err = driver.step()
+			assert.NilError(t, err)
+			// A restored search carries on the same way.
+			if i == 200 {
+				snapshot, snapshotErr := search.Snapshot()
+				assert.NilError(t, snapshotErr)
+				search = newRacingSearch(config).(*racingSearch)
+				assert.NilError(t, search.Restore(snapshot))
+				method.SearchMethod = search
+			}
+		}
+
+		assert.Equal(t, len(search.Trials), config.MaxTrials)
+		assert.Equal(t, search.TrialsCompleted, config.MaxTrials)
+		assert.Equal(t, search.progress(model.NewLengthInBatches(0)), 1.0)
+		for requestID, trial := range search.Trials {
+			trialIndex := driver.trialIndex[requestID]
+			switch {
+			case trialIndex == 0:
+				// The best trial survives the race and trains for the full length.
+				assert.Assert(t, !search.EliminatedTrials[requestID])
+				assert.Equal(t, trial.Trained, config.MaxLength)
+			case trialIndex >= 2:
+				// Trials that are clearly worse are closed early.
+				assert.Assert(t, search.EliminatedTrials[requestID], "trial %d", trialIndex)
+				assert.Assert(t, trial.Trained.Units < config.MaxLength.Units)
+			}
+		}
+	}
+}
+
+func TestRacingSearchBounds(t *testing.T) {
+	search := newRacingSearch(model.RacingConfig{Delta: 0.05, MetricRange: 2}).(*racingSearch)
+	_, _, ok := search.bounds(&racingTrial{})
+	assert.Assert(t, !ok)
+
+	// The bounds narrow with the square root of the number of validations.
+	width := 2 * math.Sqrt(math.Log(40)/2)
+	lower, upper, ok := search.bounds(&racingTrial{Validations: 1, MetricSum: 0.5})
+	assert.Assert(t, ok)
+	assert.Equal(t, lower, 0.5-width)
+	assert.Equal(t, upper, 0.5+width)
+	lower, upper, _ = search.bounds(&racingTrial{Validations: 4, MetricSum: 2})
+	assert.Assert(t, math.Abs(lower-(0.5-width/2)) < 1e-12)
+	assert.Assert(t, math.Abs(upper-(0.5+width/2)) < 1e-12)
+}
@@ -1,44 +1,248 @@
 package searcher
 
 import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
 	"github.com/determined-ai/determined/master/pkg/model"
 )
 
 // randomSearch corresponds to the standard random search method. Each random trial configuration
-// is trained for the specified number of steps, and then validation metrics are computed.
+// is trained for the specified number of steps, and then validation metrics are computed. If a
+// validation period is configured, trials instead train and validate in increments of that
+// period, and, with a positive patience, trials that stop improving are closed early.
 type randomSearch struct {
 	defaultSearchMethod
 	model.RandomConfig
+	randomSearchState
 }
 
-func newRandomSearch(config model.RandomConfig) SearchMethod {
-	return &randomSearch{RandomConfig: config}
+// randomTrial is the state of a single trial of an incremental random search.
+type randomTrial struct {
+	Trained model.Length  `json:"trained"`
+	History metricHistory `json:"history"`
 }
 
-func newSingleSearch(config model.SingleConfig) SearchMethod {
+type randomSearchState struct {
+	Trials          map[RequestID]*randomTrial `json:"trials"`
+	TrialsCreated   int                        `json:"trials_created"`
+	TrialsCompleted int                        `json:"trials_completed"`
+	ClosedTrials    map[RequestID]bool         `json:"closed_trials"`
+}
+
+func newRandomSearch(config model.RandomConfig) SearchMethod {
 	return &randomSearch{
-		RandomConfig: model.RandomConfig{MaxTrials: 1, MaxLength: config.MaxLength},
+		RandomConfig: config,
+		randomSearchState: randomSearchState{
+			Trials:       make(map[RequestID]*randomTrial),
+			ClosedTrials: make(map[RequestID]bool),
+		},
 	}
 }
 
+func newSingleSearch(config model.SingleConfig) SearchMethod {
+	return newRandomSearch(model.RandomConfig{MaxTrials: 1, MaxLength: config.MaxLength})
+}
+
+// incremental returns whether trials are trained in increments of the validation period rather
+// than all at once.
+func (s *randomSearch) incremental() bool {
+	return s.ValidationPeriod != nil
+}
+
 func (s *randomSearch) initialOperations(ctx context) ([]Operation, error) {
+	if !s.incremental() {
+		var ops []Operation
+		for trial := 0; trial < s.MaxTrials; trial++ {
+			hparams, err := sampleAll(ctx)
+			if err != nil {
+				return nil, err
+			}
+			create := NewCreate(ctx.rand, hparams, model.TrialWorkloadSequencerType)
+			ops = append(ops, create)
+			ops = append(ops, NewTrain(create.RequestID, s.MaxLength))
+			ops = append(ops, NewValidate(create.RequestID))
+			ops = append(ops, NewClose(create.RequestID))
+		}
+		return ops, nil
+	}
+
+	concurrency := s.MaxConcurrentTrials
+	if concurrency <= 0 || concurrency > s.MaxTrials {
+		concurrency = s.MaxTrials
+	}
 	var ops []Operation
-	for trial := 0; trial < s.MaxTrials; trial++ {
-		create := NewCreate(ctx.rand, sampleAll(ctx.hparams, ctx.rand), model.TrialWorkloadSequencerType)
-		ops = append(ops, create)
-		ops = append(ops, NewTrain(create.RequestID, s.MaxLength))
-		ops = append(ops, NewValidate(create.RequestID))
-		ops = append(ops, NewClose(create.RequestID))
+	for trial := 0; trial < concurrency; trial++ {
+		created, err := s.createTrial(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, created...)
 	}
 	return ops, nil
 }
 
-func (s *randomSearch) progress(unitsCompleted model.Length) float64 {
-	return float64(unitsCompleted.Units) / float64(s.MaxLength.MultInt(s.MaxTrials).Units)
+// createTrial samples a new trial and starts training it for its first validation period.
+func (s *randomSearch) createTrial(ctx context) ([]Operation, error) {
+	hparams, err := sampleAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	create := NewCreate(ctx.rand, hparams, model.TrialWorkloadSequencerType)
+	s.TrialsCreated++
+	s.Trials[create.RequestID] = &randomTrial{Trained: model.NewLength(s.Unit(), 0)}
+	ops := []Operation{create}
+	return append(ops, s.trainNext(create.RequestID)...), nil
+}
+
+// trainNext trains the trial for another validation period, without going past the maximum
+// length, and validates it.
+func (s *randomSearch) trainNext(requestID RequestID) []Operation {
+	trial := s.Trials[requestID]
+	length := *s.ValidationPeriod
+	if remaining := s.MaxLength.Units - trial.Trained.Units; length.Units > remaining {
+		length.Units = remaining
+	}
+	trial.Trained = trial.Trained.Add(length)
+	return []Operation{NewTrain(requestID, length), NewValidate(requestID)}
+}
+
+// backfill creates a new trial if the search has not yet created all of its trials.
+func (s *randomSearch) backfill(ctx context) ([]Operation, error) {
+	if s.TrialsCreated >= s.MaxTrials {
+		return nil, nil
+	}
+	return s.createTrial(ctx)
+}
+
+// complete marks the trial as done for the purposes of progress reporting.
+func (s *randomSearch) complete(requestID RequestID) {
+	if !s.ClosedTrials[requestID] {
+		s.ClosedTrials[requestID] = true
+		s.TrialsCompleted++
+	}
+}
+
+func (s *randomSearch) validationCompleted(
+	ctx context, requestID RequestID, validate Validate, metrics ValidationMetrics,
+) ([]Operation, error) {
+	trial, ok := s.Trials[requestID]
+	if !s.incremental() || !ok || s.ClosedTrials[requestID] {
+		return nil, nil
+	}
+
+	if window := s.historyWindow(); window > 0 {
+		metric, err := metrics.Metric(s.Metric)
+		if err != nil {
+			return nil, err
+		}
+		trial.History.add(window, metricPoint{Units: trial.Trained.Units, Metric: metric})
+	}
+
+	if trial.Trained.Units < s.MaxLength.Units && !s.plateaued(requestID) {
+		return s.trainNext(requestID), nil
+	}
+	s.complete(requestID)
+	ops := []Operation{NewClose(requestID)}
+	created, err := s.backfill(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return append(ops, created...), nil
+}
+
+// historyWindow returns how many of the most recent metrics of each trial are kept: at least enough
+// to tell whether the trial has plateaued.
+func (s *randomSearch) historyWindow() int {
+	if s.MetricHistoryWindow > 0 {
+		return s.MetricHistoryWindow
+	}
+	if s.Patience > 0 {
+		return s.Patience + 1
+	}
+	return 0
+}
+
+// metricHistory returns the most recent metrics reported by the trial, from oldest to newest.
+func (s *randomSearch) metricHistory(requestID RequestID) []metricPoint {
+	trial, ok := s.Trials[requestID]
+	if !ok {
+		return nil
+	}
+	return trial.History.recent()
+}
+
+// plateaued returns whether none of the last Patience metrics of the trial improved by more than
+// MinImprovement on the best of the metrics it reported before them within its history.
+func (s *randomSearch) plateaued(requestID RequestID) bool {
+	history := s.metricHistory(requestID)
+	if s.Patience == 0 || len(history) <= s.Patience {
+		return false
+	}
+	better := func(a, b float64) bool {
+		if s.SmallerIsBetter {
+			return a < b-s.MinImprovement
+		}
+		return a > b+s.MinImprovement
+	}
+	previous := history[:len(history)-s.Patience]
+	best := previous[0].Metric
+	for _, point := range previous[1:] {
+		if better(point.Metric, best) {
+			best = point.Metric
+		}
+	}
+	for _, point := range history[len(history)-s.Patience:] {
+		if better(point.Metric, best) {
+			return false
+		}
+	}
+	return true
 }
 
-// trialExitedEarly does nothing since random does not take actions based on
-// search status or progress.
-func (s *randomSearch) trialExitedEarly(context, RequestID) ([]Operation, error) {
+func (s *randomSearch) trialClosed(ctx context, requestID RequestID) ([]Operation, error) {
+	s.complete(requestID)
 	return nil, nil
 }
+
+func (s *randomSearch) progress(unitsCompleted model.Length) float64 {
+	progress := float64(unitsCompleted.Units) / float64(s.MaxLength.MultInt(s.MaxTrials).Units)
+	if trials := float64(s.TrialsCompleted) / float64(s.MaxTrials); trials > progress {
+		progress = trials
+	}
+	if progress > 1 {
+		return 1
+	}
+	return progress
+}
+
+// trialExitedEarly creates a replacement trial for an incremental search, since that search
+// keeps a bounded number of trials running; otherwise, it does nothing since random does not take
+// actions based on search status or progress.
+func (s *randomSearch) trialExitedEarly(
+	ctx context, requestID RequestID, _ ExitedReason,
+) ([]Operation, error) {
+	if !s.incremental() || s.ClosedTrials[requestID] {
+		return nil, nil
+	}
+	s.complete(requestID)
+	return s.backfill(ctx)
+}
+
+func (s *randomSearch) Snapshot() ([]byte, error) {
+	return json.Marshal(s.randomSearchState)
+}
+
+func (s *randomSearch) Restore(state []byte) error {
+	if err := json.Unmarshal(state, &s.randomSearchState); err != nil {
+		return errors.Wrap(err, "failed to restore random search state")
+	}
+	if s.Trials == nil {
+		s.Trials = make(map[RequestID]*randomTrial)
+	}
+	if s.ClosedTrials == nil {
+		s.ClosedTrials = make(map[RequestID]bool)
+	}
+	return nil
+}
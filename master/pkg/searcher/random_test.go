@@ -3,6 +3,8 @@ package searcher
 import (
 	"testing"
 
+	"gotest.tools/assert"
+
 	"github.com/determined-ai/determined/master/pkg/model"
 )
 
@@ -90,3 +92,89 @@ func TestSingleSearchMethod(t *testing.T) {
 
 	runValueSimulationTestCases(t, testCases)
 }
+
+func TestRandomSearcherEarlyStopping(t *testing.T) {
+	period := model.NewLengthInBatches(100)
+	config := model.RandomConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		MaxLength:           model.NewLengthInBatches(1000),
+		MaxTrials:           4,
+		MaxConcurrentTrials: 2,
+		ValidationPeriod:    &period,
+		Patience:            3,
+		MinImprovement:      0.01,
+	}
+	search := newRandomSearch(config)
+	method := &recordingMethod{SearchMethod: search}
+	// Even trials keep improving, while odd trials get worse after their first validation.
+	driver, err := newQueueDriver(method, nil, func(trialIndex, validations int) float64 {
+		if trialIndex%2 == 0 {
+			return 1 - 0.05*float64(validations)
+		}
+		return 1 + 0.05*float64(validations)
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(driver.pending), 2*3)
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+
+	trained := make(map[int]int)
+	for _, op := range method.ops {
+		if train, ok := op.(Train); ok {
+			trained[driver.trialIndex[train.RequestID]] += train.Length.Units
+		}
+	}
+	assert.DeepEqual(t, trained, map[int]int{0: 1000, 1: 400, 2: 1000, 3: 400})
+	for _, count := range method.closeCounts() {
+		assert.Equal(t, count, 1)
+	}
+	assert.Equal(t, search.progress(model.NewLengthInBatches(2800)), 1.0)
+}
+
+func TestRandomSearcherIncrementalLength(t *testing.T) {
+	period := model.NewLengthInBatches(300)
+	config := model.RandomConfig{
+		MaxLength:        model.NewLengthInBatches(1000),
+		MaxTrials:        2,
+		ValidationPeriod: &period,
+	}
+	expected := [][]Runnable{
+		toOps("300B V 300B V 300B V 100B V"),
+		toOps("300B V 300B V 300B V 100B V"),
+	}
+	checkSimulation(t, newRandomSearch(config), nil, ConstantValidation, expected)
+}
+
+func TestRandomSearcherMetricHistory(t *testing.T) {
+	period := model.NewLengthInBatches(100)
+	config := model.RandomConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		MaxLength:           model.NewLengthInBatches(500),
+		MaxTrials:           1,
+		ValidationPeriod:    &period,
+		Patience:            1,
+		MetricHistoryWindow: 3,
+	}
+	search := newRandomSearch(config).(*randomSearch)
+	driver, err := newQueueDriver(search, nil, func(_, validations int) float64 {
+		return 1 / float64(validations+1)
+	})
+	assert.NilError(t, err)
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+
+	// Only the last three of the five validations are kept.
+	for requestID := range driver.trialIndex {
+		assert.DeepEqual(t, search.metricHistory(requestID), []metricPoint{
+			{Units: 300, Metric: 1.0 / 3},
+			{Units: 400, Metric: 1.0 / 4},
+			{Units: 500, Metric: 1.0 / 5},
+		})
+	}
+}
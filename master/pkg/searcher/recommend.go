@@ -0,0 +1,164 @@
+package searcher
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+
+	"github.com/determined-ai/determined/master/pkg/check"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// RecommendationSpace is the set of asynchronous halving configurations RecommendConfig chooses
+// from.
+type RecommendationSpace struct {
+	Metric          string
+	SmallerIsBetter bool
+	// MaxLength is how long the trials that reach the top rung train for.
+	MaxLength model.Length
+	// MinTrials is the fewest trials the search must explore.
+	MinTrials int
+	// NumRungs and Divisors are the numbers of rungs and divisors to consider; they default to 1
+	// through 5 rungs and divisors of 2, 3, and 4.
+	NumRungs []int
+	Divisors []float64
+	// Hyperparameters are those of the experiment, which the simulations sample trials from.
+	Hyperparameters model.Hyperparameters
+}
+
+// Recommendation is an asynchronous halving configuration proposed by RecommendConfig.
+type Recommendation struct {
+	Config model.AsyncHalvingConfig `json:"config"`
+	// RungTrials is the number of trials that reached each rung in a simulation of the search.
+	RungTrials []int `json:"rung_trials"`
+	// TotalLength is the sum of the lengths trained by all trials in the simulation.
+	TotalLength model.Length `json:"total_length"`
+}
+
+// RecommendConfig proposes the asynchronous halving configuration from the space that would bring
+// the most trials to the top rung without training for longer than the budget in all, while
+// exploring at least MinTrials trials; ties go to the configuration that explores more trials. For
+// each number of rungs and divisor, the geometric schedule of the search gives the expected length
+// each trial trains for, and so the number of trials that fit into the budget; since promotions
+// depend on the order trials report in, that number is then checked, and lowered if need be, by
+// simulating the search with random metrics.
+func RecommendConfig(budget model.Length, space RecommendationSpace) (Recommendation, error) {
+	if budget.Unit != space.MaxLength.Unit {
+		return Recommendation{}, ConfigError{errors.Errorf(
+			"cannot fit a search in %s into a budget in %s", space.MaxLength.Unit, budget.Unit)}
+	}
+	numRungs := space.NumRungs
+	if len(numRungs) == 0 {
+		numRungs = []int{1, 2, 3, 4, 5}
+	}
+	divisors := space.Divisors
+	if len(divisors) == 0 {
+		divisors = []float64{2, 3, 4}
+	}
+
+	var best *Recommendation
+	for _, rungs := range numRungs {
+		for _, divisor := range divisors {
+			config := model.AsyncHalvingConfig{
+				Metric:          space.Metric,
+				SmallerIsBetter: space.SmallerIsBetter,
+				NumRungs:        rungs,
+				MaxLength:       space.MaxLength,
+				Divisor:         divisor,
+				MaxTrials:       1,
+			}
+			if !distinctRungs(config) || check.Validate(config) != nil {
+				continue
+			}
+			recommendation, ok, err := fitBudget(config, budget, space)
+			if err != nil {
+				return Recommendation{}, err
+			}
+			if ok && (best == nil || betterRecommendation(recommendation, *best)) {
+				best = &recommendation
+			}
+		}
+	}
+	if best == nil {
+		return Recommendation{}, ConfigError{errors.Errorf(
+			"no configuration brings a trial to the top rung while exploring %d trials within %d %s",
+			space.MinTrials, budget.Units, budget.Unit)}
+	}
+	return *best, nil
+}
+
+// distinctRungs returns whether each rung of the configuration trains for longer than the one
+// below, which it does not if max_length is too short for the number of rungs and divisor.
+func distinctRungs(config model.AsyncHalvingConfig) bool {
+	units := config.RungUnits()
+	for i := 1; i < len(units); i++ {
+		if units[i] <= units[i-1] {
+			return false
+		}
+	}
+	return true
+}
+
+// fitBudget returns the configuration with the most trials that fits into the budget in
+// simulation, or false if it would have to explore fewer than MinTrials trials or bring none to the
+// top rung.
+func fitBudget(
+	config model.AsyncHalvingConfig, budget model.Length, space RecommendationSpace,
+) (Recommendation, bool, error) {
+	maxTrials := int(float64(budget.Units) / expectedTrialLength(config))
+	for maxTrials >= max(space.MinTrials, 1) {
+		config.MaxTrials = maxTrials
+		experiment := model.DefaultExperimentConfig()
+		experiment.Searcher = model.SearcherConfig{
+			Metric:             config.Metric,
+			SmallerIsBetter:    config.SmallerIsBetter,
+			AsyncHalvingConfig: &config,
+		}
+		experiment.Hyperparameters = space.Hyperparameters
+		summary, err := SimulateConfig(experiment, 0, RandomValidation)
+		if err != nil {
+			return Recommendation{}, false, err
+		}
+		if total := summary.TotalLength.Units; total > budget.Units {
+			// Shrink the search in proportion to how far it went over the budget.
+			maxTrials = min(maxTrials-1, int(float64(maxTrials)*float64(budget.Units)/float64(total)))
+			continue
+		}
+		recommendation := Recommendation{
+			Config:      config,
+			RungTrials:  make([]int, config.NumRungs),
+			TotalLength: summary.TotalLength,
+		}
+		for i := 0; i < len(summary.Rungs) && i < config.NumRungs; i++ {
+			recommendation.RungTrials[i] = len(summary.Rungs[i].Trials)
+		}
+		return recommendation, recommendation.topRungTrials() > 0, nil
+	}
+	return Recommendation{}, false, nil
+}
+
+// expectedTrialLength is the length a trial of the search is expected to train for: the length of
+// each rung beyond the one below, for the fraction of trials that reach it under the geometric
+// schedule.
+func expectedTrialLength(config model.AsyncHalvingConfig) float64 {
+	expected := 0.0
+	previousUnits := 0
+	for rungIndex, units := range config.RungUnits() {
+		expected += float64(units-previousUnits) / math.Pow(config.Divisor, float64(rungIndex))
+		previousUnits = units
+	}
+	return expected
+}
+
+func (r Recommendation) topRungTrials() int {
+	return r.RungTrials[len(r.RungTrials)-1]
+}
+
+// betterRecommendation returns whether a brings more trials to the top rung than b or, failing
+// that, explores more trials.
+func betterRecommendation(a, b Recommendation) bool {
+	if a.topRungTrials() != b.topRungTrials() {
+		return a.topRungTrials() > b.topRungTrials()
+	}
+	return a.Config.MaxTrials > b.Config.MaxTrials
+}
@@ -0,0 +1,71 @@
+package searcher
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/determined-ai/determined/master/pkg/check"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func TestRecommendConfig(t *testing.T) {
+	space := RecommendationSpace{
+		Metric:          defaultMetric,
+		SmallerIsBetter: true,
+		MaxLength:       model.NewLengthInBatches(900),
+		MinTrials:       30,
+		Hyperparameters: model.Hyperparameters{
+			"x": {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: 0, Maxval: 1}},
+		},
+	}
+	budget := model.NewLengthInBatches(10000)
+	recommendation, err := RecommendConfig(budget, space)
+	assert.NilError(t, err)
+	config := recommendation.Config
+	assert.NilError(t, check.Validate(config))
+
+	// Training 30 trials for the full length would take 27000 batches, so the budget calls for
+	// several rungs.
+	assert.Assert(t, config.NumRungs > 1, config.NumRungs)
+	assert.Assert(t, config.MaxTrials >= space.MinTrials, config.MaxTrials)
+	assert.Assert(t, recommendation.TotalLength.Units <= budget.Units, recommendation.TotalLength)
+	assert.Equal(t, len(recommendation.RungTrials), config.NumRungs)
+	assert.Equal(t, recommendation.RungTrials[0], config.MaxTrials)
+	for i := 1; i < len(recommendation.RungTrials); i++ {
+		assert.Assert(t, recommendation.RungTrials[i] <= recommendation.RungTrials[i-1])
+	}
+	assert.Assert(t, recommendation.RungTrials[config.NumRungs-1] >= 1)
+
+	// The prediction is what a simulation of the recommended search does.
+	experiment := model.DefaultExperimentConfig()
+	experiment.Searcher = model.SearcherConfig{
+		Metric:             defaultMetric,
+		SmallerIsBetter:    true,
+		AsyncHalvingConfig: &config,
+	}
+	experiment.Hyperparameters = space.Hyperparameters
+	summary, err := SimulateConfig(experiment, 0, RandomValidation)
+	assert.NilError(t, err)
+	assert.Equal(t, summary.TotalLength, recommendation.TotalLength)
+
+	// No schedule brings more trials to the top rung than training each trial fully, so that is
+	// recommended when the budget allows for enough trials.
+	space.MinTrials = 5
+	recommendation, err = RecommendConfig(budget, space)
+	assert.NilError(t, err)
+	assert.Equal(t, recommendation.Config.NumRungs, 1)
+	assert.Equal(t, recommendation.Config.MaxTrials, 11)
+	assert.DeepEqual(t, recommendation.RungTrials, []int{11})
+	assert.Equal(t, recommendation.TotalLength, model.NewLengthInBatches(9900))
+
+	// Nothing reaches the top rung with less than the length of a single trial.
+	_, err = RecommendConfig(model.NewLengthInBatches(500), space)
+	assert.ErrorContains(t, err, "no configuration")
+	kind, ok := ErrorKindOf(err)
+	assert.Assert(t, ok)
+	assert.Equal(t, kind, ConfigErrorKind)
+
+	_, err = RecommendConfig(model.NewLengthInRecords(10000), space)
+	assert.ErrorContains(t, err, "cannot fit a search in batches into a budget in records")
+}
@@ -0,0 +1,100 @@
+package searcher
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/determined-ai/determined/master/pkg/check"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// SearchMethodFactory creates a search method from the searcher configuration of an experiment.
+type SearchMethodFactory func(model.SearcherConfig) (SearchMethod, error)
+
+var (
+	searchMethodsMu sync.RWMutex
+	searchMethods   = make(map[string]SearchMethodFactory)
+)
+
+// RegisterSearchMethod makes a search method available to experiments under the given searcher
+// name. The configuration of searchers that are not built in is passed to the factory as
+// model.SearcherConfig.CustomConfig, and validates only once the name is registered. Since the
+// callbacks of SearchMethod are unexported, search methods registered from outside this package
+// implement ExternalSearchMethod and are adapted with AdaptSearchMethod, or are built from the
+// ones inside it, e.g., by embedding one. RegisterSearchMethod panics if a search method is
+// already registered under the name.
+func RegisterSearchMethod(name string, factory SearchMethodFactory) {
+	searchMethodsMu.Lock()
+	defer searchMethodsMu.Unlock()
+	if _, ok := searchMethods[name]; ok {
+		panic(fmt.Sprintf("search method already registered: %s", name))
+	}
+	searchMethods[name] = factory
+	model.RegisterSearcherName(name)
+}
+
+func lookupSearchMethod(name string) (SearchMethodFactory, bool) {
+	searchMethodsMu.RLock()
+	defer searchMethodsMu.RUnlock()
+	factory, ok := searchMethods[name]
+	return factory, ok
+}
+
+func init() {
+	RegisterSearchMethod("single", func(c model.SearcherConfig) (SearchMethod, error) {
+		return newSingleSearch(*c.SingleConfig), nil
+	})
+	RegisterSearchMethod("random", func(c model.SearcherConfig) (SearchMethod, error) {
+		return newRandomSearch(*c.RandomConfig), nil
+	})
+	RegisterSearchMethod("grid", func(c model.SearcherConfig) (SearchMethod, error) {
+		return newGridSearch(*c.GridConfig), nil
+	})
+	RegisterSearchMethod("sync_halving", func(c model.SearcherConfig) (SearchMethod, error) {
+		return newSyncHalvingSearch(*c.SyncHalvingConfig), nil
+	})
+	RegisterSearchMethod("adaptive", func(c model.SearcherConfig) (SearchMethod, error) {
+		return newAdaptiveSearch(*c.AdaptiveConfig), nil
+	})
+	RegisterSearchMethod("adaptive_simple", func(c model.SearcherConfig) (SearchMethod, error) {
+		return newAdaptiveSimpleSearch(*c.AdaptiveSimpleConfig), nil
+	})
+	RegisterSearchMethod("async_halving", func(c model.SearcherConfig) (SearchMethod, error) {
+		// An invalid configuration would otherwise only surface once the search misbehaves.
+		if err := check.Validate(*c.AsyncHalvingConfig); err != nil {
+			return nil, errors.WithStack(ConfigError{
+				Err: errors.Wrap(err, "invalid async_halving searcher configuration"),
+			})
+		}
+		if c.AsyncHalvingConfig.AutoFallback != nil {
+			return newFallbackSearch(*c.AsyncHalvingConfig), nil
+		}
+		return newAsyncHalvingSearch(*c.AsyncHalvingConfig), nil
+	})
+	RegisterSearchMethod("adaptive_asha", func(c model.SearcherConfig) (SearchMethod, error) {
+		return newAdaptiveASHASearch(*c.AdaptiveASHAConfig), nil
+	})
+	RegisterSearchMethod("pbt", func(c model.SearcherConfig) (SearchMethod, error) {
+		return newPBTSearch(*c.PBTConfig), nil
+	})
+	RegisterSearchMethod("multi_objective_asha", func(c model.SearcherConfig) (SearchMethod, error) {
+		return newMultiObjectiveSearch(*c.MultiObjectiveConfig), nil
+	})
+	RegisterSearchMethod("tpe", func(c model.SearcherConfig) (SearchMethod, error) {
+		return newTPESearch(*c.TPEConfig), nil
+	})
+	RegisterSearchMethod("bayesian", func(c model.SearcherConfig) (SearchMethod, error) {
+		return newBayesianSearch(*c.BayesianConfig), nil
+	})
+	RegisterSearchMethod("bohb", func(c model.SearcherConfig) (SearchMethod, error) {
+		return newBOHBSearch(*c.BOHBConfig), nil
+	})
+	RegisterSearchMethod("list", func(c model.SearcherConfig) (SearchMethod, error) {
+		return newListSearch(*c.ListConfig), nil
+	})
+	RegisterSearchMethod("racing", func(c model.SearcherConfig) (SearchMethod, error) {
+		return newRacingSearch(*c.RacingConfig), nil
+	})
+}
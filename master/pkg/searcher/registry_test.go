@@ -0,0 +1,192 @@
+package searcher
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/determined-ai/determined/master/pkg/check"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// dummySearch is a search method built from the random search method with its own configuration.
+type dummySearch struct {
+	SearchMethod
+	label string
+}
+
+func TestRegisterSearchMethod(t *testing.T) {
+	RegisterSearchMethod("dummy", func(c model.SearcherConfig) (SearchMethod, error) {
+		var config struct {
+			Label     string       `json:"label"`
+			MaxTrials int          `json:"max_trials"`
+			MaxLength model.Length `json:"max_length"`
+		}
+		if err := json.Unmarshal(c.CustomConfig.Config, &config); err != nil {
+			return nil, err
+		}
+		random := newRandomSearch(model.RandomConfig{
+			MaxTrials: config.MaxTrials, MaxLength: config.MaxLength,
+		})
+		return &dummySearch{SearchMethod: random, label: config.Label}, nil
+	})
+
+	config := model.DefaultExperimentConfig().Searcher
+	assert.NilError(t, json.Unmarshal([]byte(`{
+  "name": "dummy",
+  "metric": "loss",
+  "label": "custom",
+  "max_trials": 2,
+  "max_length": {"epochs": 1}
+}`), &config))
+	assert.Equal(t, config.Name(), "dummy")
+	assert.Equal(t, config.Unit(), model.Epochs)
+
+	method, err := NewSearchMethod(config)
+	assert.NilError(t, err)
+	dummy, ok := method.(*dummySearch)
+	assert.Assert(t, ok)
+	assert.Equal(t, dummy.label, "custom")
+
+	searcher := NewSearcher(0, method, nil, nil)
+	ops, err := searcher.InitialOperations()
+	assert.NilError(t, err)
+	assert.Equal(t, len(ops), 8)
+
+	assert.Assert(t, func() (panicked bool) {
+		defer func() { panicked = recover() != nil }()
+		RegisterSearchMethod("dummy", nil)
+		return false
+	}())
+}
+
+func TestNewSearchMethodUnknownName(t *testing.T) {
+	config := model.DefaultExperimentConfig().Searcher
+	assert.NilError(t, json.Unmarshal([]byte(`{"name": "nonexistent", "metric": "loss"}`), &config))
+	_, err := NewSearchMethod(config)
+	assert.ErrorContains(t, err, "unknown searcher type: nonexistent")
+}
+
+func TestSearcherConfigUnregisteredName(t *testing.T) {
+	config := model.DefaultExperimentConfig().Searcher
+	assert.NilError(t, json.Unmarshal([]byte(`{
+  "name": "asynch_halving",
+  "metric": "loss",
+  "max_length": {"batches": 100}
+}`), &config))
+	assert.ErrorContains(t, check.Validate(config), "unknown searcher type: asynch_halving")
+}
+
+// countdownSearch is a search method that uses only the exported API of the package: it trains
+// and validates one trial after another until it has created MaxTrials of them.
+type countdownSearch struct {
+	MaxTrials int
+	Created   int
+	Closed    int
+}
+
+func (s *countdownSearch) create(ctx SearchContext) ([]Operation, error) {
+	hparams, err := ctx.SampleHyperparameters()
+	if err != nil {
+		return nil, err
+	}
+	s.Created++
+	create := NewCreate(ctx.Rand(), hparams, model.TrialWorkloadSequencerType)
+	return []Operation{
+		create,
+		NewTrain(create.RequestID, model.NewLengthInBatches(10)),
+		NewValidate(create.RequestID),
+		NewClose(create.RequestID),
+	}, nil
+}
+
+func (s *countdownSearch) InitialOperations(ctx SearchContext) ([]Operation, error) {
+	return s.create(ctx)
+}
+
+func (s *countdownSearch) TrialCreated(SearchContext, RequestID) ([]Operation, error) {
+	return nil, nil
+}
+
+func (s *countdownSearch) TrainCompleted(SearchContext, RequestID, Train) ([]Operation, error) {
+	return nil, nil
+}
+
+func (s *countdownSearch) CheckpointCompleted(
+	SearchContext, RequestID, Checkpoint, CheckpointMetrics,
+) ([]Operation, error) {
+	return nil, nil
+}
+
+func (s *countdownSearch) ValidationCompleted(
+	SearchContext, RequestID, Validate, ValidationMetrics,
+) ([]Operation, error) {
+	return nil, nil
+}
+
+func (s *countdownSearch) TrialClosed(ctx SearchContext, _ RequestID) ([]Operation, error) {
+	s.Closed++
+	if s.Created >= s.MaxTrials {
+		return nil, nil
+	}
+	return s.create(ctx)
+}
+
+func (s *countdownSearch) TrialExitedEarly(
+	ctx SearchContext, requestID RequestID, _ ExitedReason,
+) ([]Operation, error) {
+	return s.TrialClosed(ctx, requestID)
+}
+
+func (s *countdownSearch) Progress(model.Length) float64 {
+	return float64(s.Closed) / float64(s.MaxTrials)
+}
+
+func (s *countdownSearch) Snapshot() ([]byte, error) {
+	return json.Marshal(s)
+}
+
+func (s *countdownSearch) Restore(state []byte) error {
+	return json.Unmarshal(state, s)
+}
+
+func (s *countdownSearch) Unit() model.Unit {
+	return model.Batches
+}
+
+func TestAdaptSearchMethod(t *testing.T) {
+	RegisterSearchMethod("countdown", func(c model.SearcherConfig) (SearchMethod, error) {
+		var config struct {
+			MaxTrials int `json:"max_trials"`
+		}
+		if err := json.Unmarshal(c.CustomConfig.Config, &config); err != nil {
+			return nil, err
+		}
+		return AdaptSearchMethod(&countdownSearch{MaxTrials: config.MaxTrials}), nil
+	})
+	config := model.DefaultExperimentConfig().Searcher
+	assert.NilError(t, json.Unmarshal(
+		[]byte(`{"name": "countdown", "metric": "loss", "max_trials": 3}`), &config))
+	assert.NilError(t, check.Validate(config))
+	method, err := NewSearchMethod(config)
+	assert.NilError(t, err)
+
+	hparams := model.Hyperparameters{
+		"x": {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: 0, Maxval: 1}},
+	}
+	driver, err := newQueueDriver(method, hparams, func(int, int) float64 { return 0 })
+	assert.NilError(t, err)
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+	assert.Equal(t, len(driver.trialIndex), 3)
+	assert.Equal(t, method.progress(model.Length{}), 1.0)
+
+	snapshot, err := method.Snapshot()
+	assert.NilError(t, err)
+	restored := AdaptSearchMethod(&countdownSearch{})
+	assert.NilError(t, restored.Restore(snapshot))
+	assert.Equal(t, restored.progress(model.Length{}), 1.0)
+}
@@ -1,6 +1,10 @@
 package searcher
 
 import (
+	"time"
+
+	"github.com/pkg/errors"
+
 	"github.com/determined-ai/determined/master/pkg/model"
 	"github.com/determined-ai/determined/master/pkg/nprand"
 )
@@ -8,6 +12,34 @@ import (
 type context struct {
 	rand    *nprand.State
 	hparams model.Hyperparameters
+	// constraints must be satisfied by all sampled hyperparameters.
+	constraints []model.HyperparameterConstraint
+	// clock returns the current time; it defaults to time.Now when unset.
+	clock func() time.Time
+	// events, if set, receives the decisions of the search method.
+	events EventSink
+	// pinned, if set, is returned by sampleAll in place of a sample.
+	pinned hparamSample
+	// metadata is the metadata of the Create operation of each trial that had any.
+	metadata map[RequestID]map[string]string
+}
+
+func (c context) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+	return c.clock()
+}
+
+// trialMetadata returns the metadata the Create operation of the trial was tagged with, if any.
+func (c context) trialMetadata(requestID RequestID) map[string]string {
+	return c.metadata[requestID]
+}
+
+func (c context) decided(decision Decision) {
+	if c.events != nil {
+		c.events.Decided(decision)
+	}
 }
 
 // SearchMethod is the interface for hyper-parameter tuning methods. Implementations of this
@@ -41,36 +73,81 @@ type SearchMethod interface {
 	// progress returns experiment progress as a float between 0.0 and 1.0. As search methods
 	// receive completed workloads, they should internally track progress.
 	progress(totalUnitsCompleted model.Length) float64
-	// trialExitedEarly informs the searcher that the trial has exited earlier than expected.
-	trialExitedEarly(ctx context, requestID RequestID) ([]Operation, error)
+	// trialExitedEarly informs the searcher that the trial has exited earlier than expected for the
+	// given reason.
+	trialExitedEarly(ctx context, requestID RequestID, reason ExitedReason) ([]Operation, error)
+	// Snapshot returns the serialized state of the search method so that it can be persisted and
+	// later loaded by Restore, e.g., across master restarts.
+	Snapshot() ([]byte, error)
+	// Restore loads a state produced by Snapshot into a search method created from the same
+	// configuration.
+	Restore(state []byte) error
 	// SearchMethod embeds the InUnits interface because it is in terms of a specific unit.
 	model.InUnits
 }
 
-// NewSearchMethod returns a new search method for the provided searcher configuration.
-func NewSearchMethod(c model.SearcherConfig) SearchMethod {
-	switch {
-	case c.SingleConfig != nil:
-		return newSingleSearch(*c.SingleConfig)
-	case c.RandomConfig != nil:
-		return newRandomSearch(*c.RandomConfig)
-	case c.GridConfig != nil:
-		return newGridSearch(*c.GridConfig)
-	case c.SyncHalvingConfig != nil:
-		return newSyncHalvingSearch(*c.SyncHalvingConfig)
-	case c.AdaptiveConfig != nil:
-		return newAdaptiveSearch(*c.AdaptiveConfig)
-	case c.AdaptiveSimpleConfig != nil:
-		return newAdaptiveSimpleSearch(*c.AdaptiveSimpleConfig)
-	case c.AsyncHalvingConfig != nil:
-		return newAsyncHalvingSearch(*c.AsyncHalvingConfig)
-	case c.AdaptiveASHAConfig != nil:
-		return newAdaptiveASHASearch(*c.AdaptiveASHAConfig)
-	case c.PBTConfig != nil:
-		return newPBTSearch(*c.PBTConfig)
-	default:
-		panic("no searcher type specified")
+// timeoutChecker is implemented by search methods that act on trials that have not reported for
+// too long. Since nothing else prompts the search method when a trial stalls, checkTimeouts is
+// called periodically; it returns any new operations as a result.
+type timeoutChecker interface {
+	checkTimeouts(ctx context) ([]Operation, error)
+}
+
+// pauser is implemented by search methods that can be paused. While paused, the search method
+// keeps handling callbacks but holds back the operations it decides on, and resume returns them.
+type pauser interface {
+	pause()
+	resume(ctx context) ([]Operation, error)
+	paused() bool
+}
+
+// costRecorder is implemented by search methods that take into account how long trials take to
+// train. recordCost is called with the wall-clock duration of each step of training a trial
+// completes and the number of batches in the step.
+type costRecorder interface {
+	recordCost(requestID RequestID, duration time.Duration, batches int)
+}
+
+// stepRecorder is implemented by search methods whose progress accounts for the training trials
+// have done within a Train operation. recordStep is called with the length of each step of
+// training a trial completes.
+type stepRecorder interface {
+	recordStep(requestID RequestID, length model.Length)
+}
+
+// trialResumer is implemented by search methods that can resume a trial that was preempted from
+// where it left off, rather than retrain it for the whole of its current operations. trialResumed
+// is called with the length of training the trial had completed in all as of the checkpoint it
+// resumed from, as its workload sequencer reports it, and returns the operations that replace
+// those the trial had yet to complete.
+type trialResumer interface {
+	trialResumed(ctx context, requestID RequestID, completed model.Length) ([]Operation, error)
+}
+
+// validationHinter is implemented by search methods that want trials to validate within a Train
+// operation, e.g., more often early in training than late. nextValidationStep returns the total
+// length of training at which the trial should next validate, given how far it has trained, or
+// false if it need not validate before its operations call for it. These validations are not
+// reported to the search method as Validate operations, so they never cause promotions.
+// hintsValidations returns whether nextValidationStep ever returns a step for the configuration of
+// the search method; trials of a method that never does skip asking for one.
+type validationHinter interface {
+	nextValidationStep(requestID RequestID, trained model.Length) (model.Length, bool)
+	hintsValidations() bool
+}
+
+// NewSearchMethod returns a new search method for the provided searcher configuration, using the
+// factory registered for the name of the configured searcher.
+func NewSearchMethod(c model.SearcherConfig) (SearchMethod, error) {
+	name := c.Name()
+	if name == "" {
+		return nil, errors.WithStack(ConfigError{Err: errors.New("no searcher type specified")})
+	}
+	factory, ok := lookupSearchMethod(name)
+	if !ok {
+		return nil, errors.WithStack(ConfigError{Err: errors.Errorf("unknown searcher type: %s", name)})
 	}
+	return factory(c)
 }
 
 type defaultSearchMethod struct{}
@@ -97,6 +174,14 @@ func (defaultSearchMethod) trialClosed(context, RequestID) ([]Operation, error)
 }
 
 func (defaultSearchMethod) trialExitedEarly( //nolint: unused
-	context, RequestID) ([]Operation, error) {
+	context, RequestID, ExitedReason) ([]Operation, error) {
 	return []Operation{Shutdown{Failure: true}}, nil
 }
+
+func (defaultSearchMethod) Snapshot() ([]byte, error) {
+	return nil, errors.New("search method does not support snapshots")
+}
+
+func (defaultSearchMethod) Restore([]byte) error {
+	return errors.New("search method does not support restoring from a snapshot")
+}
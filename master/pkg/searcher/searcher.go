@@ -2,8 +2,10 @@ package searcher
 
 import (
 	"math"
+	"time"
 
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 
 	"github.com/determined-ai/determined/master/pkg/model"
 	"github.com/determined-ai/determined/master/pkg/nprand"
@@ -11,24 +13,108 @@ import (
 
 // Searcher encompasses the state as the searcher progresses using the provided search method.
 type Searcher struct {
-	rand     *nprand.State
-	hparams  model.Hyperparameters
-	method   SearchMethod
-	eventLog *EventLog
+	seed        uint32
+	rand        *nprand.State
+	hparams     model.Hyperparameters
+	constraints []model.HyperparameterConstraint
+	method      SearchMethod
+	eventLog    *EventLog
+	events      EventSink
+	pinned      hparamSample
+
+	maxOperations int
+	// queued holds the operations held back by maxOperations, in the order they were decided on.
+	queued []Operation
 }
 
 // NewSearcher creates a new Searcher configured with the provided searcher config.
-func NewSearcher(seed uint32, method SearchMethod, hparams model.Hyperparameters) *Searcher {
+func NewSearcher(
+	seed uint32, method SearchMethod, hparams model.Hyperparameters,
+	constraints []model.HyperparameterConstraint,
+) *Searcher {
 	return &Searcher{
-		rand:     nprand.New(seed),
-		hparams:  hparams,
-		method:   method,
-		eventLog: NewEventLog(method.Unit()),
+		seed:        seed,
+		rand:        nprand.New(seed),
+		hparams:     hparams,
+		constraints: constraints,
+		method:      method,
+		eventLog:    NewEventLog(method.Unit()),
 	}
 }
 
+// SetMaxOperationsPerCall limits how many operations each call returns to n, if it is positive,
+// so that a burst of decisions, e.g., promotions after many validations, is handed out gradually.
+// The rest of the operations are queued and returned ahead of any new ones by later calls,
+// including DrainPending.
+func (s *Searcher) SetMaxOperationsPerCall(n int) {
+	s.maxOperations = n
+}
+
+// limit queues the operations and returns as many of the queued operations as maxOperations
+// allows, oldest first.
+func (s *Searcher) limit(operations []Operation) []Operation {
+	if len(s.queued) == 0 && (s.maxOperations <= 0 || len(operations) <= s.maxOperations) {
+		return operations
+	}
+	s.queued = append(s.queued, operations...)
+	n := len(s.queued)
+	if s.maxOperations > 0 && s.maxOperations < n {
+		n = s.maxOperations
+	}
+	batch := append([]Operation(nil), s.queued[:n]...)
+	s.queued = s.queued[n:]
+	if len(s.queued) == 0 {
+		s.queued = nil
+	}
+	return batch
+}
+
+// DrainPending returns the next operations held back by SetMaxOperationsPerCall, if any.
+func (s *Searcher) DrainPending() []Operation {
+	return s.limit(nil)
+}
+
+// HasPendingOperations returns whether any operations are held back by SetMaxOperationsPerCall.
+func (s *Searcher) HasPendingOperations() bool {
+	return len(s.queued) > 0
+}
+
+// SetEventSink sets the sink to which the search method reports each decision it makes about
+// trials; a nil sink disables reporting.
+func (s *Searcher) SetEventSink(events EventSink) {
+	s.events = events
+}
+
 func (s *Searcher) context() context {
-	return context{rand: s.rand, hparams: s.hparams}
+	return context{
+		rand: s.rand, hparams: s.hparams, constraints: s.constraints, clock: time.Now,
+		events: s.events, pinned: s.pinned, metadata: s.eventLog.TrialMetadata,
+	}
+}
+
+// record seeds the trials that the operations create and adds the operations to the event log.
+// Each trial's seed is derived from the experiment seed and the order in which the trial was
+// requested alone, so it does not depend on how much randomness the search method has used.
+func (s *Searcher) record(operations []Operation) {
+	index := s.eventLog.TrialsRequested
+	for i, operation := range operations {
+		if create, ok := operation.(Create); ok {
+			create.TrialSeed = trialSeed(s.seed, index)
+			operations[i] = create
+			index++
+		}
+	}
+	s.eventLog.OperationsCreated(operations...)
+}
+
+// trialSeed derives the seed of the trial requested at the given index from the experiment seed
+// using the SplitMix64 mixing function; the result is between 0 and 2**31 - 1.
+func trialSeed(experimentSeed uint32, index int) uint32 {
+	z := uint64(experimentSeed)<<32 + uint64(index) + 0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	z ^= z >> 31
+	return uint32(z >> 33)
 }
 
 // InitialOperations return a set of initial operations that the searcher would like to take.
@@ -38,8 +124,8 @@ func (s *Searcher) InitialOperations() ([]Operation, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "error while fetching initial operations of search method")
 	}
-	s.eventLog.OperationsCreated(operations...)
-	return operations, nil
+	s.record(operations)
+	return s.limit(operations), nil
 }
 
 // TrialCreated informs the searcher that a trial has been created as a result of a Create
@@ -51,40 +137,93 @@ func (s *Searcher) TrialCreated(create Create, trialID int) ([]Operation, error)
 		return nil, errors.Wrapf(err,
 			"error while handling a trial created event: %s", create.RequestID)
 	}
-	s.eventLog.OperationsCreated(operations...)
-	return operations, nil
+	s.record(operations)
+	return s.limit(operations), nil
 }
 
-// TrialExitedEarly indicates to the searcher that the trial with the given trialID exited early.
-func (s *Searcher) TrialExitedEarly(trialID int) ([]Operation, error) {
+// TrialExitedEarly indicates to the searcher that the trial with the given trialID exited early for
+// the given reason.
+func (s *Searcher) TrialExitedEarly(trialID int, reason ExitedReason) ([]Operation, error) {
 	requestID, ok := s.eventLog.RequestIDs[trialID]
 	if !ok {
-		return nil, errors.Errorf("unexpected trial ID sent to searcher: %d", trialID)
+		return nil, errors.WithStack(InternalError{
+			Err: errors.Errorf("unexpected trial ID sent to searcher: %d", trialID),
+		})
 	}
 
 	s.eventLog.TrialExitedEarly(requestID)
-	operations, err := s.method.trialExitedEarly(s.context(), requestID)
-	s.eventLog.OperationsCreated(operations...)
+	operations, err := s.method.trialExitedEarly(s.context(), requestID, reason)
 	if err != nil {
 		return nil, errors.Wrapf(err, "error relaying trial exited early to trial %d", trialID)
 	}
-	return operations, nil
+	s.record(operations)
+	return s.limit(operations), nil
 }
 
 // WorkloadCompleted informs the searcher that the workload is completed. This relays the message
 // to the event log and records the units as complete for search method progress.
 func (s *Searcher) WorkloadCompleted(msg CompletedMessage, unitsCompleted model.Length) {
 	s.eventLog.WorkloadCompleted(msg, unitsCompleted)
+	requestID, known := s.eventLog.RequestIDs[msg.Workload.TrialID]
+	if !known || msg.Workload.Kind != RunStep {
+		return
+	}
+	if recorder, ok := s.method.(stepRecorder); ok {
+		recorder.recordStep(requestID, unitsCompleted)
+	}
+	recorder, ok := s.method.(costRecorder)
+	if !ok || msg.Workload.NumBatches <= 0 || !msg.EndTime.After(msg.StartTime) {
+		return
+	}
+	recorder.recordCost(requestID, msg.EndTime.Sub(msg.StartTime), msg.Workload.NumBatches)
+}
+
+// TrialResumed informs the searcher that a preempted trial has resumed from a checkpoint at which
+// it had completed the given length of training. If the search method supports it, the returned
+// operations replace those the trial had yet to complete, so that it does only the training that is
+// left; otherwise, no operations are returned and the trial carries on with its own.
+func (s *Searcher) TrialResumed(trialID int, completed model.Length) ([]Operation, error) {
+	requestID, ok := s.eventLog.RequestIDs[trialID]
+	if !ok {
+		return nil, errors.WithStack(InternalError{
+			Err: errors.Errorf("unexpected trial ID sent to searcher: %d", trialID),
+		})
+	}
+	// The resume is not itself an event of the event log, which only tracks the operations and the
+	// trials they create; a search method that does not replan the trial has nothing to record.
+	resumer, ok := s.method.(trialResumer)
+	if !ok {
+		return nil, nil
+	}
+	operations, err := resumer.trialResumed(s.context(), requestID, completed)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error relaying trial resumed to trial %d", trialID)
+	}
+	s.record(operations)
+	return s.limit(operations), nil
 }
 
 // OperationCompleted informs the searcher that the given workload initiated by the same searcher
 // has completed. Returns any new operations as a result of this workload completing.
+//
+// A trial that was preempted and resumed elsewhere may report a validation after it has already
+// exited early. The validation is dropped with a warning rather than used to bring the trial back
+// into the search: the search method has already accounted for the exit, e.g., by recording the
+// worst possible metric for the trial and promoting other trials past it, and the trial no longer
+// has any work scheduled by the searcher.
 func (s *Searcher) OperationCompleted(
 	trialID int, op Runnable, metrics interface{},
 ) ([]Operation, error) {
 	requestID, ok := s.eventLog.RequestIDs[trialID]
 	if !ok {
-		return nil, errors.Errorf("unexpected trial ID sent to searcher: %d", trialID)
+		return nil, errors.WithStack(InternalError{
+			Err: errors.Errorf("unexpected trial ID sent to searcher: %d", trialID),
+		})
+	}
+	if _, validate := op.(Validate); validate && s.eventLog.earlyExits[requestID] {
+		log.WithField("request-id", requestID).Warn(
+			"dropping validation from a trial that already exited early")
+		return s.limit(nil), nil
 	}
 
 	var operations []Operation
@@ -100,14 +239,14 @@ func (s *Searcher) OperationCompleted(
 		operations, err = s.method.validationCompleted(
 			s.context(), requestID, tOp, *metrics.(*ValidationMetrics))
 	default:
-		return nil, errors.Errorf("unexpected op: %s", tOp)
+		return nil, errors.WithStack(InternalError{Err: errors.Errorf("unexpected op: %s", tOp)})
 	}
 
 	if err != nil {
 		return nil, errors.Wrapf(err, "error while handling a workload completed event: %s", requestID)
 	}
-	s.eventLog.OperationsCreated(operations...)
-	return operations, nil
+	s.record(operations)
+	return s.limit(operations), nil
 }
 
 // TrialClosed informs the searcher that the trial has been closed as a result of a Close operation.
@@ -117,13 +256,118 @@ func (s *Searcher) TrialClosed(requestID RequestID) ([]Operation, error) {
 	if err != nil {
 		return nil, errors.Wrapf(err, "error while handling a trial closed event: %s", requestID)
 	}
-	s.eventLog.OperationsCreated(operations...)
-	if s.eventLog.TrialsRequested == s.eventLog.TrialsClosed {
-		shutdown := Shutdown{Failure: len(s.eventLog.earlyExits) >= s.eventLog.TrialsRequested}
-		s.eventLog.OperationsCreated(shutdown)
-		operations = append(operations, shutdown)
+	s.record(operations)
+	return s.limit(s.shutdownIfDone(operations)), nil
+}
+
+// shutdownIfDone appends a Shutdown to the operations once every trial requested has been closed,
+// unless the search method is paused and may yet request more.
+func (s *Searcher) shutdownIfDone(operations []Operation) []Operation {
+	if s.eventLog.TrialsRequested != s.eventLog.TrialsClosed {
+		return operations
+	}
+	if p, ok := s.method.(pauser); ok && p.paused() {
+		return operations
+	}
+	shutdown := Shutdown{Failure: len(s.eventLog.earlyExits) >= s.eventLog.TrialsRequested}
+	s.eventLog.OperationsCreated(shutdown)
+	return append(operations, shutdown)
+}
+
+// Pause stops the search method from emitting operations, e.g., to create or promote trials,
+// until Resume is called. The search method keeps track of what trials report in the meantime.
+func (s *Searcher) Pause() error {
+	p, ok := s.method.(pauser)
+	if !ok {
+		return errors.Errorf("search method %T cannot be paused", s.method)
+	}
+	// A search method that wraps another, e.g., a fallbackSearch, may only find out that it cannot
+	// be paused when it tries to.
+	p.pause()
+	if !p.paused() {
+		return errors.Errorf("search method %T cannot be paused", s.method)
+	}
+	return nil
+}
+
+// Resume lets a paused search method emit operations again and returns those it decided on while
+// it was paused.
+func (s *Searcher) Resume() ([]Operation, error) {
+	p, ok := s.method.(pauser)
+	if !ok {
+		return nil, errors.Errorf("search method %T cannot be paused", s.method)
+	}
+	operations, err := p.resume(s.context())
+	if err != nil {
+		return nil, errors.Wrap(err, "error while resuming the search method")
+	}
+	s.record(operations)
+	return s.limit(s.shutdownIfDone(operations)), nil
+}
+
+// CheckTimeouts gives the search method the chance to close trials that have stalled, if it
+// supports timeouts; it should be called periodically. It returns any new operations as a result.
+func (s *Searcher) CheckTimeouts() ([]Operation, error) {
+	checker, ok := s.method.(timeoutChecker)
+	if !ok {
+		return nil, nil
+	}
+	operations, err := checker.checkTimeouts(s.context())
+	if err != nil {
+		return nil, errors.Wrap(err, "error while checking for trials that timed out")
+	}
+	s.record(operations)
+	return s.limit(operations), nil
+}
+
+// HintsValidations returns whether the search method asks trials to validate within their
+// operations, i.e., whether NextValidationStep is worth calling for its trials at all.
+func (s *Searcher) HintsValidations() bool {
+	hinter, ok := s.method.(validationHinter)
+	return ok && hinter.hintsValidations()
+}
+
+// NextValidationStep returns the total length of training at which the trial should next validate
+// within its current operations, given how far it has trained, if its search method asks for such
+// validations; otherwise, the trial only validates when its operations call for it.
+func (s *Searcher) NextValidationStep(
+	requestID RequestID, trained model.Length,
+) (model.Length, bool) {
+	hinter, ok := s.method.(validationHinter)
+	if !ok || !hinter.hintsValidations() {
+		return model.Length{}, false
+	}
+	step, ok := hinter.nextValidationStep(requestID, trained)
+	if !ok || step.Units <= trained.Units {
+		return model.Length{}, false
 	}
-	return operations, nil
+	return step, true
+}
+
+// PinHparams makes every trial that the search method creates from sampled hyperparameters use the
+// given point instead until UnpinHparams is called, e.g., to reproduce a flaky trial while the rest
+// of the search carries on as usual. Trials whose hyperparameters the search method chooses
+// otherwise, e.g., grid points or the proposals of a model-based search, are unaffected. The point
+// must be valid for the hyperparameters and satisfy the constraints of the experiment. Pinning is
+// not part of the state of the search method, so a restored search samples as usual.
+func (s *Searcher) PinHparams(point map[string]interface{}) error {
+	pinned, err := listPoint(s.hparams, point)
+	if err != nil {
+		return errors.Wrap(err, "invalid hyperparameters to pin")
+	}
+	switch satisfied, constraintErr := satisfiesConstraints(s.constraints, pinned); {
+	case constraintErr != nil:
+		return errors.Wrap(constraintErr, "invalid hyperparameters to pin")
+	case !satisfied:
+		return errors.New("hyperparameters to pin do not satisfy the constraints")
+	}
+	s.pinned = pinned
+	return nil
+}
+
+// UnpinHparams restores sampling the hyperparameters of new trials after PinHparams.
+func (s *Searcher) UnpinHparams() {
+	s.pinned = nil
 }
 
 // Progress returns experiment progress as a float between 0.0 and 1.0.
@@ -0,0 +1,435 @@
+package searcher
+
+import (
+	"strconv"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/nprand"
+)
+
+// runSearcher drives a searcher, handling its operations in order, with each trial reporting its
+// "x" hyperparameter as its metric, and returns all of the operations it returned.
+func runSearcher(t *testing.T, searcher *Searcher) []Operation {
+	pending, err := searcher.InitialOperations()
+	assert.NilError(t, err)
+	all := append([]Operation{}, pending...)
+	trialIDs := make(map[RequestID]int)
+	hparams := make(map[RequestID]hparamSample)
+	for len(pending) > 0 {
+		operation := pending[0]
+		pending = pending[1:]
+		var ops []Operation
+		switch operation := operation.(type) {
+		case Create:
+			trialIDs[operation.RequestID] = len(trialIDs) + 1
+			hparams[operation.RequestID] = operation.Hparams
+			ops, err = searcher.TrialCreated(operation, trialIDs[operation.RequestID])
+		case Train:
+			ops, err = searcher.OperationCompleted(trialIDs[operation.RequestID], operation, nil)
+		case Validate:
+			ops, err = searcher.OperationCompleted(
+				trialIDs[operation.RequestID], operation, &ValidationMetrics{
+					Metrics: map[string]interface{}{defaultMetric: hparams[operation.RequestID]["x"]},
+				})
+		case Close:
+			ops, err = searcher.TrialClosed(operation.RequestID)
+		}
+		assert.NilError(t, err)
+		pending = append(pending, ops...)
+		all = append(all, ops...)
+	}
+	return all
+}
+
+func TestSearcherSeedReproducibility(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           9,
+		MaxConcurrentTrials: 3,
+	}
+	hparams := model.Hyperparameters{
+		"x": {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: 0, Maxval: 1}},
+	}
+	run := func(seed uint32) []Operation {
+		return runSearcher(t, NewSearcher(seed, newAsyncHalvingSearch(config), hparams, nil))
+	}
+
+	ops := run(42)
+	assert.DeepEqual(t, run(42), ops)
+	assert.Assert(t, len(ops) > 0)
+
+	// Each trial's seed depends only on the experiment seed and when the trial was requested.
+	var creates []Create
+	for _, op := range ops {
+		if create, ok := op.(Create); ok {
+			creates = append(creates, create)
+		}
+	}
+	assert.Equal(t, len(creates), config.MaxTrials)
+	seeds := make(map[uint32]bool)
+	for i, create := range creates {
+		assert.Equal(t, create.TrialSeed, trialSeed(42, i))
+		assert.Assert(t, create.TrialSeed < 1<<31)
+		seeds[create.TrialSeed] = true
+	}
+	assert.Equal(t, len(seeds), len(creates))
+
+	for _, op := range run(43) {
+		if create, ok := op.(Create); ok {
+			assert.Assert(t, create.TrialSeed != creates[0].TrialSeed)
+			break
+		}
+	}
+}
+
+func TestSearcherValidationAfterEarlyExit(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(200),
+		Divisor:             2,
+		MaxTrials:           2,
+		MaxConcurrentTrials: 2,
+	}
+	search := newAsyncHalvingSearch(config).(*asyncHalvingSearch)
+	searcher := NewSearcher(0, search, nil, nil)
+	ops, err := searcher.InitialOperations()
+	assert.NilError(t, err)
+	exited, reported := ops[0].(Create), ops[3].(Create)
+	_, err = searcher.TrialCreated(exited, 1)
+	assert.NilError(t, err)
+	_, err = searcher.TrialCreated(reported, 2)
+	assert.NilError(t, err)
+	validate := func(trialID int, requestID RequestID, metric float64) []Operation {
+		ops, validateErr := searcher.OperationCompleted(trialID, NewValidate(requestID),
+			&ValidationMetrics{Metrics: map[string]interface{}{defaultMetric: metric}})
+		assert.NilError(t, validateErr)
+		return ops
+	}
+
+	// The first trial exits early, so the worst possible metric is recorded for it; its validation
+	// then arrives after all, with a metric that would have ranked it first, and is dropped.
+	_, err = searcher.TrialExitedEarly(1, Errored)
+	assert.NilError(t, err)
+	assert.Equal(t, len(validate(1, exited.RequestID, 0)), 0)
+	assert.Equal(t, len(search.Rungs[0].Metrics), 1)
+	assert.Equal(t, search.Rungs[0].Metrics[0].Metric, ashaExitedMetricValue)
+	assert.Equal(t, search.Rungs[0].OutstandingTrials, 1)
+	assert.Equal(t, search.OutstandingTrials, 1)
+
+	// The other trial is promoted past it as if the late validation never arrived.
+	assert.DeepEqual(t, validate(2, reported.RequestID, 1), []Operation{
+		NewTrain(reported.RequestID, model.NewLengthInBatches(100)),
+		NewValidate(reported.RequestID),
+	})
+	assert.Equal(t, len(search.Rungs[0].Metrics), 2)
+	assert.Equal(t, search.TrialRungs[exited.RequestID], 0)
+	assert.Equal(t, search.TrialRungs[reported.RequestID], 1)
+}
+
+// hintingMethod asks trials to validate at the steps returned by hint.
+type hintingMethod struct {
+	SearchMethod
+	hint func(trained int) int
+}
+
+func (m *hintingMethod) nextValidationStep(
+	_ RequestID, trained model.Length,
+) (model.Length, bool) {
+	return model.NewLengthInBatches(m.hint(trained.Units)), true
+}
+
+func (m *hintingMethod) hintsValidations() bool {
+	return true
+}
+
+func TestSearcherNextValidationStep(t *testing.T) {
+	config := model.SingleConfig{MaxLength: model.NewLengthInBatches(1000)}
+	// Trials validate every 100 batches for their first 400 batches and every 200 batches after.
+	method := &hintingMethod{SearchMethod: newSingleSearch(config), hint: func(trained int) int {
+		if trained < 400 {
+			return trained/100*100 + 100
+		}
+		return trained/200*200 + 200
+	}}
+	searcher := NewSearcher(0, method, nil, nil)
+	assert.Assert(t, searcher.HintsValidations())
+	ops, err := searcher.InitialOperations()
+	assert.NilError(t, err)
+	requestID := ops[0].(Create).RequestID
+
+	var steps []int
+	for trained := 0; trained < config.MaxLength.Units; {
+		step, ok := searcher.NextValidationStep(requestID, model.NewLengthInBatches(trained))
+		assert.Assert(t, ok)
+		steps = append(steps, step.Units)
+		trained = step.Units
+	}
+	assert.DeepEqual(t, steps, []int{100, 200, 300, 400, 600, 800, 1000})
+
+	// A step the trial has already trained to is no hint at all.
+	method.hint = func(trained int) int { return trained }
+	_, ok := searcher.NextValidationStep(requestID, model.NewLengthInBatches(100))
+	assert.Assert(t, !ok)
+
+	// Other search methods only validate when their operations call for it.
+	searcher = NewSearcher(0, newSingleSearch(config), nil, nil)
+	assert.Assert(t, !searcher.HintsValidations())
+	_, ok = searcher.NextValidationStep(requestID, model.NewLengthInBatches(0))
+	assert.Assert(t, !ok)
+
+	// Asynchronous halving implements the hook without ever hinting.
+	searcher = NewSearcher(0, newAsyncHalvingSearch(model.AsyncHalvingConfig{
+		Metric:    defaultMetric,
+		NumRungs:  2,
+		MaxLength: model.NewLengthInBatches(1000),
+		Divisor:   2,
+		MaxTrials: 2,
+	}), nil, nil)
+	assert.Assert(t, !searcher.HintsValidations())
+	_, ok = searcher.NextValidationStep(requestID, model.NewLengthInBatches(0))
+	assert.Assert(t, !ok)
+}
+
+func TestSearcherPinHparams(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            1,
+		MaxLength:           model.NewLengthInBatches(100),
+		Divisor:             2,
+		MaxTrials:           4,
+		MaxConcurrentTrials: 1,
+	}
+	searcher := NewSearcher(0, newAsyncHalvingSearch(config), listHyperparameters(), nil)
+	assert.ErrorContains(t, searcher.PinHparams(map[string]interface{}{"lr": 0.1}),
+		"invalid hyperparameters to pin: missing a value for hyperparameter layers")
+	assert.NilError(t, searcher.PinHparams(map[string]interface{}{
+		"lr": 0.1, "layers": 2.0, "optimizer": "adam",
+	}))
+	pinned := hparamSample{"lr": 0.1, "layers": 2, "optimizer": "adam"}
+
+	// Each trial is created when the previous one validates.
+	ops, err := searcher.InitialOperations()
+	assert.NilError(t, err)
+	trialID := 0
+	next := func() hparamSample {
+		var creates []Create
+		for _, op := range ops {
+			if create, ok := op.(Create); ok {
+				creates = append(creates, create)
+			}
+		}
+		assert.Equal(t, len(creates), 1)
+		trialID++
+		_, err = searcher.TrialCreated(creates[0], trialID)
+		assert.NilError(t, err)
+		ops, err = searcher.OperationCompleted(trialID, NewValidate(creates[0].RequestID),
+			&ValidationMetrics{Metrics: map[string]interface{}{defaultMetric: 1.0}})
+		assert.NilError(t, err)
+		return creates[0].Hparams
+	}
+	// The second trial is created while the hyperparameters are still pinned, and the rest after
+	// they are unpinned.
+	assert.DeepEqual(t, next(), pinned)
+	searcher.UnpinHparams()
+	assert.DeepEqual(t, next(), pinned)
+	for i := 0; i < 2; i++ {
+		hparams := next()
+		assert.Assert(t, hparams["lr"] != 0.1, hparams)
+		assert.NilError(t, listHyperparameters().CheckPoint(hparams))
+	}
+}
+
+func TestSearcherMaxOperationsPerCall(t *testing.T) {
+	const maxOperations = 4
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           18,
+		MaxConcurrentTrials: 9,
+	}
+	unlimited := NewSearcher(0, newAsyncHalvingSearch(config), nil, nil)
+	limited := NewSearcher(0, newAsyncHalvingSearch(config), nil, nil)
+	limited.SetMaxOperationsPerCall(maxOperations)
+
+	// Each call to the limited searcher is followed by draining it, which together return the same
+	// operations in the same order as the call to the unlimited one.
+	compare := func(call func(s *Searcher) ([]Operation, error)) []Operation {
+		expected, err := call(unlimited)
+		assert.NilError(t, err)
+		actual, err := call(limited)
+		assert.NilError(t, err)
+		assert.Assert(t, len(actual) <= maxOperations, "batch of %d", len(actual))
+		for batch := limited.DrainPending(); len(batch) > 0; batch = limited.DrainPending() {
+			assert.Assert(t, len(batch) <= maxOperations, "batch of %d", len(batch))
+			actual = append(actual, batch...)
+		}
+		assert.Assert(t, !limited.HasPendingOperations())
+		assert.DeepEqual(t, actual, expected)
+		return expected
+	}
+	ops := compare(func(s *Searcher) ([]Operation, error) { return s.InitialOperations() })
+	assert.Equal(t, len(ops), 27)
+
+	// Without draining, the queued operations are returned by the calls that follow, ahead of
+	// their own operations.
+	undrained := NewSearcher(0, newAsyncHalvingSearch(config), nil, nil)
+	undrained.SetMaxOperationsPerCall(maxOperations)
+	batch, err := undrained.InitialOperations()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, batch, ops[:maxOperations])
+	batch, err = undrained.TrialCreated(ops[0].(Create), 1)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, batch, ops[maxOperations:2*maxOperations])
+
+	// Every trial exits early at once, and each exit promotes or creates trials in a burst.
+	var creates []Create
+	for _, op := range ops {
+		if create, ok := op.(Create); ok {
+			creates = append(creates, create)
+		}
+	}
+	for i, create := range creates {
+		compare(func(s *Searcher) ([]Operation, error) { return s.TrialCreated(create, i+1) })
+	}
+	total := 0
+	for i := range creates {
+		total += len(compare(func(s *Searcher) ([]Operation, error) {
+			return s.TrialExitedEarly(i+1, Errored)
+		}))
+	}
+	assert.Assert(t, total > maxOperations)
+}
+
+// taggingMethod tags each trial its search method creates with the order in which it was created,
+// and records the tag of each trial as the context reports it at validation time.
+type taggingMethod struct {
+	SearchMethod
+	created   int
+	validated map[RequestID]string
+}
+
+func (m *taggingMethod) tag(ops []Operation) []Operation {
+	for i, op := range ops {
+		if create, ok := op.(Create); ok {
+			ops[i] = create.WithMetadata("generation", strconv.Itoa(m.created))
+			m.created++
+		}
+	}
+	return ops
+}
+
+func (m *taggingMethod) initialOperations(ctx context) ([]Operation, error) {
+	ops, err := m.SearchMethod.initialOperations(ctx)
+	return m.tag(ops), err
+}
+
+func (m *taggingMethod) validationCompleted(
+	ctx context, requestID RequestID, validate Validate, metrics ValidationMetrics,
+) ([]Operation, error) {
+	m.validated[requestID] = ctx.trialMetadata(requestID)["generation"]
+	ops, err := m.SearchMethod.validationCompleted(ctx, requestID, validate, metrics)
+	return m.tag(ops), err
+}
+
+func TestSearcherTrialMetadata(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            2,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           6,
+		MaxConcurrentTrials: 2,
+	}
+	hparams := model.Hyperparameters{
+		"x": {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: 0, Maxval: 1}},
+	}
+	method := &taggingMethod{
+		SearchMethod: newAsyncHalvingSearch(config),
+		validated:    make(map[RequestID]string),
+	}
+	searcher := NewSearcher(0, method, hparams, nil)
+	ops := runSearcher(t, searcher)
+
+	// The metadata set on each Create is what the context reports for the trial at validation time,
+	// including for the trials created in response to validations and those promoted to the top
+	// rung.
+	var creates []Create
+	for _, op := range ops {
+		if create, ok := op.(Create); ok {
+			creates = append(creates, create)
+		}
+	}
+	assert.Equal(t, len(creates), config.MaxTrials)
+	assert.Equal(t, len(method.validated), config.MaxTrials)
+	for i, create := range creates {
+		assert.Equal(t, create.Metadata["generation"], strconv.Itoa(i))
+		assert.Equal(t, method.validated[create.RequestID], strconv.Itoa(i))
+		assert.DeepEqual(t, searcher.context().trialMetadata(create.RequestID), create.Metadata)
+	}
+
+	// Trials without metadata have none.
+	assert.Assert(t, searcher.context().trialMetadata(RequestID{}) == nil)
+	create := NewCreate(nprand.New(0), nil, model.TrialWorkloadSequencerType)
+	tagged := create.WithMetadata("bracket", "1")
+	assert.Assert(t, create.Metadata == nil)
+	assert.DeepEqual(t, tagged.WithMetadata("generation", "2").Metadata,
+		map[string]string{"bracket": "1", "generation": "2"})
+	assert.DeepEqual(t, tagged.Metadata, map[string]string{"bracket": "1"})
+}
+
+func TestSearcherTrialResumed(t *testing.T) {
+	config := model.AsyncHalvingConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		NumRungs:            3,
+		MaxLength:           model.NewLengthInBatches(900),
+		Divisor:             3,
+		MaxTrials:           9,
+		MaxConcurrentTrials: 3,
+	}
+	hparams := model.Hyperparameters{
+		"x": {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: 0, Maxval: 1}},
+	}
+	searcher := NewSearcher(0, newAsyncHalvingSearch(config), hparams, nil)
+	ops, err := searcher.InitialOperations()
+	assert.NilError(t, err)
+	create := ops[0].(Create)
+	_, err = searcher.TrialCreated(create, 1)
+	assert.NilError(t, err)
+
+	ops, err = searcher.TrialResumed(1, model.NewLengthInBatches(70))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, ops, []Operation{
+		NewTrain(create.RequestID, model.NewLengthInBatches(30)),
+		NewValidate(create.RequestID),
+	})
+
+	_, err = searcher.TrialResumed(2, model.NewLengthInBatches(70))
+	assert.ErrorContains(t, err, "unexpected trial ID")
+
+	// Search methods that cannot resume trials leave them to carry on with their operations.
+	searcher = NewSearcher(0, newRandomSearch(model.RandomConfig{
+		MaxTrials: 1, MaxLength: model.NewLengthInBatches(100),
+	}), hparams, nil)
+	ops, err = searcher.InitialOperations()
+	assert.NilError(t, err)
+	_, err = searcher.TrialCreated(ops[0].(Create), 1)
+	assert.NilError(t, err)
+	ops, err = searcher.TrialResumed(1, model.NewLengthInBatches(70))
+	assert.NilError(t, err)
+	assert.Equal(t, len(ops), 0)
+}
@@ -24,6 +24,9 @@ type syncHalvingSearch struct {
 	expectedUnits model.Length
 }
 
+// shaExitedMetricValue is the metric recorded for trials that exit early. It is only
+// ever compared against metrics that validationCompleted has already sign-adjusted so that smaller
+// is better, so it ranks as the worst possible result whatever the value of SmallerIsBetter.
 const shaExitedMetricValue = math.MaxFloat64
 
 func newSyncHalvingSearch(config model.SyncHalvingConfig) SearchMethod {
@@ -38,14 +41,14 @@ func newSyncHalvingSearch(config model.SyncHalvingConfig) SearchMethod {
 		startTrials := max(int(compound), 1)
 		rungs = append(rungs,
 			&rung{
-				unitsNeeded: unitsNeeded,
-				startTrials: startTrials,
+				UnitsNeeded: unitsNeeded,
+				StartTrials: startTrials,
 			},
 		)
 		if id == 0 {
 			expectedUnits += unitsNeeded.Units * startTrials
 		} else {
-			expectedUnits += (unitsNeeded.Units - rungs[id-1].unitsNeeded.Units) * startTrials
+			expectedUnits += (unitsNeeded.Units - rungs[id-1].UnitsNeeded.Units) * startTrials
 		}
 	}
 
@@ -53,18 +56,18 @@ func newSyncHalvingSearch(config model.SyncHalvingConfig) SearchMethod {
 	expectedUnits = 0
 	for id := 0; id < config.NumRungs; id++ {
 		cur := rungs[id]
-		cur.startTrials = int(multiplier * float64(cur.startTrials))
+		cur.StartTrials = int(multiplier * float64(cur.StartTrials))
 		if id == 0 {
-			expectedUnits += cur.unitsNeeded.Units * cur.startTrials
+			expectedUnits += cur.UnitsNeeded.Units * cur.StartTrials
 		} else {
 			prev := rungs[id-1]
-			cur.unitsNeeded = model.NewLength(
+			cur.UnitsNeeded = model.NewLength(
 				config.Unit(),
-				max(cur.unitsNeeded.Units, prev.unitsNeeded.Units),
+				max(cur.UnitsNeeded.Units, prev.UnitsNeeded.Units),
 			)
-			cur.startTrials = max(min(cur.startTrials, prev.startTrials), 1)
-			prev.promoteTrials = cur.startTrials
-			expectedUnits += (cur.unitsNeeded.Units - prev.unitsNeeded.Units) * cur.startTrials
+			cur.StartTrials = max(min(cur.StartTrials, prev.StartTrials), 1)
+			prev.PromoteTrials = cur.StartTrials
+			expectedUnits += (cur.UnitsNeeded.Units - prev.UnitsNeeded.Units) * cur.StartTrials
 		}
 	}
 
@@ -78,57 +81,78 @@ func newSyncHalvingSearch(config model.SyncHalvingConfig) SearchMethod {
 }
 
 type trialMetric struct {
-	requestID RequestID
-	metric    float64
+	RequestID RequestID `json:"request_id"`
+	Metric    float64   `json:"metric"`
 	// fields below used by asha.go.
-	promoted bool
+	Promoted   bool      `json:"promoted"`
+	Objectives []float64 `json:"objectives,omitempty"`
+}
+
+// value returns the result the trial reported, as ranked by a MetricComparator.
+func (t trialMetric) value() TrialMetricValue {
+	return TrialMetricValue{Metric: t.Metric, Objectives: t.Objectives}
 }
 
 // rung describes a set of trials that are to be trained for the same number of units.
 type rung struct {
-	unitsNeeded   model.Length
-	metrics       []trialMetric
-	startTrials   int
-	promoteTrials int
+	UnitsNeeded   model.Length  `json:"units_needed"`
+	Metrics       []trialMetric `json:"metrics"`
+	StartTrials   int           `json:"start_trials"`
+	PromoteTrials int           `json:"promote_trials"`
 	// field below used by asha.go.
-	outstandingTrials int
+	OutstandingTrials int `json:"outstanding_trials"`
 }
 
 // promotions handles bookkeeping of validation metrics and returns a RequestID to promote if
-// appropriate.
-func (r *rung) promotionsSync(requestID RequestID, metric float64) []RequestID {
+// appropriate. If barrier is set, nothing is promoted until every trial in the rung has reported,
+// at which point all of the best trials are promoted at once.
+func (r *rung) promotionsSync(requestID RequestID, metric float64, barrier bool) []RequestID {
 	// Insert the new trial result in the appropriate place in the sorted list.
 	insertIndex := sort.Search(
-		len(r.metrics),
-		func(i int) bool { return r.metrics[i].metric > metric },
+		len(r.Metrics),
+		func(i int) bool { return r.Metrics[i].Metric > metric },
 	)
-	r.metrics = append(r.metrics, trialMetric{})
-	copy(r.metrics[insertIndex+1:], r.metrics[insertIndex:])
-	r.metrics[insertIndex] = trialMetric{
-		requestID: requestID,
-		metric:    metric,
+	r.Metrics = append(r.Metrics, trialMetric{})
+	copy(r.Metrics[insertIndex+1:], r.Metrics[insertIndex:])
+	r.Metrics[insertIndex] = trialMetric{
+		RequestID: requestID,
+		Metric:    metric,
+	}
+
+	if barrier {
+		if len(r.Metrics) < r.StartTrials {
+			return nil
+		}
+		promotions := make([]RequestID, 0, r.PromoteTrials)
+		for _, t := range r.Metrics[:r.PromoteTrials] {
+			promotions = append(promotions, t.RequestID)
+		}
+		return promotions
 	}
 
 	// If there are enough trials done to definitively promote one, do so. Otherwise, return nil.
-	currPromote := len(r.metrics) + r.promoteTrials - r.startTrials
+	currPromote := len(r.Metrics) + r.PromoteTrials - r.StartTrials
 	switch {
 	case currPromote <= 0: // Not enough trials completed for any promotions.
 		return nil
 	case insertIndex < currPromote: // Incoming trial should be promoted.
 		return []RequestID{requestID}
 	default: // Promote next trial in sorted metrics array.
-		t := &r.metrics[currPromote-1]
-		return []RequestID{t.requestID}
+		t := &r.Metrics[currPromote-1]
+		return []RequestID{t.RequestID}
 	}
 }
 
 func (s *syncHalvingSearch) initialOperations(ctx context) ([]Operation, error) {
 	var ops []Operation
-	for trial := 0; trial < s.rungs[0].startTrials; trial++ {
-		create := NewCreate(
-			ctx.rand, sampleAll(ctx.hparams, ctx.rand), model.TrialWorkloadSequencerType)
+	for trial := 0; trial < s.rungs[0].StartTrials; trial++ {
+		hparams, err := sampleAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		create := NewCreate(ctx.rand, hparams, model.TrialWorkloadSequencerType)
 		ops = append(ops, create)
-		ops = append(ops, NewTrain(create.RequestID, s.rungs[0].unitsNeeded))
+		ops = append(ops, NewTrain(create.RequestID, s.rungs[0].UnitsNeeded))
 		ops = append(ops, NewValidate(create.RequestID))
 	}
 	return ops, nil
@@ -167,11 +191,11 @@ func (s *syncHalvingSearch) promoteSync(
 	var ops []Operation
 	// Since this is not the top rung, handle promotions if there are any, then close the rung if
 	// all trials have finished.
-	if toPromote := rung.promotionsSync(requestID, metric); len(toPromote) > 0 {
+	if toPromote := rung.promotionsSync(requestID, metric, s.RungBarrier); len(toPromote) > 0 {
 		for _, promotionID := range toPromote {
 			s.trialRungs[promotionID] = rungIndex + 1
 			if !s.earlyExitTrials[promotionID] {
-				unitsNeeded := max(s.rungs[rungIndex+1].unitsNeeded.Units-rung.unitsNeeded.Units, 1)
+				unitsNeeded := max(s.rungs[rungIndex+1].UnitsNeeded.Units-rung.UnitsNeeded.Units, 1)
 				ops = append(ops, NewTrain(promotionID, model.NewLength(s.Unit(), unitsNeeded)))
 				ops = append(ops, NewValidate(promotionID))
 			} else {
@@ -179,19 +203,24 @@ func (s *syncHalvingSearch) promoteSync(
 				// we should treat it as immediately completing the next rung with the worst possible result.
 				// The recursive call is safe because the rung being considered goes up by one each time and
 				// there are a finite number of rungs.
-				return s.promoteSync(ctx, promotionID, shaExitedMetricValue)
+				promoteOps, err := s.promoteSync(ctx, promotionID, shaExitedMetricValue)
+				if err != nil {
+					return nil, err
+				}
+				ops = append(ops, promoteOps...)
 			}
 		}
 		// Close the unpromoted trials in the rung once all trials in the rung finish.
-		if rung.startTrials < len(rung.metrics) {
-			return nil, errors.Errorf("number of trials exceeded initial trials for rung: %d < %d",
-				rung.startTrials, len(rung.metrics))
+		if rung.StartTrials < len(rung.Metrics) {
+			return nil, errors.WithStack(InternalError{Err: errors.Errorf(
+				"number of trials exceeded initial trials for rung: %d < %d",
+				rung.StartTrials, len(rung.Metrics))})
 		}
-		if len(rung.metrics) == rung.startTrials {
-			for _, trialMetric := range rung.metrics[rung.promoteTrials:] {
+		if len(rung.Metrics) == rung.StartTrials {
+			for _, trialMetric := range rung.Metrics[rung.PromoteTrials:] {
 				s.trialsCompleted++
-				if !s.earlyExitTrials[trialMetric.requestID] {
-					ops = append(ops, NewClose(trialMetric.requestID))
+				if !s.earlyExitTrials[trialMetric.RequestID] {
+					ops = append(ops, NewClose(trialMetric.RequestID))
 				}
 			}
 		}
@@ -204,7 +233,7 @@ func (s *syncHalvingSearch) progress(unitsCompleted model.Length) float64 {
 }
 
 func (s *syncHalvingSearch) trialExitedEarly(
-	ctx context, requestID RequestID,
+	ctx context, requestID RequestID, _ ExitedReason,
 ) ([]Operation, error) {
 	s.earlyExitTrials[requestID] = true
 	return s.promoteSync(ctx, requestID, shaExitedMetricValue)
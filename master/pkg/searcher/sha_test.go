@@ -3,6 +3,8 @@ package searcher
 import (
 	"testing"
 
+	"gotest.tools/assert"
+
 	"github.com/determined-ai/determined/master/pkg/model"
 )
 
@@ -160,3 +162,52 @@ func TestSHASearchMethod(t *testing.T) {
 
 	runValueSimulationTestCases(t, testCases)
 }
+
+func TestSHASearcherRungBarrier(t *testing.T) {
+	config := model.SyncHalvingConfig{
+		Metric:    defaultMetric,
+		NumRungs:  3,
+		MaxLength: model.NewLengthInBatches(900),
+		Budget:    model.NewLengthInBatches(5400),
+		Divisor:   3,
+	}
+	metricFn := func(trialIndex, _ int) float64 { return float64((trialIndex * 7) % 11) }
+
+	// runSearch runs the search to completion, returning the train lengths of each trial and
+	// whether any trial was promoted out of a rung before all of its trials had reported.
+	runSearch := func(barrier bool) (map[int][]model.Length, bool) {
+		config.RungBarrier = barrier
+		method := newSyncHalvingSearch(config).(*syncHalvingSearch)
+		driver, err := newQueueDriver(method, nil, metricFn)
+		assert.NilError(t, err)
+
+		trains := make(map[int][]model.Length)
+		promotedEarly := false
+		for len(driver.pending) > 0 {
+			ops, stepErr := driver.step()
+			assert.NilError(t, stepErr)
+			for _, op := range ops {
+				train, ok := op.(Train)
+				if !ok {
+					continue
+				}
+				trialIndex := driver.trialIndex[train.RequestID]
+				trains[trialIndex] = append(trains[trialIndex], train.Length)
+				if rungIndex := method.trialRungs[train.RequestID]; rungIndex > 0 {
+					prev := method.rungs[rungIndex-1]
+					promotedEarly = promotedEarly || len(prev.Metrics) < prev.StartTrials
+				}
+			}
+		}
+		return trains, promotedEarly
+	}
+
+	withBarrier, promotedEarly := runSearch(true)
+	assert.Assert(t, !promotedEarly, "a trial was promoted before its rung finished")
+
+	// Without the barrier, promotions happen as soon as they are certain, but the promotion
+	// decisions themselves do not change.
+	withoutBarrier, promotedEarly := runSearch(false)
+	assert.Assert(t, promotedEarly)
+	assert.DeepEqual(t, withBarrier, withoutBarrier)
+}
@@ -184,6 +184,73 @@ func Simulate(
 	return simulation, nil
 }
 
+// SimulatedRung describes the trials of a simulation that trained for a given number of train
+// operations, which for methods based on successive halving are the trials that reached a rung.
+type SimulatedRung struct {
+	// Length is the total length each trial had trained for by the end of the rung.
+	Length model.Length `json:"length"`
+	// Trials are the trials in the rung, ordered by request ID; every trial in a rung is also in
+	// each of the rungs before it.
+	Trials []RequestID `json:"trials"`
+}
+
+// SimulationSummary summarizes the workloads a search would run.
+type SimulationSummary struct {
+	Simulation Simulation      `json:"simulation"`
+	Rungs      []SimulatedRung `json:"rungs"`
+	// TotalLength is the sum of the lengths trained by all trials.
+	TotalLength model.Length `json:"total_length"`
+}
+
+// SimulateConfig previews the search an experiment configuration describes by driving its search
+// method through a Searcher, as an experiment would, with validation metrics computed by valFunc
+// instead of by real workloads.
+func SimulateConfig(
+	config model.ExperimentConfig, seed int64, valFunc ValidationFunction,
+) (SimulationSummary, error) {
+	method, err := NewSearchMethod(config.Searcher)
+	if err != nil {
+		return SimulationSummary{}, err
+	}
+	s := NewSearcher(uint32(seed), method, config.Hyperparameters, config.Searcher.Constraints)
+	simulation, err := Simulate(s, &seed, valFunc, true, config.Searcher.Metric)
+	if err != nil {
+		return SimulationSummary{}, err
+	}
+
+	summary := SimulationSummary{
+		Simulation:  simulation,
+		TotalLength: model.NewLength(method.Unit(), 0),
+	}
+	for requestID, ops := range simulation.Results {
+		length := model.NewLength(method.Unit(), 0)
+		rungIndex := 0
+		for _, op := range ops {
+			train, ok := op.(Train)
+			if !ok {
+				continue
+			}
+			length = length.Add(train.Length)
+			summary.TotalLength = summary.TotalLength.Add(train.Length)
+			if rungIndex == len(summary.Rungs) {
+				summary.Rungs = append(summary.Rungs, SimulatedRung{Length: length})
+			}
+			rung := &summary.Rungs[rungIndex]
+			if length.Units > rung.Length.Units {
+				rung.Length = length
+			}
+			rung.Trials = append(rung.Trials, requestID)
+			rungIndex++
+		}
+	}
+	for _, rung := range summary.Rungs {
+		sort.Slice(rung.Trials, func(i, j int) bool {
+			return rung.Trials[i].Before(rung.Trials[j])
+		})
+	}
+	return summary, nil
+}
+
 func handleOperations(
 	pending map[RequestID][]Operation, requestIDs *[]RequestID, operations []Operation,
 ) (bool, error) {
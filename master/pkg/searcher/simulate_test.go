@@ -0,0 +1,57 @@
+package searcher
+
+import (
+	"math/rand"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func TestSimulateConfigASHA(t *testing.T) {
+	config := model.DefaultExperimentConfig()
+	config.Searcher = model.SearcherConfig{
+		Metric:          defaultMetric,
+		SmallerIsBetter: true,
+		AsyncHalvingConfig: &model.AsyncHalvingConfig{
+			Metric:              defaultMetric,
+			SmallerIsBetter:     true,
+			NumRungs:            2,
+			MaxLength:           model.NewLengthInBatches(200),
+			Divisor:             2,
+			MaxTrials:           4,
+			MaxConcurrentTrials: 1,
+		},
+	}
+	// Trials that are created earlier are better.
+	valFunc := func(_ *rand.Rand, trialID, _ int) float64 { return float64(trialID) }
+
+	summary, err := SimulateConfig(config, 0, valFunc)
+	assert.NilError(t, err)
+
+	// All 4 trials train for 100 batches in the bottom rung, and half of them are promoted to train
+	// for another 100 batches in the top rung: 4 * 100 + 2 * 100 = 600 batches.
+	assert.Equal(t, summary.TotalLength, model.NewLengthInBatches(600))
+	assert.Equal(t, len(summary.Rungs), 2)
+	assert.Equal(t, summary.Rungs[0].Length, model.NewLengthInBatches(100))
+	assert.Equal(t, len(summary.Rungs[0].Trials), 4)
+	assert.Equal(t, summary.Rungs[1].Length, model.NewLengthInBatches(200))
+	assert.Equal(t, len(summary.Rungs[1].Trials), 2)
+
+	bottom := make(map[RequestID]bool)
+	for _, requestID := range summary.Rungs[0].Trials {
+		bottom[requestID] = true
+	}
+	for _, requestID := range summary.Rungs[1].Trials {
+		assert.Assert(t, bottom[requestID])
+	}
+	assert.Equal(t, len(summary.Simulation.Results), 4)
+}
+
+func TestSimulateConfigUnknownSearcher(t *testing.T) {
+	config := model.DefaultExperimentConfig()
+	config.Searcher = model.SearcherConfig{Metric: defaultMetric}
+	_, err := SimulateConfig(config, 0, ConstantValidation)
+	assert.ErrorContains(t, err, "no searcher type specified")
+}
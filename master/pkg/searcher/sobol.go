@@ -0,0 +1,193 @@
+package searcher
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// batchSampler samples the hyperparameters of several trials jointly, e.g., so that together they
+// cover the hyperparameter space more evenly than independent samples would.
+type batchSampler interface {
+	sampleBatch(ctx context, count int) ([]hparamSample, error)
+}
+
+// newBatchSampler returns the sampler for the initial design, or nil if the initial trials should
+// be sampled independently.
+func newBatchSampler(design model.InitialDesign) batchSampler {
+	switch design {
+	case model.SobolDesign:
+		return sobolSampler{}
+	default:
+		return nil
+	}
+}
+
+// sobolSampler samples points of a Sobol sequence, randomized by a digital shift drawn from the
+// random state of the search, and maps each coordinate to a hyperparameter through the quantile
+// function of the distribution that sampleOne draws it from. Hyperparameters beyond the dimensions
+// for which direction numbers are known are sampled independently, as are points that do not
+// satisfy the constraints.
+type sobolSampler struct{}
+
+func (sobolSampler) sampleBatch(ctx context, count int) ([]hparamSample, error) {
+	var names []string
+	ctx.hparams.Each(func(name string, _ model.Hyperparameter) {
+		names = append(names, name)
+	})
+	dimensions := names
+	if len(dimensions) > len(sobolDirections)+1 {
+		dimensions = dimensions[:len(sobolDirections)+1]
+	}
+	points := sobolPoints(len(dimensions), count)
+	shifts := make([]uint32, len(dimensions))
+	for i := range shifts {
+		shifts[i] = ctx.rand.Bits32()
+	}
+
+	samples := make([]hparamSample, 0, count)
+	for _, point := range points {
+		sample := make(hparamSample, len(names))
+		for i, name := range names {
+			if i < len(dimensions) {
+				u := float64(point[i]^shifts[i]) / (1 << 32)
+				sample[name] = quantileOne(ctx.hparams[name], u)
+			} else {
+				sample[name] = sampleOne(ctx.hparams[name], ctx.rand)
+			}
+		}
+		sample = pruneInactive(ctx.hparams, sample)
+		ok, err := satisfiesConstraints(ctx.constraints, sample)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			if sample, err = sampleAll(ctx); err != nil {
+				return nil, err
+			}
+		}
+		samples = append(samples, sample)
+	}
+	return samples, nil
+}
+
+// quantileOne returns the value of the hyperparameter below which a sample from sampleOne falls
+// with probability u, for u in [0, 1).
+func quantileOne(h model.Hyperparameter, u float64) interface{} {
+	switch {
+	case h.ConstHyperparameter != nil:
+		return h.ConstHyperparameter.Val
+	case h.IntHyperparameter != nil:
+		p := h.IntHyperparameter
+		size := intLatticeSize(*p)
+		return p.Minval + p.StepSize()*intClamp(int(u*float64(size)), 0, max(size-1, 0))
+	case h.DoubleHyperparameter != nil:
+		p := h.DoubleHyperparameter
+		return p.Minval + u*(p.Maxval-p.Minval)
+	case h.LogHyperparameter != nil:
+		p := h.LogHyperparameter
+		return math.Pow(p.Base, p.Minval+u*(p.Maxval-p.Minval))
+	case h.CategoricalHyperparameter != nil:
+		p := h.CategoricalHyperparameter
+		weights := p.Weights
+		if weights == nil {
+			weights = make([]float64, len(p.Vals))
+			for i := range weights {
+				weights[i] = 1
+			}
+		}
+		total := 0.0
+		for _, weight := range weights {
+			total += weight
+		}
+		target := u * total
+		for i, weight := range weights {
+			if target < weight {
+				return p.Vals[i]
+			}
+			target -= weight
+		}
+		return p.Vals[len(p.Vals)-1]
+	default:
+		panic(fmt.Sprintf("unexpected hyperparameter type: %+v", h))
+	}
+}
+
+// sobolDirection is the primitive polynomial of degree Degree with the coefficients Coefficients
+// and the initial direction numbers Initial of one dimension of a Sobol sequence.
+type sobolDirection struct {
+	Degree       uint
+	Coefficients uint32
+	Initial      []uint32
+}
+
+// sobolDirections holds the direction numbers of the dimensions after the first from
+// new-joe-kuo-6.21201, by S. Joe and F. Y. Kuo; see https://web.maths.unsw.edu.au/~fkuo/sobol/.
+var sobolDirections = []sobolDirection{
+	{1, 0, []uint32{1}},
+	{2, 1, []uint32{1, 3}},
+	{3, 1, []uint32{1, 3, 1}},
+	{3, 2, []uint32{1, 1, 1}},
+	{4, 1, []uint32{1, 1, 3, 3}},
+	{4, 4, []uint32{1, 3, 5, 13}},
+	{5, 2, []uint32{1, 1, 5, 5, 17}},
+	{5, 4, []uint32{1, 1, 5, 5, 5}},
+	{5, 7, []uint32{1, 1, 7, 11, 19}},
+	{5, 11, []uint32{1, 1, 5, 1, 1}},
+	{5, 13, []uint32{1, 1, 1, 3, 11}},
+	{5, 14, []uint32{1, 3, 5, 5, 31}},
+	{6, 1, []uint32{1, 3, 3, 9, 7, 49}},
+	{6, 13, []uint32{1, 1, 1, 15, 21, 21}},
+	{6, 16, []uint32{1, 3, 1, 13, 27, 49}},
+}
+
+// sobolBits is the number of bits of each coordinate of a Sobol point.
+const sobolBits = 32
+
+// sobolPoints returns the first count points of the Sobol sequence in the given number of
+// dimensions, each coordinate as a fraction of 2**32, generated in Gray code order.
+func sobolPoints(dimensions, count int) [][]uint32 {
+	directions := make([][sobolBits]uint32, dimensions)
+	for d := range directions {
+		v := &directions[d]
+		if d == 0 {
+			for k := 0; k < sobolBits; k++ {
+				v[k] = 1 << (sobolBits - 1 - k)
+			}
+			continue
+		}
+		direction := sobolDirections[d-1]
+		degree := int(direction.Degree)
+		for k := 0; k < sobolBits; k++ {
+			if k < degree {
+				v[k] = direction.Initial[k] << (sobolBits - 1 - k)
+				continue
+			}
+			v[k] = v[k-degree] ^ (v[k-degree] >> direction.Degree)
+			for j := 1; j < degree; j++ {
+				if (direction.Coefficients>>(degree-1-j))&1 == 1 {
+					v[k] ^= v[k-j]
+				}
+			}
+		}
+	}
+
+	points := make([][]uint32, 0, count)
+	current := make([]uint32, dimensions)
+	for i := 0; i < count; i++ {
+		points = append(points, append([]uint32{}, current...))
+		// The next point differs from this one by the direction numbers of the lowest zero bit of i.
+		bit := 0
+		for (i>>bit)&1 == 1 {
+			bit++
+		}
+		if bit >= sobolBits {
+			break
+		}
+		for d := range current {
+			current[d] ^= directions[d][bit]
+		}
+	}
+	return points
+}
@@ -0,0 +1,82 @@
+package searcher
+
+import (
+	"math"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/nprand"
+)
+
+func TestSobolPoints(t *testing.T) {
+	points := sobolPoints(2, 8)
+	var xs, ys []float64
+	for _, point := range points {
+		xs = append(xs, float64(point[0])/(1<<32))
+		ys = append(ys, float64(point[1])/(1<<32))
+	}
+	assert.DeepEqual(t, xs, []float64{0, 0.5, 0.75, 0.25, 0.375, 0.875, 0.625, 0.125})
+	assert.DeepEqual(t, ys, []float64{0, 0.5, 0.25, 0.75, 0.375, 0.875, 0.125, 0.625})
+
+	// The first 2**m points of every dimension fall one in each of 2**m equal intervals, and those
+	// of every pair of dimensions in as many of the cells of a 2**(m/2) by 2**(m/2) grid.
+	const m = 8
+	points = sobolPoints(len(sobolDirections)+1, 1<<m)
+	for d := 0; d <= len(sobolDirections); d++ {
+		seen := make(map[uint32]bool)
+		for _, point := range points {
+			seen[point[d]>>(32-m)] = true
+		}
+		assert.Equal(t, len(seen), 1<<m, "dimension %d", d)
+	}
+	for d := 0; d < 3; d++ {
+		for e := d + 1; e < 3; e++ {
+			cells := make(map[[2]uint32]int)
+			for _, point := range points {
+				cells[[2]uint32{point[d] >> (32 - m/2), point[e] >> (32 - m/2)}]++
+			}
+			assert.Equal(t, len(cells), 1<<m, "dimensions %d and %d", d, e)
+		}
+	}
+}
+
+func TestSobolSamplerUniformity(t *testing.T) {
+	hparams := model.Hyperparameters{
+		"x": {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: -1, Maxval: 1}},
+		"y": {LogHyperparameter: &model.LogHyperparameter{Base: 10, Minval: -4, Maxval: 0}},
+		"n": {IntHyperparameter: &model.IntHyperparameter{Minval: 0, Maxval: 8}},
+	}
+	// emptyCells returns how many cells of an 8 by 8 grid over x and log10(y) contain no sample.
+	emptyCells := func(samples []hparamSample) int {
+		cells := make(map[[2]int]bool)
+		for _, sample := range samples {
+			x := sample["x"].(float64)
+			y := sample["y"].(float64)
+			assert.Assert(t, x >= -1 && x < 1 && y >= 1e-4 && y < 1, "sample %v", sample)
+			cells[[2]int{int((x + 1) * 4), int((math.Log10(y) + 4) * 2)}] = true
+		}
+		return 64 - len(cells)
+	}
+
+	ctx := context{rand: nprand.New(0), hparams: hparams}
+	sobol, err := sobolSampler{}.sampleBatch(ctx, 64)
+	assert.NilError(t, err)
+	assert.Equal(t, len(sobol), 64)
+	var random []hparamSample
+	for i := 0; i < 64; i++ {
+		sample, sampleErr := sampleAll(ctx)
+		assert.NilError(t, sampleErr)
+		random = append(random, sample)
+	}
+
+	// Independent samples leave about 64/e of the cells empty; the design leaves none.
+	assert.Equal(t, emptyCells(sobol), 0)
+	assert.Assert(t, emptyCells(random) > 10, "empty cells: %d", emptyCells(random))
+	counts := make(map[int]int)
+	for _, sample := range sobol {
+		counts[sample["n"].(int)]++
+	}
+	assert.DeepEqual(t, counts, map[int]int{0: 8, 1: 8, 2: 8, 3: 8, 4: 8, 5: 8, 6: 8, 7: 8})
+}
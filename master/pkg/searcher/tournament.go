@@ -1,6 +1,10 @@
 package searcher
 
 import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
 	"github.com/determined-ai/determined/master/pkg/model"
 )
 
@@ -75,9 +79,11 @@ func (s *tournamentSearch) trialClosed(ctx context, requestID RequestID) ([]Oper
 	return s.markCreates(subSearch, ops), err
 }
 
-func (s *tournamentSearch) trialExitedEarly(ctx context, requestID RequestID) ([]Operation, error) {
+func (s *tournamentSearch) trialExitedEarly(
+	ctx context, requestID RequestID, reason ExitedReason,
+) ([]Operation, error) {
 	subSearch := s.trialTable[requestID]
-	ops, err := subSearch.trialExitedEarly(ctx, requestID)
+	ops, err := subSearch.trialExitedEarly(ctx, requestID, reason)
 	return s.markCreates(subSearch, ops), err
 }
 
@@ -90,6 +96,65 @@ func (s *tournamentSearch) progress(model.Length) float64 {
 	return sum / float64(len(s.subSearches))
 }
 
+// tournamentSearchState is the serialized form of a tournamentSearch. Sub-searches are referred to
+// by their index in subSearches.
+type tournamentSearchState struct {
+	SubSearchUnitsCompleted []model.Length    `json:"sub_search_units_completed"`
+	TrialTable              map[RequestID]int `json:"trial_table"`
+	SubSearchStates         []json.RawMessage `json:"sub_search_states"`
+}
+
+// Snapshot implements the SearchMethod interface.
+func (s *tournamentSearch) Snapshot() ([]byte, error) {
+	state := tournamentSearchState{
+		SubSearchUnitsCompleted: make([]model.Length, 0, len(s.subSearches)),
+		TrialTable:              make(map[RequestID]int),
+		SubSearchStates:         make([]json.RawMessage, 0, len(s.subSearches)),
+	}
+	subSearchIndices := make(map[SearchMethod]int)
+	for i, subSearch := range s.subSearches {
+		subSearchIndices[subSearch] = i
+		state.SubSearchUnitsCompleted = append(
+			state.SubSearchUnitsCompleted, s.subSearchUnitsCompleted[subSearch])
+		subSearchState, err := subSearch.Snapshot()
+		if err != nil {
+			return nil, err
+		}
+		state.SubSearchStates = append(state.SubSearchStates, subSearchState)
+	}
+	for requestID, subSearch := range s.trialTable {
+		state.TrialTable[requestID] = subSearchIndices[subSearch]
+	}
+	return json.Marshal(state)
+}
+
+// Restore implements the SearchMethod interface.
+func (s *tournamentSearch) Restore(state []byte) error {
+	var restored tournamentSearchState
+	if err := json.Unmarshal(state, &restored); err != nil {
+		return errors.Wrap(err, "failed to restore tournament search state")
+	}
+	if len(restored.SubSearchStates) != len(s.subSearches) ||
+		len(restored.SubSearchUnitsCompleted) != len(s.subSearches) {
+		return errors.Errorf("cannot restore %d sub-searches into a tournament of %d",
+			len(restored.SubSearchStates), len(s.subSearches))
+	}
+	for i, subSearch := range s.subSearches {
+		if err := subSearch.Restore(restored.SubSearchStates[i]); err != nil {
+			return err
+		}
+		s.subSearchUnitsCompleted[subSearch] = restored.SubSearchUnitsCompleted[i]
+	}
+	s.trialTable = make(map[RequestID]SearchMethod)
+	for requestID, i := range restored.TrialTable {
+		if i < 0 || i >= len(s.subSearches) {
+			return errors.Errorf("invalid sub-search index %d for trial %s", i, requestID)
+		}
+		s.trialTable[requestID] = s.subSearches[i]
+	}
+	return nil
+}
+
 func (s *tournamentSearch) Unit() model.Unit {
 	return s.subSearches[0].Unit()
 }
@@ -63,7 +63,8 @@ func TestTournamentSearchMethod(t *testing.T) {
 			Divisor:         4,
 		},
 	}
-	adaptiveMethod1 := NewSearchMethod(adaptiveConfig1)
+	adaptiveMethod1, err := NewSearchMethod(adaptiveConfig1)
+	assert.NilError(t, err)
 
 	adaptiveConfig2 := model.SearcherConfig{
 		AdaptiveConfig: &model.AdaptiveConfig{
@@ -76,12 +77,13 @@ func TestTournamentSearchMethod(t *testing.T) {
 			Divisor:         4,
 		},
 	}
-	adaptiveMethod2 := NewSearchMethod(adaptiveConfig2)
+	adaptiveMethod2, err := NewSearchMethod(adaptiveConfig2)
+	assert.NilError(t, err)
 
 	params := model.Hyperparameters{}
 
 	method := newTournamentSearch(adaptiveMethod1, adaptiveMethod2)
 
-	err := checkValueSimulation(t, method, params, expectedTrials)
+	err = checkValueSimulation(t, method, params, expectedTrials)
 	assert.NilError(t, err)
 }
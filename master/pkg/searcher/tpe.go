@@ -0,0 +1,428 @@
+package searcher
+
+import (
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/nprand"
+)
+
+// tpeSearch implements the tree-structured Parzen estimator (TPE) search method. See
+// https://papers.nips.cc/paper/4443-algorithms-for-hyper-parameter-optimization.pdf for details.
+// The first NumStartupTrials trials are sampled randomly. After that, the completed trials are
+// split into the best Gamma fraction and the rest, a density over each hyperparameter is fit to
+// each group, and each new trial uses the candidate, out of NumCandidates drawn from the density of
+// the best trials, that maximizes the ratio of the density of the best trials to that of the rest.
+type tpeSearch struct {
+	*modelBasedSearch
+	model.TPEConfig
+}
+
+func newTPESearch(config model.TPEConfig) SearchMethod {
+	s := &tpeSearch{
+		modelBasedSearch: newModelBasedSearch(modelBasedConfig{
+			Name:                "TPE",
+			Metric:              config.Metric,
+			SmallerIsBetter:     config.SmallerIsBetter,
+			MaxLength:           config.MaxLength,
+			MaxTrials:           config.MaxTrials,
+			MaxConcurrentTrials: config.MaxConcurrentTrials,
+			PriorWeight:         config.PriorWeight,
+		}),
+		TPEConfig: config,
+	}
+	s.propose = s.proposeTrial
+	return s
+}
+
+// proposeTrial returns the hyperparameters of the next trial: a random sample until enough trials
+// have been observed, and the best of NumCandidates candidates afterwards, unless the trial is a
+// random restart. It also returns whether the trial is a restart.
+func (s *tpeSearch) proposeTrial(ctx context) (hparamSample, bool, error) {
+	if observedWeight(s.Observations) < float64(s.NumStartupTrials) {
+		hparams, err := sampleAll(ctx)
+		return hparams, false, err
+	}
+	if restart(ctx, s.RestartProbability) {
+		hparams, err := sampleAll(ctx)
+		return hparams, true, err
+	}
+	hparams, err := proposeTPE(ctx, parzenEstimator, s.Observations, s.Gamma, s.NumCandidates)
+	return hparams, false, err
+}
+
+// restart returns, with the given probability, that a model-based search should sample the next
+// trial randomly instead of proposing it from its model. No random number is drawn when the
+// probability is zero, so that searches without restarts propose the same trials as before.
+func restart(ctx context, probability float64) bool {
+	return probability > 0 && ctx.rand.UnitInterval() < probability
+}
+
+// proposeTPE splits the observations into the best gamma fraction and the rest, and returns the
+// candidate, out of numCandidates drawn from the densities of the best observations, that
+// maximizes the ratio of those densities to the densities of the rest.
+func proposeTPE(
+	ctx context, estimator densityEstimator, observations []modelObservation, gamma float64,
+	numCandidates int,
+) (hparamSample, error) {
+	good, bad := splitTPEObservations(observations, gamma)
+	goodDensities := fitTPEDensities(ctx.hparams, estimator, good)
+	badDensities := fitTPEDensities(ctx.hparams, estimator, bad)
+	var best hparamSample
+	bestScore := math.Inf(-1)
+	for candidate := 0; candidate < numCandidates; candidate++ {
+		params, err := sampleTPECandidate(ctx, goodDensities)
+		if err != nil {
+			return nil, err
+		}
+		// Compare the log densities of only the parameters that are active in the candidate.
+		score := 0.0
+		for name, value := range params {
+			if density, ok := goodDensities[name]; ok {
+				score += density.logDensity(value) - badDensities[name].logDensity(value)
+			}
+		}
+		if best == nil || score > bestScore {
+			best, bestScore = params, score
+		}
+	}
+	return best, nil
+}
+
+// splitTPEObservations returns the best observations that make up the gamma fraction of the total
+// weight of the observations, counting each observation that starts within it, and the rest. Ties
+// are broken in favor of the earlier observation.
+func splitTPEObservations(
+	observations []modelObservation, gamma float64,
+) (good, bad []modelObservation) {
+	observations = append([]modelObservation{}, observations...)
+	sort.SliceStable(observations, func(i, j int) bool {
+		return observations[i].Metric < observations[j].Metric
+	})
+	goodWeight := gamma * observedWeight(observations)
+	weight := 0.0
+	for _, observation := range observations {
+		if weight < goodWeight {
+			good = append(good, observation)
+		} else {
+			bad = append(bad, observation)
+		}
+		weight += observation.weight()
+	}
+	return good, bad
+}
+
+// sampleTPECandidate samples every hyperparameter from its density, redrawing the whole sample
+// until it satisfies all of the constraints.
+func sampleTPECandidate(ctx context, densities map[string]tpeDensity) (hparamSample, error) {
+	for attempt := 0; attempt < maxSampleAttempts; attempt++ {
+		sample := make(hparamSample)
+		ctx.hparams.Each(func(name string, param model.Hyperparameter) {
+			if density, ok := densities[name]; ok {
+				sample[name] = density.sample(ctx.rand)
+			} else {
+				sample[name] = sampleOne(param, ctx.rand)
+			}
+		})
+		sample = pruneInactive(ctx.hparams, sample)
+		switch ok, err := satisfiesConstraints(ctx.constraints, sample); {
+		case err != nil:
+			return nil, err
+		case ok:
+			return sample, nil
+		}
+	}
+	return nil, errors.Errorf(
+		"failed to sample hyperparameters satisfying the constraints after %d attempts",
+		maxSampleAttempts)
+}
+
+// tpeDensity is a density over the values of a single hyperparameter.
+type tpeDensity interface {
+	sample(rand *nprand.State) interface{}
+	logDensity(value interface{}) float64
+}
+
+// densityEstimator selects how the density of a numeric hyperparameter is fit to its values.
+type densityEstimator int
+
+const (
+	// parzenEstimator is the estimator of TPE: a uniform prior over the range of the
+	// hyperparameter plus a Gaussian at each value, each as wide as the larger of the distances to
+	// the neighboring values. The prior keeps the search exploring where no trial has been.
+	parzenEstimator densityEstimator = iota
+	// kernelDensityEstimator is the estimator of BOHB: a Gaussian at each value, all as wide as
+	// Scott's rule gives for the values, with no prior.
+	kernelDensityEstimator
+)
+
+// fitTPEDensities fits a density to the values each hyperparameter took in the given
+// observations, each contributing by its weight. Observations in which a conditional
+// hyperparameter was inactive do not contribute to its density. Constant hyperparameters have no
+// density.
+func fitTPEDensities(
+	h model.Hyperparameters, estimator densityEstimator, observations []modelObservation,
+) map[string]tpeDensity {
+	densities := make(map[string]tpeDensity)
+	h.Each(func(name string, param model.Hyperparameter) {
+		var values []interface{}
+		// weights stays nil while every value counts in full, so that searches without priors sample
+		// as before.
+		var weights []float64
+		for _, observation := range observations {
+			value, ok := observation.Hparams[name]
+			if !ok {
+				continue
+			}
+			if observation.Weight != 0 && weights == nil {
+				weights = make([]float64, len(values), len(observations))
+				for i := range weights {
+					weights[i] = 1
+				}
+			}
+			values = append(values, value)
+			if weights != nil {
+				weights = append(weights, observation.weight())
+			}
+		}
+		switch {
+		case param.IntHyperparameter != nil:
+			p := param.IntHyperparameter
+			// Each integer covers the interval up to the next one, matching how integers are sampled.
+			step, size := float64(p.StepSize()), intLatticeSize(*p)
+			densities[name] = newParzenDensity(
+				estimator, float64(p.Minval), float64(p.Minval)+step*float64(size), values, weights,
+				func(value interface{}) float64 { return hparamFloat(value) + step/2 },
+				func(x float64) interface{} {
+					k := intClamp(int(math.Floor((x-float64(p.Minval))/step)), 0, size-1)
+					return p.Minval + p.StepSize()*k
+				})
+		case param.DoubleHyperparameter != nil:
+			p := param.DoubleHyperparameter
+			densities[name] = newParzenDensity(estimator, p.Minval, p.Maxval, values, weights,
+				hparamFloat, func(x float64) interface{} { return x })
+		case param.LogHyperparameter != nil:
+			// The density is over the exponent, in which the parameter is sampled uniformly.
+			p := param.LogHyperparameter
+			densities[name] = newParzenDensity(estimator, p.Minval, p.Maxval, values, weights,
+				func(value interface{}) float64 { return math.Log(hparamFloat(value)) / math.Log(p.Base) },
+				func(x float64) interface{} { return math.Pow(p.Base, x) })
+		case param.CategoricalHyperparameter != nil:
+			densities[name] = newCategoricalDensity(*param.CategoricalHyperparameter, values, weights)
+		}
+	})
+	return densities
+}
+
+// hparamFloat returns a numeric hyperparameter value as a float, since restored samples hold
+// integers as floats.
+func hparamFloat(value interface{}) float64 {
+	if i, ok := value.(int); ok {
+		return float64(i)
+	}
+	return value.(float64)
+}
+
+// parzenDensity is a mixture of a Gaussian centered at each observed point, truncated to
+// [minval, maxval], and, if prior is set, a uniform prior over the same interval. The components
+// are as likely as the weights of their points, or all equally likely if weights is nil; the
+// uniform prior has a weight of one.
+type parzenDensity struct {
+	prior          bool
+	minval, maxval float64
+	points, widths []float64
+	weights        []float64
+	toValue        func(float64) interface{}
+	fromValue      func(interface{}) float64
+}
+
+func newParzenDensity(
+	estimator densityEstimator, minval, maxval float64, values []interface{}, weights []float64,
+	fromValue func(interface{}) float64, toValue func(float64) interface{},
+) *parzenDensity {
+	points := make([]float64, 0, len(values))
+	for _, value := range values {
+		points = append(points, fromValue(value))
+	}
+	if weights != nil {
+		weights = append([]float64{}, weights...)
+		sort.Sort(weightedPoints{points, weights})
+	} else {
+		sort.Float64s(points)
+	}
+
+	span := maxval - minval
+	widths := make([]float64, len(points))
+	// Values that were never observed in the samples only have the prior.
+	if estimator == kernelDensityEstimator && len(points) > 0 {
+		var mean, variance float64
+		for _, point := range points {
+			mean += point
+		}
+		mean /= float64(len(points))
+		for _, point := range points {
+			variance += (point - mean) * (point - mean)
+		}
+		std := math.Sqrt(variance / float64(len(points)))
+		width := math.Max(1.06*std*math.Pow(float64(len(points)), -0.2), 1e-3*span)
+		for i := range widths {
+			widths[i] = width
+		}
+		return &parzenDensity{
+			minval: minval, maxval: maxval, points: points, widths: widths, weights: weights,
+			toValue: toValue, fromValue: fromValue,
+		}
+	}
+
+	minWidth := span / math.Min(100, float64(len(points)+1))
+	for i, point := range points {
+		left, right := minval, maxval
+		if i > 0 {
+			left = points[i-1]
+		}
+		if i < len(points)-1 {
+			right = points[i+1]
+		}
+		widths[i] = doubleClamp(math.Max(point-left, right-point), minWidth, span)
+	}
+	return &parzenDensity{
+		prior: true, minval: minval, maxval: maxval, points: points, widths: widths,
+		weights: weights, toValue: toValue, fromValue: fromValue,
+	}
+}
+
+// weightedPoints sorts points along with their weights.
+type weightedPoints struct {
+	points, weights []float64
+}
+
+func (w weightedPoints) Len() int           { return len(w.points) }
+func (w weightedPoints) Less(i, j int) bool { return w.points[i] < w.points[j] }
+func (w weightedPoints) Swap(i, j int) {
+	w.points[i], w.points[j] = w.points[j], w.points[i]
+	w.weights[i], w.weights[j] = w.weights[j], w.weights[i]
+}
+
+// components returns the number of components of the mixture.
+func (d *parzenDensity) components() int {
+	if d.prior {
+		return len(d.points) + 1
+	}
+	return len(d.points)
+}
+
+// componentWeights returns the weight of each component of the mixture, the uniform prior last,
+// and their total.
+func (d *parzenDensity) componentWeights() ([]float64, float64) {
+	weights := make([]float64, 0, d.components())
+	total := 0.0
+	for i := range d.points {
+		weight := 1.0
+		if d.weights != nil {
+			weight = d.weights[i]
+		}
+		weights = append(weights, weight)
+		total += weight
+	}
+	if d.prior {
+		weights = append(weights, 1)
+		total++
+	}
+	return weights, total
+}
+
+func (d *parzenDensity) sample(rand *nprand.State) interface{} {
+	var component int
+	if d.weights == nil {
+		component = rand.Intn(d.components())
+	} else {
+		weights, _ := d.componentWeights()
+		component = weightedIndex(weights, rand)
+	}
+	if component == len(d.points) {
+		return d.toValue(rand.Uniform(d.minval, d.maxval))
+	}
+	// Invert the CDF of the truncated Gaussian.
+	mean, width := d.points[component], d.widths[component]
+	low, high := normalCDF((d.minval-mean)/width), normalCDF((d.maxval-mean)/width)
+	x := mean + width*math.Sqrt2*math.Erfinv(2*rand.Uniform(low, high)-1)
+	return d.toValue(doubleClamp(x, d.minval, d.maxval))
+}
+
+func (d *parzenDensity) logDensity(value interface{}) float64 {
+	x := d.fromValue(value)
+	weights, total := d.componentWeights()
+	density := 0.0
+	if d.prior {
+		density = 1 / (d.maxval - d.minval)
+	}
+	for i, mean := range d.points {
+		width := d.widths[i]
+		mass := normalCDF((d.maxval-mean)/width) - normalCDF((d.minval-mean)/width)
+		z := (x - mean) / width
+		density += weights[i] * math.Exp(-z*z/2) / (width * math.Sqrt(2*math.Pi) * mass)
+	}
+	return math.Log(density / total)
+}
+
+func normalCDF(z float64) float64 {
+	return (1 + math.Erf(z/math.Sqrt2)) / 2
+}
+
+// categoricalDensity gives each value a probability proportional to its prior weight, which
+// totals one across the values, plus the number of times it was observed, each time counting for
+// the weight of the observation.
+type categoricalDensity struct {
+	vals    []interface{}
+	weights []float64
+}
+
+func newCategoricalDensity(
+	p model.CategoricalHyperparameter, values []interface{}, observationWeights []float64,
+) *categoricalDensity {
+	weights := make([]float64, len(p.Vals))
+	total := 0.0
+	for i := range p.Vals {
+		weights[i] = 1
+		if p.Weights != nil {
+			weights[i] = p.Weights[i]
+		}
+		total += weights[i]
+	}
+	for i := range weights {
+		weights[i] /= total
+	}
+	for j, value := range values {
+		for i, val := range p.Vals {
+			if hparamValuesEqual(value, val) {
+				if observationWeights != nil {
+					weights[i] += observationWeights[j]
+				} else {
+					weights[i]++
+				}
+				break
+			}
+		}
+	}
+	return &categoricalDensity{vals: p.Vals, weights: weights}
+}
+
+func (d *categoricalDensity) sample(rand *nprand.State) interface{} {
+	return d.vals[weightedIndex(d.weights, rand)]
+}
+
+func (d *categoricalDensity) logDensity(value interface{}) float64 {
+	total := 0.0
+	for _, weight := range d.weights {
+		total += weight
+	}
+	for i, val := range d.vals {
+		if hparamValuesEqual(value, val) {
+			return math.Log(d.weights[i] / total)
+		}
+	}
+	return math.Inf(-1)
+}
@@ -0,0 +1,274 @@
+package searcher
+
+import (
+	"math"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/nprand"
+)
+
+// searchQuadratic runs the search method to completion on a one-dimensional quadratic with its
+// minimum at x = 1 and returns the value of x that each trial used, in order of creation.
+func searchQuadratic(t *testing.T, search SearchMethod) []float64 {
+	hparams := model.Hyperparameters{
+		"x": {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: -5, Maxval: 5}},
+	}
+	method := &recordingMethod{SearchMethod: search}
+	driver, err := newQueueDriver(method, hparams, nil)
+	assert.NilError(t, err)
+	xs := func() []float64 {
+		var xs []float64
+		for _, op := range method.ops {
+			if create, ok := op.(Create); ok {
+				xs = append(xs, create.Hparams["x"].(float64))
+			}
+		}
+		return xs
+	}
+	driver.metricsFn = func(trialIndex, validations int) map[string]interface{} {
+		x := xs()[trialIndex]
+		return map[string]interface{}{defaultMetric: (x - 1) * (x - 1)}
+	}
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+	return xs()
+}
+
+func TestTPESearcherQuadratic(t *testing.T) {
+	const maxTrials = 60
+	tpe := searchQuadratic(t, newTPESearch(model.TPEConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		MaxLength:           model.NewLengthInBatches(100),
+		MaxTrials:           maxTrials,
+		MaxConcurrentTrials: 1,
+		NumStartupTrials:    10,
+		Gamma:               0.25,
+		NumCandidates:       24,
+	}))
+	random := searchQuadratic(t, newRandomSearch(model.RandomConfig{
+		Metric:          defaultMetric,
+		SmallerIsBetter: true,
+		MaxLength:       model.NewLengthInBatches(100),
+		MaxTrials:       maxTrials,
+	}))
+	assert.Equal(t, len(tpe), maxTrials)
+	assert.Equal(t, len(random), maxTrials)
+
+	// The startup trials are sampled the same way as random search's.
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, tpe[i], random[i])
+	}
+
+	// Later proposals concentrate near the optimum, much more so than random samples.
+	distance := func(xs []float64) float64 {
+		total := 0.0
+		for _, x := range xs {
+			total += math.Abs(x - 1)
+		}
+		return total / float64(len(xs))
+	}
+	nearby := func(xs []float64) int {
+		count := 0
+		for _, x := range xs {
+			if math.Abs(x-1) < 0.5 {
+				count++
+			}
+		}
+		return count
+	}
+	late := maxTrials / 2
+	assert.Assert(t, distance(tpe[late:]) < distance(random[late:])/2,
+		"tpe: %v, random: %v", distance(tpe[late:]), distance(random[late:]))
+	assert.Assert(t, nearby(tpe[late:]) > 2*nearby(random[late:]),
+		"tpe: %d, random: %d", nearby(tpe[late:]), nearby(random[late:]))
+}
+
+func TestTPESearcherObserveExternal(t *testing.T) {
+	const maxTrials = 10
+	search := newTPESearch(model.TPEConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		MaxLength:           model.NewLengthInBatches(100),
+		MaxTrials:           maxTrials,
+		MaxConcurrentTrials: 1,
+		NumStartupTrials:    10,
+		Gamma:               0.25,
+		NumCandidates:       24,
+	}).(*tpeSearch)
+	assert.ErrorContains(t, search.ObserveExternal(
+		map[string]interface{}{"x": 0.0}, math.NaN()), "non-finite")
+	for x := -5.0; x <= 5; x += 0.5 {
+		assert.NilError(t, search.ObserveExternal(
+			map[string]interface{}{"x": x}, (x-1)*(x-1)))
+	}
+	assert.Equal(t, search.TrialsCreated, 0)
+
+	// The external results skip the startup trials: even the first proposals are near the optimum,
+	// and they do not use up any of the trials of the search.
+	xs := searchQuadratic(t, search)
+	assert.Equal(t, len(xs), maxTrials)
+	for _, x := range xs {
+		assert.Assert(t, math.Abs(x-1) < 1.5, "proposals %v", xs)
+	}
+}
+
+// restartFrequency returns the fraction of many proposals, all made from the same observations,
+// that were random restarts.
+func TestTPESearcherNonFiniteMetrics(t *testing.T) {
+	const maxTrials = 20
+	search := newTPESearch(model.TPEConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		MaxLength:           model.NewLengthInBatches(100),
+		MaxTrials:           maxTrials,
+		MaxConcurrentTrials: 1,
+		NumStartupTrials:    5,
+		Gamma:               0.25,
+		NumCandidates:       24,
+	}).(*tpeSearch)
+	hparams := model.Hyperparameters{
+		"x": {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: -5, Maxval: 5}},
+	}
+	// Every third trial diverges, alternating between NaN and infinite metrics.
+	driver, err := newQueueDriver(search, hparams, func(trialIndex, _ int) float64 {
+		switch trialIndex % 6 {
+		case 0:
+			return math.NaN()
+		case 3:
+			return math.Inf(1)
+		}
+		return float64(trialIndex)
+	})
+	assert.NilError(t, err)
+	for len(driver.pending) > 0 {
+		_, err = driver.step()
+		assert.NilError(t, err)
+	}
+
+	// The diverged trials still complete, but are not observed.
+	assert.Equal(t, search.TrialsCreated, maxTrials)
+	assert.Equal(t, search.TrialsCompleted, maxTrials)
+	assert.Equal(t, len(search.Observations), maxTrials-7)
+	for _, observation := range search.Observations {
+		assert.Assert(t, !math.IsNaN(observation.Metric) && !math.IsInf(observation.Metric, 0))
+	}
+}
+
+func restartFrequency(t *testing.T, propose func(ctx context) (hparamSample, bool, error)) float64 {
+	const proposals = 2000
+	ctx := context{rand: nprand.New(0), hparams: model.Hyperparameters{
+		"x": {DoubleHyperparameter: &model.DoubleHyperparameter{Minval: -5, Maxval: 5}},
+	}}
+	restarts := 0
+	for i := 0; i < proposals; i++ {
+		_, restart, err := propose(ctx)
+		assert.NilError(t, err)
+		if restart {
+			restarts++
+		}
+	}
+	return float64(restarts) / proposals
+}
+
+func TestTPESearcherRestarts(t *testing.T) {
+	config := model.TPEConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		MaxLength:           model.NewLengthInBatches(100),
+		MaxTrials:           30,
+		MaxConcurrentTrials: 1,
+		NumStartupTrials:    10,
+		Gamma:               0.25,
+		NumCandidates:       24,
+	}
+	for _, probability := range []float64{0, 0.1, 0.5} {
+		config.RestartProbability = probability
+		search := newTPESearch(config).(*tpeSearch)
+		for x := -5.0; x <= 5; x++ {
+			search.Observations = append(search.Observations, modelObservation{
+				Hparams: hparamSample{"x": x}, Metric: (x - 1) * (x - 1),
+			})
+		}
+		frequency := restartFrequency(t, search.propose)
+		assert.Assert(t, math.Abs(frequency-probability) < 0.03,
+			"restart probability %v, frequency %v", probability, frequency)
+	}
+
+	// Every trial after the startup trials is recorded as a restart when they all are.
+	config.RestartProbability = 1
+	search := newTPESearch(config).(*tpeSearch)
+	xs := searchQuadratic(t, search)
+	assert.Equal(t, len(xs), config.MaxTrials)
+	assert.Equal(t, len(search.Restarts), config.MaxTrials-config.NumStartupTrials)
+	for requestID := range search.Restarts {
+		_, ok := search.TrialParams[requestID]
+		assert.Assert(t, ok)
+	}
+
+	snapshot, err := search.Snapshot()
+	assert.NilError(t, err)
+	restored := newTPESearch(config).(*tpeSearch)
+	assert.NilError(t, restored.Restore(snapshot))
+	assert.DeepEqual(t, restored.Restarts, search.Restarts)
+}
+
+func TestTPESearcherLoadPrior(t *testing.T) {
+	config := model.TPEConfig{
+		Metric:              defaultMetric,
+		SmallerIsBetter:     true,
+		MaxLength:           model.NewLengthInBatches(100),
+		MaxTrials:           10,
+		MaxConcurrentTrials: 1,
+		NumStartupTrials:    10,
+		Gamma:               0.25,
+		NumCandidates:       24,
+		PriorWeight:         0.5,
+	}
+	var prior []Observation
+	for x := -5.0; x <= 5; x += 0.5 {
+		prior = append(prior, Observation{
+			Hparams: map[string]interface{}{"x": x}, Metric: (x - 1) * (x - 1),
+		})
+	}
+	coldStart := searchQuadratic(t, newTPESearch(config))
+
+	search := newTPESearch(config).(*tpeSearch)
+	assert.ErrorContains(t, search.LoadPrior([]Observation{
+		prior[0], {Hparams: map[string]interface{}{"x": 0.0}, Metric: math.NaN()},
+	}), "non-finite")
+	assert.Equal(t, len(search.Observations), 0)
+	assert.NilError(t, search.LoadPrior(prior))
+	warmStart := searchQuadratic(t, search)
+
+	// The 21 prior observations count for more than the startup trials, so even the first
+	// proposals come from the densities and concentrate near the optimum, unlike the random
+	// startup trials of a cold start.
+	assert.Equal(t, len(warmStart), config.MaxTrials)
+	for i := 0; i < 3; i++ {
+		assert.Assert(t, warmStart[i] != coldStart[i], "trial %d", i)
+	}
+	distance := func(xs []float64) float64 {
+		total := 0.0
+		for _, x := range xs {
+			total += math.Abs(x - 1)
+		}
+		return total / float64(len(xs))
+	}
+	assert.Assert(t, distance(warmStart) < distance(coldStart)/2,
+		"warm start: %v, cold start: %v", warmStart, coldStart)
+
+	// With a smaller weight, the prior only counts for about five of the startup trials, so the
+	// first five trials are sampled as in a cold start and the rest are proposed.
+	config.PriorWeight = 0.25
+	search = newTPESearch(config).(*tpeSearch)
+	assert.NilError(t, search.LoadPrior(prior))
+	lightPrior := searchQuadratic(t, search)
+	assert.DeepEqual(t, lightPrior[:5], coldStart[:5])
+	assert.Assert(t, lightPrior[5] != coldStart[5])
+}
@@ -50,7 +50,7 @@ func checkSimulation(
 	validation ValidationFunction,
 	expected [][]Runnable,
 ) {
-	search := NewSearcher(0, method, params)
+	search := NewSearcher(0, method, params, nil)
 	actual, err := Simulate(search, new(int64), validation, true, defaultMetric)
 	assert.NilError(t, err)
 
@@ -77,8 +77,8 @@ func checkReproducibility(
 	t assert.TestingT, methodGen func() SearchMethod, hparams model.Hyperparameters, metric string,
 ) {
 	seed := int64(17)
-	searcher1 := NewSearcher(uint32(seed), methodGen(), hparams)
-	searcher2 := NewSearcher(uint32(seed), methodGen(), hparams)
+	searcher1 := NewSearcher(uint32(seed), methodGen(), hparams, nil)
+	searcher2 := NewSearcher(uint32(seed), methodGen(), hparams, nil)
 
 	results1, err1 := Simulate(searcher1, &seed, ConstantValidation, true, metric)
 	assert.NilError(t, err1)
@@ -320,8 +320,9 @@ func runValueSimulationTestCases(t *testing.T, testCases []valueSimulationTestCa
 	for _, testCase := range testCases {
 		tc := testCase
 		t.Run(tc.name, func(t *testing.T) {
-			method := NewSearchMethod(tc.config)
-			err := checkValueSimulation(t, method, tc.hparams, tc.expectedTrials)
+			method, err := NewSearchMethod(tc.config)
+			assert.NilError(t, err)
+			err = checkValueSimulation(t, method, tc.hparams, tc.expectedTrials)
 			assert.NilError(t, err)
 		})
 	}
@@ -351,7 +352,7 @@ func simulateOperationComplete(
 		}
 
 		if trial.EarlyExit != nil && opIndex == *trial.EarlyExit {
-			ops, err = method.trialExitedEarly(ctx, operation.RequestID)
+			ops, err = method.trialExitedEarly(ctx, operation.RequestID, Errored)
 		} else {
 			ops, err = method.trainCompleted(ctx, operation.RequestID, operation)
 		}
@@ -390,3 +391,169 @@ func simulateOperationComplete(
 
 	return ops, nil
 }
+
+// recordingMethod wraps a SearchMethod and records every operation it returns.
+type recordingMethod struct {
+	SearchMethod
+	ops []Operation
+}
+
+func (r *recordingMethod) record(ops []Operation, err error) ([]Operation, error) {
+	r.ops = append(r.ops, ops...)
+	return ops, err
+}
+
+func (r *recordingMethod) initialOperations(ctx context) ([]Operation, error) {
+	return r.record(r.SearchMethod.initialOperations(ctx))
+}
+
+func (r *recordingMethod) trialCreated(ctx context, requestID RequestID) ([]Operation, error) {
+	return r.record(r.SearchMethod.trialCreated(ctx, requestID))
+}
+
+func (r *recordingMethod) trainCompleted(
+	ctx context, requestID RequestID, train Train,
+) ([]Operation, error) {
+	return r.record(r.SearchMethod.trainCompleted(ctx, requestID, train))
+}
+
+func (r *recordingMethod) checkpointCompleted(
+	ctx context, requestID RequestID, checkpoint Checkpoint, metrics CheckpointMetrics,
+) ([]Operation, error) {
+	return r.record(r.SearchMethod.checkpointCompleted(ctx, requestID, checkpoint, metrics))
+}
+
+func (r *recordingMethod) validationCompleted(
+	ctx context, requestID RequestID, validate Validate, metrics ValidationMetrics,
+) ([]Operation, error) {
+	return r.record(r.SearchMethod.validationCompleted(ctx, requestID, validate, metrics))
+}
+
+func (r *recordingMethod) trialClosed(ctx context, requestID RequestID) ([]Operation, error) {
+	return r.record(r.SearchMethod.trialClosed(ctx, requestID))
+}
+
+func (r *recordingMethod) trialExitedEarly(
+	ctx context, requestID RequestID, reason ExitedReason,
+) ([]Operation, error) {
+	return r.record(r.SearchMethod.trialExitedEarly(ctx, requestID, reason))
+}
+
+// closeCounts returns the number of Close operations recorded for each request ID.
+func (r *recordingMethod) closeCounts() map[RequestID]int {
+	counts := make(map[RequestID]int)
+	for _, op := range r.ops {
+		if op, ok := op.(Close); ok {
+			counts[op.RequestID]++
+		}
+	}
+	return counts
+}
+
+// queueDriver feeds the operations returned by a search method back into it in FIFO order. Each
+// completed validation reports the metric returned by metricFn for the index of the trial (in
+// order of creation) and the number of validations the trial has previously completed, unless
+// metricsFn is set, in which case it reports the metrics metricsFn returns. If exitFn is set and
+// returns true, the trial exits early instead of completing the validation.
+type queueDriver struct {
+	ctx       context
+	method    SearchMethod
+	metricFn  func(trialIndex, validations int) float64
+	metricsFn func(trialIndex, validations int) map[string]interface{}
+	exitFn    func(trialIndex, validations int) bool
+	// exitReason is the reason trials exit early for when exitFn says so; it defaults to Errored.
+	exitReason ExitedReason
+
+	pending     []Operation
+	trialIndex  map[RequestID]int
+	validations map[RequestID]int
+}
+
+func newQueueDriver(
+	method SearchMethod,
+	hparams model.Hyperparameters,
+	metricFn func(trialIndex, validations int) float64,
+) (*queueDriver, error) {
+	d := &queueDriver{
+		ctx:         context{rand: nprand.New(0), hparams: hparams},
+		method:      method,
+		metricFn:    metricFn,
+		trialIndex:  make(map[RequestID]int),
+		validations: make(map[RequestID]int),
+	}
+	ops, err := method.initialOperations(d.ctx)
+	d.pending = ops
+	return d, err
+}
+
+// clone returns a driver with the same pending operations and random state that drives method.
+func (d *queueDriver) clone(method SearchMethod) *queueDriver {
+	rand := *d.ctx.rand
+	clone := &queueDriver{
+		ctx:         context{rand: &rand, hparams: d.ctx.hparams},
+		method:      method,
+		metricFn:    d.metricFn,
+		metricsFn:   d.metricsFn,
+		exitFn:      d.exitFn,
+		exitReason:  d.exitReason,
+		pending:     append([]Operation{}, d.pending...),
+		trialIndex:  make(map[RequestID]int),
+		validations: make(map[RequestID]int),
+	}
+	for requestID, index := range d.trialIndex {
+		clone.trialIndex[requestID] = index
+	}
+	for requestID, validations := range d.validations {
+		clone.validations[requestID] = validations
+	}
+	return clone
+}
+
+// step handles the next pending operation and returns the operations it generated.
+func (d *queueDriver) step() ([]Operation, error) {
+	if len(d.pending) == 0 {
+		return nil, errors.New("no pending operations")
+	}
+	operation := d.pending[0]
+	d.pending = d.pending[1:]
+
+	var ops []Operation
+	var err error
+	switch operation := operation.(type) {
+	case Create:
+		d.trialIndex[operation.RequestID] = len(d.trialIndex)
+		ops, err = d.method.trialCreated(d.ctx, operation.RequestID)
+	case Train:
+		ops, err = d.method.trainCompleted(d.ctx, operation.RequestID, operation)
+	case Validate:
+		trialIndex, validations := d.trialIndex[operation.RequestID], d.validations[operation.RequestID]
+		if d.exitFn != nil && d.exitFn(trialIndex, validations) {
+			reason := d.exitReason
+			if reason == "" {
+				reason = Errored
+			}
+			ops, err = d.method.trialExitedEarly(d.ctx, operation.RequestID, reason)
+			break
+		}
+		var metrics ValidationMetrics
+		if d.metricsFn != nil {
+			metrics.Metrics = d.metricsFn(trialIndex, validations)
+		} else {
+			metrics.Metrics = map[string]interface{}{
+				defaultMetric: d.metricFn(trialIndex, validations),
+			}
+		}
+		d.validations[operation.RequestID]++
+		ops, err = d.method.validationCompleted(d.ctx, operation.RequestID, operation, metrics)
+	case Checkpoint:
+		ops, err = d.method.checkpointCompleted(
+			d.ctx, operation.RequestID, operation, CheckpointMetrics{})
+	case Close:
+		ops, err = d.method.trialClosed(d.ctx, operation.RequestID)
+	case Shutdown:
+	default:
+		return nil, errors.Errorf("unexpected operation: %T", operation)
+	}
+	d.pending = append(d.pending, ops...)
+	return ops, err
+}
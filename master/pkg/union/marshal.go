@@ -65,11 +65,20 @@ func Marshal(v interface{}) ([]byte, error) {
 			jsonTagValue = field.Name
 			fallthrough
 		default:
-			if strings.Contains(jsonTagValue, ",") {
+			name, options := jsonTagValue, ""
+			if index := strings.Index(jsonTagValue, ","); index >= 0 {
+				name, options = jsonTagValue[:index], jsonTagValue[index+1:]
+			}
+			switch {
+			case options == "omitempty":
+				if value.Field(i).IsZero() {
+					continue
+				}
+			case options != "":
 				return nil, errors.New(
 					"advanced json tag features not support in union type marshaling")
 			}
-			data[jsonTagValue] = value.Field(i).Interface()
+			data[name] = value.Field(i).Interface()
 		}
 	}
 
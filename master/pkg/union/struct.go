@@ -44,3 +44,29 @@ func parseUnionTypes(v reflect.Type) (unionTypes, error) {
 	}
 	return types, nil
 }
+
+// Defines returns whether the provided union type (a pointer to a struct) has a union field for
+// the given key and union type value.
+func Defines(v interface{}, key, value string) (bool, error) {
+	unionTypes, err := parseUnionTypes(reflect.TypeOf(v).Elem())
+	if err != nil {
+		return false, err
+	}
+	_, ok := unionTypes[key][value]
+	return ok, nil
+}
+
+// Clear sets every union field of the provided union type (a pointer to a struct) to nil.
+func Clear(v interface{}) error {
+	value := reflect.ValueOf(v).Elem()
+	unionTypes, err := parseUnionTypes(value.Type())
+	if err != nil {
+		return err
+	}
+	for _, fields := range unionTypes {
+		for _, field := range fields {
+			value.Field(field.index).Set(reflect.Zero(field.field.Type))
+		}
+	}
+	return nil
+}